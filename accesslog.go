@@ -0,0 +1,85 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Common/Combined Log Format, as written by Apache and by nginx's default
+// "combined" log_format. An optional trailing number is read as the request
+// duration: seconds when it has a decimal point (nginx $request_time),
+// microseconds otherwise (Apache %D).
+var accessLogRegex = regexp.MustCompile(
+	`^(\S+) (\S+) (\S+) \[([^\]]+)\] "((?:[^"\\]|\\.)*)" (\d{3}) (\d+|-)` +
+		`(?: "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)")?` +
+		`(?: (\d+(?:\.\d+)?))?$`)
+
+const accessLogTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// parseAccessLog parses a CLF/combined access log line into an HTTP entry.
+func parseAccessLog(line string) (LogEntry, bool) {
+	m := accessLogRegex.FindStringSubmatch(line)
+	if m == nil {
+		return LogEntry{}, false
+	}
+
+	ts, err := time.Parse(accessLogTimeLayout, m[4])
+	if err != nil {
+		return LogEntry{}, false
+	}
+
+	var entry LogEntry
+	entry.Timestamp = ts.Format(time.RFC3339)
+	entry.Category = "http"
+	entry.Source.IP = m[1]
+	if m[3] != "-" {
+		entry.User.Name = m[3]
+	}
+
+	// Request line: METHOD URI PROTOCOL
+	request := strings.Fields(m[5])
+	if len(request) >= 2 {
+		entry.HTTP.Request.Method = request[0]
+		path, query, _ := strings.Cut(request[1], "?")
+		entry.URL.Path = path
+		entry.URL.Query = query
+	}
+	if len(request) >= 3 {
+		entry.HTTP.Version = strings.TrimPrefix(request[2], "HTTP/")
+	}
+	if entry.HTTP.Request.Method == "" {
+		// Malformed or empty request line ("-"), keep it visible
+		entry.Message = m[5]
+	}
+
+	entry.HTTP.Response.StatusCode, _ = strconv.Atoi(m[6])
+	if m[7] != "-" {
+		entry.HTTP.Response.Body.Bytes, _ = strconv.Atoi(m[7])
+	}
+	if m[9] != "-" {
+		entry.UserAgent.Original = strings.ReplaceAll(m[9], `\"`, `"`)
+	}
+
+	if m[10] != "" {
+		if strings.Contains(m[10], ".") {
+			seconds, _ := strconv.ParseFloat(m[10], 64)
+			entry.Event.Duration = int64(seconds * float64(time.Second))
+		} else {
+			micros, _ := strconv.ParseInt(m[10], 10, 64)
+			entry.Event.Duration = micros * int64(time.Microsecond)
+		}
+	}
+
+	switch status := entry.HTTP.Response.StatusCode; {
+	case status >= 500:
+		entry.Level = "error"
+	case status >= 400:
+		entry.Level = "warn"
+	default:
+		entry.Level = "info"
+	}
+
+	return entry, true
+}
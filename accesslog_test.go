@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAccessLog(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantOK    bool
+		method    string
+		path      string
+		query     string
+		status    int
+		bytes     int
+		userAgent string
+		duration  time.Duration
+		level     string
+	}{
+		{
+			name:   "common log format",
+			input:  `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`,
+			wantOK: true,
+			method: "GET",
+			path:   "/apache_pb.gif",
+			status: 200,
+			bytes:  2326,
+			level:  "info",
+		},
+		{
+			name:      "nginx combined",
+			input:     `203.0.113.42 - - [28/Jun/2025:11:50:00 +0000] "POST /api/users?page=2 HTTP/1.1" 503 0 "-" "curl/8.7.1"`,
+			wantOK:    true,
+			method:    "POST",
+			path:      "/api/users",
+			query:     "page=2",
+			status:    503,
+			userAgent: "curl/8.7.1",
+			level:     "error",
+		},
+		{
+			name:      "nginx request_time suffix",
+			input:     `10.0.0.1 - - [28/Jun/2025:11:50:00 +0000] "GET /health HTTP/1.1" 404 12 "https://example.com/" "kube-probe/1.31" 0.250`,
+			wantOK:    true,
+			method:    "GET",
+			path:      "/health",
+			status:    404,
+			bytes:     12,
+			userAgent: "kube-probe/1.31",
+			duration:  250 * time.Millisecond,
+			level:     "warn",
+		},
+		{
+			name:      "apache microseconds suffix",
+			input:     `10.0.0.1 - - [28/Jun/2025:11:50:00 +0000] "GET / HTTP/1.1" 200 - "-" "Mozilla/5.0" 1500`,
+			wantOK:    true,
+			method:    "GET",
+			path:      "/",
+			status:    200,
+			userAgent: "Mozilla/5.0",
+			duration:  1500 * time.Microsecond,
+			level:     "info",
+		},
+		{
+			name:   "not an access log",
+			input:  `hello world`,
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, ok := parseAccessLog(tt.input)
+			if ok != tt.wantOK {
+				t.Fatalf("parseAccessLog() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if entry.Category != "http" {
+				t.Errorf("Category = %q, want http", entry.Category)
+			}
+			if entry.HTTP.Request.Method != tt.method {
+				t.Errorf("Method = %q, want %q", entry.HTTP.Request.Method, tt.method)
+			}
+			if entry.URL.Path != tt.path {
+				t.Errorf("Path = %q, want %q", entry.URL.Path, tt.path)
+			}
+			if entry.URL.Query != tt.query {
+				t.Errorf("Query = %q, want %q", entry.URL.Query, tt.query)
+			}
+			if entry.HTTP.Response.StatusCode != tt.status {
+				t.Errorf("StatusCode = %d, want %d", entry.HTTP.Response.StatusCode, tt.status)
+			}
+			if entry.HTTP.Response.Body.Bytes != tt.bytes {
+				t.Errorf("Bytes = %d, want %d", entry.HTTP.Response.Body.Bytes, tt.bytes)
+			}
+			if entry.UserAgent.Original != tt.userAgent {
+				t.Errorf("UserAgent = %q, want %q", entry.UserAgent.Original, tt.userAgent)
+			}
+			if time.Duration(entry.Event.Duration) != tt.duration {
+				t.Errorf("Duration = %v, want %v", time.Duration(entry.Event.Duration), tt.duration)
+			}
+			if entry.Level != tt.level {
+				t.Errorf("Level = %q, want %q", entry.Level, tt.level)
+			}
+		})
+	}
+}
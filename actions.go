@@ -0,0 +1,92 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// ActionsCommand is a GitHub Actions runner log line carrying a command:
+// the ##[error] annotations of downloaded job logs, or the ::error:: and
+// ::group:: workflow commands printed by steps.
+type ActionsCommand struct {
+	// Command is e.g. "error", "warning", "group" or "endgroup".
+	Command string
+	// Location is where an annotation points, e.g. "app.js:12".
+	Location string
+}
+
+// actionsLineRegex matches a runner log line, with its optional timestamp.
+var actionsLineRegex = regexp.MustCompile(`^(?:\x{FEFF})?(?:(\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(?:\.\d+)?Z) )?(?:##\[(\w+)\]|::(\w+)(?: ([^:]*))?::)(.*)$`)
+
+// actionsLevels are the levels of the commands, others being info.
+var actionsLevels = map[string]string{
+	"error": "error", "warning": "warn", "notice": "notice", "debug": "debug",
+}
+
+// parseActionsLine recognizes a GitHub Actions runner log line with a
+// command.
+func parseActionsLine(line string) (LogEntry, bool) {
+	if !strings.Contains(line, "##[") && !strings.Contains(line, "::") {
+		return LogEntry{}, false
+	}
+	m := actionsLineRegex.FindStringSubmatch(line)
+	if m == nil {
+		return LogEntry{}, false
+	}
+	command := m[2]
+	if command == "" {
+		command = m[3]
+	}
+	switch command {
+	case "error", "warning", "notice", "debug", "group", "endgroup", "section", "command":
+	default:
+		return LogEntry{}, false
+	}
+	level, ok := actionsLevels[command]
+	if !ok {
+		level = "info"
+	}
+	return LogEntry{
+		Timestamp: m[1],
+		Level:     level,
+		Message:   m[5],
+		Actions:   &ActionsCommand{Command: command, Location: actionsLocation(m[4])},
+	}, true
+}
+
+// actionsLocation reads the file and line of an annotation's parameters,
+// e.g. "file=app.js,line=12,col=5".
+func actionsLocation(params string) string {
+	values := map[string]string{}
+	for _, param := range strings.Split(params, ",") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(param), "="); ok {
+			values[key] = value
+		}
+	}
+	location := values["file"]
+	if line := values["line"]; location != "" && line != "" {
+		location += ":" + line
+	}
+	return location
+}
+
+var actionsGroupPaint = newPaint(color.FgHiWhite, color.Bold)
+
+// actionsLine renders the body of a runner command: groups as headings,
+// commands dimmed, and annotations with where they point, e.g.
+// "▸ Run npm test" or "Missing semicolon app.js:12".
+func actionsLine(command *ActionsCommand, message string) string {
+	switch command.Command {
+	case "group", "section":
+		return actionsGroupPaint.sprint("▸ " + message)
+	case "command":
+		return labelPaint.sprint("$ " + message)
+	}
+	line := messagePaint.sprint(message)
+	if command.Location != "" {
+		line += " " + labelPaint.sprint(command.Location)
+	}
+	return line
+}
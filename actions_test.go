@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestParseActionsLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		ok      bool
+		level   string
+		message string
+		command ActionsCommand
+		time    string
+	}{
+		{"group in job log", "2024-01-15T14:02:11.1234567Z ##[group]Run npm test", true, "info", "Run npm test", ActionsCommand{Command: "group"}, "2024-01-15T14:02:11.1234567Z"},
+		{"error in job log", "2024-01-15T14:02:13.1234567Z ##[error]Process completed with exit code 1.", true, "error", "Process completed with exit code 1.", ActionsCommand{Command: "error"}, "2024-01-15T14:02:13.1234567Z"},
+		{"workflow command with location", "::warning file=app.js,line=12,col=5::Missing semicolon", true, "warn", "Missing semicolon", ActionsCommand{Command: "warning", Location: "app.js:12"}, ""},
+		{"endgroup", "::endgroup::", true, "info", "", ActionsCommand{Command: "endgroup"}, ""},
+		{"unknown command", "::set-output name=x::1", false, "", "", ActionsCommand{}, ""},
+		{"C++ scope", "std::vector::size", false, "", "", ActionsCommand{}, ""},
+		{"plain runner output", "2024-01-15T14:02:11.2234567Z npm test", false, "", "", ActionsCommand{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, ok := parseActionsLine(tt.line)
+			if ok != tt.ok {
+				t.Fatalf("parseActionsLine() ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if entry.Level != tt.level || entry.Message != tt.message || entry.Timestamp != tt.time || *entry.Actions != tt.command {
+				t.Errorf("parseActionsLine() = level %q, message %q, time %q, %+v", entry.Level, entry.Message, entry.Timestamp, *entry.Actions)
+			}
+		})
+	}
+}
+
+func TestActionsLine(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	tests := []struct {
+		command ActionsCommand
+		want    string
+	}{
+		{ActionsCommand{Command: "group"}, "▸ msg"},
+		{ActionsCommand{Command: "command"}, "$ msg"},
+		{ActionsCommand{Command: "error", Location: "app.js:12"}, "msg app.js:12"},
+	}
+	for _, tt := range tests {
+		if got := actionsLine(&tt.command, "msg"); got != tt.want {
+			t.Errorf("actionsLine(%+v) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
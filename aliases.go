@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// FieldAlias maps a field of the user's logs onto the field logpipe reads,
+// e.g. severity_level onto log.level. Unit scales a duration given in
+// another unit than event.duration's nanoseconds.
+type FieldAlias struct {
+	Field string `json:"field"`
+	Unit  string `json:"unit"`
+}
+
+// UnmarshalJSON accepts the target field name alone as a shorthand.
+func (a *FieldAlias) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		a.Unit = ""
+		return json.Unmarshal(data, &a.Field)
+	}
+	type plainAlias FieldAlias
+	return json.Unmarshal(data, (*plainAlias)(a))
+}
+
+// durationUnits are the units accepted for event.duration aliases, in
+// nanoseconds.
+var durationUnits = map[string]float64{
+	"ns": 1,
+	"us": 1e3,
+	"µs": 1e3,
+	"ms": 1e6,
+	"s":  1e9,
+}
+
+// flatEntryKeys are the LogEntry fields whose JSON name contains a dot, so
+// they are written as one key instead of nested objects.
+var flatEntryKeys = map[string]bool{"log.level": true}
+
+// fieldAliases holds the aliases from the config, keyed by source field.
+var fieldAliases = map[string]FieldAlias{}
+
+// addFieldAliases registers field aliases from the config.
+func addFieldAliases(aliases map[string]FieldAlias) error {
+	for source, alias := range aliases {
+		if alias.Field == "" {
+			return fmt.Errorf("field alias %q: missing target field", source)
+		}
+		if alias.Unit != "" {
+			if alias.Field != "event.duration" {
+				return fmt.Errorf("field alias %q: a unit is only supported for event.duration", source)
+			}
+			if _, ok := durationUnits[alias.Unit]; !ok {
+				return fmt.Errorf("field alias %q: unknown unit %q (expected ns, us, ms or s)", source, alias.Unit)
+			}
+		}
+		fieldAliases[source] = alias
+	}
+	return nil
+}
+
+// aliasFields copies aliased fields of a decoded JSON object to the fields
+// logpipe reads. A target already present in the entry is left alone. It
+// returns false when no alias applied.
+func aliasFields(fields map[string]interface{}) bool {
+	changed := false
+	for _, source := range sortedKeys(fieldAliases) {
+		alias := fieldAliases[source]
+		value, ok := lookupField(fields, source)
+		if !ok || value == nil {
+			continue
+		}
+		if _, exists := lookupField(fields, alias.Field); exists {
+			continue
+		}
+		if alias.Unit != "" {
+			if value, ok = scaleDuration(value, durationUnits[alias.Unit]); !ok {
+				continue
+			}
+		}
+		if setEntryField(fields, alias.Field, value) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// scaleDuration converts a number, or a string holding one, to whole
+// nanoseconds.
+func scaleDuration(value interface{}, nanoseconds float64) (interface{}, bool) {
+	var f float64
+	switch v := value.(type) {
+	case json.Number:
+		n, err := v.Float64()
+		if err != nil {
+			return nil, false
+		}
+		f = n
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, false
+		}
+		f = n
+	default:
+		return nil, false
+	}
+	return int64(math.Round(f * nanoseconds)), true
+}
+
+// setEntryField writes value at a dotted path in the shape LogEntry
+// expects, creating nested objects as needed. It returns false when a
+// non-object value is in the way.
+func setEntryField(fields map[string]interface{}, path string, value interface{}) bool {
+	if flatEntryKeys[path] {
+		fields[path] = value
+		return true
+	}
+	keys := strings.Split(path, ".")
+	parent := fields
+	for _, key := range keys[:len(keys)-1] {
+		next, exists := parent[key]
+		if !exists {
+			nested := map[string]interface{}{}
+			parent[key] = nested
+			parent = nested
+			continue
+		}
+		nested, ok := next.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		parent = nested
+	}
+	parent[keys[len(keys)-1]] = value
+	return true
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFieldAliases(t *testing.T) {
+	var config Config
+	err := json.Unmarshal([]byte(`{"field_aliases":{
+		"severity_level": "log.level",
+		"req.path": "url.path",
+		"latency_us": {"field": "event.duration", "unit": "us"}
+	}}`), &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := fieldAliases
+	fieldAliases = map[string]FieldAlias{}
+	defer func() { fieldAliases = saved }()
+	if err := addFieldAliases(config.FieldAliases); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		line         string
+		wantLevel    string
+		wantPath     string
+		wantDuration int64
+	}{
+		{"nested source", `{"severity_level":"warn","req":{"path":"/users"},"latency_us":1500}`, "warn", "/users", 1500000},
+		{"flattened source", `{"severity_level":"error","req.path":"/orders","latency_us":"2.5"}`, "error", "/orders", 2500},
+		{"entry fields win", `{"log.level":"info","severity_level":"error","url":{"path":"/a"},"req.path":"/b"}`, "info", "/a", 0},
+		{"bad duration ignored", `{"latency_us":"slow"}`, "", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, ok := parseLine(tt.line)
+			if !ok {
+				t.Fatal("parseLine() failed")
+			}
+			if entry.Level != tt.wantLevel || entry.URL.Path != tt.wantPath || entry.Event.Duration != tt.wantDuration {
+				t.Errorf("entry = level %q, path %q, duration %d, want %q, %q, %d",
+					entry.Level, entry.URL.Path, entry.Event.Duration, tt.wantLevel, tt.wantPath, tt.wantDuration)
+			}
+		})
+	}
+}
+
+func TestAddFieldAliasesErrors(t *testing.T) {
+	tests := map[string]FieldAlias{
+		"no target":     {},
+		"unit on level": {Field: "log.level", Unit: "ms"},
+		"unknown unit":  {Field: "event.duration", Unit: "weeks"},
+	}
+	for name, alias := range tests {
+		if err := addFieldAliases(map[string]FieldAlias{"x": alias}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
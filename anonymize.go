@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// anonymizedFields are the fields --anonymize pseudonymizes by path, with
+// the kind of pseudonym used. Emails and IPs are also found in any string.
+var anonymizedFields = map[string]string{
+	"user.id":                   "user",
+	"user.name":                 "user",
+	"user.full_name":            "user",
+	"user_id":                   "user",
+	"client.user.id":            "user",
+	"host":                      "host",
+	"host.name":                 "host",
+	"host.hostname":             "host",
+	"hostname":                  "host",
+	"agent.hostname":            "host",
+	"observer.hostname":         "host",
+	"log.syslog.hostname":       "host",
+	"kubernetes.node.name":      "host",
+	"url.domain":                "host",
+	"source.domain":             "host",
+	"destination.domain":        "host",
+	"client.domain":             "host",
+	"server.domain":             "host",
+	"http.request.headers.host": "host",
+}
+
+// pseudonymPath is a field whose value is replaced by a pseudonym.
+type pseudonymPath struct {
+	path    []string
+	replace func(interface{}) interface{}
+}
+
+// pseudonymizer derives stable pseudonyms from values with a keyed hash,
+// so the same user, host or IP always gets the same pseudonym and entries
+// can still be correlated, while the key keeps small spaces such as IPv4
+// addresses from being reversed by brute force.
+type pseudonymizer struct {
+	key []byte
+}
+
+// newPseudonymizer uses key, or a random key when empty, giving pseudonyms
+// that are only stable for one run.
+func newPseudonymizer(key string) (*pseudonymizer, error) {
+	if key != "" {
+		return &pseudonymizer{key: []byte(key)}, nil
+	}
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	return &pseudonymizer{key: random}, nil
+}
+
+func (p *pseudonymizer) hash(kind, value string) []byte {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(kind + ":" + value))
+	return mac.Sum(nil)
+}
+
+// pseudonym returns the pseudonym of a value of the given kind, keeping its
+// shape: IPs stay IPs (in 10.0.0.0/8 or fd00::/8) and emails stay emails.
+func (p *pseudonymizer) pseudonym(kind, value string) string {
+	sum := p.hash(kind, strings.ToLower(value))
+	short := hex.EncodeToString(sum[:3])
+	switch kind {
+	case "ip":
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return value
+		}
+		if ip.To4() != nil {
+			return fmt.Sprintf("10.%d.%d.%d", sum[0], sum[1], sum[2])
+		}
+		anon := make(net.IP, net.IPv6len)
+		anon[0] = 0xfd
+		copy(anon[1:], sum[:15])
+		return anon.String()
+	case "email":
+		return "user-" + short + "@example.invalid"
+	}
+	return kind + "-" + short
+}
+
+// replaceWith returns a field replacement giving strings and numbers their
+// pseudonym. Objects and lists are left alone, and so are IPs and emails,
+// which the detectors replace wherever they appear.
+func (p *pseudonymizer) replaceWith(kind string) func(interface{}) interface{} {
+	return func(value interface{}) interface{} {
+		switch v := value.(type) {
+		case string:
+			if v == "" || net.ParseIP(v) != nil || redactDetectors["emails"].re.MatchString(v) {
+				return v
+			}
+			return p.pseudonym(kind, v)
+		case json.Number:
+			return p.pseudonym(kind, v.String())
+		}
+		return value
+	}
+}
+
+// newAnonymizer returns a redactor for --anonymize, pseudonymizing users,
+// hosts, emails and IPs instead of masking them.
+func newAnonymizer(key string) (*redactor, error) {
+	p, err := newPseudonymizer(key)
+	if err != nil {
+		return nil, err
+	}
+	r := &redactor{}
+	for _, path := range sortedKeys(anonymizedFields) {
+		r.pseudonyms = append(r.pseudonyms, pseudonymPath{
+			path:    strings.Split(strings.ToLower(path), "."),
+			replace: p.replaceWith(anonymizedFields[path]),
+		})
+	}
+	r.detectors = []redactDetector{
+		{re: redactDetectors["emails"].re, replace: func(match string) string { return p.pseudonym("email", match) }},
+		{re: redactDetectors["ips"].re, replace: func(match string) string { return p.pseudonym("ip", match) }},
+	}
+	return r, nil
+}
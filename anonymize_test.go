@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestAnonymizeLine(t *testing.T) {
+	r, err := newAnonymizer("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := newPseudonymizer("key")
+
+	tests := []struct {
+		name string
+		line string
+		want map[string]string
+	}{
+		{
+			name: "fields and detectors",
+			line: `{"message":"login by bob@corp.com from 203.0.113.7","user":{"id":42,"name":"bob"},"host":{"name":"web-1"},"source":{"ip":"203.0.113.7"}}`,
+			want: map[string]string{
+				"message":   "login by " + p.pseudonym("email", "bob@corp.com") + " from " + p.pseudonym("ip", "203.0.113.7"),
+				"user.id":   p.pseudonym("user", "42"),
+				"user.name": p.pseudonym("user", "bob"),
+				"host.name": p.pseudonym("host", "web-1"),
+				"source.ip": p.pseudonym("ip", "203.0.113.7"),
+			},
+		},
+		{
+			name: "ip in a host field keeps its IP pseudonym",
+			line: `{"hostname":"203.0.113.7","client.domain":"Example.COM"}`,
+			want: map[string]string{
+				"hostname":      p.pseudonym("ip", "203.0.113.7"),
+				"client.domain": p.pseudonym("host", "example.com"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := decodeJSONObject(r.redactLine(tt.line))
+			for path, want := range tt.want {
+				if got := fieldString(fields, path); got != want {
+					t.Errorf("%s = %q, want %q", path, got, want)
+				}
+			}
+		})
+	}
+
+	if got := r.redactLine("plain line from 10.0.0.1"); strings.Contains(got, "10.0.0.1") {
+		t.Errorf("redactLine() of a plain line = %q", got)
+	}
+}
+
+func TestPseudonym(t *testing.T) {
+	p, _ := newPseudonymizer("key")
+	other, _ := newPseudonymizer("other")
+
+	if a, b := p.pseudonym("user", "bob"), p.pseudonym("user", "bob"); a != b {
+		t.Errorf("pseudonym() not stable: %q, %q", a, b)
+	}
+	if a, b := p.pseudonym("user", "bob"), other.pseudonym("user", "bob"); a == b {
+		t.Errorf("pseudonym() doesn't depend on the key: %q", a)
+	}
+	if got := p.pseudonym("user", "bob"); !strings.HasPrefix(got, "user-") || len(got) != len("user-")+6 {
+		t.Errorf("pseudonym(user) = %q", got)
+	}
+	if got := p.pseudonym("ip", "203.0.113.7"); !strings.HasPrefix(got, "10.") {
+		t.Errorf("pseudonym(ipv4) = %q", got)
+	}
+	if got := p.pseudonym("ip", "2001:db8::1"); !strings.HasPrefix(got, "fd") {
+		t.Errorf("pseudonym(ipv6) = %q", got)
+	}
+	if got := p.pseudonym("email", "bob@corp.com"); !strings.HasSuffix(got, "@example.invalid") {
+		t.Errorf("pseudonym(email) = %q", got)
+	}
+
+	random1, _ := newPseudonymizer("")
+	random2, _ := newPseudonymizer("")
+	if random1.pseudonym("user", "bob") == random2.pseudonym("user", "bob") {
+		t.Error("runs without a key share pseudonyms")
+	}
+
+	replace := p.replaceWith("user")
+	if got := replace(json.Number("7")); got != p.pseudonym("user", "7") {
+		t.Errorf("replace(7) = %v", got)
+	}
+	if got := replace(map[string]interface{}{}); got == nil {
+		t.Error("replace() dropped an object")
+	}
+}
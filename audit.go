@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// renderSchema is set by --schema to render entries with a schema's
+// profile even when they don't declare it: "audit" renders every entry
+// with an action as an audit event.
+var renderSchema = ""
+
+// renderSchemas are the values --schema accepts outside `logpipe lint`.
+var renderSchemas = []string{"audit"}
+
+func checkSchema(schema string) error {
+	if schema == "" || slices.Contains(renderSchemas, schema) {
+		return nil
+	}
+	return fmt.Errorf("unknown schema %q (expected audit)", schema)
+}
+
+// AuditEvent is an audit-style entry: who (the actor) did what (the
+// action) to what (the target), and whether it was allowed.
+type AuditEvent struct {
+	Actor, Action, Target string
+	// Outcome is "success", "failure", another logged value, or empty.
+	Outcome string
+	// Time is when the event happened, for entries without @timestamp.
+	Time string
+}
+
+var (
+	auditActorFields = []string{
+		"user.name", "user.email", "user.username", "user.id",
+		"actor.name", "actor.email", "actor.id", "actor", "principal",
+		"userIdentity.arn", "userIdentity.userName",
+		"protoPayload.authenticationInfo.principalEmail",
+	}
+	auditActionFields = []string{
+		"event.action", "action", "verb", "eventName", "protoPayload.methodName",
+	}
+	auditTargetFields = []string{
+		"target.name", "target.id", "target", "object.name", "object",
+		"resource.name", "entity", "protoPayload.resourceName",
+	}
+	auditOutcomeFields = []string{"event.outcome", "outcome", "result", "decision"}
+	auditTimeFields    = []string{"eventTime", "requestReceivedTimestamp", "timestamp", "time"}
+)
+
+// isAuditEntry tells whether an entry declares itself an audit event: an
+// ECS event.category of audit, a Kubernetes audit event, a GCP audit log
+// or an AWS CloudTrail record.
+func isAuditEntry(fields map[string]interface{}) bool {
+	if categories, _ := lookupField(fields, "event.category"); hasCategory(categories, "audit") {
+		return true
+	}
+	return strings.HasPrefix(fieldString(fields, "apiVersion"), "audit.k8s.io/") ||
+		strings.HasSuffix(fieldString(fields, "protoPayload.@type"), ".AuditLog") ||
+		fieldString(fields, "eventName") != "" && fieldString(fields, "eventSource") != ""
+}
+
+// parseAudit extracts the actor, action, target and outcome of an audit
+// event from a JSON log line, returning nil for other entries or, under
+// --schema audit, for entries without an action.
+func parseAudit(line *lineFields) *AuditEvent {
+	if renderSchema != "audit" && !strings.Contains(line.text, "audit") && !strings.Contains(line.text, "eventName") {
+		return nil
+	}
+	fields := line.object()
+	if fields == nil || renderSchema != "audit" && !isAuditEntry(fields) {
+		return nil
+	}
+	event := &AuditEvent{
+		Actor:   fieldString(fields, auditActorFields...),
+		Action:  fieldString(fields, auditActionFields...),
+		Target:  fieldString(fields, auditTargetFields...),
+		Outcome: normalizeOutcome(fieldString(fields, auditOutcomeFields...)),
+		Time:    fieldString(fields, auditTimeFields...),
+	}
+	if event.Action == "" {
+		return nil
+	}
+	if event.Target == "" {
+		event.Target = kubernetesObjectRef(fields)
+	}
+	if event.Outcome == "" {
+		event.Outcome = auditStatusOutcome(fields)
+	}
+	return event
+}
+
+// kubernetesObjectRef names the object of a Kubernetes audit event, e.g.
+// "pods default/web-1".
+func kubernetesObjectRef(fields map[string]interface{}) string {
+	resource := fieldString(fields, "objectRef.resource")
+	if resource == "" {
+		return ""
+	}
+	if sub := fieldString(fields, "objectRef.subresource"); sub != "" {
+		resource += "/" + sub
+	}
+	name := fieldString(fields, "objectRef.name")
+	if ns := fieldString(fields, "objectRef.namespace"); ns != "" && name != "" {
+		name = ns + "/" + name
+	}
+	return strings.TrimSpace(resource + " " + name)
+}
+
+// auditStatusOutcome derives the outcome of events that log a status
+// instead: Kubernetes response codes, GCP status codes and CloudTrail
+// error codes.
+func auditStatusOutcome(fields map[string]interface{}) string {
+	if code := fieldString(fields, "responseStatus.code"); code != "" {
+		if n, err := strconv.Atoi(code); err == nil && n >= 400 {
+			return "failure"
+		}
+		return "success"
+	}
+	if code := fieldString(fields, "protoPayload.status.code"); code != "" && code != "0" {
+		return "failure"
+	}
+	if fieldString(fields, "errorCode") != "" {
+		return "failure"
+	}
+	if fieldString(fields, "eventSource", "protoPayload.methodName") != "" {
+		return "success"
+	}
+	return ""
+}
+
+// normalizeOutcome maps the usual words for allowed and denied actions to
+// ECS's success and failure.
+func normalizeOutcome(outcome string) string {
+	switch strings.ToLower(outcome) {
+	case "success", "succeeded", "successful", "ok", "allow", "allowed", "permit", "granted":
+		return "success"
+	case "failure", "failed", "fail", "error", "deny", "denied", "forbidden", "rejected":
+		return "failure"
+	}
+	return outcome
+}
+
+var (
+	auditActorPaint  = newPaint(color.FgCyan, color.Bold)
+	auditTargetPaint = newPaint(color.FgMagenta)
+)
+
+var (
+	auditSuccessPaint = newPaint(color.FgGreen)
+	auditFailurePaint = newPaint(color.FgRed, color.Bold)
+	auditUnknownPaint = newPaint(color.FgYellow)
+)
+
+// auditOutcomePaint colors an action and its outcome: green when allowed,
+// bold red when denied, yellow otherwise.
+func auditOutcomePaint(outcome string) paint {
+	switch outcome {
+	case "success":
+		return auditSuccessPaint
+	case "failure":
+		return auditFailurePaint
+	}
+	return auditUnknownPaint
+}
+
+// auditSentence renders who did what to what, e.g.
+// "alice@example.com repo.delete acme/api → failure".
+func auditSentence(event *AuditEvent) string {
+	actor := event.Actor
+	if actor == "" {
+		actor = "unknown"
+	}
+	outcome := auditOutcomePaint(event.Outcome)
+	var b strings.Builder
+	b.WriteString(auditActorPaint.sprint(actor) + " " + outcome.sprint(event.Action))
+	if event.Target != "" {
+		b.WriteString(" " + auditTargetPaint.sprint(event.Target))
+	}
+	if event.Outcome != "" {
+		b.WriteString(" " + outcome.sprint("→ "+event.Outcome))
+	}
+	return b.String()
+}
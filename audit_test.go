@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestParseAudit(t *testing.T) {
+	saved := renderSchema
+	defer func() { renderSchema = saved }()
+
+	tests := []struct {
+		name   string
+		schema string
+		line   string
+		want   *AuditEvent
+	}{
+		{
+			"ECS audit category",
+			"",
+			`{"event":{"category":["audit"],"action":"repo.delete","outcome":"failure"},"user":{"email":"alice@example.com"},"target":{"name":"acme/api"}}`,
+			&AuditEvent{Actor: "alice@example.com", Action: "repo.delete", Target: "acme/api", Outcome: "failure"},
+		},
+		{
+			"Kubernetes audit",
+			"",
+			`{"apiVersion":"audit.k8s.io/v1","kind":"Event","verb":"delete","user":{"username":"bob"},"objectRef":{"resource":"pods","namespace":"default","name":"web-1"},"responseStatus":{"code":403},"requestReceivedTimestamp":"2024-01-15T14:03:00Z"}`,
+			&AuditEvent{Actor: "bob", Action: "delete", Target: "pods default/web-1", Outcome: "failure", Time: "2024-01-15T14:03:00Z"},
+		},
+		{
+			"GCP audit log",
+			"",
+			`{"protoPayload":{"@type":"type.googleapis.com/google.cloud.audit.AuditLog","methodName":"storage.buckets.delete","resourceName":"projects/_/buckets/logs","authenticationInfo":{"principalEmail":"carol@example.com"}}}`,
+			&AuditEvent{Actor: "carol@example.com", Action: "storage.buckets.delete", Target: "projects/_/buckets/logs", Outcome: "success"},
+		},
+		{
+			"CloudTrail error",
+			"",
+			`{"eventTime":"2024-01-15T14:04:00Z","eventSource":"s3.amazonaws.com","eventName":"DeleteBucket","userIdentity":{"arn":"arn:aws:iam::1:user/carol"},"errorCode":"AccessDenied"}`,
+			&AuditEvent{Actor: "arn:aws:iam::1:user/carol", Action: "DeleteBucket", Outcome: "failure", Time: "2024-01-15T14:04:00Z"},
+		},
+		{"undeclared", "", `{"action":"login","user":{"name":"dave"},"outcome":"ok"}`, nil},
+		{"forced by --schema audit", "audit", `{"action":"login","user":{"name":"dave"},"outcome":"ok"}`, &AuditEvent{Actor: "dave", Action: "login", Outcome: "success"}},
+		{"without action", "audit", `{"message":"hello","user":{"name":"dave"}}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			renderSchema = tt.schema
+			got := parseAudit(newLineFields([]byte(tt.line)))
+			if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+				t.Errorf("parseAudit() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditSentence(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	tests := []struct {
+		event AuditEvent
+		want  string
+	}{
+		{AuditEvent{Actor: "alice", Action: "repo.delete", Target: "acme/api", Outcome: "failure"}, "alice repo.delete acme/api → failure"},
+		{AuditEvent{Action: "login"}, "unknown login"},
+	}
+	for _, tt := range tests {
+		if got := auditSentence(&tt.event); got != tt.want {
+			t.Errorf("auditSentence(%+v) = %q, want %q", tt.event, got, tt.want)
+		}
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	for schema, ok := range map[string]bool{"": true, "audit": true, "ecs": false} {
+		if err := checkSchema(schema); (err == nil) != ok {
+			t.Errorf("checkSchema(%q) = %v", schema, err)
+		}
+	}
+}
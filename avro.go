@@ -0,0 +1,343 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// avroType is a resolved node of an Avro schema.
+type avroType struct {
+	kind     string
+	logical  string
+	name     string
+	fields   []avroField
+	symbols  []string
+	items    *avroType
+	branches []*avroType
+	size     int
+}
+
+type avroField struct {
+	name string
+	typ  *avroType
+}
+
+// avroSchema decodes Avro binary-encoded records of its root type.
+type avroSchema struct {
+	root *avroType
+}
+
+func (s *avroSchema) Decode(data []byte) (map[string]interface{}, error) {
+	r := &avroReader{data: data}
+	value, err := r.decode(s.root)
+	if err != nil {
+		return nil, err
+	}
+	record, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("schema root is not a record")
+	}
+	return record, nil
+}
+
+// parseAvroSchema parses an .avsc JSON schema.
+func parseAvroSchema(src []byte) (*avroSchema, error) {
+	var raw interface{}
+	if err := json.Unmarshal(src, &raw); err != nil {
+		return nil, err
+	}
+	p := &avroSchemaParser{named: make(map[string]*avroType)}
+	root, err := p.parse(raw, "")
+	if err != nil {
+		return nil, err
+	}
+	if root.kind != "record" {
+		return nil, errors.New("schema root must be a record")
+	}
+	return &avroSchema{root: root}, nil
+}
+
+type avroSchemaParser struct {
+	named map[string]*avroType
+}
+
+var avroPrimitives = map[string]bool{
+	"null": true, "boolean": true, "int": true, "long": true,
+	"float": true, "double": true, "bytes": true, "string": true,
+}
+
+func (p *avroSchemaParser) parse(raw interface{}, namespace string) (*avroType, error) {
+	switch v := raw.(type) {
+	case string:
+		if avroPrimitives[v] {
+			return &avroType{kind: v}, nil
+		}
+		if t, ok := p.named[qualify(namespace, v)]; ok {
+			return t, nil
+		}
+		if t, ok := p.named[v]; ok {
+			return t, nil
+		}
+		return nil, fmt.Errorf("unknown type %q", v)
+	case []interface{}:
+		union := &avroType{kind: "union"}
+		for _, branch := range v {
+			t, err := p.parse(branch, namespace)
+			if err != nil {
+				return nil, err
+			}
+			union.branches = append(union.branches, t)
+		}
+		return union, nil
+	case map[string]interface{}:
+		return p.parseComplex(v, namespace)
+	}
+	return nil, fmt.Errorf("invalid schema node %v", raw)
+}
+
+func (p *avroSchemaParser) parseComplex(v map[string]interface{}, namespace string) (*avroType, error) {
+	kind, _ := v["type"].(string)
+	logical, _ := v["logicalType"].(string)
+
+	switch kind {
+	case "record", "error", "enum", "fixed":
+		name, _ := v["name"].(string)
+		if name == "" {
+			return nil, fmt.Errorf("%s without a name", kind)
+		}
+		if ns, ok := v["namespace"].(string); ok && !strings.Contains(name, ".") {
+			namespace = ns
+		}
+		if strings.Contains(name, ".") {
+			namespace = name[:strings.LastIndex(name, ".")]
+			name = name[strings.LastIndex(name, ".")+1:]
+		}
+		t := &avroType{kind: kind, logical: logical, name: qualify(namespace, name)}
+		if kind == "error" {
+			t.kind = "record"
+		}
+		// Register before descending so records can refer to themselves
+		p.named[t.name] = t
+		p.named[name] = t
+
+		switch kind {
+		case "enum":
+			symbols, _ := v["symbols"].([]interface{})
+			for _, s := range symbols {
+				symbol, _ := s.(string)
+				t.symbols = append(t.symbols, symbol)
+			}
+		case "fixed":
+			size, _ := v["size"].(float64)
+			t.size = int(size)
+		default:
+			fields, _ := v["fields"].([]interface{})
+			for _, f := range fields {
+				field, _ := f.(map[string]interface{})
+				fieldName, _ := field["name"].(string)
+				if jsonName, ok := field["json_name"].(string); ok {
+					fieldName = jsonName
+				}
+				fieldType, err := p.parse(field["type"], namespace)
+				if err != nil {
+					return nil, fmt.Errorf("field %s: %w", fieldName, err)
+				}
+				t.fields = append(t.fields, avroField{name: fieldName, typ: fieldType})
+			}
+		}
+		return t, nil
+	case "array":
+		items, err := p.parse(v["items"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &avroType{kind: "array", items: items}, nil
+	case "map":
+		values, err := p.parse(v["values"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &avroType{kind: "map", items: values}, nil
+	}
+
+	// Primitive type with attributes, e.g. {"type":"long","logicalType":...}
+	t, err := p.parse(v["type"], namespace)
+	if err != nil {
+		return nil, err
+	}
+	if logical != "" {
+		annotated := *t
+		annotated.logical = logical
+		return &annotated, nil
+	}
+	return t, nil
+}
+
+type avroReader struct {
+	data []byte
+	pos  int
+}
+
+var errAvroTruncated = errors.New("truncated avro record")
+
+// maxAvroEmptyItems bounds the items of a block beyond what the rest of the
+// record could hold, so a corrupt count can't keep decoding empty items.
+const maxAvroEmptyItems = 1 << 16
+
+func (r *avroReader) long() (int64, error) {
+	v, n := binary.Varint(r.data[r.pos:])
+	if n <= 0 {
+		return 0, errAvroTruncated
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *avroReader) bytes(n int) ([]byte, error) {
+	if n < 0 || n > len(r.data)-r.pos {
+		return nil, errAvroTruncated
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *avroReader) decode(t *avroType) (interface{}, error) {
+	switch t.kind {
+	case "null":
+		return nil, nil
+	case "boolean":
+		b, err := r.bytes(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0] != 0, nil
+	case "int", "long":
+		v, err := r.long()
+		if err != nil {
+			return nil, err
+		}
+		switch t.logical {
+		case "timestamp-millis":
+			return time.UnixMilli(v).UTC().Format(time.RFC3339Nano), nil
+		case "timestamp-micros":
+			return time.UnixMicro(v).UTC().Format(time.RFC3339Nano), nil
+		}
+		return v, nil
+	case "float":
+		b, err := r.bytes(4)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(b)), nil
+	case "double":
+		b, err := r.bytes(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case "bytes", "string":
+		size, err := r.long()
+		if err != nil {
+			return nil, err
+		}
+		b, err := r.bytes(int(size))
+		if err != nil {
+			return nil, err
+		}
+		if t.kind == "string" {
+			return string(b), nil
+		}
+		return b, nil
+	case "fixed":
+		return r.bytes(t.size)
+	case "enum":
+		idx, err := r.long()
+		if err != nil {
+			return nil, err
+		}
+		if idx < 0 || int(idx) >= len(t.symbols) {
+			return nil, fmt.Errorf("%s: enum index %d out of range", t.name, idx)
+		}
+		return t.symbols[idx], nil
+	case "union":
+		idx, err := r.long()
+		if err != nil {
+			return nil, err
+		}
+		if idx < 0 || int(idx) >= len(t.branches) {
+			return nil, fmt.Errorf("union index %d out of range", idx)
+		}
+		return r.decode(t.branches[idx])
+	case "record":
+		record := make(map[string]interface{}, len(t.fields))
+		for _, field := range t.fields {
+			value, err := r.decode(field.typ)
+			if err != nil {
+				return nil, err
+			}
+			record[field.name] = value
+		}
+		return record, nil
+	case "array":
+		var list []interface{}
+		err := r.blocks(func() error {
+			value, err := r.decode(t.items)
+			list = append(list, value)
+			return err
+		})
+		return list, err
+	case "map":
+		m := make(map[string]interface{})
+		err := r.blocks(func() error {
+			size, err := r.long()
+			if err != nil {
+				return err
+			}
+			key, err := r.bytes(int(size))
+			if err != nil {
+				return err
+			}
+			value, err := r.decode(t.items)
+			m[string(key)] = value
+			return err
+		})
+		return m, err
+	}
+	return nil, fmt.Errorf("unsupported avro type %s", t.kind)
+}
+
+// blocks iterates over the items of an array or map, which are encoded as a
+// series of counted blocks terminated by an empty one.
+func (r *avroReader) blocks(item func() error) error {
+	for {
+		count, err := r.long()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+		if count < 0 {
+			// Negative counts are followed by the block size in bytes
+			count = -count
+			if _, err := r.long(); err != nil {
+				return err
+			}
+		}
+		// Items take a byte or more, except nulls and empty records
+		if count < 0 || count > int64(len(r.data)-r.pos) && count > maxAvroEmptyItems {
+			return fmt.Errorf("block of %d items is longer than the record", count)
+		}
+		for i := int64(0); i < count; i++ {
+			if err := item(); err != nil {
+				return err
+			}
+		}
+	}
+}
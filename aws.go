@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// loadAWSConfig loads the AWS SDK's default configuration: the region and
+// credentials of the AWS_* variables, else of the profiles and SSO sessions
+// in ~/.aws, else of the container or instance role. Credentials are
+// resolved on first use and refreshed as they expire. The region defaults
+// to us-east-1.
+func loadAWSConfig(ctx context.Context) (aws.Config, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("loading the AWS configuration: %w", err)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return cfg, nil
+}
+
+// checkAWSCredentials fails with advice when no credentials resolve, rather
+// than letting requests go out unsigned.
+func checkAWSCredentials(ctx context.Context, cfg aws.Config) error {
+	if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+		return fmt.Errorf("no AWS credentials found: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, "+
+			"pick a profile with AWS_PROFILE, or run `aws sso login` (%w)", err)
+	}
+	return nil
+}
+
+// awsSigner signs requests to AWS APIs with the credentials of
+// loadAWSConfig. Requests go unsigned when none resolve, which public S3
+// buckets accept.
+type awsSigner struct {
+	region string
+	// credentials is nil when none resolved, and unsigned tells why.
+	credentials aws.CredentialsProvider
+	unsigned    error
+	// endpoint replaces the AWS endpoint, for compatible services or
+	// LocalStack.
+	endpoint string
+	now      func() time.Time
+}
+
+// awsCredentialsProbeTimeout bounds how long newAWSSigner looks for
+// credentials.
+const awsCredentialsProbeTimeout = 2 * time.Second
+
+func newAWSSigner(ctx context.Context) (awsSigner, error) {
+	cfg, err := loadAWSConfig(ctx)
+	if err != nil {
+		return awsSigner{}, err
+	}
+	signer := awsSigner{
+		region:   cfg.Region,
+		endpoint: strings.TrimSuffix(os.Getenv("AWS_ENDPOINT_URL"), "/"),
+		now:      time.Now,
+	}
+	// Public buckets need no credentials: don't wait long on the instance
+	// role of a machine that has none
+	probe, cancel := context.WithTimeout(ctx, awsCredentialsProbeTimeout)
+	defer cancel()
+	if err := checkAWSCredentials(probe, cfg); err != nil {
+		signer.unsigned = err
+	} else {
+		signer.credentials = cfg.Credentials
+	}
+	return signer, nil
+}
+
+// sign adds an AWS Signature Version 4 for service to a request whose body
+// is payload.
+func (c awsSigner) sign(req *http.Request, service string, payload []byte) error {
+	if c.credentials == nil {
+		return nil
+	}
+	creds, err := c.credentials.Retrieve(req.Context())
+	if err != nil {
+		return fmt.Errorf("refreshing AWS credentials: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+	payloadHash := hex.EncodeToString(sum[:])
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	signer := v4.NewSigner(func(o *v4.SignerOptions) {
+		// S3 signs object keys as they are sent rather than escaped again
+		o.DisableURIPathEscaping = service == "s3"
+	})
+	return signer.SignHTTP(req.Context(), creds, req, payloadHash, service, c.region, c.now())
+}
+
+// escapeKey percent-encodes an object key for a URL path: everything but
+// unreserved characters and slashes, as object stores expect.
+func escapeKey(key string) string {
+	var out strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			out.WriteByte(c)
+		default:
+			fmt.Fprintf(&out, "%%%02X", c)
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// closingWriter writes to stdout and calls closed once the reader has gone
+// away, e.g. after `logpipe | head`, so logpipe can stop instead of reading
+// input it can no longer show.
+type closingWriter struct {
+	w      io.Writer
+	closed func()
+}
+
+func (w closingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	if err != nil && isBrokenPipe(err) {
+		w.closed()
+	}
+	return n, err
+}
+
+// shedQueueSize is how many lines may wait for a slow output before --shed
+// starts dropping.
+const shedQueueSize = 4096
+
+type queuedLine struct {
+	line, label, stream string
+}
+
+// loadShedder decouples reading input from rendering it. While the output
+// keeps up, every line is rendered; once shedQueueSize lines are waiting,
+// entries at or below a level are dropped instead of stalling the input.
+type loadShedder struct {
+	level   int
+	queue   chan queuedLine
+	dropped atomic.Int64
+	done    chan struct{}
+}
+
+// newLoadShedder renders queued lines with handle and calls report with
+// the number of entries dropped since the last report.
+func newLoadShedder(level string, handle func(line, label, stream string), report func(dropped int)) (*loadShedder, error) {
+	index := slices.Index(canonicalLevels, normalizeLevel(level))
+	if index < 0 {
+		return nil, fmt.Errorf("unknown level %q (expected one of %s)", level, strings.Join(canonicalLevels, ", "))
+	}
+	s := &loadShedder{level: index, queue: make(chan queuedLine, shedQueueSize), done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		for l := range s.queue {
+			handle(l.line, l.label, l.stream)
+			if n := s.dropped.Swap(0); n > 0 {
+				report(int(n))
+			}
+		}
+		if n := s.dropped.Swap(0); n > 0 {
+			report(int(n))
+		}
+	}()
+	return s, nil
+}
+
+// add queues a line, dropping it if the queue is full and it is an entry
+// at or below the level. Other lines wait for room.
+func (s *loadShedder) add(line, label, stream string) {
+	l := queuedLine{line, label, stream}
+	select {
+	case s.queue <- l:
+		return
+	default:
+	}
+	if s.droppable(line) {
+		s.dropped.Add(1)
+		return
+	}
+	s.queue <- l
+}
+
+// droppable reports whether a line is an entry at or below the level.
+// Unparseable lines, such as stack traces, are kept.
+func (s *loadShedder) droppable(line string) bool {
+	entry, ok := parseLine(line)
+	if !ok {
+		return false
+	}
+	index := slices.Index(canonicalLevels, normalizeLevel(entry.Level))
+	return index >= 0 && index <= s.level
+}
+
+// close waits for the queued lines to be rendered. It may be called on a
+// nil loadShedder.
+func (s *loadShedder) close() {
+	if s == nil {
+		return
+	}
+	close(s.queue)
+	<-s.done
+}
+
+// droppedNotice is shown where entries were dropped by --shed.
+func droppedNotice(n int) string {
+	noun := "entries"
+	if n == 1 {
+		noun = "entry"
+	}
+	return labelColor.Sprintf("· %d %s dropped, output too slow", n, noun)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+)
+
+// brokenPipe returns the writing end of a pipe whose reader is closed.
+func brokenPipe(t *testing.T) *os.File {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	t.Cleanup(func() { w.Close() })
+	return w
+}
+
+type failingWriter struct{ err error }
+
+func (w failingWriter) Write(p []byte) (int, error) { return 0, w.err }
+
+func TestClosingWriter(t *testing.T) {
+	tests := []struct {
+		name       string
+		w          io.Writer
+		wantClosed bool
+	}{
+		{"ok", &bytes.Buffer{}, false},
+		{"broken pipe", brokenPipe(t), true},
+		{"other error", failingWriter{os.ErrClosed}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			closed := false
+			w := closingWriter{w: tt.w, closed: func() { closed = true }}
+			fmt.Fprintln(w, "entry")
+			if closed != tt.wantClosed {
+				t.Errorf("closed = %v, want %v", closed, tt.wantClosed)
+			}
+		})
+	}
+}
+
+func TestLoadShedder(t *testing.T) {
+	release := make(chan struct{})
+	handling := make(chan struct{}, 1)
+	var handled []string
+	var dropped int
+	shed, err := newLoadShedder("info", func(line, label, stream string) {
+		select {
+		case handling <- struct{}{}:
+		default:
+		}
+		<-release
+		handled = append(handled, line)
+	}, func(n int) {
+		dropped += n
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One line is being handled and the queue is full after these
+	shed.add(`{"log.level":"debug","message":"handled"}`, "", "")
+	<-handling
+	for i := 0; i < shedQueueSize; i++ {
+		shed.add(`{"log.level":"debug","message":"queued"}`, "", "")
+	}
+	for _, line := range []string{
+		`{"log.level":"debug","message":"dropped"}`,
+		`{"log.level":"info","message":"dropped"}`,
+	} {
+		shed.add(line, "", "")
+	}
+
+	kept := []string{`{"log.level":"error","message":"kept"}`, "panic: kept"}
+	added := make(chan struct{})
+	go func() {
+		for _, line := range kept {
+			shed.add(line, "", "")
+		}
+		close(added)
+	}()
+	close(release)
+	<-added
+	shed.close()
+
+	if want := shedQueueSize + 1 + len(kept); len(handled) != want {
+		t.Errorf("handled %d lines, want %d", len(handled), want)
+	}
+	if handled[len(handled)-1] != "panic: kept" {
+		t.Errorf("last line = %q, want the unparseable line", handled[len(handled)-1])
+	}
+	if dropped != 2 {
+		t.Errorf("dropped = %d, want 2", dropped)
+	}
+}
+
+func TestNewLoadShedderUnknownLevel(t *testing.T) {
+	if _, err := newLoadShedder("loud", func(line, label, stream string) {}, func(int) {}); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
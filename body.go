@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const defaultBodyLimit = 2048
+
+// showBodies and maxBodyBytes are set by --bodies and --body-limit.
+var (
+	showBodies   = false
+	maxBodyBytes = defaultBodyLimit
+)
+
+// writeBodies prints the http.request.body.content and
+// http.response.body.content of an entry indented under it. Bodies are
+// redacted along with the rest of the line when --redact is used.
+func writeBodies(w io.Writer, log LogEntry) {
+	bodies := []struct {
+		name    string
+		content interface{}
+	}{
+		{"request body", log.HTTP.Request.Body.Content},
+		{"response body", log.HTTP.Response.Body.Content},
+	}
+
+	for _, body := range bodies {
+		text := formatBody(body.content)
+		if text == "" {
+			continue
+		}
+		writeBlock(w, body.name, truncateBody(text, maxBodyBytes))
+	}
+}
+
+// writeBlock prints a titled, indented block of text under an entry.
+func writeBlock(w io.Writer, name, text string) {
+	fmt.Fprintf(w, "  %s\n", labelPaint.sprint(name+":"))
+	for _, line := range strings.Split(text, "\n") {
+		fmt.Fprintf(w, "    %s\n", messagePaint.sprint(line))
+	}
+}
+
+// formatBody pretty-prints JSON bodies, whether logged as objects or as
+// JSON strings, and returns other text as is.
+func formatBody(content interface{}) string {
+	switch v := content.(type) {
+	case nil:
+		return ""
+	case string:
+		text := strings.TrimSpace(v)
+		var buf bytes.Buffer
+		if json.Indent(&buf, []byte(text), "", "  ") == nil {
+			return buf.String()
+		}
+		return text
+	}
+	out, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return fmt.Sprint(content)
+	}
+	return string(out)
+}
+
+// truncateBody cuts text to at most limit bytes on a character boundary and
+// notes how much was left out. A limit of 0 or less disables truncation.
+func truncateBody(text string, limit int) string {
+	if limit <= 0 || len(text) <= limit {
+		return text
+	}
+	kept := truncateBytes(text, limit)
+	return fmt.Sprintf("%s... (%d more bytes)", kept, len(text)-len(kept))
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestFormatBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		content interface{}
+		want    string
+	}{
+		{"nil", nil, ""},
+		{"json string", `{"id":1,"tags":["a"]}`, "{\n  \"id\": 1,\n  \"tags\": [\n    \"a\"\n  ]\n}"},
+		{"plain text", "  hello world\n", "hello world"},
+		{"object", map[string]interface{}{"ok": true}, "{\n  \"ok\": true\n}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatBody(tt.content); got != tt.want {
+				t.Errorf("formatBody() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateBody(t *testing.T) {
+	tests := []struct {
+		text  string
+		limit int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"abcdefghij", 4, "abcd... (6 more bytes)"},
+		{"héllo", 2, "h... (5 more bytes)"},
+		{"unlimited", 0, "unlimited"},
+	}
+
+	for _, tt := range tests {
+		if got := truncateBody(tt.text, tt.limit); got != tt.want {
+			t.Errorf("truncateBody(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestWriteBodies(t *testing.T) {
+	color.NoColor = true
+	var log LogEntry
+	line := `{"message":"created","http":{"request":{"body":{"content":"{\"name\":\"bob\"}"}},"response":{"body":{"content":{"id":7}}}}}`
+	if err := json.Unmarshal([]byte(line), &log); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	writeBodies(&buf, log)
+	want := strings.Join([]string{
+		"  request body:",
+		"    {",
+		`      "name": "bob"`,
+		"    }",
+		"  response body:",
+		"    {",
+		`      "id": 7`,
+		"    }",
+		"",
+	}, "\n")
+	if buf.String() != want {
+		t.Errorf("writeBodies() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// BuildKitStatus is a status update printed by BuildKit with
+// `docker buildx build --progress=rawjson` (or `buildctl --progress
+// rawjson`): build steps (vertexes) starting and completing, and their
+// output.
+type BuildKitStatus struct {
+	Vertexes []BuildKitVertex `json:"vertexes"`
+	Logs     []BuildKitLog    `json:"logs"`
+}
+
+// BuildKitVertex is a build step, e.g. "[2/3] RUN make".
+type BuildKitVertex struct {
+	Digest    string     `json:"digest"`
+	Name      string     `json:"name"`
+	Started   *time.Time `json:"started"`
+	Completed *time.Time `json:"completed"`
+	Cached    bool       `json:"cached"`
+	Error     string     `json:"error"`
+}
+
+// BuildKitLog is output of a build step; Data is base64 in the JSON.
+type BuildKitLog struct {
+	Vertex    string    `json:"vertex"`
+	Data      []byte    `json:"data"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// parseBuildKit recognizes a BuildKit status update, returning nil for
+// other log lines.
+func parseBuildKit(line *lineFields) *BuildKitStatus {
+	if !strings.Contains(line.text, `"vertexes"`) && !strings.Contains(line.text, `"logs"`) {
+		return nil
+	}
+	// Only lines with the fields of a status update are decoded into one
+	fields := line.object()
+	if fields == nil || fields["vertexes"] == nil && fields["logs"] == nil && fields["statuses"] == nil {
+		return nil
+	}
+	var status struct {
+		BuildKitStatus
+		Statuses json.RawMessage `json:"statuses"`
+	}
+	if json.Unmarshal([]byte(line.text), &status) != nil {
+		return nil
+	}
+	// Other entries may have logs; BuildKit's always point at a vertex
+	for _, l := range status.Logs {
+		if l.Vertex == "" {
+			return nil
+		}
+	}
+	if len(status.Vertexes) == 0 && len(status.Logs) == 0 && status.Statuses == nil {
+		return nil
+	}
+	return &status.BuildKitStatus
+}
+
+var (
+	buildKitDoneColor  = color.New(color.FgGreen)
+	buildKitErrorColor = color.New(color.FgRed, color.Bold)
+	buildKitStepColor  = color.New(color.FgHiWhite, color.Bold)
+)
+
+// buildKitView renders BuildKit status updates as a build log: a line
+// when a step starts and when it completes, its output in between, each
+// shown once although BuildKit repeats steps in many updates.
+type buildKitView struct {
+	names     map[string]string
+	started   map[string]bool
+	completed map[string]bool
+	// partial holds output of a step not yet ended by a newline.
+	partial map[string]string
+}
+
+func newBuildKitView() *buildKitView {
+	return &buildKitView{
+		names:     make(map[string]string),
+		started:   make(map[string]bool),
+		completed: make(map[string]bool),
+		partial:   make(map[string]string),
+	}
+}
+
+// buildKit holds the state of the build being rendered.
+var buildKit = newBuildKitView()
+
+func (v *buildKitView) write(w io.Writer, status *BuildKitStatus) {
+	for _, vertex := range status.Vertexes {
+		if vertex.Name != "" {
+			v.names[vertex.Digest] = vertex.Name
+		}
+		if vertex.Completed != nil && !v.completed[vertex.Digest] {
+			v.completed[vertex.Digest] = true
+			v.writeCompleted(w, vertex)
+		} else if vertex.Started != nil && vertex.Completed == nil && !v.started[vertex.Digest] {
+			v.started[vertex.Digest] = true
+			fmt.Fprintf(w, "%s %s %s\n", plainTimestampColor.Sprint(vertex.Started.Format("15:04:05.000")), buildKitStepColor.Sprint("▸"), buildKitStepColor.Sprint(vertex.Name))
+		}
+	}
+	for _, l := range status.Logs {
+		text := v.partial[l.Vertex] + string(l.Data)
+		lines := strings.Split(text, "\n")
+		v.partial[l.Vertex] = lines[len(lines)-1]
+		for _, line := range lines[:len(lines)-1] {
+			fmt.Fprintf(w, "%s   %s %s\n", plainTimestampColor.Sprint(l.Timestamp.Format("15:04:05.000")), labelColor.Sprint(v.stepLabel(l.Vertex)+" │"), strings.TrimRight(line, "\r"))
+		}
+	}
+}
+
+func (v *buildKitView) writeCompleted(w io.Writer, vertex BuildKitVertex) {
+	timestamp := plainTimestampColor.Sprint(vertex.Completed.Format("15:04:05.000"))
+	if rest := v.partial[vertex.Digest]; rest != "" {
+		delete(v.partial, vertex.Digest)
+		fmt.Fprintf(w, "%s   %s %s\n", timestamp, labelColor.Sprint(v.stepLabel(vertex.Digest)+" │"), rest)
+	}
+	switch {
+	case vertex.Error != "":
+		fmt.Fprintf(w, "%s %s %s %s\n", timestamp, buildKitErrorColor.Sprint("✗"), buildKitErrorColor.Sprint(vertex.Name), buildKitErrorColor.Sprint(vertex.Error))
+	case vertex.Cached:
+		fmt.Fprintf(w, "%s %s %s\n", timestamp, labelColor.Sprint("CACHED"), labelColor.Sprint(vertex.Name))
+	default:
+		elapsed := ""
+		if vertex.Started != nil {
+			elapsed = " " + labelColor.Sprintf("%.1fs", vertex.Completed.Sub(*vertex.Started).Seconds())
+		}
+		fmt.Fprintf(w, "%s %s %s%s\n", timestamp, buildKitDoneColor.Sprint("✓"), vertex.Name, elapsed)
+	}
+}
+
+// stepLabel names a step in front of its output: its "[2/3]" position
+// when it has one, else its name.
+func (v *buildKitView) stepLabel(digest string) string {
+	name := v.names[digest]
+	if strings.HasPrefix(name, "[") {
+		if end := strings.Index(name, "]"); end > 0 {
+			return name[:end+1]
+		}
+	}
+	if name == "" {
+		return truncateWidth(strings.TrimPrefix(digest, "sha256:"), 12)
+	}
+	return truncateWidth(name, 30)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestParseBuildKit(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		ok   bool
+	}{
+		{"vertexes", `{"vertexes":[{"digest":"sha256:a","name":"[1/2] FROM alpine"}]}`, true},
+		{"logs", `{"logs":[{"vertex":"sha256:a","stream":1,"data":"aGk=","timestamp":"2024-01-15T14:02:13Z"}]}`, true},
+		{"statuses only", `{"statuses":[{"id":"layer","vertex":"sha256:a"}],"logs":[]}`, true},
+		{"other logs field", `{"message":"x","logs":["a"]}`, false},
+		{"logs without vertex", `{"message":"x","logs":[{"text":"a"}]}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseBuildKit(newLineFields([]byte(tt.line))); (got != nil) != tt.ok {
+				t.Errorf("parseBuildKit() = %+v, want recognized %v", got, tt.ok)
+			}
+		})
+	}
+}
+
+func TestBuildKitView(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	lines := []string{
+		`{"vertexes":[{"digest":"sha256:a","name":"[1/2] FROM alpine","started":"2024-01-15T14:02:11Z","completed":"2024-01-15T14:02:11Z","cached":true},{"digest":"sha256:b","name":"[2/2] RUN make","started":"2024-01-15T14:02:12Z"}]}`,
+		`{"vertexes":[{"digest":"sha256:b","name":"[2/2] RUN make","started":"2024-01-15T14:02:12Z"}],"logs":[{"vertex":"sha256:b","data":"b25lCnR3","timestamp":"2024-01-15T14:02:13Z"}]}`,
+		`{"logs":[{"vertex":"sha256:b","data":"bwo=","timestamp":"2024-01-15T14:02:14Z"}]}`,
+		`{"vertexes":[{"digest":"sha256:b","name":"[2/2] RUN make","started":"2024-01-15T14:02:12Z","completed":"2024-01-15T14:02:20Z","error":"exit code: 2"}]}`,
+	}
+	want := "14:02:11.000 CACHED [1/2] FROM alpine\n" +
+		"14:02:12.000 ▸ [2/2] RUN make\n" +
+		"14:02:13.000   [2/2] │ one\n" +
+		"14:02:14.000   [2/2] │ two\n" +
+		"14:02:20.000 ✗ [2/2] RUN make exit code: 2\n"
+
+	v := newBuildKitView()
+	var out bytes.Buffer
+	for _, line := range lines {
+		status := parseBuildKit(newLineFields([]byte(line)))
+		if status == nil {
+			t.Fatalf("%s not recognized", line)
+		}
+		v.write(&out, status)
+	}
+	if got := out.String(); got != want {
+		t.Errorf("output =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionHeader is the first record of a session bundle, describing how
+// it was captured.
+type sessionHeader struct {
+	Logpipe string    `json:"logpipe"`
+	Started time.Time `json:"started"`
+	Args    []string  `json:"args"`
+}
+
+// secretFlags are flags whose values are masked in session headers.
+var secretFlags = []string{"anonymize-key"}
+
+// captureArgs returns the command line recorded in a session header, with
+// the values of secretFlags masked.
+func captureArgs(args []string) []string {
+	masked := make([]string, len(args))
+	copy(masked, args)
+	for i := 0; i < len(masked); i++ {
+		name, _, hasValue := strings.Cut(strings.TrimLeft(masked[i], "-"), "=")
+		if !strings.HasPrefix(masked[i], "-") || !slices.Contains(secretFlags, name) {
+			continue
+		}
+		if hasValue {
+			masked[i] = masked[i][:strings.Index(masked[i], "=")+1] + redactedValue
+		} else if i+1 < len(masked) {
+			masked[i+1] = redactedValue
+			i++
+		}
+	}
+	return masked
+}
+
+// sessionLine is an input line of a session bundle, with its arrival time
+// and the source label and stream it was rendered with.
+type sessionLine struct {
+	Time   time.Time `json:"t"`
+	Line   string    `json:"line"`
+	Label  string    `json:"label,omitempty"`
+	Stream string    `json:"stream,omitempty"`
+}
+
+// sessionWriter records raw input lines into a session bundle for
+// --capture: gzip-compressed JSON lines, a sessionHeader then one
+// sessionLine per input line.
+type sessionWriter struct {
+	mu   sync.Mutex
+	file *os.File
+	gz   *gzip.Writer
+	enc  *json.Encoder
+	err  error
+}
+
+func newSessionWriter(path string, args []string) (*sessionWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(file)
+	s := &sessionWriter{file: file, gz: gz, enc: json.NewEncoder(gz)}
+	s.enc.SetEscapeHTML(false)
+	if err := s.enc.Encode(sessionHeader{Logpipe: version, Started: time.Now(), Args: captureArgs(args)}); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// record appends a line as it was read, after --redact and --anonymize
+// but before filtering.
+// The first write error is kept and reported by close.
+func (s *sessionWriter) record(line, label, stream string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = s.enc.Encode(sessionLine{Time: time.Now(), Line: line, Label: label, Stream: stream})
+	}
+}
+
+// protectLines returns handle behind --redact, --anonymize and --capture:
+// lines are masked by redact and pseudonymized by anonymize, either of
+// which may be nil, so that captures never hold what was masked, then
+// recorded into capture, if any.
+func protectLines(handle func(line, label, stream string), redact, anonymize *redactor, capture *sessionWriter) func(line, label, stream string) {
+	return func(line, label, stream string) {
+		if redact != nil {
+			line = redact.redactLine(strings.TrimSuffix(line, "\r"))
+		}
+		if anonymize != nil {
+			line = anonymize.redactLine(line)
+		}
+		if capture != nil {
+			capture.record(line, label, stream)
+		}
+		handle(line, label, stream)
+	}
+}
+
+// close completes the bundle. It is safe to call on a nil writer and more
+// than once.
+func (s *sessionWriter) close() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return s.err
+	}
+	if err := s.gz.Close(); s.err == nil {
+		s.err = err
+	}
+	if err := s.file.Close(); s.err == nil {
+		s.err = err
+	}
+	s.file = nil
+	return s.err
+}
+
+// sessionReader reads a session bundle written by --capture.
+type sessionReader struct {
+	header sessionHeader
+	file   *os.File
+	reader *bufio.Reader
+}
+
+// openSession opens a session bundle and reads its header.
+func openSession(path string) (*sessionReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: not a session bundle: %w", path, err)
+	}
+	s := &sessionReader{file: file, reader: bufio.NewReader(gz)}
+	data, err := s.reader.ReadBytes('\n')
+	if err != nil || json.Unmarshal(data, &s.header) != nil || s.header.Logpipe == "" {
+		file.Close()
+		return nil, fmt.Errorf("%s: not a session bundle: missing header", path)
+	}
+	return s, nil
+}
+
+// replay hands each recorded line to handle with its label and stream.
+func (s *sessionReader) replay(handle func(line, label, stream string)) error {
+	for n := 1; ; n++ {
+		data, err := s.reader.ReadBytes('\n')
+		if len(data) > 0 {
+			var record sessionLine
+			if err := json.Unmarshal(data, &record); err != nil {
+				return fmt.Errorf("line %d: %w", n, err)
+			}
+			handle(record.Line, record.Label, record.Stream)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *sessionReader) close() error {
+	return s.file.Close()
+}
+
+// describe summarizes how a session was captured, shown before replaying it.
+func (h sessionHeader) describe() string {
+	command := "logpipe"
+	if len(h.Args) > 0 {
+		command += " " + strings.Join(h.Args, " ")
+	}
+	return fmt.Sprintf("· session captured %s with %s", h.Started.Format("2006-01-02 15:04:05"), command)
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// command describes a logpipe subcommand. Every command accepts the shared
+// rendering flags; register adds the command's own.
+type command struct {
+	name  string
+	usage string
+	// aliases are other names the command answers to.
+	aliases []string
+	// register adds the command's own flags, if any.
+	register func(fs *flag.FlagSet)
+	// minArgs and maxArgs bound the positional arguments (-1: unbounded).
+	minArgs, maxArgs int
+	// passthrough stops flag parsing at the first positional argument, so
+	// the rest (e.g. a command line to run) is left untouched.
+	passthrough bool
+}
+
+// defaultCommand is used when no subcommand is named.
+const defaultCommand = "pipe"
+
+// errVersion is returned when the version was requested.
+var errVersion = errors.New("version requested")
+
+// newFlagSet returns the flag set logpipe parses its command line with.
+// Errors are returned instead of printed so they can be explained better.
+func newFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("logpipe", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+	return fs
+}
+
+// parseCommandLine picks the subcommand named by args, registers its flags
+// on fs and parses the rest. Flags and positional arguments may be mixed.
+// It returns flag.ErrHelp or errVersion when help or the version was asked
+// for.
+func parseCommandLine(fs *flag.FlagSet, commands []command, args []string) (*command, []string, error) {
+	name := defaultCommand
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+		switch name {
+		case "help":
+			return nil, nil, flag.ErrHelp
+		case "version":
+			return nil, nil, errVersion
+		}
+	}
+	cmd := findCommand(commands, name)
+	if cmd == nil && isPath(name) {
+		// A file or directory to read, not a command
+		name, args = defaultCommand, append([]string{name}, args...)
+		cmd = findCommand(commands, name)
+	}
+	if cmd == nil {
+		names := make([]string, len(commands))
+		for i, c := range commands {
+			names[i] = c.name
+		}
+		if match := closest(name, names); match != "" {
+			return nil, nil, fmt.Errorf("unknown command %q, did you mean %q?", name, match)
+		}
+		return nil, nil, fmt.Errorf("unknown command %q", name)
+	}
+	if cmd.register != nil {
+		cmd.register(fs)
+	}
+	version := fs.Bool("version", false, "Show version information")
+	fs.BoolVar(version, "v", false, "Show version information")
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, nil, explainFlagError(err, fs, commands)
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			break
+		}
+		// Parse stops at the first positional argument or after "--"
+		if consumed := len(args) - len(rest); cmd.passthrough || consumed > 0 && args[consumed-1] == "--" {
+			positional = append(positional, rest...)
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+	if *version {
+		return nil, nil, errVersion
+	}
+
+	switch {
+	case len(positional) < cmd.minArgs:
+		return nil, nil, fmt.Errorf("missing argument (usage: %s)", cmd.usage)
+	case cmd.maxArgs >= 0 && len(positional) > cmd.maxArgs:
+		return nil, nil, fmt.Errorf("unexpected argument %q (usage: %s)", positional[cmd.maxArgs], cmd.usage)
+	}
+	return cmd, positional, nil
+}
+
+// isPath reports whether an argument names an existing file or directory,
+// a URL or objects in a bucket.
+func isPath(arg string) bool {
+	if isHTTPURL(arg) || isObjectURL(arg) {
+		return true
+	}
+	_, err := os.Stat(arg)
+	return err == nil
+}
+
+func findCommand(commands []command, name string) *command {
+	for i := range commands {
+		if commands[i].name == name || slices.Contains(commands[i].aliases, name) {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// explainFlagError rewords flag parsing errors, pointing at the command an
+// unknown flag belongs to or at a similarly named flag.
+func explainFlagError(err error, fs *flag.FlagSet, commands []command) error {
+	if errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	name, ok := strings.CutPrefix(err.Error(), "flag provided but not defined: -")
+	if !ok {
+		return err
+	}
+	name = strings.TrimPrefix(name, "-")
+
+	if owner := flagOwner(commands, name); owner != nil {
+		return fmt.Errorf("--%s is only available with: %s", name, owner.usage)
+	}
+
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name)
+	})
+	if match := closest(name, names); match != "" {
+		return fmt.Errorf("unknown flag --%s, did you mean --%s?", name, match)
+	}
+	return fmt.Errorf("unknown flag --%s", name)
+}
+
+// flagOwner returns the command that adds the flag name, or nil if no
+// command does.
+func flagOwner(commands []command, name string) *command {
+	for i, cmd := range commands {
+		if cmd.register == nil {
+			continue
+		}
+		scratch := newFlagSet()
+		cmd.register(scratch)
+		if scratch.Lookup(name) != nil {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// closest returns the candidate within two edits of name, or "" if none is.
+func closest(name string, candidates []string) string {
+	best, bestDistance := "", 3
+	for _, candidate := range candidates {
+		if d := editDistance(name, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// registerFollowSwitch adds --follow and -f as a switch for commands that
+// stream from a server. Elsewhere they name files to follow, so a flag
+// already defined is turned into the switch.
+func registerFollowSwitch(fs *flag.FlagSet, follow *bool, usage string) {
+	for _, name := range []string{"follow", "f"} {
+		if f := fs.Lookup(name); f != nil {
+			f.Value, f.DefValue, f.Usage = switchValue{follow}, "false", usage
+			continue
+		}
+		fs.Var(switchValue{follow}, name, usage)
+	}
+}
+
+// switchValue is a boolean flag.Value, to turn a flag of another type into
+// a switch.
+type switchValue struct{ p *bool }
+
+func (v switchValue) String() string {
+	if v.p == nil {
+		return "false"
+	}
+	return strconv.FormatBool(*v.p)
+}
+
+func (v switchValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*v.p = b
+	return nil
+}
+
+func (v switchValue) IsBoolFlag() bool { return true }
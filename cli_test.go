@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestParseCommandLine(t *testing.T) {
+	tests := []struct {
+		name           string
+		args           string
+		wantCommand    string
+		wantPositional string
+		wantLevel      string
+		wantErr        string
+	}{
+		{"default", "--level error", "pipe", "", "error", ""},
+		{"explicit pipe", "pipe --level error", "pipe", "", "error", ""},
+		{"url before flags", "sub nats://x --level warn", "sub", "nats://x", "warn", ""},
+		{"url after flags", "sub --level warn nats://x", "sub", "nats://x", "warn", ""},
+		{"command flag", "kafka --topic logs", "kafka", "", "", ""},
+		{"run passthrough", "run --level info -- go test -json", "run", "go test -json", "info", ""},
+		{"run without dashes", "run go test -json", "run", "go test -json", "", ""},
+		{"files after dashes", "lint -- -odd.log", "lint", "-odd.log", "", ""},
+		{"unknown command", "kafak", "", "", "", `unknown command "kafak", did you mean "kafka"?`},
+		{"unknown flag", "--levle info", "", "", "", "unknown flag --levle, did you mean --level?"},
+		{"unrelated flag", "--xyz", "", "", "", "unknown flag --xyz"},
+		{"other command's flag", "--topic logs", "", "", "", "--topic is only available with: logpipe kafka"},
+		{"missing argument", "ws", "", "", "", "missing argument (usage: logpipe ws URL)"},
+		{"extra argument", "ws nats://x nats://y", "", "", "", `unexpected argument "nats://y"`},
+		{"alias", "replay s.lpz --level warn", "open", "s.lpz", "warn", ""},
+		{"file argument", "pipe app.log --level info", "pipe", "app.log", "info", ""},
+		{"existing path as command", "cli_test.go --level info", "pipe", "cli_test.go", "info", ""},
+		{"help", "help", "", "", "", flag.ErrHelp.Error()},
+		{"help flag", "kafka -h", "", "", "", flag.ErrHelp.Error()},
+		{"version", "--version", "", "", "", errVersion.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var kafkaOpts kafkaOptions
+			commands := []command{
+				{name: "pipe", usage: "logpipe [pipe] [FILE|DIR...]", maxArgs: -1},
+				{name: "kafka", usage: "logpipe kafka", register: kafkaOpts.register},
+				{name: "ws", usage: "logpipe ws URL", minArgs: 1, maxArgs: 1},
+				{name: "sub", usage: "logpipe sub URL", minArgs: 1, maxArgs: 1},
+				{name: "lint", usage: "logpipe lint [FILE...]", maxArgs: -1},
+				{name: "run", usage: "logpipe run -- COMMAND", minArgs: 1, maxArgs: -1, passthrough: true},
+				{name: "open", usage: "logpipe open SESSION", aliases: []string{"replay"}, minArgs: 1, maxArgs: 1},
+			}
+			fs := newFlagSet()
+			level := fs.String("level", "", "")
+
+			cmd, positional, err := parseCommandLine(fs, commands, strings.Fields(tt.args))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cmd.name != tt.wantCommand || strings.Join(positional, " ") != tt.wantPositional || *level != tt.wantLevel {
+				t.Errorf("got %s %q --level %q, want %s %q --level %q", cmd.name, positional, *level, tt.wantCommand, tt.wantPositional, tt.wantLevel)
+			}
+			if tt.wantCommand == "kafka" && kafkaOpts.topic != "logs" {
+				t.Errorf("kafka --topic = %q", kafkaOpts.topic)
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"level", "level", 0},
+		{"levl", "level", 1},
+		{"kafak", "kafka", 2},
+		{"", "ws", 2},
+	} {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+)
+
+// cloudwatchPollInterval is how often --follow asks for new events.
+var cloudwatchPollInterval = 2 * time.Second
+
+// cloudwatchLateWindow is how far behind the newest event seen --follow
+// keeps asking, for events that become searchable late.
+var cloudwatchLateWindow = 2 * time.Minute
+
+// cloudwatchDefaultSince is how far back reads start without --since.
+const cloudwatchDefaultSince = time.Hour
+
+// cloudwatchOptions configures `logpipe cloudwatch`.
+type cloudwatchOptions struct {
+	logGroup     string
+	filter       string
+	streamPrefix string
+	limit        int
+	follow       bool
+}
+
+func (o *cloudwatchOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.logGroup, "log-group", "", "Log group to read, e.g. /aws/lambda/my-fn")
+	fs.StringVar(&o.filter, "filter", "", `CloudWatch filter pattern, e.g. '{ $.level = "error" }'`)
+	fs.StringVar(&o.streamPrefix, "stream-prefix", "", "Only read log streams whose name starts with this")
+	fs.IntVar(&o.limit, "limit", 0, "Most events read before following (default: all in the time range)")
+	registerFollowSwitch(fs, &o.follow, "Keep polling for new events after the time range")
+}
+
+// cloudwatchEvent is an event of a FilterLogEvents response, with its
+// timestamp in milliseconds.
+type cloudwatchEvent struct {
+	ID        string `json:"eventId"`
+	Stream    string `json:"logStreamName"`
+	Timestamp int64  `json:"timestamp"`
+	Message   string `json:"message"`
+}
+
+// cloudwatchClient calls the CloudWatch Logs API with the AWS SDK, with the
+// credentials of loadAWSConfig.
+type cloudwatchClient struct {
+	api  *cloudwatchlogs.Client
+	opts cloudwatchOptions
+}
+
+func newCloudWatchClient(ctx context.Context, opts cloudwatchOptions) (*cloudwatchClient, error) {
+	if opts.logGroup == "" {
+		return nil, errors.New("--log-group is required, e.g. --log-group /aws/lambda/my-fn")
+	}
+	cfg, err := loadAWSConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAWSCredentials(ctx, cfg); err != nil {
+		return nil, err
+	}
+	api := cloudwatchlogs.NewFromConfig(cfg, func(o *cloudwatchlogs.Options) {
+		if endpoint := os.Getenv("AWS_ENDPOINT_URL"); endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
+	return &cloudwatchClient{api: api, opts: opts}, nil
+}
+
+// filterLogEvents fetches a page of the events from start (inclusive) to
+// end (exclusive) in milliseconds (end 0: open), and the token of the next
+// page if there is one.
+func (c *cloudwatchClient) filterLogEvents(ctx context.Context, start, end int64, limit int, token string) ([]cloudwatchEvent, string, error) {
+	params := &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName: aws.String(c.opts.logGroup),
+		StartTime:    aws.Int64(start),
+	}
+	if end > 0 {
+		// endTime is inclusive
+		params.EndTime = aws.Int64(end - 1)
+	}
+	if c.opts.filter != "" {
+		params.FilterPattern = aws.String(c.opts.filter)
+	}
+	if c.opts.streamPrefix != "" {
+		params.LogStreamNamePrefix = aws.String(c.opts.streamPrefix)
+	}
+	if limit > 0 {
+		params.Limit = aws.Int32(int32(min(limit, 10000)))
+	}
+	if token != "" {
+		params.NextToken = aws.String(token)
+	}
+	resp, err := c.api.FilterLogEvents(ctx, params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	events := make([]cloudwatchEvent, 0, len(resp.Events))
+	for _, e := range resp.Events {
+		events = append(events, cloudwatchEvent{
+			ID:        aws.ToString(e.EventId),
+			Stream:    aws.ToString(e.LogStreamName),
+			Timestamp: aws.ToInt64(e.Timestamp),
+			Message:   aws.ToString(e.Message),
+		})
+	}
+	return events, aws.ToString(resp.NextToken), nil
+}
+
+// consumeCloudWatch reads the events of a log group over a time range, then
+// with --follow polls for newer ones until ctx is cancelled. Without
+// --since, the range starts an hour ago.
+func consumeCloudWatch(ctx context.Context, opts cloudwatchOptions, timeFilter *timeRange, handle lineHandler) error {
+	client, err := newCloudWatchClient(ctx, opts)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	start, end := now.Add(-cloudwatchDefaultSince), now
+	if timeFilter != nil {
+		if !timeFilter.since.IsZero() {
+			start = timeFilter.since
+		}
+		if !timeFilter.until.IsZero() {
+			if opts.follow {
+				return errors.New("--until can't be used with --follow")
+			}
+			end = timeFilter.until
+		}
+	}
+
+	if err := client.readRange(ctx, start.UnixMilli(), end.UnixMilli(), handle); err != nil || !opts.follow {
+		return ignoreClosed(ctx, err)
+	}
+	return ignoreClosed(ctx, client.poll(ctx, end.UnixMilli(), handle))
+}
+
+// readRange hands over the events of a time range, page by page.
+func (c *cloudwatchClient) readRange(ctx context.Context, start, end int64, handle lineHandler) error {
+	read := 0
+	token := ""
+	for {
+		limit := 0
+		if c.opts.limit > 0 {
+			limit = c.opts.limit - read
+		}
+		events, next, err := c.filterLogEvents(ctx, start, end, limit, token)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			if c.opts.limit > 0 && read == c.opts.limit {
+				return nil
+			}
+			handle(cloudwatchLine(event), event.Stream)
+			read++
+		}
+		if next == "" || next == token {
+			return nil
+		}
+		token = next
+	}
+}
+
+// poll asks for events from a time on every cloudwatchPollInterval. Events
+// reach CloudWatch out of order and late, so each request goes back
+// cloudwatchLateWindow before the newest timestamp seen and skips the
+// events already handed over.
+func (c *cloudwatchClient) poll(ctx context.Context, from int64, handle lineHandler) error {
+	newest := from
+	seen := map[string]int64{}
+	ticker := time.NewTicker(cloudwatchPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		start := max(from, newest-cloudwatchLateWindow.Milliseconds())
+		token := ""
+		for {
+			events, next, err := c.filterLogEvents(ctx, start, 0, 0, token)
+			if err != nil {
+				return err
+			}
+			for _, event := range events {
+				if _, ok := seen[event.ID]; ok {
+					continue
+				}
+				seen[event.ID] = event.Timestamp
+				newest = max(newest, event.Timestamp)
+				handle(cloudwatchLine(event), event.Stream)
+			}
+			if next == "" || next == token {
+				break
+			}
+			token = next
+		}
+		// Events older than the next request's start can't be returned again
+		start = max(from, newest-cloudwatchLateWindow.Milliseconds())
+		for id, timestamp := range seen {
+			if timestamp < start {
+				delete(seen, id)
+			}
+		}
+	}
+}
+
+// cloudwatchLine turns an event into a log line. Lambda and many agents end
+// messages with a newline.
+func cloudwatchLine(event cloudwatchEvent) string {
+	return strings.TrimRight(event.Message, "\r\n")
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCloudWatch serves FilterLogEvents over a fixed set of events, two per
+// page. Polls find a new event, then another one that arrived late with the
+// same timestamp and a last one.
+func fakeCloudWatch(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	events := []cloudwatchEvent{
+		{"a", "web/1", 1000, "one\n"},
+		{"b", "web/2", 2000, `{"message":"two"}`},
+		{"c", "worker/1", 3000, "three"},
+		{"d", "web/1", 9000, "after the range"},
+	}
+	polls := 0
+	future := time.Now().Add(time.Hour).UnixMilli()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("X-Amz-Target") != "Logs_20140328.FilterLogEvents" {
+			http.Error(w, `{"__type":"UnknownOperationException"}`, http.StatusBadRequest)
+			return
+		}
+		if !strings.Contains(r.Header.Get("Authorization"), "/logs/aws4_request") {
+			http.Error(w, `{"__type":"MissingAuthenticationTokenException"}`, http.StatusForbidden)
+			return
+		}
+		var params struct {
+			LogGroupName        string `json:"logGroupName"`
+			StartTime           int64  `json:"startTime"`
+			EndTime             *int64 `json:"endTime"`
+			FilterPattern       string `json:"filterPattern"`
+			LogStreamNamePrefix string `json:"logStreamNamePrefix"`
+			Limit               int    `json:"limit"`
+			NextToken           string `json:"nextToken"`
+		}
+		json.NewDecoder(r.Body).Decode(&params)
+		if params.LogGroupName != "/aws/lambda/my-fn" {
+			http.Error(w, `{"__type":"ResourceNotFoundException","message":"The specified log group does not exist."}`, http.StatusBadRequest)
+			return
+		}
+		if params.EndTime == nil {
+			polls++
+			switch polls {
+			case 1:
+				events = append(events, cloudwatchEvent{"e", "web/1", future, "tailed"})
+			case 2:
+				events = append(events, cloudwatchEvent{"f", "web/2", future, "late at the same time"}, cloudwatchEvent{"g", "web/1", future + 1, "last"})
+			case 3:
+				events = append(events, cloudwatchEvent{"h", "web/2", future - 30000, "late and older"})
+			}
+		}
+
+		var matched []cloudwatchEvent
+		for _, e := range events {
+			if e.Timestamp >= params.StartTime && (params.EndTime == nil || e.Timestamp <= *params.EndTime) &&
+				strings.Contains(e.Message, params.FilterPattern) && strings.HasPrefix(e.Stream, params.LogStreamNamePrefix) {
+				matched = append(matched, e)
+			}
+		}
+		offset, _ := strconv.Atoi(params.NextToken)
+		n := min(2, len(matched)-offset)
+		if params.Limit > 0 {
+			n = min(n, params.Limit)
+		}
+		resp := map[string]interface{}{"events": matched[offset : offset+n]}
+		if offset+n < len(matched) {
+			resp["nextToken"] = strconv.Itoa(offset + n)
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestCloudWatch(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	defer func(interval time.Duration) { cloudwatchPollInterval = interval }(cloudwatchPollInterval)
+	cloudwatchPollInterval = time.Millisecond
+
+	tests := []struct {
+		name    string
+		opts    cloudwatchOptions
+		until   int64
+		want    []string
+		wantErr string
+	}{
+		{
+			name:  "pages",
+			opts:  cloudwatchOptions{logGroup: "/aws/lambda/my-fn"},
+			until: 5000,
+			want:  []string{"web/1: one", `web/2: {"message":"two"}`, "worker/1: three"},
+		},
+		{
+			name:  "filter",
+			opts:  cloudwatchOptions{logGroup: "/aws/lambda/my-fn", filter: "two"},
+			until: 5000,
+			want:  []string{`web/2: {"message":"two"}`},
+		},
+		{
+			name:  "stream prefix",
+			opts:  cloudwatchOptions{logGroup: "/aws/lambda/my-fn", streamPrefix: "web/"},
+			until: 5000,
+			want:  []string{"web/1: one", `web/2: {"message":"two"}`},
+		},
+		{
+			name:  "limit",
+			opts:  cloudwatchOptions{logGroup: "/aws/lambda/my-fn", limit: 3},
+			until: 5000,
+			want:  []string{"web/1: one", `web/2: {"message":"two"}`, "worker/1: three"},
+		},
+		{
+			name: "read then poll",
+			opts: cloudwatchOptions{logGroup: "/aws/lambda/my-fn", follow: true},
+			want: []string{
+				"web/1: one", `web/2: {"message":"two"}`, "worker/1: three", "web/1: after the range",
+				"web/1: tailed", "web/2: late at the same time", "web/1: last", "web/2: late and older",
+			},
+		},
+		{
+			name:    "missing log group",
+			opts:    cloudwatchOptions{},
+			wantErr: "--log-group is required",
+		},
+		{
+			name:    "unknown log group",
+			opts:    cloudwatchOptions{logGroup: "/aws/lambda/other"},
+			until:   5000,
+			wantErr: "ResourceNotFoundException",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fakeCloudWatch(t)
+			defer server.Close()
+			t.Setenv("AWS_ENDPOINT_URL", server.URL)
+			timeFilter := &timeRange{since: time.UnixMilli(1)}
+			if tt.until > 0 {
+				timeFilter.until = time.UnixMilli(tt.until)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			var got []string
+			err := consumeCloudWatch(ctx, tt.opts, timeFilter, func(line, label string) {
+				got = append(got, fmt.Sprintf("%s: %s", label, line))
+				if len(got) == len(tt.want) && tt.opts.follow {
+					cancel()
+				}
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCloudWatchWithoutCredentials(t *testing.T) {
+	withoutAWSCredentials(t)
+	err := consumeCloudWatch(context.Background(), cloudwatchOptions{logGroup: "/aws/lambda/my-fn"}, nil, func(line, label string) {
+		t.Errorf("unexpected line %q", line)
+	})
+	if err == nil || !strings.Contains(err.Error(), "no AWS credentials found") {
+		t.Errorf("error = %v, want no AWS credentials found", err)
+	}
+}
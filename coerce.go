@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fieldCoercion converts a mistyped value into the type LogEntry expects.
+// It returns false when the value can't be converted and should be dropped.
+type fieldCoercion func(value interface{}) (interface{}, bool)
+
+// coercedFields lists the LogEntry fields that are commonly logged with the
+// wrong JSON type, such as status codes, pids and ports as strings.
+var coercedFields = map[string]fieldCoercion{
+	"event.duration":            coerceDuration,
+	"http.request.body.bytes":   coerceInt,
+	"http.response.body.bytes":  coerceInt,
+	"http.response.status_code": coerceInt,
+	"log.origin.file.line":      coerceInt,
+	"process.pid":               coerceInt,
+	"process.thread.id":         coerceInt,
+	"url.port":                  coerceInt,
+}
+
+// coerceFields rewrites a JSON object so that the fields in coercedFields
+// have the expected types. It returns false when nothing was changed.
+func coerceFields(data []byte) ([]byte, bool) {
+	var fields map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if decoder.Decode(&fields) != nil {
+		return nil, false
+	}
+
+	changed := false
+	for path, coerce := range coercedFields {
+		keys := strings.Split(path, ".")
+		parent := fields
+		for _, key := range keys[:len(keys)-1] {
+			nested, ok := parent[key].(map[string]interface{})
+			if !ok {
+				parent = nil
+				break
+			}
+			parent = nested
+		}
+		if parent == nil {
+			continue
+		}
+		key := keys[len(keys)-1]
+		value, ok := parent[key]
+		if !ok || value == nil {
+			continue
+		}
+		if n, ok := value.(json.Number); ok {
+			if _, err := n.Int64(); err == nil {
+				continue
+			}
+		}
+
+		if coerced, ok := coerce(value); ok {
+			parent[key] = coerced
+		} else {
+			delete(parent, key)
+		}
+		changed = true
+	}
+	if !changed {
+		return nil, false
+	}
+
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+// coerceInt accepts integers written as strings ("200") or as decimals.
+func coerceInt(value interface{}) (interface{}, bool) {
+	var f float64
+	switch v := value.(type) {
+	case json.Number:
+		n, err := v.Float64()
+		if err != nil {
+			return nil, false
+		}
+		f = n
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, false
+		}
+		f = n
+	default:
+		return nil, false
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, false
+	}
+	return int64(math.Round(f)), true
+}
+
+// coerceDuration converts event.duration to nanoseconds, accepting decimals,
+// numeric strings and Go-style durations with a unit ("12ms", "1.5 s").
+func coerceDuration(value interface{}) (interface{}, bool) {
+	if s, ok := value.(string); ok {
+		s = strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+		if d, err := time.ParseDuration(s); err == nil {
+			return d.Nanoseconds(), true
+		}
+	}
+	return coerceInt(value)
+}
@@ -0,0 +1,78 @@
+package main
+
+import "testing"
+
+func TestTolerantDecoding(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		wantStatus   int
+		wantDuration int64
+		wantPID      int
+		wantPort     int
+	}{
+		{
+			name:       "status code as string",
+			input:      `{"http":{"response":{"status_code":"404"}}}`,
+			wantStatus: 404,
+		},
+		{
+			name:         "duration as float",
+			input:        `{"event":{"duration":1500000.7}}`,
+			wantDuration: 1500001,
+		},
+		{
+			name:         "duration with unit",
+			input:        `{"event":{"duration":"12.5 ms"}}`,
+			wantDuration: 12500000,
+		},
+		{
+			name:         "duration as numeric string",
+			input:        `{"event":{"duration":"3000"}}`,
+			wantDuration: 3000,
+		},
+		{
+			name:     "pid and port as strings",
+			input:    `{"process":{"pid":"4242"},"url":{"port":"8080"}}`,
+			wantPID:  4242,
+			wantPort: 8080,
+		},
+		{
+			name:       "unconvertible value is dropped",
+			input:      `{"http":{"response":{"status_code":"OK"}},"process":{"pid":"7"}}`,
+			wantStatus: 0,
+			wantPID:    7,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, ok := parseLine(tt.input)
+			if !ok {
+				t.Fatal("parseLine() failed")
+			}
+			if entry.HTTP.Response.StatusCode != tt.wantStatus {
+				t.Errorf("status code = %d, want %d", entry.HTTP.Response.StatusCode, tt.wantStatus)
+			}
+			if entry.Event.Duration != tt.wantDuration {
+				t.Errorf("duration = %d, want %d", entry.Event.Duration, tt.wantDuration)
+			}
+			if entry.Process.PID != tt.wantPID {
+				t.Errorf("pid = %d, want %d", entry.Process.PID, tt.wantPID)
+			}
+			if entry.URL.Port != tt.wantPort {
+				t.Errorf("port = %d, want %d", entry.URL.Port, tt.wantPort)
+			}
+		})
+	}
+}
+
+func TestTolerantDecodingKeepsStructureErrors(t *testing.T) {
+	// Docker lines rely on "log" as a string failing to decode
+	if _, changed := coerceFields([]byte(`{"log":"x","stream":"stdout"}`)); changed {
+		t.Error("coerceFields() changed a line without coercible fields")
+	}
+	if _, ok := parseLine(`{"message":"hi","user":"bob"}`); ok {
+		t.Error("parseLine() should still reject mismatches it can't coerce")
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Config is the optional JSON configuration file, read from --config or
+// from logpipe/config.json in the user's config directory.
+type Config struct {
+	// NumericLevels selects how numeric levels are read (see
+	// numericLevelScheme).
+	NumericLevels string `json:"numeric_levels"`
+	// DurationUnit is the unit of duration fields (see durationUnit).
+	DurationUnit string `json:"duration_unit"`
+	// LevelAliases maps custom level names or numbers onto canonical
+	// levels, e.g. {"sev3": "error", "100": "fatal"}.
+	LevelAliases map[string]string `json:"level_aliases"`
+	// LevelStyles sets the abbreviation and colors of levels, keyed by
+	// canonical level or by a level name such as "audit".
+	LevelStyles map[string]LevelStyle `json:"level_styles"`
+	// Icons prefixes entries with level icons from IconSet ("emoji" or
+	// "nerd").
+	Icons   bool   `json:"icons"`
+	IconSet string `json:"icon_set"`
+	// Redact lists dotted field paths to mask, RedactDetectors the
+	// built-in detectors to run ("emails", "tokens", "cards", "ips", "all").
+	Redact          []string `json:"redact"`
+	RedactDetectors []string `json:"redact_detectors"`
+	// Enrich lists lookup files labelling field values, as
+	// FIELD=FILE[:KEY:VALUE] (see parseLookupSpec).
+	Enrich []string `json:"enrich"`
+	// Promote lists the keys of labels, tags, attributes or baggage shown
+	// on an entry's main line (see promotedLabels).
+	Promote []string `json:"promote"`
+	// GeoIP is the MaxMind DB used to locate source IPs.
+	GeoIP string `json:"geoip"`
+	// TraceURL links trace IDs to a tracing UI (see traceURL).
+	TraceURL string `json:"trace_url"`
+	// URLHeaders are extra request headers for URLs read as input, keyed
+	// by URL prefix (see urlHeaders).
+	URLHeaders map[string]map[string]string `json:"url_headers"`
+	// Unwrap names the wrapper field holding the real event, UnwrapKeep
+	// the wrapper fields shown along with it.
+	Unwrap     string   `json:"unwrap"`
+	UnwrapKeep []string `json:"unwrap_keep"`
+	// FieldAliases maps fields of the user's logs onto the fields logpipe
+	// reads, e.g. {"severity_level": "log.level"}.
+	FieldAliases map[string]FieldAlias `json:"field_aliases"`
+	// DerivedFields computes extra fields from expressions over an entry's
+	// fields, e.g. {"endpoint": "http.request.method + \" \" + url.path"}.
+	DerivedFields map[string]string `json:"derived_fields"`
+	// CorrelationRules fill the correlation field from other fields or
+	// from matches in them (see correlate).
+	CorrelationRules []CorrelationRule `json:"correlation_rules"`
+	// StyleRules style whole entries matching an expression, the first
+	// matching rule winning.
+	StyleRules []StyleRule `json:"style_rules"`
+	// Profiles are named bundles of flag values selected with --profile.
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+// defaultConfigPath returns the per-user config file location.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "logpipe", "config.json")
+}
+
+// loadConfig reads the config file at path. An empty path means the default
+// location, which is allowed not to exist.
+func loadConfig(path string) (Config, error) {
+	var config Config
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigPath()
+		if path == "" {
+			return config, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return config, nil
+		}
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// apply installs the config's settings into the package-level registries.
+func (c Config) apply() error {
+	if c.NumericLevels != "" {
+		if err := setNumericLevelScheme(c.NumericLevels); err != nil {
+			return err
+		}
+	}
+	if c.DurationUnit != "" {
+		if err := setDurationUnit(c.DurationUnit); err != nil {
+			return err
+		}
+	}
+	if c.Icons {
+		iconSetName := c.IconSet
+		if iconSetName == "" {
+			iconSetName = "emoji"
+		}
+		if err := setIconSet(iconSetName); err != nil {
+			return err
+		}
+	}
+	if err := addLevelAliases(c.LevelAliases); err != nil {
+		return err
+	}
+	if err := addFieldAliases(c.FieldAliases); err != nil {
+		return err
+	}
+	if err := addDerivedFields(c.DerivedFields); err != nil {
+		return err
+	}
+	if err := addCorrelationRules(c.CorrelationRules); err != nil {
+		return err
+	}
+	if err := addStyleRules(c.StyleRules); err != nil {
+		return err
+	}
+	for prefix, headers := range c.URLHeaders {
+		urlHeaders[prefix] = headers
+	}
+	return addLevelStyles(c.LevelStyles)
+}
+
+func setNumericLevelScheme(scheme string) error {
+	if !slices.Contains(numericLevelSchemes, scheme) {
+		return fmt.Errorf("unknown numeric level scheme %q (expected auto, syslog, pino or otel)", scheme)
+	}
+	numericLevelScheme = scheme
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	os.WriteFile(path, []byte(`{"numeric_levels":"pino","level_aliases":{"audit":"notice"}}`), 0o644)
+
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if config.NumericLevels != "pino" || config.LevelAliases["audit"] != "notice" {
+		t.Errorf("config = %+v", config)
+	}
+
+	if _, err := loadConfig(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Expected explicit missing config to fail")
+	}
+
+	os.WriteFile(path, []byte(`{not json`), 0o644)
+	if _, err := loadConfig(path); err == nil {
+		t.Error("Expected invalid JSON to fail")
+	}
+}
+
+func TestLoadDefaultConfigMissing(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	if _, err := loadConfig(""); err != nil {
+		t.Errorf("Expected missing default config to be ignored, got %v", err)
+	}
+}
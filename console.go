@@ -0,0 +1,13 @@
+package main
+
+import (
+	"os"
+
+	"github.com/mattn/go-isatty"
+)
+
+// isTerminal reports whether f is an interactive terminal, including the
+// Cygwin and MSYS terminals (e.g. Git Bash) that look like pipes on Windows.
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
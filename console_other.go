@@ -0,0 +1,17 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+// enableConsoleColors prepares the console for colored output, which
+// terminals outside Windows support as they are.
+func enableConsoleColors() {}
+
+// isBrokenPipe reports whether a write failed because the reader is gone.
+func isBrokenPipe(err error) bool {
+	return errors.Is(err, syscall.EPIPE)
+}
@@ -0,0 +1,35 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/fatih/color"
+	"golang.org/x/sys/windows"
+)
+
+// enableConsoleColors turns on escape sequence processing for stdout and
+// stderr. Consoles that can't process them (before Windows 10) get no
+// colors rather than raw escape sequences.
+func enableConsoleColors() {
+	for _, f := range []*os.File{os.Stdout, os.Stderr} {
+		handle := windows.Handle(f.Fd())
+		var mode uint32
+		if err := windows.GetConsoleMode(handle, &mode); err != nil {
+			// Not a console: a pipe, a file or a Cygwin terminal
+			continue
+		}
+		mode |= windows.ENABLE_PROCESSED_OUTPUT | windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING
+		if err := windows.SetConsoleMode(handle, mode); err != nil && f == os.Stdout {
+			color.NoColor = true
+		}
+	}
+}
+
+// isBrokenPipe reports whether a write failed because the reader is gone.
+// Windows reports it as "the pipe is being closed" or "broken pipe".
+func isBrokenPipe(err error) bool {
+	return errors.Is(err, windows.ERROR_NO_DATA) || errors.Is(err, windows.ERROR_BROKEN_PIPE)
+}
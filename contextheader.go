@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+)
+
+// contextFields describe what is running rather than what happened, so
+// they stay the same from one entry to the next until a deploy or a
+// restart.
+var contextFields = []string{
+	"service.name",
+	"service.version",
+	"service.environment",
+	"process.name",
+	"kubernetes.namespace",
+	"kubernetes.pod.name",
+	"kubernetes.container.name",
+	"kubernetes.node.name",
+	"k8s.namespace.name",
+	"k8s.deployment.name",
+	"k8s.pod.name",
+	"k8s.container.name",
+}
+
+// contextTracker remembers the context of each input, to show it in a
+// header when it starts or changes instead of on every entry.
+type contextTracker struct {
+	last map[string]string
+}
+
+func newContextTracker() *contextTracker {
+	return &contextTracker{last: make(map[string]string)}
+}
+
+// header returns the header to show in front of an entry of the input
+// label, or "" when its context is the same as the previous entry's.
+// Entries without any context fields keep the current one.
+func (c *contextTracker) header(label string, fields map[string]interface{}) string {
+	var parts []string
+	for _, path := range contextFields {
+		if value := fieldString(fields, path); value != "" {
+			parts = append(parts, path+"="+value)
+		}
+	}
+	context := strings.Join(parts, " ")
+	if context == "" || context == c.last[label] {
+		return ""
+	}
+	c.last[label] = context
+	if label != "" {
+		context = label + ": " + context
+	}
+	return labelColor.Sprintf("— %s —", context)
+}
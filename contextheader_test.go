@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestContextHeader(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	c := newContextTracker()
+	tests := []struct {
+		name  string
+		label string
+		line  string
+		want  string
+	}{
+		{"first", "", `{"service":{"name":"api","version":"1.4.2"},"kubernetes":{"pod":{"name":"api-7d9f"}}}`, "— service.name=api service.version=1.4.2 kubernetes.pod.name=api-7d9f —"},
+		{"same", "", `{"service.name":"api","service.version":"1.4.2","kubernetes.pod.name":"api-7d9f","message":"x"}`, ""},
+		{"no context", "", `{"message":"x"}`, ""},
+		{"same after none", "", `{"service":{"name":"api","version":"1.4.2"},"kubernetes":{"pod":{"name":"api-7d9f"}}}`, ""},
+		{"deploy", "", `{"service":{"name":"api","version":"1.5.0"},"k8s.pod.name":"api-5c2e"}`, "— service.name=api service.version=1.5.0 k8s.pod.name=api-5c2e —"},
+		{"other input", "worker.log", `{"service":{"name":"api","version":"1.5.0"},"k8s.pod.name":"api-5c2e"}`, "— worker.log: service.name=api service.version=1.5.0 k8s.pod.name=api-5c2e —"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.header(tt.label, decodeJSONObject(tt.line)); got != tt.want {
+				t.Errorf("header() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// CorrelationRule takes a correlation ID from a field, or from the first
+// submatch (or the whole match) of Regex in the field's value.
+type CorrelationRule struct {
+	Field string `json:"field"`
+	Regex string `json:"regex"`
+}
+
+type correlationRule struct {
+	field string
+	regex *regexp.Regexp
+}
+
+// correlationRules are tried in order to fill the correlation field, when
+// --correlation or the config's correlation_rules set any.
+var correlationRules []correlationRule
+
+// autoCorrelationRules are the rules of --correlation auto: the usual
+// request and correlation ID fields and headers, then IDs mentioned in
+// messages, then the trace ID.
+var autoCorrelationRules = []CorrelationRule{
+	{Field: "correlation_id"},
+	{Field: "correlationId"},
+	{Field: "request_id"},
+	{Field: "requestId"},
+	{Field: "http.request.id"},
+	{Field: "http.request.headers.x-correlation-id"},
+	{Field: "http.request.headers.x-request-id"},
+	{Field: "x-correlation-id"},
+	{Field: "x-request-id"},
+	{Field: "traceparent", Regex: `^[0-9a-f]{2}-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}$`},
+	{Field: "message", Regex: `(?i)\b(?:x-)?(?:request|correlation)[-_]id[=:]\s*"?([\w.-]+)`},
+	{Field: "message", Regex: `\btraceparent[=:]\s*"?[0-9a-f]{2}-([0-9a-f]{32})-`},
+	{Field: "trace.id"},
+}
+
+// addCorrelationRules compiles rules from the config or --correlation.
+func addCorrelationRules(rules []CorrelationRule) error {
+	for _, rule := range rules {
+		if rule.Field == "" {
+			return errors.New("correlation rule without a field")
+		}
+		compiled := correlationRule{field: rule.Field}
+		if rule.Regex != "" {
+			re, err := regexp.Compile(rule.Regex)
+			if err != nil {
+				return fmt.Errorf("correlation rule for %s: %w", rule.Field, err)
+			}
+			compiled.regex = re
+		}
+		correlationRules = append(correlationRules, compiled)
+	}
+	return nil
+}
+
+// parseCorrelationFlag reads --correlation: auto for the built-in rules,
+// or comma-separated fields holding the ID.
+func parseCorrelationFlag(value string) []CorrelationRule {
+	var rules []CorrelationRule
+	for _, field := range splitList(value) {
+		if field == "auto" {
+			rules = append(rules, autoCorrelationRules...)
+		} else {
+			rules = append(rules, CorrelationRule{Field: field})
+		}
+	}
+	return rules
+}
+
+// correlate writes the ID found by the first matching rule into the
+// correlation field of a decoded JSON object, unless it has one. Field
+// names are matched regardless of case when no field has the exact path,
+// as header names are.
+func correlate(fields map[string]interface{}) {
+	if len(correlationRules) == 0 {
+		return
+	}
+	if _, exists := fields["correlation"]; exists {
+		return
+	}
+	var flat map[string]interface{}
+	for _, rule := range correlationRules {
+		value := fieldString(fields, rule.field)
+		if value == "" {
+			if flat == nil {
+				flat = flattenFields(fields, nil)
+			}
+			for key := range flat {
+				if strings.EqualFold(key, rule.field) {
+					value = fieldString(flat, key)
+					break
+				}
+			}
+		}
+		if value != "" && rule.regex != nil {
+			match := rule.regex.FindStringSubmatch(value)
+			switch {
+			case match == nil:
+				value = ""
+			case len(match) > 1:
+				value = match[1]
+			default:
+				value = match[0]
+			}
+		}
+		if value != "" {
+			fields["correlation"] = value
+			return
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCorrelate(t *testing.T) {
+	saved := correlationRules
+	defer func() { correlationRules = saved }()
+
+	tests := []struct {
+		name  string
+		rules []CorrelationRule
+		line  string
+		want  interface{}
+	}{
+		{"request ID field", parseCorrelationFlag("auto"), `{"requestId":"r-1","trace":{"id":"t-1"}}`, "r-1"},
+		{"header in any case", parseCorrelationFlag("auto"), `{"http":{"request":{"headers":{"X-Request-ID":"r-2"}}}}`, "r-2"},
+		{"flat header", parseCorrelationFlag("auto"), `{"http.request.headers.X-Correlation-Id":"c-3"}`, "c-3"},
+		{"traceparent field", parseCorrelationFlag("auto"), `{"traceparent":"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}`, "0af7651916cd43dd8448eb211c80319c"},
+		{"ID in message", parseCorrelationFlag("auto"), `{"message":"calling payments X-Request-ID: r-77 now"}`, "r-77"},
+		{"traceparent in message", parseCorrelationFlag("auto"), `{"message":"headers traceparent=00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}`, "0af7651916cd43dd8448eb211c80319c"},
+		{"trace ID last", parseCorrelationFlag("auto"), `{"message":"x","trace":{"id":"t-1"}}`, "t-1"},
+		{"none", parseCorrelationFlag("auto"), `{"message":"x"}`, nil},
+		{"existing kept", parseCorrelationFlag("auto"), `{"correlation":"mine","request_id":"r-1"}`, "mine"},
+		{"named fields in order", parseCorrelationFlag("rid,ctx.id"), `{"ctx":{"id":"c-9"},"rid":"r-9"}`, "r-9"},
+		{"regex submatch", []CorrelationRule{{Field: "message", Regex: `order #(\d+)`}}, `{"message":"paid order #42"}`, "42"},
+		{"regex whole match", []CorrelationRule{{Field: "message", Regex: `ord-\d+`}}, `{"message":"paid ord-42"}`, "ord-42"},
+		{"regex without match", []CorrelationRule{{Field: "message", Regex: `order #(\d+)`}, {Field: "id"}}, `{"message":"paid","id":7}`, "7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			correlationRules = nil
+			if err := addCorrelationRules(tt.rules); err != nil {
+				t.Fatal(err)
+			}
+			fields := decodeJSONObject(tt.line)
+			correlate(fields)
+			if got := fields["correlation"]; got != tt.want {
+				t.Errorf("correlation = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	correlationRules = nil
+	for _, rule := range []CorrelationRule{{Regex: "x"}, {Field: "message", Regex: "("}} {
+		if err := addCorrelationRules([]CorrelationRule{rule}); err == nil {
+			t.Errorf("addCorrelationRules(%+v) succeeded", rule)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// sensitiveNameRegex matches header and query parameter names whose values
+// are secrets, masked in curl commands.
+var sensitiveNameRegex = regexp.MustCompile(`(?i)^(authorization|proxy-authorization|cookie|set-cookie)$|token|secret|password|passwd|api[-_]?key|signature|session`)
+
+// curlSkippedHeaders are set by curl itself.
+var curlSkippedHeaders = map[string]bool{"host": true, "content-length": true}
+
+// curlCommand rebuilds a curl command reproducing the request of an HTTP
+// entry from http.request.method, url.* fields, http.request.headers and
+// http.request.body.content. Secrets in headers and the query string are
+// replaced by redactedValue.
+func curlCommand(fields map[string]interface{}) (string, bool) {
+	method := strings.ToUpper(fieldString(fields, "http.request.method"))
+	if method == "" {
+		return "", false
+	}
+	headers := requestHeaders(fields)
+
+	target, err := url.Parse(fieldString(fields, "url.full", "url.original"))
+	if err != nil || target.Host == "" {
+		target = &url.URL{
+			Scheme:   fieldString(fields, "url.scheme"),
+			Host:     fieldString(fields, "url.domain", "destination.domain"),
+			Path:     fieldString(fields, "url.path"),
+			RawQuery: fieldString(fields, "url.query"),
+		}
+		if target.Host == "" {
+			target.Host = headers["host"]
+		}
+		if target.Host == "" {
+			target.Host = "localhost"
+		}
+		if port := fieldString(fields, "url.port"); port != "" && !strings.Contains(target.Host, ":") {
+			target.Host += ":" + port
+		}
+		if target.Scheme == "" {
+			target.Scheme = "http"
+		}
+	}
+	target.RawQuery = redactQuery(target.RawQuery)
+
+	parts := []string{"curl"}
+	body := requestBody(fields)
+	if method != "GET" || body != "" {
+		parts = append(parts, "-X", method)
+	}
+	parts = append(parts, shellQuote(target.String()))
+	for _, name := range sortedKeys(headers) {
+		if curlSkippedHeaders[name] {
+			continue
+		}
+		value := headers[name]
+		if sensitiveNameRegex.MatchString(name) {
+			value = redactedValue
+		}
+		parts = append(parts, "-H", shellQuote(name+": "+value))
+	}
+	if body != "" {
+		parts = append(parts, "--data-raw", shellQuote(body))
+	}
+	return strings.Join(parts, " "), true
+}
+
+// requestHeaders returns the logged request headers with lower-cased
+// names. Headers logged as lists are joined with commas.
+func requestHeaders(fields map[string]interface{}) map[string]string {
+	value, _ := lookupField(fields, "http.request.headers")
+	logged, _ := value.(map[string]interface{})
+	headers := make(map[string]string, len(logged))
+	for name, value := range logged {
+		name = strings.ToLower(name)
+		switch v := value.(type) {
+		case []interface{}:
+			var values []string
+			for _, item := range v {
+				values = append(values, formatDiffValue(item))
+			}
+			headers[name] = strings.Join(values, ", ")
+		default:
+			headers[name] = formatDiffValue(v)
+		}
+	}
+	return headers
+}
+
+// requestBody returns http.request.body.content as sent: strings as they
+// are, objects as compact JSON.
+func requestBody(fields map[string]interface{}) string {
+	value, ok := lookupField(fields, "http.request.body.content")
+	if !ok || value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// redactQuery masks the values of sensitive query parameters, keeping the
+// others as they were written.
+func redactQuery(query string) string {
+	if query == "" {
+		return ""
+	}
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		name, _, found := strings.Cut(param, "=")
+		if decoded, err := url.QueryUnescape(name); err == nil {
+			name = decoded
+		}
+		if found && sensitiveNameRegex.MatchString(name) {
+			params[i] = url.QueryEscape(name) + "=" + url.QueryEscape(redactedValue)
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// shellQuote quotes s for POSIX shells.
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`!*?[]{}()<>|&;#~=%") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
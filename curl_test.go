@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCurlCommand(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]interface{}
+		want   string
+		ok     bool
+	}{
+		{
+			name:   "not an HTTP entry",
+			fields: map[string]interface{}{"message": "started"},
+		},
+		{
+			name: "GET from url parts",
+			fields: map[string]interface{}{
+				"http": map[string]interface{}{"request": map[string]interface{}{"method": "get"}},
+				"url":  map[string]interface{}{"domain": "api.example.com", "path": "/health", "scheme": "https"},
+			},
+			want: "curl https://api.example.com/health",
+			ok:   true,
+		},
+		{
+			name: "host header and port",
+			fields: map[string]interface{}{
+				"http": map[string]interface{}{"request": map[string]interface{}{
+					"method":  "DELETE",
+					"headers": map[string]interface{}{"Host": "internal", "Accept": []interface{}{"text/plain", "application/json"}},
+				}},
+				"url": map[string]interface{}{"path": "/users/1", "port": json.Number("8080")},
+			},
+			want: "curl -X DELETE http://internal:8080/users/1 -H 'accept: text/plain, application/json'",
+			ok:   true,
+		},
+		{
+			name: "secrets redacted",
+			fields: map[string]interface{}{
+				"http": map[string]interface{}{"request": map[string]interface{}{
+					"method": "POST",
+					"headers": map[string]interface{}{
+						"Authorization": "Bearer abc",
+						"X-Auth-Token":  "abc",
+						"Content-Type":  "application/json",
+					},
+					"body": map[string]interface{}{"content": map[string]interface{}{"name": "o'brien"}},
+				}},
+				"url": map[string]interface{}{"full": "https://api.example.com/login?user=alice&access_token=abc"},
+			},
+			want: `curl -X POST 'https://api.example.com/login?user=alice&access_token=%5BREDACTED%5D'` +
+				` -H 'authorization: [REDACTED]' -H 'content-type: application/json' -H 'x-auth-token: [REDACTED]'` +
+				` --data-raw '{"name":"o'\''brien"}'`,
+			ok: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := curlCommand(tt.fields)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("curlCommand() = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+)
+
+// densities are the presets --density accepts: compact leaves out the
+// details shown around the message, detailed turns on the optional
+// columns (--bytes, --process, --logger-column and --ip-info).
+var densities = []string{"compact", "normal", "detailed"}
+
+func checkDensity(density string) error {
+	for _, d := range densities {
+		if d == density {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown density %q (expected compact, normal or detailed)", density)
+}
+
+// compactOutput is set by --density compact to leave out user agents,
+// logfmt pairs, syslog structured data and embedded JSON.
+var compactOutput = false
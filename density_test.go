@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestCheckDensity(t *testing.T) {
+	for _, density := range densities {
+		if err := checkDensity(density); err != nil {
+			t.Errorf("checkDensity(%q) = %v", density, err)
+		}
+	}
+	if err := checkDensity("verbose"); err == nil {
+		t.Error("checkDensity(verbose) succeeded")
+	}
+}
+
+func TestCompactOutput(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	tests := []struct {
+		name        string
+		line        string
+		notCompact  string
+		bothContain string
+	}{
+		{
+			name:        "http",
+			line:        `{"@timestamp":"2024-01-15T14:00:00Z","log.level":"info","category":"http","event":{"duration":12000000},"http":{"request":{"method":"GET"},"response":{"status_code":200}},"url":{"path":"/api"},"user_agent":{"original":"curl/8.0"},"message":"served"}`,
+			notCompact:  "ua=curl/8.0",
+			bothContain: "GET  200 /api 12ms",
+		},
+		{
+			name:        "logfmt pairs",
+			line:        `time=2024-01-15T14:00:00Z level=info msg="cache warmed" keys=120`,
+			notCompact:  "keys=120",
+			bothContain: "cache warmed",
+		},
+		{
+			name:        "embedded JSON",
+			line:        `{"@timestamp":"2024-01-15T14:00:00Z","message":"{\"order\":42}"}`,
+			notCompact:  "order",
+			bothContain: "14:00:00.000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, ok := parseLine(tt.line)
+			if !ok {
+				t.Fatalf("parseLine(%q) failed", tt.line)
+			}
+			defer func() { compactOutput = false }()
+			for _, compact := range []bool{false, true} {
+				compactOutput = compact
+				var out bytes.Buffer
+				writePrettyLog(&out, entry)
+				if !strings.Contains(out.String(), tt.bothContain) {
+					t.Errorf("compact=%v: %q doesn't contain %q", compact, out.String(), tt.bothContain)
+				}
+				if got := strings.Contains(out.String(), tt.notCompact); got == compact {
+					t.Errorf("compact=%v: %q containing %q = %v", compact, out.String(), tt.notCompact, got)
+				}
+			}
+		})
+	}
+}
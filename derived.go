@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// derivedFields holds the computed fields from the config, keyed by the
+// field they are written to.
+var derivedFields = map[string]*expr{}
+
+// addDerivedFields compiles derived field expressions from the config, e.g.
+// {"endpoint": "http.request.method + \" \" + url.path"}.
+func addDerivedFields(definitions map[string]string) error {
+	for name, source := range definitions {
+		e, err := compileExpr(source)
+		if err != nil {
+			return fmt.Errorf("derived field %q: %w", name, err)
+		}
+		derivedFields[name] = e
+	}
+	return nil
+}
+
+// deriveFields computes the derived fields of a decoded JSON object and
+// writes them into it. Derived fields may use each other; a field the entry
+// already has, or whose expression has no value (e.g. a missing operand),
+// is left alone. It returns false when nothing was written.
+func deriveFields(fields map[string]interface{}) bool {
+	if len(derivedFields) == 0 {
+		return false
+	}
+	values := make(map[string]interface{}, len(derivedFields))
+	inProgress := make(map[string]bool)
+
+	var lookup func(path string) interface{}
+	lookup = func(path string) interface{} {
+		e, ok := derivedFields[path]
+		if !ok {
+			value, _ := lookupField(fields, path)
+			return exprValue(value)
+		}
+		if value, done := values[path]; done {
+			return value
+		}
+		if inProgress[path] {
+			return nil
+		}
+		if value, exists := lookupField(fields, path); exists {
+			values[path] = exprValue(value)
+			return values[path]
+		}
+		inProgress[path] = true
+		values[path] = e.eval(lookup)
+		return values[path]
+	}
+
+	changed := false
+	for _, name := range sortedKeys(derivedFields) {
+		if _, exists := lookupField(fields, name); exists {
+			continue
+		}
+		if value := lookup(name); value != nil && setEntryField(fields, name, value) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// entryFields returns the fields of a log entry for --where: the JSON
+// object of the line with aliases and derived fields applied, or the
+// parsed entry for other formats. The fields decoded to unmarshal the entry
+// are reused when it was unmarshaled from the line, and completed once.
+func entryFields(line string, entry LogEntry) map[string]interface{} {
+	cached := entry.fields
+	if cached != nil && cached.source != strings.TrimSpace(line) {
+		cached = nil
+	}
+	if cached != nil && cached.complete {
+		return cached.fields
+	}
+	var fields map[string]interface{}
+	if cached != nil {
+		fields = cached.object()
+	} else {
+		fields = decodeJSONObject(line)
+	}
+	if fields == nil {
+		cached = nil
+		data, _ := json.Marshal(entry)
+		fields = decodeJSONObject(string(data))
+		for _, kv := range entry.KeyValues {
+			if _, err := strconv.ParseFloat(kv.Value, 64); err == nil {
+				fields[kv.Key] = json.Number(kv.Value)
+			} else {
+				fields[kv.Key] = kv.Value
+			}
+		}
+	}
+	aliasFields(fields)
+	applyTraceparent(fields)
+	correlate(fields)
+	deriveFields(fields)
+	if cached != nil {
+		cached.complete = true
+	}
+	return fields
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDerivedFields(t *testing.T) {
+	saved := derivedFields
+	derivedFields = map[string]*expr{}
+	defer func() { derivedFields = saved }()
+	err := addDerivedFields(map[string]string{
+		"throughput": "http.response.body.bytes / (event.duration/1e9)",
+		"endpoint":   `http.request.method + " " + url.path`,
+		"slow":       "latency_ms > 250",
+		"latency_ms": "event.duration / 1e6",
+		"loop":       "loop + 1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, ok := parseLine(`{"message":"done","http":{"request":{"method":"GET"},"response":{"body":{"bytes":1000}}},"url":{"path":"/a"},"event":{"duration":500000000}}`)
+	if !ok {
+		t.Fatal("parseLine() failed")
+	}
+	if entry.Message != "done" || entry.URL.Path != "/a" {
+		t.Errorf("entry = %+v", entry)
+	}
+
+	fields := entryFields(`{"http":{"request":{"method":"GET"},"response":{"body":{"bytes":1000}}},"url":{"path":"/a"},"event":{"duration":500000000},"endpoint":"kept"}`, LogEntry{})
+	want := map[string]interface{}{
+		"throughput": 2000.0,
+		"endpoint":   "kept",
+		"latency_ms": 500.0,
+		"slow":       true,
+	}
+	for name, value := range want {
+		if got := exprValue(fields[name]); got != value {
+			t.Errorf("%s = %#v, want %#v", name, got, value)
+		}
+	}
+	if _, ok := fields["loop"]; ok {
+		t.Error("a self-referencing field should have no value")
+	}
+
+	// Non-JSON formats use the parsed entry's fields
+	fields = entryFields("not json", LogEntry{Message: "hi"})
+	if fields["message"] != "hi" {
+		t.Errorf("message = %#v, want \"hi\"", fields["message"])
+	}
+
+	if err := addDerivedFields(map[string]string{"bad": "a +"}); err == nil {
+		t.Error("expected an invalid expression to fail")
+	}
+}
+
+func TestEntryFieldsReusesDecodedLine(t *testing.T) {
+	saved := fieldAliases
+	fieldAliases = map[string]FieldAlias{"lvl": {Field: "log.level"}}
+	defer func() { fieldAliases = saved }()
+
+	line := `{"lvl":"warn","message":"disk almost full","event":{"duration":1000}}`
+	entry, ok := parseLine(line)
+	if !ok {
+		t.Fatal("line not parsed")
+	}
+	first := entryFields(line, entry)
+	if !entry.fields.complete || reflect.ValueOf(first).Pointer() != reflect.ValueOf(entry.fields.fields).Pointer() {
+		t.Fatal("fields decoded again instead of reusing the ones the entry was parsed from")
+	}
+	if got := fieldString(first, "log.level"); got != "warn" {
+		t.Errorf("log.level = %q, want the alias applied", got)
+	}
+	if again := entryFields(line, entry); reflect.ValueOf(again).Pointer() != reflect.ValueOf(first).Pointer() {
+		t.Error("fields completed again on a second call")
+	}
+
+	other := `{"message":"another line"}`
+	if got := fieldString(entryFields(other, entry), "message"); got != "another line" {
+		t.Errorf("message = %q, want the fields of the line given", got)
+	}
+}
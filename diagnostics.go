@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// debugParse is set by --debug-parse to annotate unparseable lines.
+var debugParse = false
+
+var parseErrorColor = color.New(color.FgRed, color.Faint)
+
+// diagnoseLine explains why a line could not be parsed, returning a short
+// kind for stats and a detailed message.
+func diagnoseLine(line string) (string, string) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		return "empty line", "empty line"
+	}
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return "unknown format", unknownFormatMessage()
+	}
+
+	if strings.HasPrefix(trimmed, "[") && json.Valid([]byte(trimmed)) {
+		return "type mismatch", "JSON array instead of an object"
+	}
+
+	var entry LogEntry
+	err := json.Unmarshal([]byte(line), &entry)
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case err == nil:
+		return "unknown format", "valid JSON, but not a log entry"
+	case errors.As(err, &syntaxErr):
+		return "invalid JSON", fmt.Sprintf("invalid JSON at byte %d: %v", syntaxErr.Offset, err)
+	case errors.As(err, &typeErr):
+		field := strings.TrimPrefix(typeErr.Field, "plainEntry.")
+		if _, coerced := coerceFields([]byte(line)); coerced {
+			// The offset refers to the line after coercion, not the input
+			return "type mismatch", fmt.Sprintf("field %s: expected %s, got %s", field, jsonKind(typeErr.Type), typeErr.Value)
+		}
+		return "type mismatch", fmt.Sprintf("field %s at byte %d: expected %s, got %s",
+			field, typeErr.Offset, jsonKind(typeErr.Type), typeErr.Value)
+	}
+	return "invalid JSON", err.Error()
+}
+
+// unknownFormatMessage lists the formats parseLine tries, in its order.
+func unknownFormatMessage() string {
+	names := []string{"JSON"}
+	for _, parser := range lineParsers {
+		names = append(names, parser.name)
+	}
+	return "not " + strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// jsonKind names the JSON type a Go type decodes from.
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Pointer:
+		return jsonKind(t.Elem())
+	}
+	return "number"
+}
+
+// parseStats counts parsed and unparseable lines for the --debug-parse
+// summary.
+type parseStats struct {
+	lines    int
+	failures map[string]int
+}
+
+func newParseStats() *parseStats {
+	return &parseStats{failures: make(map[string]int)}
+}
+
+func (s *parseStats) record(kind string) {
+	s.lines++
+	if kind != "" {
+		s.failures[kind]++
+	}
+}
+
+func (s *parseStats) unparsed() int {
+	n := 0
+	for _, count := range s.failures {
+		n += count
+	}
+	return n
+}
+
+// report writes a one-line summary such as
+// "logpipe: 120 lines, 3 unparseable (2 invalid JSON, 1 type mismatch)".
+func (s *parseStats) report(w io.Writer) {
+	fmt.Fprintf(w, "logpipe: %d lines, %d unparseable", s.lines, s.unparsed())
+	if len(s.failures) > 0 {
+		kinds := sortedKeys(s.failures)
+		sort.SliceStable(kinds, func(i, j int) bool { return s.failures[kinds[i]] > s.failures[kinds[j]] })
+		parts := make([]string, len(kinds))
+		for i, kind := range kinds {
+			parts[i] = fmt.Sprintf("%d %s", s.failures[kind], kind)
+		}
+		fmt.Fprintf(w, " (%s)", strings.Join(parts, ", "))
+	}
+	fmt.Fprintln(w)
+}
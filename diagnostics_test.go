@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDiagnoseLine(t *testing.T) {
+	tests := []struct {
+		name       string
+		line       string
+		wantKind   string
+		wantDetail string
+	}{
+		{"truncated", `{"message":"oops`, "invalid JSON", "invalid JSON at byte"},
+		{"syntax", `{"a":1,}`, "invalid JSON", "at byte 8"},
+		{"type mismatch", `{"message":"login","user":"bob"}`, "type mismatch", "field user at byte 31: expected object, got string"},
+		{"after coercion", `{"http":{"response":{"status_code":"200"}},"user":"bob"}`, "type mismatch", "field user: expected object, got string"},
+		{"object expected", `{"log":"x"}`, "type mismatch", "expected object, got string"},
+		{"array", `[1,2]`, "type mismatch", "JSON array"},
+		{"plain text", "hello world", "unknown format", "not JSON"},
+		{"every format named", "hello world", "unknown format", "logfmt or a level-prefixed line"},
+		{"empty", "  ", "empty line", "empty line"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, detail := diagnoseLine(tt.line)
+			if kind != tt.wantKind || !strings.Contains(detail, tt.wantDetail) {
+				t.Errorf("diagnoseLine() = %q, %q, want %q, containing %q", kind, detail, tt.wantKind, tt.wantDetail)
+			}
+		})
+	}
+}
+
+func TestParseStatsReport(t *testing.T) {
+	stats := newParseStats()
+	for _, kind := range []string{"", "", "invalid JSON", "type mismatch", "invalid JSON"} {
+		stats.record(kind)
+	}
+
+	var buf bytes.Buffer
+	stats.report(&buf)
+	want := "logpipe: 5 lines, 3 unparseable (2 invalid JSON, 1 type mismatch)\n"
+	if buf.String() != want {
+		t.Errorf("report() = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/fatih/color"
+)
+
+// diffIgnoredFields differ between almost any two entries, so comparing all
+// fields leaves them out.
+var diffIgnoredFields = map[string]bool{"@timestamp": true}
+
+// fieldChange is a field that differs between two entries.
+type fieldChange struct {
+	path          string
+	before, after string
+	// added and removed are set when the field is only in one entry
+	added, removed bool
+}
+
+// entryDiff compares each shown entry with the one before it, for
+// --diff-fields.
+type entryDiff struct {
+	// fields are the compared fields; nil compares all of them
+	fields   []string
+	previous map[string]string
+}
+
+// newEntryDiff returns a diff of the comma-separated fields, or of all
+// fields for "all".
+func newEntryDiff(fields []string) *entryDiff {
+	if len(fields) == 1 && fields[0] == "all" {
+		fields = nil
+	}
+	return &entryDiff{fields: fields}
+}
+
+// next records an entry's fields and returns how they differ from the
+// previous entry's, sorted by path. The first entry has nothing to differ
+// from.
+func (d *entryDiff) next(fields map[string]interface{}) []fieldChange {
+	flat := make(map[string]string)
+	if d.fields == nil {
+		for path, value := range flattenFields(fields, nil) {
+			if !diffIgnoredFields[path] {
+				flat[path] = formatDiffValue(value)
+			}
+		}
+	} else {
+		for _, path := range d.fields {
+			value, ok := lookupField(fields, path)
+			if !ok {
+				continue
+			}
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				flat[path] = formatDiffValue(value)
+				continue
+			}
+			for nested, value := range flattenFields(object, nil) {
+				flat[path+"."+nested] = formatDiffValue(value)
+			}
+		}
+	}
+
+	previous := d.previous
+	d.previous = flat
+	if previous == nil {
+		return nil
+	}
+	var changes []fieldChange
+	for path, after := range flat {
+		before, ok := previous[path]
+		switch {
+		case !ok:
+			changes = append(changes, fieldChange{path: path, after: after, added: true})
+		case before != after:
+			changes = append(changes, fieldChange{path: path, before: before, after: after})
+		}
+	}
+	for path, before := range previous {
+		if _, ok := flat[path]; !ok {
+			changes = append(changes, fieldChange{path: path, before: before, removed: true})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].path < changes[j].path })
+	return changes
+}
+
+// formatDiffValue formats a field value for display: strings and numbers
+// as they are, other values as JSON.
+func formatDiffValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+var (
+	diffChangedColor = color.New(color.FgYellow)
+	diffAddedColor   = color.New(color.FgGreen)
+	diffRemovedColor = color.New(color.FgRed)
+)
+
+// writeDiff prints the changes under an entry, e.g.
+// "~ http.response.status_code: 200 → 500".
+func writeDiff(w io.Writer, changes []fieldChange) {
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "  %s\n", labelColor.Sprint("changed from the previous entry:"))
+	for _, c := range changes {
+		switch {
+		case c.added:
+			fmt.Fprintf(w, "    %s\n", diffAddedColor.Sprintf("+ %s: %s", c.path, c.after))
+		case c.removed:
+			fmt.Fprintf(w, "    %s\n", diffRemovedColor.Sprintf("- %s: %s", c.path, c.before))
+		default:
+			fmt.Fprintf(w, "    %s\n", diffChangedColor.Sprintf("~ %s: %s → %s", c.path, c.before, c.after))
+		}
+	}
+}
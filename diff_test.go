@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEntryDiff(t *testing.T) {
+	first := `{"@timestamp":"2024-01-15T10:00:00Z","message":"served","http":{"response":{"status_code":200}},"user":{"id":"u1"},"cache":"hit"}`
+	second := `{"@timestamp":"2024-01-15T10:00:01Z","message":"served","http":{"response":{"status_code":500}},"user":{"id":"u1"},"region":"eu"}`
+
+	tests := []struct {
+		name   string
+		fields []string
+		want   []fieldChange
+	}{
+		{"all fields", []string{"all"}, []fieldChange{
+			{path: "cache", before: "hit", removed: true},
+			{path: "http.response.status_code", before: "200", after: "500"},
+			{path: "region", after: "eu", added: true},
+		}},
+		{"chosen fields", []string{"http.response.status_code", "user.id"}, []fieldChange{
+			{path: "http.response.status_code", before: "200", after: "500"},
+		}},
+		{"chosen object", []string{"http"}, []fieldChange{
+			{path: "http.response.status_code", before: "200", after: "500"},
+		}},
+		{"no differences", []string{"user.id", "message"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := newEntryDiff(tt.fields)
+			if changes := diff.next(decodeJSONObject(first)); changes != nil {
+				t.Errorf("first entry changes = %v, want none", changes)
+			}
+			if got := diff.next(decodeJSONObject(second)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changes = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteDiff(t *testing.T) {
+	var out bytes.Buffer
+	writeDiff(&out, []fieldChange{
+		{path: "a", before: "1", after: "2"},
+		{path: "b", after: "x", added: true},
+		{path: "c", before: "y", removed: true},
+	})
+	want := "  changed from the previous entry:\n    ~ a: 1 → 2\n    + b: x\n    - c: y\n"
+	if out.String() != want {
+		t.Errorf("writeDiff() = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	writeDiff(&out, nil)
+	if strings.TrimSpace(out.String()) != "" {
+		t.Errorf("writeDiff() without changes = %q, want nothing", out.String())
+	}
+}
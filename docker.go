@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// dockerLogLine is the envelope written by Docker's json-file logging driver
+// (/var/lib/docker/containers/<id>/<id>-json.log).
+type dockerLogLine struct {
+	Log    *string `json:"log"`
+	Stream string  `json:"stream"`
+	Time   string  `json:"time"`
+}
+
+// parseDockerLog unwraps a Docker json-file line and parses the inner
+// payload, keeping the outer time and stream as metadata.
+func parseDockerLog(line string) (LogEntry, bool) {
+	var wrapper dockerLogLine
+	if err := json.Unmarshal([]byte(line), &wrapper); err != nil {
+		return LogEntry{}, false
+	}
+	if wrapper.Log == nil || wrapper.Stream == "" {
+		return LogEntry{}, false
+	}
+
+	inner := strings.TrimRight(*wrapper.Log, "\r\n")
+	logEntry, ok := parseLine(inner)
+	if !ok {
+		logEntry = LogEntry{Message: inner}
+	}
+	if logEntry.Timestamp == "" {
+		logEntry.Timestamp = wrapper.Time
+	}
+	logEntry.Stream = wrapper.Stream
+	return logEntry, true
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseDockerLog(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantOK    bool
+		level     string
+		message   string
+		stream    string
+		timestamp string
+	}{
+		{
+			name:      "inner JSON log",
+			input:     `{"log":"{\"@timestamp\":\"2025-06-28T11:50:00.000Z\",\"log.level\":\"warn\",\"message\":\"slow query\"}\n","stream":"stdout","time":"2025-06-28T11:50:00.123456789Z"}`,
+			wantOK:    true,
+			level:     "warn",
+			message:   "slow query",
+			stream:    "stdout",
+			timestamp: "2025-06-28T11:50:00.000Z",
+		},
+		{
+			name:      "inner JSON without timestamp uses outer time",
+			input:     `{"log":"{\"log.level\":\"info\",\"message\":\"ready\"}\n","stream":"stdout","time":"2025-06-28T11:50:00.123456789Z"}`,
+			wantOK:    true,
+			level:     "info",
+			message:   "ready",
+			stream:    "stdout",
+			timestamp: "2025-06-28T11:50:00.123456789Z",
+		},
+		{
+			name:      "inner plain text",
+			input:     `{"log":"panic: something broke\n","stream":"stderr","time":"2025-06-28T11:50:00Z"}`,
+			wantOK:    true,
+			message:   "panic: something broke",
+			stream:    "stderr",
+			timestamp: "2025-06-28T11:50:00Z",
+		},
+		{
+			name:   "regular JSON log is not an envelope",
+			input:  `{"log.level":"info","message":"hello"}`,
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, ok := parseDockerLog(tt.input)
+			if ok != tt.wantOK {
+				t.Fatalf("parseDockerLog() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if entry.Level != tt.level {
+				t.Errorf("Level = %q, want %q", entry.Level, tt.level)
+			}
+			if entry.Message != tt.message {
+				t.Errorf("Message = %q, want %q", entry.Message, tt.message)
+			}
+			if entry.Stream != tt.stream {
+				t.Errorf("Stream = %q, want %q", entry.Stream, tt.stream)
+			}
+			if entry.Timestamp != tt.timestamp {
+				t.Errorf("Timestamp = %q, want %q", entry.Timestamp, tt.timestamp)
+			}
+		})
+	}
+}
+
+func TestParseLineDockerEnvelope(t *testing.T) {
+	entry, ok := parseLine(`{"log":"<11>1 2025-06-28T11:50:00Z host app - - - nested syslog\n","stream":"stdout","time":"2025-06-28T11:50:00Z"}`)
+	if !ok {
+		t.Fatal("Expected Docker line to parse")
+	}
+	if entry.Log.Syslog == nil || entry.Message != "nested syslog" {
+		t.Errorf("Expected inner syslog line to be parsed, got %+v", entry)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// durationUnit is set by --duration-unit or the config's duration_unit:
+// "auto", or one of durationUnits for every duration field without a unit
+// in its name.
+var durationUnit = "auto"
+
+func setDurationUnit(unit string) error {
+	if _, ok := durationUnits[unit]; !ok && unit != "auto" {
+		return fmt.Errorf("unknown duration unit %q (expected auto, ns, us, ms or s)", unit)
+	}
+	durationUnit = unit
+	return nil
+}
+
+// durationField is a field producers log durations in, with the unit its
+// schema defines. Fields whose name gives their unit are fixed; the unit
+// of the others can be set with --duration-unit.
+type durationField struct {
+	path string
+	// unit is in nanoseconds, 0 when the schema doesn't say and the unit
+	// is inferred.
+	unit  float64
+	fixed bool
+}
+
+// durationFields are checked in order, the first present giving the
+// duration of an entry.
+var durationFields = []durationField{
+	// ECS and Elastic APM
+	{path: "event.duration", unit: 1},
+	{path: "span.duration.us", unit: 1e3, fixed: true},
+	{path: "transaction.duration.us", unit: 1e3, fixed: true},
+	// gRPC interceptors and OpenTelemetry
+	{path: "grpc.time_ms", unit: 1e6, fixed: true},
+	{path: "rpc.duration_ms", unit: 1e6, fixed: true},
+	// Database drivers and ORMs
+	{path: "db.duration_ms", unit: 1e6, fixed: true},
+	// Fields named after their unit
+	{path: "duration_ns", unit: 1, fixed: true},
+	{path: "duration_us", unit: 1e3, fixed: true},
+	{path: "duration_ms", unit: 1e6, fixed: true},
+	{path: "elapsed_ms", unit: 1e6, fixed: true},
+	{path: "duration_seconds", unit: 1e9, fixed: true},
+	// pino-http
+	{path: "responseTime", unit: 1e6},
+	// nginx and Envoy access logs written as JSON
+	{path: "request_time", unit: 1e9},
+	{path: "upstream_response_time", unit: 1e9},
+	// Elasticsearch
+	{path: "took", unit: 1e6},
+	// Anything else
+	{path: "duration"},
+	{path: "latency"},
+	{path: "elapsed"},
+}
+
+// mayHaveDuration reports whether a line may hold one of durationFields,
+// so most lines skip decoding. Its words are parts of the names of
+// durationFields that timestamp fields ("time", "@timestamp") don't share.
+func mayHaveDuration(text string) bool {
+	for _, word := range []string{"uration", "Time\"", "_time\"", "time_ms", "took", "latency", "elapsed"} {
+		if strings.Contains(text, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseEntryDuration returns the duration of a JSON entry.
+func parseEntryDuration(line *lineFields) (time.Duration, bool) {
+	if !mayHaveDuration(line.text) {
+		return 0, false
+	}
+	fields := line.object()
+	if fields == nil {
+		return 0, false
+	}
+	return entryDuration(fields)
+}
+
+// entryDuration reads the duration of an entry from the first of
+// durationFields present, or from a field aliased to event.duration in the
+// config when the entry has no event.duration. Strings with a unit ("850ms", "1.5 s") are read as written;
+// numbers are read in --duration-unit when set, else in their schema's
+// unit, else in a unit guessed from their magnitude.
+func entryDuration(fields map[string]interface{}) (time.Duration, bool) {
+	_, hasDuration := lookupField(fields, "event.duration")
+	for _, source := range sortedKeys(fieldAliases) {
+		alias := fieldAliases[source]
+		if alias.Field != "event.duration" || hasDuration {
+			continue
+		}
+		if value, ok := lookupField(fields, source); ok && value != nil {
+			unit := "ns"
+			if alias.Unit != "" {
+				unit = alias.Unit
+			}
+			return readDuration(value, durationUnits[unit])
+		}
+	}
+
+	for _, field := range durationFields {
+		value, ok := lookupField(fields, field.path)
+		if !ok || value == nil {
+			continue
+		}
+		unit := field.unit
+		if !field.fixed && durationUnit != "auto" {
+			unit = durationUnits[durationUnit]
+		}
+		if d, ok := readDuration(value, unit); ok {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// readDuration reads a duration written with a unit, or a number in the
+// given unit, in nanoseconds. A unit of 0 is inferred from the number.
+func readDuration(value interface{}, unit float64) (time.Duration, bool) {
+	if s, ok := value.(string); ok {
+		s = strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+		if d, err := time.ParseDuration(s); err == nil {
+			return d, true
+		}
+	}
+	n, ok := durationNumber(value)
+	if !ok {
+		return 0, false
+	}
+	switch {
+	case unit == 0:
+		unit = inferDurationUnit(n)
+	case unit == 1 && n < 1000 && n != math.Trunc(n):
+		// Fractions of a nanosecond are seconds written as floats
+		unit = 1e9
+	}
+	return time.Duration(math.Round(n * unit)), true
+}
+
+func durationNumber(value interface{}) (float64, bool) {
+	var n float64
+	var err error
+	switch v := value.(type) {
+	case json.Number:
+		n, err = v.Float64()
+	case float64:
+		n = v
+	case string:
+		n, err = strconv.ParseFloat(strings.TrimSpace(v), 64)
+	default:
+		return 0, false
+	}
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// inferDurationUnit guesses the unit of a duration from its magnitude:
+// small decimals are seconds (0.85), other decimals milliseconds (12.5),
+// and integers milliseconds up to 100000, microseconds up to 10^8 and
+// nanoseconds beyond, which keeps typical request durations from 1ms to a
+// minute right in each unit.
+func inferDurationUnit(n float64) float64 {
+	switch {
+	case n != math.Trunc(n) && n < 100:
+		return 1e9
+	case n != math.Trunc(n), n < 1e5:
+		return 1e6
+	case n < 1e8:
+		return 1e3
+	}
+	return 1
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEntryDuration(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		unit  string
+		want  time.Duration
+		found bool
+	}{
+		{"ECS nanoseconds", `{"event":{"duration":850000000}}`, "auto", 850 * time.Millisecond, true},
+		{"float seconds in event.duration", `{"event":{"duration":0.85}}`, "auto", 850 * time.Millisecond, true},
+		{"string with unit", `{"duration":"850ms"}`, "auto", 850 * time.Millisecond, true},
+		{"named unit", `{"duration_ms":850}`, "auto", 850 * time.Millisecond, true},
+		{"APM microseconds", `{"span":{"duration":{"us":850000}}}`, "auto", 850 * time.Millisecond, true},
+		{"nginx seconds", `{"request_time":"0.850"}`, "auto", 850 * time.Millisecond, true},
+		{"pino-http", `{"responseTime":850}`, "auto", 850 * time.Millisecond, true},
+		{"gRPC milliseconds", `{"grpc.time_ms":850}`, "auto", 850 * time.Millisecond, true},
+		{"Elasticsearch", `{"took":850}`, "auto", 850 * time.Millisecond, true},
+		{"inferred seconds", `{"duration":0.85}`, "auto", 850 * time.Millisecond, true},
+		{"inferred milliseconds", `{"duration":850}`, "auto", 850 * time.Millisecond, true},
+		{"inferred microseconds", `{"latency":850000}`, "auto", 850 * time.Millisecond, true},
+		{"inferred nanoseconds", `{"elapsed":850000000}`, "auto", 850 * time.Millisecond, true},
+		{"forced unit", `{"event":{"duration":850}}`, "ms", 850 * time.Millisecond, true},
+		{"forced unit ignores named units", `{"duration_us":850000}`, "s", 850 * time.Millisecond, true},
+		{"not a duration", `{"duration":{"ms":5}}`, "auto", 0, false},
+		{"negative", `{"duration":-5}`, "auto", 0, false},
+		{"none", `{"message":"hi"}`, "auto", 0, false},
+	}
+
+	defer func(unit string) { durationUnit = unit }(durationUnit)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			durationUnit = tt.unit
+			got, found := parseEntryDuration(newLineFields([]byte(tt.line)))
+			if got != tt.want || found != tt.found {
+				t.Errorf("parseEntryDuration() = %v, %v, want %v, %v", got, found, tt.want, tt.found)
+			}
+		})
+	}
+}
+
+func TestParseEntryDurationSkipsTimestamps(t *testing.T) {
+	line := newLineFields([]byte(`{"time":"2024-01-15T10:00:00Z","@timestamp":"2024-01-15T10:00:00Z","message":"hi"}`))
+	if _, found := parseEntryDuration(line); found || line.decoded {
+		t.Errorf("found = %v, decoded = %v, want a line with only timestamps left undecoded", found, line.decoded)
+	}
+}
+
+func TestDurationAliases(t *testing.T) {
+	defer func(aliases map[string]FieldAlias) { fieldAliases = aliases }(fieldAliases)
+	defer func(unit string) { durationUnit = unit }(durationUnit)
+	fieldAliases = map[string]FieldAlias{"took_us": {Field: "event.duration", Unit: "us"}}
+	durationUnit = "ms"
+
+	entry, ok := parseLine(`{"message":"done","took_us":850000}`)
+	if !ok || entry.Event.Duration != int64(850*time.Millisecond) {
+		t.Errorf("aliased duration = %d, want %d", entry.Event.Duration, 850*time.Millisecond)
+	}
+	entry, _ = parseLine(`{"message":"done","took_us":5,"event":{"duration":850}}`)
+	if entry.Event.Duration != int64(850*time.Millisecond) {
+		t.Errorf("event.duration with an alias = %d, want %d", entry.Event.Duration, 850*time.Millisecond)
+	}
+}
+
+func TestSetDurationUnit(t *testing.T) {
+	defer func(unit string) { durationUnit = unit }(durationUnit)
+	for _, unit := range []string{"auto", "ns", "us", "ms", "s"} {
+		if err := setDurationUnit(unit); err != nil {
+			t.Errorf("setDurationUnit(%q) = %v", unit, err)
+		}
+	}
+	if err := setDurationUnit("minutes"); err == nil {
+		t.Error("setDurationUnit(minutes) succeeded")
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// maxEmbeddedDepth bounds how many layers of double-encoded JSON are
+// unwrapped.
+const maxEmbeddedDepth = 3
+
+// unwrapEmbeddedJSON handles double-encoded logs, where message or
+// log.original holds a JSON object as a string. The embedded object is
+// merged into the entry: its fields win over a message wrapper's, while
+// fields of the processed entry win over its log.original. Embedded objects
+// without a message of their own are kept in Embedded to be pretty-printed.
+func unwrapEmbeddedJSON(line string, entry LogEntry, depth int) LogEntry {
+	if depth >= maxEmbeddedDepth {
+		return entry
+	}
+
+	for _, field := range []struct {
+		path      string
+		value     string
+		innerWins bool
+	}{
+		{"message", entry.Message, true},
+		{"log.original", entry.Log.Original, false},
+	} {
+		inner := decodeJSONObject(field.value)
+		if inner == nil {
+			continue
+		}
+		outer := decodeJSONObject(line)
+		if outer == nil {
+			return entry
+		}
+		deleteField(outer, field.path)
+
+		merged, overlay := inner, outer
+		if field.innerWins {
+			merged, overlay = outer, inner
+		}
+		mergeFields(merged, overlay)
+
+		data, err := json.Marshal(merged)
+		if err != nil {
+			return entry
+		}
+		var unwrapped LogEntry
+		if err := json.Unmarshal(data, &unwrapped); err != nil {
+			// The embedded fields don't fit the entry (e.g. "user" as a
+			// string): show them as they are
+			if field.innerWins {
+				entry.Message = ""
+			}
+			entry.Embedded = inner
+			return entry
+		}
+		unwrapped = unwrapEmbeddedJSON(string(data), unwrapped, depth+1)
+		if unwrapped.Message == "" && unwrapped.Embedded == nil {
+			unwrapped.Embedded = inner
+		}
+		return unwrapped
+	}
+	return entry
+}
+
+// decodeJSONObject decodes s when it holds a single JSON object, or returns
+// nil.
+func decodeJSONObject(s string) map[string]interface{} {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+		return nil
+	}
+	var fields map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(s))
+	decoder.UseNumber()
+	if decoder.Decode(&fields) != nil || decoder.More() {
+		return nil
+	}
+	return fields
+}
+
+// mergeFields copies src into dst, recursing into objects present in both.
+func mergeFields(dst, src map[string]interface{}) {
+	for key, value := range src {
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		srcMap, srcIsMap := value.(map[string]interface{})
+		if dstIsMap && srcIsMap {
+			mergeFields(dstMap, srcMap)
+		} else {
+			dst[key] = value
+		}
+	}
+}
+
+// deleteField removes a dotted path given as a flattened key or nested
+// objects.
+func deleteField(fields map[string]interface{}, path string) {
+	if _, ok := fields[path]; ok {
+		delete(fields, path)
+		return
+	}
+	prefix, rest, ok := strings.Cut(path, ".")
+	if !ok {
+		return
+	}
+	if nested, ok := fields[prefix].(map[string]interface{}); ok {
+		deleteField(nested, rest)
+	}
+}
+
+// formatEmbedded pretty-prints an embedded object for display.
+func formatEmbedded(value interface{}) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if encoder.Encode(value) != nil {
+		return ""
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,75 @@
+package main
+
+import "testing"
+
+func TestUnwrapEmbeddedJSON(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		wantLevel    string
+		wantMessage  string
+		wantError    interface{}
+		wantEmbedded bool
+	}{
+		{
+			name:        "double-encoded message",
+			input:       `{"log.level":"info","message":"{\"log.level\":\"error\",\"message\":\"db down\",\"error\":\"timeout\"}"}`,
+			wantLevel:   "error",
+			wantMessage: "db down",
+			wantError:   "timeout",
+		},
+		{
+			name:        "triple-encoded message",
+			input:       `{"log.level":"warn","message":"{\"message\":\"{\\\"message\\\":\\\"deep\\\"}\"}"}`,
+			wantLevel:   "warn",
+			wantMessage: "deep",
+		},
+		{
+			name:        "log.original fills missing fields",
+			input:       `{"message":"processed","log":{"original":"{\"log.level\":\"warn\",\"message\":\"raw\"}"}}`,
+			wantLevel:   "warn",
+			wantMessage: "processed",
+		},
+		{
+			name:         "embedded object without message",
+			input:        `{"log.level":"info","message":"{\"user\":\"bob\",\"action\":\"login\"}"}`,
+			wantLevel:    "info",
+			wantEmbedded: true,
+		},
+		{
+			name:        "plain message",
+			input:       `{"log.level":"info","message":"{not json}"}`,
+			wantLevel:   "info",
+			wantMessage: "{not json}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, ok := parseLine(tt.input)
+			if !ok {
+				t.Fatal("parseLine() failed")
+			}
+			if entry.Level != tt.wantLevel || entry.Message != tt.wantMessage {
+				t.Errorf("level, message = %q, %q, want %q, %q", entry.Level, entry.Message, tt.wantLevel, tt.wantMessage)
+			}
+			if entry.Error != tt.wantError {
+				t.Errorf("error = %v, want %v", entry.Error, tt.wantError)
+			}
+			if (entry.Embedded != nil) != tt.wantEmbedded {
+				t.Errorf("embedded = %v, want embedded %v", entry.Embedded, tt.wantEmbedded)
+			}
+		})
+	}
+}
+
+func TestMergeFields(t *testing.T) {
+	dst := map[string]interface{}{"a": 1, "log": map[string]interface{}{"level": "info", "logger": "x"}}
+	src := map[string]interface{}{"b": 2, "log": map[string]interface{}{"level": "error"}}
+	mergeFields(dst, src)
+
+	log := dst["log"].(map[string]interface{})
+	if dst["a"] != 1 || dst["b"] != 2 || log["level"] != "error" || log["logger"] != "x" {
+		t.Errorf("mergeFields() = %v", dst)
+	}
+}
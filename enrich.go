@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// lookupTable maps the values of a field to labels read from a CSV or JSON
+// file, e.g. tenant IDs to customer names.
+type lookupTable struct {
+	field  string
+	labels map[string]string
+}
+
+// lookupTables are set with --enrich or the config's enrich.
+var lookupTables []*lookupTable
+
+// parseLookupSpec reads FIELD=FILE:KEY:VALUE, where KEY and VALUE are the
+// columns of a CSV file (named in its header) or the keys of the objects
+// of a JSON array. Without them, a CSV file's first two columns are used
+// and a JSON file must be an object mapping values to labels.
+func parseLookupSpec(spec string) (*lookupTable, error) {
+	field, source, ok := strings.Cut(spec, "=")
+	if !ok || field == "" || source == "" {
+		return nil, fmt.Errorf("%q: expected FIELD=FILE[:KEY:VALUE]", spec)
+	}
+	file, keyColumn, valueColumn := source, "", ""
+	// The file name may itself hold a colon, as in C:\users.csv
+	if parts := strings.Split(source, ":"); len(parts) >= 3 {
+		file = strings.Join(parts[:len(parts)-2], ":")
+		keyColumn, valueColumn = parts[len(parts)-2], parts[len(parts)-1]
+	}
+
+	var labels map[string]string
+	var err error
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		labels, err = readJSONLookup(file, keyColumn, valueColumn)
+	} else {
+		labels, err = readCSVLookup(file, keyColumn, valueColumn)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return &lookupTable{field: field, labels: labels}, nil
+}
+
+func readCSVLookup(file, keyColumn, valueColumn string) (map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	keyIndex, valueIndex := 0, 1
+	if keyColumn != "" {
+		keyIndex, valueIndex = slices.Index(header, keyColumn), slices.Index(header, valueColumn)
+		if keyIndex < 0 || valueIndex < 0 {
+			return nil, fmt.Errorf("columns %s and %s not both found in header %s", keyColumn, valueColumn, strings.Join(header, ","))
+		}
+	} else if len(header) < 2 {
+		return nil, errors.New("expected at least two columns")
+	}
+
+	labels := make(map[string]string)
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return labels, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if keyIndex < len(record) && valueIndex < len(record) {
+			labels[record[keyIndex]] = record[valueIndex]
+		}
+	}
+}
+
+func readJSONLookup(file, keyColumn, valueColumn string) (map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	decoder := json.NewDecoder(f)
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	labels := make(map[string]string)
+	switch doc := doc.(type) {
+	case map[string]interface{}:
+		if keyColumn != "" {
+			return nil, errors.New("KEY:VALUE only applies to an array of objects")
+		}
+		for key := range doc {
+			labels[key] = fieldString(doc, key)
+		}
+	case []interface{}:
+		if keyColumn == "" {
+			return nil, errors.New("an array of objects needs the KEY:VALUE keys to read")
+		}
+		for _, item := range doc {
+			if object, ok := item.(map[string]interface{}); ok {
+				if key := fieldString(object, keyColumn); key != "" {
+					labels[key] = fieldString(object, valueColumn)
+				}
+			}
+		}
+	default:
+		return nil, errors.New("expected an object or an array of objects")
+	}
+	return labels, nil
+}
+
+// Lookup is the label a lookup table gives to the value of a field.
+type Lookup struct {
+	Field, Value, Label string
+}
+
+// entryLookups returns the labels lookupTables have for an entry's fields.
+func entryLookups(fields map[string]interface{}) []Lookup {
+	var lookups []Lookup
+	for _, table := range lookupTables {
+		value := fieldString(fields, table.field)
+		if label := table.labels[value]; value != "" && label != "" {
+			lookups = append(lookups, Lookup{Field: table.field, Value: value, Label: label})
+		}
+	}
+	return lookups
+}
+
+// lookupSuffix shows the labels of an entry dimmed after its message, e.g.
+// "tenant_id=t-42 (Acme Corp)".
+func lookupSuffix(log LogEntry) string {
+	var b strings.Builder
+	for _, l := range log.Lookups {
+		b.WriteString(" " + labelPaint.sprint(l.Field+"="+l.Value+" ("+l.Label+")"))
+	}
+	return b.String()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestParseLookupSpec(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"users.csv":     "id,email,name\nu-1,ada@example.com,Ada Lovelace\nu-2,alan@example.com,\"Turing, Alan\"\nshort\n",
+		"tenants.csv":   "tenant,customer\nt-1,Acme Corp\n",
+		"tenants.json":  `{"t-1": "Acme Corp", "7": 42}`,
+		"services.json": `[{"id": 7, "owner": "payments"}, {"owner": "none"}, "x"]`,
+	}
+	for name, content := range files {
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+	}
+
+	tests := []struct {
+		name    string
+		spec    string
+		field   string
+		labels  map[string]string
+		wantErr string
+	}{
+		{
+			name:   "csv columns",
+			spec:   "user_id=" + dir + "/users.csv:id:name",
+			field:  "user_id",
+			labels: map[string]string{"u-1": "Ada Lovelace", "u-2": "Turing, Alan"},
+		},
+		{
+			name:   "csv first columns",
+			spec:   "tenant.id=" + dir + "/tenants.csv",
+			field:  "tenant.id",
+			labels: map[string]string{"t-1": "Acme Corp"},
+		},
+		{
+			name:   "json object",
+			spec:   "tenant.id=" + dir + "/tenants.json",
+			field:  "tenant.id",
+			labels: map[string]string{"t-1": "Acme Corp", "7": "42"},
+		},
+		{
+			name:   "json array",
+			spec:   "service.id=" + dir + "/services.json:id:owner",
+			field:  "service.id",
+			labels: map[string]string{"7": "payments"},
+		},
+		{name: "no field", spec: "users.csv", wantErr: "expected FIELD=FILE"},
+		{name: "unknown column", spec: "user_id=" + dir + "/users.csv:id:team", wantErr: "columns id and team not both found"},
+		{name: "json array without keys", spec: "service.id=" + dir + "/services.json", wantErr: "needs the KEY:VALUE"},
+		{name: "missing file", spec: "user_id=" + dir + "/none.csv", wantErr: "no such file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := parseLookupSpec(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if table.field != tt.field || !reflect.DeepEqual(table.labels, tt.labels) {
+				t.Errorf("table = %s %v, want %s %v", table.field, table.labels, tt.field, tt.labels)
+			}
+		})
+	}
+}
+
+func TestEntryLookups(t *testing.T) {
+	saved := lookupTables
+	defer func() { lookupTables = saved }()
+	lookupTables = []*lookupTable{
+		{field: "user_id", labels: map[string]string{"u-1": "Ada Lovelace"}},
+		{field: "tenant.id", labels: map[string]string{"7": "Acme Corp"}},
+	}
+
+	tests := []struct {
+		name string
+		line string
+		want []Lookup
+	}{
+		{
+			name: "both",
+			line: `{"user_id":"u-1","tenant":{"id":7}}`,
+			want: []Lookup{{"user_id", "u-1", "Ada Lovelace"}, {"tenant.id", "7", "Acme Corp"}},
+		},
+		{name: "unknown value", line: `{"user_id":"u-9"}`},
+		{name: "no field", line: `{"message":"hi"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := entryLookups(decodeJSONObject(tt.line)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("entryLookups() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+	log := LogEntry{Lookups: []Lookup{{"user_id", "u-1", "Ada Lovelace"}}}
+	if got, want := lookupSuffix(log), " user_id=u-1 (Ada Lovelace)"; got != want {
+		t.Errorf("lookupSuffix() = %q, want %q", got, want)
+	}
+}
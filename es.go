@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// esBatchSize is how many hits each search request asks for.
+var esBatchSize = 1000
+
+// esPollInterval is how often --follow searches for new entries.
+var esPollInterval = 2 * time.Second
+
+// esDefaultSince is how far back searches start without --since.
+const esDefaultSince = time.Hour
+
+// esKeepAlive is how long a point in time is kept between two searches.
+const esKeepAlive = "1m"
+
+// esOptions configures `logpipe es`.
+type esOptions struct {
+	addr      string
+	index     string
+	query     string
+	timeField string
+	limit     int
+	follow    bool
+}
+
+func (o *esOptions) register(fs *flag.FlagSet) {
+	addr := os.Getenv("ELASTICSEARCH_URL")
+	if addr == "" {
+		addr = "http://localhost:9200"
+	}
+	fs.StringVar(&o.addr, "addr", addr, "Elasticsearch address (default: $ELASTICSEARCH_URL or http://localhost:9200)")
+	fs.StringVar(&o.index, "index", "", "Index, alias or pattern to search, e.g. 'logs-*'")
+	fs.StringVar(&o.query, "query", "*", "Query string, e.g. 'service.name:api AND log.level:error'")
+	fs.StringVar(&o.timeField, "time-field", "@timestamp", "Field holding each document's timestamp")
+	fs.IntVar(&o.limit, "limit", 0, "Most entries read before following (default: all in the time range)")
+	registerFollowSwitch(fs, &o.follow, "Keep polling for new entries after the time range")
+}
+
+// esHit is a search hit. Its sort values are its timestamp in milliseconds
+// and its _shard_doc tiebreaker, passed back as search_after to get the
+// next page.
+type esHit struct {
+	ID     string          `json:"_id"`
+	Source json.RawMessage `json:"_source"`
+	Sort   []json.Number   `json:"sort"`
+}
+
+func (h esHit) millis() int64 {
+	if len(h.Sort) == 0 {
+		return 0
+	}
+	n, _ := h.Sort[0].Int64()
+	return n
+}
+
+// esClient searches an Elasticsearch cluster.
+type esClient struct {
+	addr   string
+	header http.Header
+	opts   esOptions
+}
+
+func newESClient(opts esOptions) (*esClient, error) {
+	if opts.index == "" {
+		return nil, errors.New("--index is required, e.g. --index 'logs-*'")
+	}
+	u, err := url.Parse(opts.addr)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported address scheme %q (expected http or https)", u.Scheme)
+	}
+	header := http.Header{"Content-Type": {"application/json"}}
+	for name, value := range headersFor(opts.addr) {
+		header.Set(name, value)
+	}
+	if key := os.Getenv("ELASTIC_API_KEY"); key != "" && header.Get("Authorization") == "" {
+		header.Set("Authorization", "ApiKey "+key)
+	}
+	return &esClient{addr: strings.TrimSuffix(opts.addr, "/"), header: header, opts: opts}, nil
+}
+
+// do sends a request with a JSON body and decodes the JSON response into
+// result.
+func (c *esClient) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.addr+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for name, values := range c.header {
+		req.Header[name] = values
+	}
+	resp, err := getObject(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+	if result == nil {
+		_, err = io.Copy(io.Discard, resp)
+		return err
+	}
+	decoder := json.NewDecoder(resp)
+	decoder.UseNumber()
+	return decoder.Decode(result)
+}
+
+// search builds a search for the query over [from, until) in milliseconds
+// (until 0: open) in a point in time, oldest first.
+func (c *esClient) search(pit string, from, until int64, size int, after []json.Number) map[string]interface{} {
+	bounds := map[string]interface{}{"gte": from, "format": "epoch_millis"}
+	if until > 0 {
+		bounds["lt"] = until
+	}
+	search := map[string]interface{}{
+		"size": size,
+		"pit":  map[string]interface{}{"id": pit, "keep_alive": esKeepAlive},
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{"query_string": map[string]interface{}{"query": c.opts.query}},
+					map[string]interface{}{"range": map[string]interface{}{c.opts.timeField: bounds}},
+				},
+			},
+		},
+		"sort": []interface{}{
+			map[string]interface{}{c.opts.timeField: "asc"},
+			map[string]interface{}{"_shard_doc": "asc"},
+		},
+	}
+	if after != nil {
+		search["search_after"] = after
+	}
+	return search
+}
+
+// esSearchResult is the part of a search response logpipe reads.
+type esSearchResult struct {
+	PitID string `json:"pit_id"`
+	Hits  struct {
+		Hits []esHit `json:"hits"`
+	} `json:"hits"`
+}
+
+// consumeElasticsearch reads the documents matching a query over a time
+// range, then with --follow polls for newer ones until ctx is cancelled.
+// Without --since, the range starts an hour ago.
+func consumeElasticsearch(ctx context.Context, opts esOptions, timeFilter *timeRange, handle lineHandler) error {
+	client, err := newESClient(opts)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	start, end := now.Add(-esDefaultSince), now
+	if timeFilter != nil {
+		if !timeFilter.since.IsZero() {
+			start = timeFilter.since
+		}
+		if !timeFilter.until.IsZero() {
+			if opts.follow {
+				return errors.New("--until can't be used with --follow")
+			}
+			end = timeFilter.until
+		}
+	}
+
+	if err := client.readRange(ctx, start.UnixMilli(), end.UnixMilli(), handle); err != nil || !opts.follow {
+		return ignoreClosed(ctx, err)
+	}
+	return ignoreClosed(ctx, client.poll(ctx, end.UnixMilli(), handle))
+}
+
+// each hands the hits of a search over [from, until) to fn, oldest first,
+// until fn returns false. Pages are searched in a point in time with
+// search_after, so that documents indexed meanwhile don't shift them.
+func (c *esClient) each(ctx context.Context, from, until int64, size int, fn func(esHit) bool) error {
+	var pit struct {
+		ID string `json:"id"`
+	}
+	err := c.do(ctx, http.MethodPost, "/"+url.PathEscape(c.opts.index)+"/_pit?keep_alive="+esKeepAlive, nil, &pit)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Free the point in time, even after Ctrl-C
+		c.do(context.Background(), http.MethodDelete, "/_pit", map[string]interface{}{"id": pit.ID}, nil)
+	}()
+
+	var after []json.Number
+	for {
+		var result esSearchResult
+		if err := c.do(ctx, http.MethodPost, "/_search", c.search(pit.ID, from, until, size, after), &result); err != nil {
+			return err
+		}
+		if result.PitID != "" {
+			pit.ID = result.PitID
+		}
+		hits := result.Hits.Hits
+		for _, hit := range hits {
+			if !fn(hit) {
+				return nil
+			}
+		}
+		if len(hits) < size {
+			return nil
+		}
+		after = hits[len(hits)-1].Sort
+	}
+}
+
+// readRange hands over the documents of a time range, batch by batch.
+func (c *esClient) readRange(ctx context.Context, from, until int64, handle lineHandler) error {
+	size := esBatchSize
+	if c.opts.limit > 0 {
+		size = min(size, c.opts.limit)
+	}
+	read := 0
+	return c.each(ctx, from, until, size, func(hit esHit) bool {
+		if c.opts.limit > 0 && read == c.opts.limit {
+			return false
+		}
+		handle(esLine(hit), "")
+		read++
+		return true
+	})
+}
+
+// poll searches for documents from a time on every esPollInterval. Each
+// search starts at the last timestamp seen, so documents indexed late with
+// that same timestamp are found too, and skips the ones at that timestamp
+// already handed over.
+func (c *esClient) poll(ctx context.Context, from int64, handle lineHandler) error {
+	last := from
+	// IDs of the documents handed over at the last timestamp
+	seen := map[string]bool{}
+	ticker := time.NewTicker(esPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		err := c.each(ctx, last, 0, esBatchSize, func(hit esHit) bool {
+			if seen[hit.ID] {
+				return true
+			}
+			if hit.millis() != last {
+				clear(seen)
+				last = hit.millis()
+			}
+			seen[hit.ID] = true
+			handle(esLine(hit), "")
+			return true
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// esLine turns a hit into a log line: its source document on one line.
+func esLine(hit esHit) string {
+	var compact bytes.Buffer
+	if json.Compact(&compact, hit.Source) != nil {
+		return string(hit.Source)
+	}
+	return compact.String()
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeElasticsearch serves searches in points in time over a fixed set of
+// documents, counting the points in time opened and not yet closed. Polls
+// find a new document, then another one indexed late with the same
+// timestamp and a last one.
+func fakeElasticsearch(t *testing.T) (*httptest.Server, *int) {
+	type doc struct {
+		id     string
+		millis int64
+		source string
+	}
+	var mu sync.Mutex
+	docs := []doc{
+		{"a", 1000, `{"message": "one"}`},
+		{"b", 2000, `{"message":"two"}`},
+		{"c", 3000, `{"message":"three"}`},
+		{"d", 9000, `{"message":"after the range"}`},
+	}
+	polls := 0
+	open := 0
+	future := time.Now().Add(time.Hour).UnixMilli()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("Authorization") != "ApiKey key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			Size int `json:"size"`
+			Pit  struct {
+				ID string `json:"id"`
+			} `json:"pit"`
+			ID          string              `json:"id"`
+			SearchAfter []int64             `json:"search_after"`
+			Sort        []map[string]string `json:"sort"`
+			Query       struct {
+				Bool struct {
+					Filter []struct {
+						QueryString struct {
+							Query string `json:"query"`
+						} `json:"query_string"`
+						Range map[string]struct {
+							GTE int64 `json:"gte"`
+							LT  int64 `json:"lt"`
+						} `json:"range"`
+					} `json:"filter"`
+				} `json:"bool"`
+			} `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		switch {
+		case r.Method == http.MethodPost && (r.URL.Path == "/logs-%2A/_pit" || r.URL.Path == "/logs-*/_pit"):
+			open++
+			fmt.Fprintf(w, `{"id":"pit-%d"}`, open)
+		case r.Method == http.MethodDelete && r.URL.Path == "/_pit":
+			if body.ID != "" {
+				open--
+			}
+			fmt.Fprint(w, `{"succeeded":true}`)
+		case r.URL.Path == "/_search":
+			if !strings.HasPrefix(body.Pit.ID, "pit-") || len(body.Sort) != 2 || body.Sort[1]["_shard_doc"] != "asc" {
+				http.Error(w, "expected a search in a point in time with a tiebreaker", http.StatusBadRequest)
+				return
+			}
+			filter := body.Query.Bool.Filter
+			bounds := filter[1].Range["@timestamp"]
+			if bounds.LT == 0 && body.SearchAfter == nil {
+				polls++
+				switch polls {
+				case 1:
+					docs = append(docs, doc{"e", future, `{"message":"tailed"}`})
+				case 2:
+					docs = append(docs, doc{"f", future, `{"message":"late at the same time"}`}, doc{"g", future + 1, `{"message":"last"}`})
+				}
+			}
+			// Documents are sorted by timestamp, then by their position
+			// standing in for _shard_doc
+			var hits []string
+			for i, d := range docs {
+				if d.millis < bounds.GTE || (bounds.LT != 0 && d.millis >= bounds.LT) {
+					continue
+				}
+				if query := filter[0].QueryString.Query; query != "*" && !strings.Contains(d.source, query) {
+					continue
+				}
+				if after := body.SearchAfter; after != nil && (d.millis < after[0] || (d.millis == after[0] && int64(i) <= after[1])) {
+					continue
+				}
+				hits = append(hits, fmt.Sprintf(`{"_id":%q,"_source":%s,"sort":[%d,%d]}`, d.id, d.source, d.millis, i))
+				if len(hits) == body.Size {
+					break
+				}
+			}
+			fmt.Fprintf(w, `{"pit_id":%q,"hits":{"hits":[%s]}}`, body.Pit.ID, strings.Join(hits, ","))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return server, &open
+}
+
+func TestElasticsearch(t *testing.T) {
+	t.Setenv("ELASTIC_API_KEY", "key")
+	defer func(size int, interval time.Duration) { esBatchSize, esPollInterval = size, interval }(esBatchSize, esPollInterval)
+	esBatchSize, esPollInterval = 2, time.Millisecond
+
+	tests := []struct {
+		name    string
+		opts    esOptions
+		until   int64
+		want    []string
+		wantErr string
+	}{
+		{
+			name:  "pages",
+			opts:  esOptions{query: "*"},
+			until: 5000,
+			want:  []string{`{"message":"one"}`, `{"message":"two"}`, `{"message":"three"}`},
+		},
+		{
+			name:  "query",
+			opts:  esOptions{query: "two"},
+			until: 5000,
+			want:  []string{`{"message":"two"}`},
+		},
+		{
+			name:  "limit",
+			opts:  esOptions{query: "*", limit: 2},
+			until: 5000,
+			want:  []string{`{"message":"one"}`, `{"message":"two"}`},
+		},
+		{
+			name: "read then poll",
+			opts: esOptions{query: "*", follow: true},
+			want: []string{
+				`{"message":"one"}`, `{"message":"two"}`, `{"message":"three"}`, `{"message":"after the range"}`,
+				`{"message":"tailed"}`, `{"message":"late at the same time"}`, `{"message":"last"}`,
+			},
+		},
+		{
+			name:    "missing index",
+			opts:    esOptions{},
+			wantErr: "--index is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, open := fakeElasticsearch(t)
+			defer server.Close()
+			tt.opts.addr = server.URL
+			if tt.wantErr == "" {
+				tt.opts.index = "logs-*"
+			}
+			tt.opts.timeField = "@timestamp"
+			timeFilter := &timeRange{since: time.UnixMilli(1)}
+			if tt.until > 0 {
+				timeFilter.until = time.UnixMilli(tt.until)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			var got []string
+			err := consumeElasticsearch(ctx, tt.opts, timeFilter, func(line, label string) {
+				got = append(got, line)
+				if len(got) == len(tt.want) && tt.opts.follow {
+					cancel()
+				}
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if *open != 0 {
+				t.Errorf("%d points in time left open", *open)
+			}
+		})
+	}
+}
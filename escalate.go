@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// escalationWebhookTimeout bounds each --escalate-webhook request.
+const escalationWebhookTimeout = 5 * time.Second
+
+// escalation watches for bursts of errors for --escalate: when error or
+// fatal entries within window reach threshold, a burst starts, and it ends
+// once they fall to half the threshold.
+type escalation struct {
+	threshold int
+	window    time.Duration
+	errors    []time.Time
+	inBurst   bool
+	now       func() time.Time
+}
+
+// burst describes the onset of a burst of errors.
+type burst struct {
+	Count   int       `json:"count"`
+	Window  string    `json:"window"`
+	Since   time.Time `json:"since"`
+	Message string    `json:"message"`
+}
+
+// parseEscalation reads --escalate values such as "20/1m": the number of
+// errors within a duration that makes a burst.
+func parseEscalation(spec string) (*escalation, error) {
+	count, window, ok := strings.Cut(spec, "/")
+	threshold, err := strconv.Atoi(count)
+	if !ok || err != nil || threshold < 1 {
+		return nil, fmt.Errorf("%q: expected COUNT/DURATION, e.g. 20/1m", spec)
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return nil, fmt.Errorf("%q: invalid duration %q", spec, window)
+	}
+	return &escalation{threshold: threshold, window: d, now: time.Now}, nil
+}
+
+// record counts an entry and returns the burst it starts, if any. Entries
+// are placed in time by their timestamp, or by their arrival when they
+// have none, so replayed files are judged like live streams.
+func (e *escalation) record(entry LogEntry) *burst {
+	if level := normalizeLevel(entry.Level); level != "error" && level != "fatal" {
+		return nil
+	}
+	at, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
+	if err != nil {
+		at = e.now()
+	}
+
+	e.errors = append(e.errors, at)
+	start := 0
+	for start < len(e.errors) && at.Sub(e.errors[start]) >= e.window {
+		start++
+	}
+	e.errors = e.errors[start:]
+
+	switch count := len(e.errors); {
+	case !e.inBurst && count >= e.threshold:
+		e.inBurst = true
+		return &burst{Count: count, Window: e.window.String(), Since: e.errors[0], Message: entry.Message}
+	case e.inBurst && count <= e.threshold/2:
+		e.inBurst = false
+	}
+	return nil
+}
+
+// burstPaint is the color of burst banners.
+var burstPaint = newPaint(color.FgWhite, color.BgRed, color.Bold)
+
+// banner returns the line marking the onset of a burst.
+func (b *burst) banner() string {
+	text := fmt.Sprintf(" ▲ error burst: %d errors within %s, since %s ", b.Count, b.Window, b.Since.Format("15:04:05.000"))
+	return burstPaint.sprint(text)
+}
+
+// webhookNotifier posts bursts to --escalate-webhook as JSON, in the
+// background so slow endpoints don't hold up the output.
+type webhookNotifier struct {
+	url     string
+	pending sync.WaitGroup
+}
+
+// notify sends a burst. The text field makes the payload usable as a Slack
+// or Mattermost message.
+func (n *webhookNotifier) notify(b *burst) {
+	payload := struct {
+		Text string `json:"text"`
+		*burst
+	}{
+		Text:  fmt.Sprintf("logpipe: %d errors within %s since %s, latest: %s", b.Count, b.Window, b.Since.Format(time.RFC3339), b.Message),
+		burst: b,
+	}
+	data, _ := json.Marshal(payload)
+	n.pending.Add(1)
+	go func() {
+		defer n.pending.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), escalationWebhookTimeout)
+		defer cancel()
+		if err := postJSON(ctx, n.url, data); err != nil {
+			fmt.Fprintf(os.Stderr, "Error sending --escalate-webhook: %v\n", err)
+		}
+	}()
+}
+
+// wait lets alerts still being sent finish before logpipe exits. It is
+// safe to call on a nil notifier.
+func (n *webhookNotifier) wait() {
+	if n != nil {
+		n.pending.Wait()
+	}
+}
+
+func postJSON(ctx context.Context, url string, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return nil
+}
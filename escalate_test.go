@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseEscalation(t *testing.T) {
+	tests := []struct {
+		spec      string
+		threshold int
+		window    time.Duration
+		wantErr   bool
+	}{
+		{"20/1m", 20, time.Minute, false},
+		{"5/30s", 5, 30 * time.Second, false},
+		{"20", 0, 0, true},
+		{"0/1m", 0, 0, true},
+		{"x/1m", 0, 0, true},
+		{"5/soon", 0, 0, true},
+		{"5/-1s", 0, 0, true},
+	}
+	for _, tt := range tests {
+		e, err := parseEscalation(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseEscalation(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if err == nil && (e.threshold != tt.threshold || e.window != tt.window) {
+			t.Errorf("parseEscalation(%q) = %d/%v", tt.spec, e.threshold, e.window)
+		}
+	}
+}
+
+func TestEscalationRecord(t *testing.T) {
+	e, _ := parseEscalation("3/10s")
+	at := func(level string, second int) LogEntry {
+		ts := time.Date(2024, 1, 15, 10, 0, second, 0, time.UTC)
+		return LogEntry{Level: level, Message: "db down", Timestamp: ts.Format(time.RFC3339Nano)}
+	}
+
+	steps := []struct {
+		entry LogEntry
+		burst bool
+	}{
+		{at("error", 0), false},
+		{at("info", 1), false},
+		{at("ERR", 2), false},
+		{at("fatal", 3), true},   // 3 errors within 10s
+		{at("error", 4), false},  // same burst
+		{at("error", 20), false}, // 1 left: burst over
+		{at("error", 21), false},
+		{at("error", 40), false},
+		{at("error", 41), false},
+		{at("error", 42), true}, // a new burst
+	}
+	for i, step := range steps {
+		b := e.record(step.entry)
+		if (b != nil) != step.burst {
+			t.Fatalf("step %d: record() = %v, want burst %v", i, b, step.burst)
+		}
+		if b != nil && b.Count != 3 {
+			t.Errorf("step %d: burst count = %d, want 3", i, b.Count)
+		}
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	received := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		received <- payload
+	}))
+	defer server.Close()
+
+	n := &webhookNotifier{url: server.URL}
+	n.notify(&burst{Count: 3, Window: "10s", Since: time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC), Message: "db down"})
+	n.wait()
+
+	payload := <-received
+	if payload["count"] != 3.0 || payload["message"] != "db down" || payload["text"] == "" {
+		t.Errorf("payload = %v", payload)
+	}
+
+	var none *webhookNotifier
+	none.wait()
+}
@@ -0,0 +1,515 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// expr is a compiled expression over an entry's fields, as used by derived
+// fields and --where. It supports field paths, numbers, quoted strings,
+// arithmetic (+ - * / %, with + joining strings), comparisons
+// (== != < <= > >=), regex matches (=~ !~ or "matches"), boolean
+// operators (&& || ! or "and", "or", "not") and durations (2s, 250ms).
+type expr struct {
+	source string
+	eval   exprFunc
+}
+
+// exprFunc evaluates to a float64, string, bool or nil when a field is
+// missing or an operation doesn't apply (e.g. division by zero).
+type exprFunc func(lookup func(path string) interface{}) interface{}
+
+// compileExpr parses an expression.
+func compileExpr(source string) (*expr, error) {
+	tokens, err := tokenizeExpr(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	eval, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.tokens[p.pos].text, p.tokens[p.pos].offset)
+	}
+	return &expr{source: source, eval: eval}, nil
+}
+
+// evaluate runs the expression against a decoded JSON object.
+func (e *expr) evaluate(fields map[string]interface{}) interface{} {
+	return e.eval(func(path string) interface{} {
+		value, _ := lookupField(fields, path)
+		return exprValue(value)
+	})
+}
+
+// exprValue converts a decoded JSON value to an expression value. Objects
+// and arrays are treated as missing.
+func exprValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return nil
+		}
+		return f
+	case float64, string, bool:
+		return v
+	}
+	return nil
+}
+
+// truthy reports whether a value counts as true: true, a non-zero number
+// or a non-empty string.
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	}
+	return false
+}
+
+type exprTokenKind int
+
+const (
+	tokenNumber exprTokenKind = iota
+	tokenString
+	tokenField
+	tokenOperator
+)
+
+type exprToken struct {
+	kind   exprTokenKind
+	text   string
+	number float64
+	offset int
+}
+
+// exprDurationUnits are the units of duration literals such as 250ms,
+// which evaluate to nanoseconds.
+var exprDurationUnits = map[string]float64{
+	"ns": 1, "us": 1e3, "ms": 1e6, "s": 1e9, "m": 60e9, "h": 3600e9,
+}
+
+// exprKeywords are operators that can be spelled out.
+var exprKeywords = map[string]string{
+	"and": "&&", "or": "||", "not": "!", "matches": "=~",
+}
+
+func tokenizeExpr(s string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9':
+			start := i
+			for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+				i++
+			}
+			if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+				j := i + 1
+				if j < len(s) && (s[j] == '+' || s[j] == '-') {
+					j++
+				}
+				if j < len(s) && s[j] >= '0' && s[j] <= '9' {
+					for i = j; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+					}
+				}
+			}
+			n, err := strconv.ParseFloat(s[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at offset %d", s[start:i], start)
+			}
+			// A unit makes a duration in nanoseconds, like event.duration
+			unitEnd := i
+			for unitEnd < len(s) && isFieldStart(rune(s[unitEnd])) {
+				unitEnd++
+			}
+			if unitEnd > i {
+				unit, ok := exprDurationUnits[s[i:unitEnd]]
+				if !ok {
+					return nil, fmt.Errorf("unknown unit %q at offset %d", s[i:unitEnd], i)
+				}
+				n *= unit
+				i = unitEnd
+			}
+			tokens = append(tokens, exprToken{kind: tokenNumber, text: s[start:i], number: n, offset: start})
+		case c == '"' || c == '\'':
+			start := i
+			var b strings.Builder
+			for i++; i < len(s) && s[i] != c; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+					switch s[i] {
+					case 'n':
+						b.WriteByte('\n')
+					case 't':
+						b.WriteByte('\t')
+					default:
+						b.WriteByte(s[i])
+					}
+					continue
+				}
+				b.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("unterminated string at offset %d", start)
+			}
+			i++
+			tokens = append(tokens, exprToken{kind: tokenString, text: b.String(), offset: start})
+		case isFieldStart(rune(c)):
+			start := i
+			for i < len(s) && isFieldChar(rune(s[i])) {
+				i++
+			}
+			word := s[start:i]
+			if op, ok := exprKeywords[word]; ok {
+				tokens = append(tokens, exprToken{kind: tokenOperator, text: op, offset: start})
+			} else {
+				tokens = append(tokens, exprToken{kind: tokenField, text: word, offset: start})
+			}
+		default:
+			op := ""
+			for _, candidate := range []string{"==", "!=", "<=", ">=", "=~", "!~", "&&", "||", "<", ">", "+", "-", "*", "/", "%", "(", ")", "!"} {
+				if strings.HasPrefix(s[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at offset %d", c, i)
+			}
+			tokens = append(tokens, exprToken{kind: tokenOperator, text: op, offset: i})
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+func isFieldStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_' || r == '@'
+}
+
+func isFieldChar(r rune) bool {
+	return isFieldStart(r) || unicode.IsDigit(r) || r == '.'
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+// accept consumes the next token if it's one of the given operators.
+func (p *exprParser) accept(ops ...string) (string, bool) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if p.tokens[p.pos].text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) parseOr() (exprFunc, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(lookup func(string) interface{}) interface{} {
+			return truthy(l(lookup)) || truthy(right(lookup))
+		}
+	}
+}
+
+func (p *exprParser) parseAnd() (exprFunc, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(lookup func(string) interface{}) interface{} {
+			return truthy(l(lookup)) && truthy(right(lookup))
+		}
+	}
+}
+
+func (p *exprParser) parseComparison() (exprFunc, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := p.accept("=~", "!~"); ok {
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenString {
+			return nil, fmt.Errorf("%s expects a quoted regular expression", op)
+		}
+		re, err := regexp.Compile(p.tokens[p.pos].text)
+		if err != nil {
+			return nil, err
+		}
+		p.pos++
+		negate := op == "!~"
+		return func(lookup func(string) interface{}) interface{} {
+			value := left(lookup)
+			if value == nil {
+				return negate
+			}
+			return re.MatchString(exprString(value)) != negate
+		}, nil
+	}
+	op, ok := p.accept("==", "!=", "<=", ">=", "<", ">")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	return func(lookup func(string) interface{}) interface{} {
+		return compareValues(op, left(lookup), right(lookup))
+	}, nil
+}
+
+func (p *exprParser) parseSum() (exprFunc, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(lookup func(string) interface{}) interface{} {
+			return arithmetic(op, l(lookup), right(lookup))
+		}
+	}
+}
+
+func (p *exprParser) parseProduct() (exprFunc, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(lookup func(string) interface{}) interface{} {
+			return arithmetic(op, l(lookup), right(lookup))
+		}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprFunc, error) {
+	op, ok := p.accept("-", "!")
+	if !ok {
+		return p.parsePrimary()
+	}
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if op == "!" {
+		return func(lookup func(string) interface{}) interface{} {
+			return !truthy(operand(lookup))
+		}, nil
+	}
+	return func(lookup func(string) interface{}) interface{} {
+		return arithmetic("-", 0.0, operand(lookup))
+	}, nil
+}
+
+func (p *exprParser) parsePrimary() (exprFunc, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	switch token.kind {
+	case tokenNumber:
+		return func(func(string) interface{}) interface{} { return token.number }, nil
+	case tokenString:
+		return func(func(string) interface{}) interface{} { return token.text }, nil
+	case tokenField:
+		switch token.text {
+		case "true", "false":
+			value := token.text == "true"
+			return func(func(string) interface{}) interface{} { return value }, nil
+		case "null":
+			return func(func(string) interface{}) interface{} { return nil }, nil
+		}
+		return func(lookup func(string) interface{}) interface{} { return lookup(token.text) }, nil
+	}
+	if token.text == "(" {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return nil, fmt.Errorf("missing ) for ( at offset %d", token.offset)
+		}
+		return inner, nil
+	}
+	return nil, fmt.Errorf("unexpected %q at offset %d", token.text, token.offset)
+}
+
+// exprNumber converts a value to a number, parsing numeric strings.
+func exprNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// exprString formats a value for string operations.
+func exprString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// arithmetic applies a numeric operator. + joins the operands when either
+// is a string. Missing operands and invalid results give nil.
+func arithmetic(op string, a, b interface{}) interface{} {
+	if a == nil || b == nil {
+		return nil
+	}
+	if op == "+" {
+		_, aString := a.(string)
+		_, bString := b.(string)
+		if aString || bString {
+			return exprString(a) + exprString(b)
+		}
+	}
+	x, ok := exprNumber(a)
+	if !ok {
+		return nil
+	}
+	y, ok := exprNumber(b)
+	if !ok {
+		return nil
+	}
+	var result float64
+	switch op {
+	case "+":
+		result = x + y
+	case "-":
+		result = x - y
+	case "*":
+		result = x * y
+	case "/":
+		result = x / y
+	case "%":
+		result = math.Mod(x, y)
+	}
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return nil
+	}
+	return result
+}
+
+// compareValues compares numerically when both values are numbers (or
+// numeric strings compared with a number), and as strings otherwise.
+// Ordering comparisons with a missing value are false.
+func compareValues(op string, a, b interface{}) bool {
+	if a == nil || b == nil {
+		switch op {
+		case "==":
+			return a == nil && b == nil
+		case "!=":
+			return (a == nil) != (b == nil)
+		}
+		return false
+	}
+
+	_, aNumber := a.(float64)
+	_, bNumber := b.(float64)
+	var cmp int
+	if aNumber || bNumber {
+		x, xok := exprNumber(a)
+		y, yok := exprNumber(b)
+		if !xok || !yok {
+			return op == "!="
+		}
+		switch {
+		case x < y:
+			cmp = -1
+		case x > y:
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(exprString(a), exprString(b))
+	}
+
+	switch op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	}
+	return cmp >= 0
+}
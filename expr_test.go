@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpr(t *testing.T) {
+	fields := decodeJSONObject(`{"http":{"request":{"method":"GET"},"response":{"status_code":503,"body":{"bytes":2048}}},` +
+		`"url.path":"/api/users","event":{"duration":500000000},"user":"bob","retries":"3","ok":false}`)
+
+	tests := []struct {
+		expr string
+		want interface{}
+	}{
+		{`http.response.body.bytes / (event.duration/1e9)`, 4096.0},
+		{`http.request.method + " " + url.path`, "GET /api/users"},
+		{`-event.duration / 1e6 + 1`, -499.0},
+		{`7 % 4 * 2`, 6.0},
+		{`retries * 2`, 6.0},
+		{`http.response.status_code >= 500`, true},
+		{`http.response.status_code == 503 && user == "bob"`, true},
+		{`user != 'bob' or ok`, false},
+		{`not ok`, true},
+		{`url.path =~ "^/api/"`, true},
+		{`url.path matches "^/healthz"`, false},
+		{`missing !~ "x"`, true},
+		{`missing == null`, true},
+		{`missing > 1`, false},
+		{`missing + 1`, nil},
+		{`1 / 0`, nil},
+		{`user + 1`, "bob1"},
+		{`"b" < "c"`, true},
+		{`event.duration < 1s && event.duration >= 500ms`, true},
+		{`1.5m / 1e9`, 90.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := compileExpr(tt.expr)
+			if err != nil {
+				t.Fatalf("compileExpr() error = %v", err)
+			}
+			if got := e.evaluate(fields); got != tt.want {
+				t.Errorf("evaluate() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileExprErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{`a +`, "unexpected end of expression"},
+		{`(a + 1`, "missing ) for ( at offset 0"},
+		{`a b`, `unexpected "b" at offset 2`},
+		{`"open`, "unterminated string at offset 0"},
+		{`a =~ b`, "=~ expects a quoted regular expression"},
+		{`a ~ 1`, `unexpected '~' at offset 2`},
+		{`a > 2weeks`, `unknown unit "weeks" at offset 5`},
+	}
+	for _, tt := range tests {
+		if _, err := compileExpr(tt.expr); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("compileExpr(%q) error = %v, want %q", tt.expr, err, tt.wantErr)
+		}
+	}
+}
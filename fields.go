@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// lineFields is a JSON log line, decoded into a fields map once, on first
+// use. The parsers of UnmarshalJSON share it, and entryFields reuses it
+// once the entry is rendered.
+type lineFields struct {
+	// source is the line as read, and text the line the parsers read,
+	// after normalizeEntry.
+	source, text string
+	fields       map[string]interface{}
+	decoded      bool
+	// complete is set once entryFields has aliased, correlated and
+	// derived the fields.
+	complete bool
+}
+
+func newLineFields(data []byte) *lineFields {
+	text := string(data)
+	return &lineFields{source: text, text: text}
+}
+
+// object returns the fields of the line, or nil when it is not a JSON
+// object.
+func (f *lineFields) object() map[string]interface{} {
+	if !f.decoded {
+		f.fields = decodeJSONObject(f.text)
+		f.decoded = true
+	}
+	return f.fields
+}
+
+// normalizeEntry applies the configured field aliases and derived fields,
+// and the trace fields of a traceparent, to the fields of a JSON log line,
+// returning the line encoded again, or nil when none applies.
+func normalizeEntry(line *lineFields) []byte {
+	traceparent := strings.Contains(line.text, "raceparent")
+	if len(fieldAliases) == 0 && len(derivedFields) == 0 && !traceparent {
+		return nil
+	}
+	fields := line.object()
+	if fields == nil {
+		return nil
+	}
+	aliased := aliasFields(fields)
+	traced := traceparent && applyTraceparent(fields)
+	if derived := deriveFields(fields); !aliased && !traced && !derived {
+		return nil
+	}
+
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return nil
+	}
+	line.text = string(out)
+	return out
+}
+
+// lookupField resolves a dotted path in a decoded JSON object, where keys
+// may be nested objects, flattened dotted keys ("grpc.method"), or a mix.
+func lookupField(fields map[string]interface{}, path string) (interface{}, bool) {
+	if value, ok := fields[path]; ok {
+		return value, true
+	}
+	for i := 0; i < len(path); i++ {
+		if path[i] != '.' {
+			continue
+		}
+		if nested, ok := fields[path[:i]].(map[string]interface{}); ok {
+			if value, ok := lookupField(nested, path[i+1:]); ok {
+				return value, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// fieldString returns the first of paths holding a non-empty value,
+// formatted as a string.
+func fieldString(fields map[string]interface{}, paths ...string) string {
+	for _, path := range paths {
+		value, ok := lookupField(fields, path)
+		if !ok || value == nil {
+			continue
+		}
+		var s string
+		switch v := value.(type) {
+		case string:
+			s = v
+		case json.Number:
+			s = v.String()
+		case float64:
+			s = strconv.FormatFloat(v, 'f', -1, 64)
+		case map[string]interface{}, []interface{}:
+			continue
+		default:
+			s = fmt.Sprint(v)
+		}
+		if s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// fieldFloat returns the first of paths holding a number, or a string that
+// parses as one.
+func fieldFloat(fields map[string]interface{}, paths ...string) (float64, bool) {
+	for _, path := range paths {
+		value, ok := lookupField(fields, path)
+		if !ok {
+			continue
+		}
+		switch v := value.(type) {
+		case float64:
+			return v, true
+		case json.Number:
+			if f, err := v.Float64(); err == nil {
+				return f, true
+			}
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f, true
+			}
+		}
+	}
+	return 0, false
+}
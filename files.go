@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// fileOrders are the orders files named on the command line can be read
+// in: by path, by modification time, or merged by entry timestamp.
+var fileOrders = []string{"name", "mtime", "timestamp"}
+
+// checkFileOrder validates --order.
+func checkFileOrder(order string) error {
+	for _, o := range fileOrders {
+		if order == o {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown order %q (expected %s)", order, strings.Join(fileOrders, ", "))
+}
+
+// inputFile is a file to read, labeled by its path below the directory it
+// was found in.
+type inputFile struct {
+	path    string
+	label   string
+	modTime time.Time
+	// open is set for files read from elsewhere than the file system.
+	open func() (io.ReadCloser, error)
+}
+
+func (f inputFile) reader() (io.ReadCloser, error) {
+	if f.open != nil {
+		return f.open()
+	}
+	return openInputFile(f.path)
+}
+
+// listInputFiles expands the files, directories, URLs and bucket prefixes
+// named on the command line into the files to read, in order. Directories are
+// read one level deep, or entirely when recursive. Hidden files are
+// skipped.
+func listInputFiles(paths []string, recursive bool, order string) ([]inputFile, error) {
+	var files []inputFile
+	for _, path := range paths {
+		if isHTTPURL(path) {
+			file, err := urlFile(path)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, file)
+			continue
+		}
+		if isObjectURL(path) {
+			objects, err := listObjectFiles(path, recursive)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, objects...)
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, inputFile{path: path, label: filepath.Base(path), modTime: info.ModTime()})
+			continue
+		}
+		err = filepath.WalkDir(path, func(name string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if name != path && strings.HasPrefix(entry.Name(), ".") {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if entry.IsDir() {
+				if name != path && !recursive {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil || !info.Mode().IsRegular() {
+				return err
+			}
+			label, _ := filepath.Rel(path, name)
+			files = append(files, inputFile{path: name, label: filepath.ToSlash(label), modTime: info.ModTime()})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if order == "mtime" {
+		sort.SliceStable(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	} else {
+		sort.SliceStable(files, func(i, j int) bool { return files[i].path < files[j].path })
+	}
+	return files, nil
+}
+
+// gzipMagic starts gzip-compressed files.
+const gzipMagic = "\x1f\x8b"
+
+// openInputFile opens a file for reading, decompressing it when it is
+// gzipped (e.g. rotated app.log.1.gz).
+func openInputFile(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return decompress(f)
+}
+
+// decompress wraps r in a gzip reader when its content is gzipped. Closing
+// the result closes r.
+func decompress(r io.ReadCloser) (io.ReadCloser, error) {
+	reader := bufio.NewReader(r)
+	if magic, _ := reader.Peek(len(gzipMagic)); string(magic) != gzipMagic {
+		return readCloser{reader, r}, nil
+	}
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	return readCloser{gz, r}, nil
+}
+
+// readCloser reads from one reader and closes another.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// readFiles hands each line of files to handle, labeled by file when there
+// are several. In timestamp order, files are read side by side and merged
+// by the timestamps of their entries.
+func readFiles(files []inputFile, order string, handle lineHandler) error {
+	label := func(file inputFile) string {
+		if len(files) == 1 {
+			return ""
+		}
+		return file.label
+	}
+	if order == "timestamp" {
+		return mergeFilesByTime(files, label, handle)
+	}
+	for _, file := range files {
+		r, err := file.reader()
+		if err != nil {
+			return err
+		}
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		joiner := newJSONJoiner(func(line string) { handle(line, label(file)) })
+		for scanner.Scan() {
+			joiner.add(scanner.Text())
+		}
+		joiner.flush()
+		err = scanner.Err()
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", file.path, err)
+		}
+	}
+	return nil
+}
+
+// mergeFilesByTime reads all files at once and hands over the line with
+// the earliest timestamp next, earlier files first on ties.
+func mergeFilesByTime(files []inputFile, label func(inputFile) string, handle lineHandler) error {
+	scanners := make([]*timedScanner, len(files))
+	for i, file := range files {
+		r, err := file.reader()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		scanners[i] = newJoiningScanner(scanner, label(file))
+		scanners[i].next()
+	}
+
+	for {
+		var next *timedScanner
+		for _, s := range scanners {
+			if s.ok && (next == nil || s.time.Before(next.time)) {
+				next = s
+			}
+		}
+		if next == nil {
+			break
+		}
+		handle(next.line, next.stream)
+		next.next()
+	}
+	for i, s := range scanners {
+		if err := s.scanner.Err(); err != nil {
+			return fmt.Errorf("%s: %w", files[i].path, err)
+		}
+	}
+	return nil
+}
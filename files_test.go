@@ -0,0 +1,166 @@
+package main
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeLogDir creates a directory of logs, some nested, hidden or gzipped,
+// with modification times in the reverse of name order.
+func writeLogDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.log":       `{"@timestamp":"2024-01-15T10:00:03Z","message":"a3"}` + "\n" + `{"@timestamp":"2024-01-15T10:00:05Z","message":"a5"}`,
+		"sub/b.log":   `{"@timestamp":"2024-01-15T10:00:04Z","message":"b4"}`,
+		".hidden.log": `{"message":"hidden"}`,
+		".git/config": "x",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Create(filepath.Join(dir, "c.log.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	gz.Write([]byte(`{"@timestamp":"2024-01-15T10:00:01Z","message":"c1"}` + "\n"))
+	gz.Close()
+	f.Close()
+
+	now := time.Now()
+	for i, name := range []string{"c.log.gz", "sub/b.log", "a.log"} {
+		at := now.Add(time.Duration(i) * time.Minute)
+		os.Chtimes(filepath.Join(dir, name), at, at)
+	}
+	return dir
+}
+
+func TestListInputFiles(t *testing.T) {
+	dir := writeLogDir(t)
+	tests := []struct {
+		name      string
+		recursive bool
+		order     string
+		want      []string
+	}{
+		{name: "top level by name", order: "name", want: []string{"a.log", "c.log.gz"}},
+		{name: "recursive by name", recursive: true, order: "name", want: []string{"a.log", "c.log.gz", "sub/b.log"}},
+		{name: "recursive by mtime", recursive: true, order: "mtime", want: []string{"c.log.gz", "sub/b.log", "a.log"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := listInputFiles([]string{dir}, tt.recursive, tt.order)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, file := range files {
+				got = append(got, file.label)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := listInputFiles([]string{filepath.Join(dir, "missing")}, false, "name"); err == nil {
+		t.Error("listInputFiles() accepted a missing path")
+	}
+}
+
+func TestReadFiles(t *testing.T) {
+	dir := writeLogDir(t)
+	tests := []struct {
+		name  string
+		paths []string
+		order string
+		want  []string
+	}{
+		{
+			name:  "one file after another",
+			paths: []string{dir},
+			order: "name",
+			want:  []string{"a.log a3", "a.log a5", "c.log.gz c1", "sub/b.log b4"},
+		},
+		{
+			name:  "merged by timestamp",
+			paths: []string{dir},
+			order: "timestamp",
+			want:  []string{"c.log.gz c1", "a.log a3", "sub/b.log b4", "a.log a5"},
+		},
+		{
+			name:  "single file is not labeled",
+			paths: []string{filepath.Join(dir, "c.log.gz")},
+			order: "name",
+			want:  []string{" c1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := listInputFiles(tt.paths, true, tt.order)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			err = readFiles(files, tt.order, func(line, label string) {
+				entry, _ := parseLine(line)
+				got = append(got, label+" "+entry.Message)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckFileOrder(t *testing.T) {
+	for _, order := range fileOrders {
+		if err := checkFileOrder(order); err != nil {
+			t.Errorf("checkFileOrder(%q) = %v", order, err)
+		}
+	}
+	if err := checkFileOrder("size"); err == nil || !strings.Contains(err.Error(), "name, mtime, timestamp") {
+		t.Errorf("checkFileOrder(size) = %v", err)
+	}
+}
+
+func TestReadFilesMergesMultiLineJSON(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.log"), []byte("{\n  \"@timestamp\": \"2024-01-15T10:00:03Z\",\n  \"message\": \"a3\"\n}\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "b.log"), []byte(`{"@timestamp":"2024-01-15T10:00:01Z","message":"b1"}`+"\n"+
+		"{\n  \"@timestamp\": \"2024-01-15T10:00:05Z\",\n  \"message\": \"b5\"\n}\n"), 0o644)
+
+	files, err := listInputFiles([]string{dir}, false, "timestamp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	err = readFiles(files, "timestamp", func(line, label string) {
+		entry, _ := parseLine(line)
+		got = append(got, label+" "+entry.Message)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"b.log b1", "a.log a3", "b.log b5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// flattenOptions configures `logpipe flatten`.
+type flattenOptions struct {
+	format string
+}
+
+func (o *flattenOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.format, "format", "json", "Output format: json or logfmt")
+}
+
+// runFlatten writes each JSON entry of files (stdin when none are given)
+// with its nested objects flattened into dotted keys. Other lines are
+// written unchanged.
+func runFlatten(opts flattenOptions, files []string, w io.Writer) int {
+	if opts.format != "json" && opts.format != "logfmt" {
+		fmt.Fprintf(os.Stderr, "Invalid --format: unknown format %q (expected json or logfmt)\n", opts.format)
+		return 2
+	}
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	for _, name := range files {
+		err := readLines(name, func(line string) {
+			fmt.Fprintln(w, flattenLine(line, opts.format))
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", name, err)
+			return 2
+		}
+	}
+	return 0
+}
+
+// flattenLine renders a JSON object line as flat JSON or logfmt with its
+// keys sorted, e.g. {"http":{"method":"GET"}} as {"http.method":"GET"}.
+func flattenLine(line, format string) string {
+	fields := decodeJSONObject(line)
+	if fields == nil {
+		return line
+	}
+	flat := flattenFields(fields, nil)
+	delete(flat, "")
+	if format == "logfmt" {
+		return logfmtLine(flat)
+	}
+	data, err := json.Marshal(flat)
+	if err != nil {
+		return line
+	}
+	return string(data)
+}
+
+// logfmtLine renders fields as key=value pairs.
+func logfmtLine(fields map[string]interface{}) string {
+	var b strings.Builder
+	for _, key := range sortedKeys(fields) {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(logfmtValue(fields[key]))
+	}
+	return b.String()
+}
+
+// logfmtValue formats a value for a logfmt pair. Strings are quoted when
+// they need to be, arrays and objects are written as JSON and nulls as
+// empty values.
+func logfmtValue(value interface{}) string {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		s = v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, _ := json.Marshal(v)
+		s = string(data)
+	}
+	if s == "" || strings.ContainsAny(s, " =\"\\\t\r\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
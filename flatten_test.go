@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFlattenLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		format string
+		want   string
+	}{
+		{
+			name:   "nested",
+			line:   `{"message":"request","http":{"request":{"method":"GET"},"response":{"status_code":200}}}`,
+			format: "json",
+			want:   `{"http.request.method":"GET","http.response.status_code":200,"message":"request"}`,
+		},
+		{
+			name:   "dotted keys kept",
+			line:   `{"log.level":"info","user":{"id":"u-1"},"tags":["a","b"]}`,
+			format: "json",
+			want:   `{"log.level":"info","tags":["a","b"],"user.id":"u-1"}`,
+		},
+		{
+			name:   "logfmt",
+			line:   `{"message":"payment failed","order":{"id":42,"paid":false},"user":null,"tags":["a"]}`,
+			format: "logfmt",
+			want:   `message="payment failed" order.id=42 order.paid=false tags="[\"a\"]" user=`,
+		},
+		{
+			name:   "logfmt empty string",
+			line:   `{"a":"","b":"x=y"}`,
+			format: "logfmt",
+			want:   `a="" b="x=y"`,
+		},
+		{
+			name:   "not JSON",
+			line:   `level=info msg=started`,
+			format: "json",
+			want:   `level=info msg=started`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := flattenLine(tt.line, tt.format); got != tt.want {
+				t.Errorf("flattenLine() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunFlatten(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	os.WriteFile(path, []byte("{\"a\":{\"b\":1}}\n{\n  \"c\": {\"d\": true}\n}\nplain\n"), 0o644)
+
+	var out bytes.Buffer
+	if code := runFlatten(flattenOptions{format: "json"}, []string{path}, &out); code != 0 {
+		t.Fatalf("exit code = %d", code)
+	}
+	want := "{\"a.b\":1}\n{\"c.d\":true}\nplain\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+
+	if code := runFlatten(flattenOptions{format: "yaml"}, []string{path}, &out); code != 2 {
+		t.Errorf("unknown format: exit code = %d, want 2", code)
+	}
+}
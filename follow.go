@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// followPollInterval is how often followed files are checked for new lines
+// and the globs for new files.
+var followPollInterval = time.Second
+
+// followFiles tails every file matching the glob patterns, like tail -F,
+// and hands each line to handle labeled with its file name. Files matching
+// when it starts are read from their end; files appearing later are read
+// from their start. It returns when ctx is done.
+func followFiles(ctx context.Context, patterns []string, handle lineHandler) error {
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return err
+		}
+	}
+
+	type labeledLine struct{ line, label string }
+	lines := make(chan labeledLine)
+	var mu sync.Mutex
+	followed := make(map[string]bool)
+	var wg sync.WaitGroup
+
+	discover := func(fromStart bool) {
+		for _, pattern := range patterns {
+			// Only an invalid pattern fails, and patterns were checked
+			paths, _ := filepath.Glob(pattern)
+			for _, path := range paths {
+				mu.Lock()
+				seen := followed[path]
+				followed[path] = true
+				mu.Unlock()
+				if seen || !isFollowable(path) {
+					continue
+				}
+				label := followLabel(pattern, path)
+				wg.Add(1)
+				go func(path string) {
+					defer wg.Done()
+					tailFile(ctx, path, fromStart, func(line string) {
+						select {
+						case lines <- labeledLine{line, label}:
+						case <-ctx.Done():
+						}
+					})
+					// Forget removed files so they are picked up again
+					// if they come back
+					mu.Lock()
+					delete(followed, path)
+					mu.Unlock()
+				}(path)
+			}
+		}
+	}
+
+	discover(false)
+	go func() {
+		ticker := time.NewTicker(followPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				discover(true)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case l := <-lines:
+			handle(l.line, l.label)
+		case <-ctx.Done():
+			wg.Wait()
+			return nil
+		}
+	}
+}
+
+func isFollowable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// followLabel names a followed file by its path below the part of the
+// pattern without wildcards, e.g. "nginx/access.log" for
+// "/var/log/*/access.log".
+func followLabel(pattern, path string) string {
+	dir := filepath.Dir(pattern)
+	for strings.ContainsAny(dir, `*?[\`) {
+		dir = filepath.Dir(dir)
+	}
+	if rel, err := filepath.Rel(dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return filepath.Base(path)
+}
+
+// tailFile reads lines appended to a file until ctx is done or the file is
+// removed. It starts over when the file is truncated or replaced (e.g.
+// rotated), and holds back a last line until its newline is written.
+func tailFile(ctx context.Context, path string, fromStart bool, handle func(line string)) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer func() { f.Close() }()
+	if !fromStart {
+		f.Seek(0, io.SeekEnd)
+	}
+	reader := bufio.NewReader(f)
+	var partial string
+
+	for {
+		chunk, err := reader.ReadString('\n')
+		if err == nil {
+			handle(strings.TrimSuffix(partial+chunk, "\n"))
+			partial = ""
+			continue
+		}
+		partial += chunk
+		if !errors.Is(err, io.EOF) {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(followPollInterval):
+		}
+
+		current, err := os.Stat(path)
+		if err != nil {
+			return
+		}
+		opened, err := f.Stat()
+		if err != nil {
+			return
+		}
+		offset, _ := f.Seek(0, io.SeekCurrent)
+		switch {
+		case !os.SameFile(current, opened):
+			// Replaced, e.g. by rotation: read the new file from its start
+			f.Close()
+			if f, err = os.Open(path); err != nil {
+				return
+			}
+			reader.Reset(f)
+			partial = ""
+		case current.Size() < offset:
+			f.Seek(0, io.SeekStart)
+			reader.Reset(f)
+			partial = ""
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFollowLabel(t *testing.T) {
+	tests := []struct {
+		pattern, path, want string
+	}{
+		{"/var/log/app/*.log", "/var/log/app/api.log", "api.log"},
+		{"/var/log/*/access.log", "/var/log/nginx/access.log", "nginx/access.log"},
+		{"app.log", "app.log", "app.log"},
+		{"logs/[ab]*/x.log", "logs/a1/x.log", "a1/x.log"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			if got := followLabel(tt.pattern, tt.path); got != tt.want {
+				t.Errorf("followLabel(%q, %q) = %q, want %q", tt.pattern, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFollowFiles(t *testing.T) {
+	defer func(interval time.Duration) { followPollInterval = interval }(followPollInterval)
+	followPollInterval = 10 * time.Millisecond
+
+	dir := t.TempDir()
+	appendTo := func(name, text string) {
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		f.WriteString(text)
+	}
+	appendTo("old.log", "before start\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	got := make(chan string, 10)
+	done := make(chan error)
+	go func() {
+		done <- followFiles(ctx, []string{filepath.Join(dir, "*.log")}, func(line, label string) {
+			got <- label + ": " + line
+		})
+	}()
+	next := func() string {
+		select {
+		case line := <-got:
+			return line
+		case <-time.After(2 * time.Second):
+			return "(timeout)"
+		}
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	appendTo("old.log", "appended\n")
+	if line := next(); line != "old.log: appended" {
+		t.Errorf("got %q, want the appended line", line)
+	}
+	appendTo("new.log", "first of new file\npartial")
+	if line := next(); line != "new.log: first of new file" {
+		t.Errorf("got %q, want the new file's first line", line)
+	}
+	appendTo("new.log", " line\n")
+	if line := next(); line != "new.log: partial line" {
+		t.Errorf("got %q, want the completed line", line)
+	}
+	os.WriteFile(filepath.Join(dir, "old.log"), []byte("after truncation\n"), 0o644)
+	if line := next(); !strings.HasSuffix(line, "after truncation") {
+		t.Errorf("got %q, want the line written after truncation", line)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
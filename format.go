@@ -0,0 +1,137 @@
+package main
+
+// Formatting helpers for the renderer. They measure text in terminal
+// columns and never split a character, whatever the input.
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// wideRanges are the ranges of characters shown two columns wide: East
+// Asian wide and fullwidth characters and emoji.
+var wideRanges = []struct{ lo, hi rune }{
+	{0x1100, 0x115F},   // Hangul Jamo
+	{0x231A, 0x231B},   // watch, hourglass
+	{0x23E9, 0x23EC},   // media controls
+	{0x23F0, 0x23F3},   // alarm clock, timers
+	{0x25FD, 0x25FE},   // small squares
+	{0x2614, 0x2615},   // umbrella, hot beverage
+	{0x2648, 0x2653},   // zodiac
+	{0x26A1, 0x26A1},   // high voltage
+	{0x26AA, 0x26AB},   // circles
+	{0x26BD, 0x26BE},   // soccer, baseball
+	{0x26C4, 0x26C5},   // snowman, sun behind cloud
+	{0x26D4, 0x26D4},   // no entry
+	{0x26EA, 0x26EA},   // church
+	{0x26F2, 0x26F5},   // fountain to sailboat
+	{0x26FA, 0x26FD},   // tent, fuel pump
+	{0x2705, 0x2705},   // check mark button
+	{0x270A, 0x270B},   // raised fists
+	{0x2728, 0x2728},   // sparkles
+	{0x274C, 0x274C},   // cross mark
+	{0x274E, 0x274E},   // cross mark button
+	{0x2753, 0x2755},   // question and exclamation marks
+	{0x2757, 0x2757},   // exclamation mark
+	{0x2795, 0x2797},   // plus, minus, divide
+	{0x27B0, 0x27B0},   // curly loop
+	{0x27BF, 0x27BF},   // double curly loop
+	{0x2B1B, 0x2B1C},   // large squares
+	{0x2B50, 0x2B50},   // star
+	{0x2B55, 0x2B55},   // hollow red circle
+	{0x2E80, 0x303E},   // CJK radicals, punctuation
+	{0x3041, 0x33FF},   // Hiragana, Katakana, CJK compatibility
+	{0x3400, 0x4DBF},   // CJK extension A
+	{0x4E00, 0x9FFF},   // CJK unified ideographs
+	{0xA000, 0xA4CF},   // Yi
+	{0xAC00, 0xD7A3},   // Hangul syllables
+	{0xF900, 0xFAFF},   // CJK compatibility ideographs
+	{0xFE30, 0xFE4F},   // CJK compatibility forms
+	{0xFF00, 0xFF60},   // fullwidth forms
+	{0xFFE0, 0xFFE6},   // fullwidth signs
+	{0x1F004, 0x1F004}, // mahjong tile
+	{0x1F0CF, 0x1F0CF}, // joker
+	{0x1F18E, 0x1F18E}, // AB button
+	{0x1F191, 0x1F19A}, // squared words
+	{0x1F200, 0x1F251}, // enclosed ideographic supplement
+	{0x1F300, 0x1F64F}, // pictographs, emoticons
+	{0x1F680, 0x1F6FF}, // transport and map symbols
+	{0x1F7E0, 0x1F7EB}, // colored circles and squares
+	{0x1F90C, 0x1F9FF}, // supplemental symbols and pictographs
+	{0x1FA70, 0x1FAFF}, // symbols and pictographs extended-A
+	{0x20000, 0x3FFFD}, // CJK extensions B and later
+}
+
+// runeWidth returns how many terminal columns r takes: 0 for combining
+// marks and invisible characters, 2 for wide characters, 1 otherwise.
+func runeWidth(r rune) int {
+	if r < 0x20 || r == 0x7F || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	if r < 0x1100 {
+		return 1
+	}
+	for _, wide := range wideRanges {
+		if r < wide.lo {
+			break
+		}
+		if r <= wide.hi {
+			return 2
+		}
+	}
+	return 1
+}
+
+// displayWidth returns how many terminal columns s takes.
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// truncateWidth cuts s to at most width columns, between characters.
+// Combining marks after the last character kept are kept with it.
+func truncateWidth(s string, width int) string {
+	used := 0
+	for i, r := range s {
+		w := runeWidth(r)
+		if used+w > width {
+			return s[:i]
+		}
+		used += w
+	}
+	return s
+}
+
+// abbreviate cuts s to width columns, ending it with "..." when anything
+// was cut.
+func abbreviate(s string, width int) string {
+	if displayWidth(s) <= width {
+		return s
+	}
+	return truncateWidth(s, width) + "..."
+}
+
+// truncateBytes cuts s to at most n bytes without splitting a character.
+func truncateBytes(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+	n = max(n, 0)
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// padWidth pads s with spaces to width columns, like %-*s does for
+// characters that are one column wide.
+func padWidth(s string, width int) string {
+	if w := displayWidth(s); w < width {
+		return s + strings.Repeat(" ", width-w)
+	}
+	return s
+}
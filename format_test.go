@@ -0,0 +1,187 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+	"unicode/utf8"
+)
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"hello", 5},
+		{"héllo", 5},
+		{"héllo", 5}, // combining acute accent
+		{"日本語", 6},
+		{"ｆｕｌｌ", 8},
+		{"🔥 fire", 7},
+		{"ℹ️", 1}, // variation selector takes no column
+		{"한국어", 6},
+		{"tab\there", 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if got := displayWidth(tt.s); got != tt.want {
+				t.Errorf("displayWidth(%q) = %d, want %d", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateWidth(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 4, "hell"},
+		{"ÉRREUR", 4, "ÉRRE"},
+		{"日本語", 4, "日本"},
+		{"日本語", 5, "日本"},
+		{"a日本", 2, "a"},
+		{"🔥🔥🔥", 3, "🔥"},
+		{"état", 1, "é"},
+		{"", 4, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if got := truncateWidth(tt.s, tt.width); got != tt.want {
+				t.Errorf("truncateWidth(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPadWidth(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"GET", 4, "GET "},
+		{"POST", 4, "POST"},
+		{"DELETE", 4, "DELETE"},
+		{"警告", 4, "警告"},
+		{"警", 4, "警  "},
+		{"", 2, "  "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if got := padWidth(tt.s, tt.width); got != tt.want {
+				t.Errorf("padWidth(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAbbreviate(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"a longer line", 8, "a longer..."},
+		{"日本語のログ", 5, "日本..."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if got := abbreviate(tt.s, tt.width); got != tt.want {
+				t.Errorf("abbreviate(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateBytes(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 3, "hel"},
+		{"héllo", 2, "h"},
+		{"日本", 4, "日"},
+		{"hello", -1, ""},
+	}
+
+	for _, tt := range tests {
+		if got := truncateBytes(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncateBytes(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
+// The helpers must hold up against any input: invalid UTF-8, control
+// characters and widths out of range included.
+func TestFormatProperties(t *testing.T) {
+	properties := map[string]interface{}{
+		"truncateWidth keeps a prefix within width": func(s string, width int8) bool {
+			got := truncateWidth(s, int(width))
+			return strings.HasPrefix(s, got) && (got == "" || displayWidth(got) <= int(width))
+		},
+		"truncateWidth keeps valid text valid": func(s string, width uint8) bool {
+			return !utf8.ValidString(s) || utf8.ValidString(truncateWidth(s, int(width)))
+		},
+		"truncateWidth only cuts what doesn't fit": func(s string, width uint8) bool {
+			got := truncateWidth(s, int(width))
+			return got == s || displayWidth(got)+runeWidth([]rune(s[len(got):])[0]) > int(width)
+		},
+		"truncateBytes keeps a prefix within n bytes": func(s string, n int8) bool {
+			got := truncateBytes(s, int(n))
+			return strings.HasPrefix(s, got) && len(got) <= max(int(n), 0) && (!utf8.ValidString(s) || utf8.ValidString(got))
+		},
+		"padWidth reaches width": func(s string, width int8) bool {
+			got := padWidth(s, int(width))
+			return strings.HasPrefix(got, s) && displayWidth(got) >= int(width)
+		},
+		"abbreviate marks cuts": func(s string, width uint8) bool {
+			got := abbreviate(s, int(width))
+			return got == s || strings.HasSuffix(got, "...") && displayWidth(got) <= int(width)+3
+		},
+		"levelLabel is at most 4 columns": func(level string) bool {
+			return displayWidth(levelLabel(level)) <= 4
+		},
+	}
+
+	for name, property := range properties {
+		t.Run(name, func(t *testing.T) {
+			if err := quick.Check(property, &quick.Config{MaxCount: 2000, Values: arbitraryText(property)}); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// arbitraryText generates property arguments, mixing ASCII, wide, combining
+// and control characters and invalid bytes into the strings.
+func arbitraryText(property interface{}) func([]reflect.Value, *rand.Rand) {
+	pieces := []string{"a", "Z", " ", "é", "é", "日", "ｆ", "🔥", "ℹ️", "\x1b", "\t", "\xff", "\xe6\x97"}
+	return func(args []reflect.Value, r *rand.Rand) {
+		fn := reflect.TypeOf(property)
+		for i := range args {
+			if fn.In(i).Kind() == reflect.String {
+				var b strings.Builder
+				for n := r.Intn(12); n > 0; n-- {
+					b.WriteString(pieces[r.Intn(len(pieces))])
+				}
+				args[i] = reflect.ValueOf(b.String())
+				continue
+			}
+			value, _ := quick.Value(fn.In(i), r)
+			args[i] = value
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// serveForward accepts Fluent Forward connections, as sent by fluent-bit's
+// and Fluentd's forward outputs and Vector's fluent sink, and hands over
+// each record as a JSON line labeled with its tag until ctx is cancelled.
+func serveForward(ctx context.Context, ln net.Listener, handle lineHandler) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return ignoreClosed(ctx, err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+			stop := context.AfterFunc(ctx, func() { conn.Close() })
+			defer stop()
+			if err := readForward(conn, handle); err != nil && ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "Fluent Forward connection from %s: %v\n", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// readForward reads the messages of a connection until it is closed,
+// acknowledging those that ask for it.
+func readForward(conn io.ReadWriter, handle lineHandler) error {
+	r := bufio.NewReader(conn)
+	for {
+		message, err := readMsgpack(r)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		chunk, err := handleForwardMessage(message, handle)
+		if err != nil {
+			return err
+		}
+		if chunk != "" {
+			ack := appendMsgpackString([]byte{0x81}, "ack")
+			if _, err := conn.Write(appendMsgpackString(ack, chunk)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handleForwardMessage hands over the records of a message in any of the
+// protocol's modes and returns the chunk ID to acknowledge, if any.
+//
+//	Message:        [tag, time, record, option?]
+//	Forward:        [tag, [[time, record], ...], option?]
+//	PackedForward:  [tag, bin of concatenated [time, record], option?]
+func handleForwardMessage(message interface{}, handle lineHandler) (string, error) {
+	fields, ok := message.([]interface{})
+	if !ok || len(fields) < 2 {
+		return "", errors.New("invalid Fluent Forward message")
+	}
+	tag, _ := fields[0].(string)
+
+	var option map[string]interface{}
+	switch entries := fields[1].(type) {
+	case []interface{}:
+		for _, entry := range entries {
+			pair, ok := entry.([]interface{})
+			if !ok || len(pair) < 2 {
+				return "", errors.New("invalid Fluent Forward entry")
+			}
+			handle(forwardLine(pair[0], pair[1]), tag)
+		}
+		if len(fields) > 2 {
+			option, _ = fields[2].(map[string]interface{})
+		}
+	case string, []byte:
+		if len(fields) > 2 {
+			option, _ = fields[2].(map[string]interface{})
+		}
+		data, ok := entries.([]byte)
+		if !ok {
+			data = []byte(entries.(string))
+		}
+		if option["compressed"] == "gzip" {
+			zr, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return "", err
+			}
+			if data, err = io.ReadAll(io.LimitReader(zr, maxMsgpackSize+1)); err != nil {
+				return "", err
+			}
+			if len(data) > maxMsgpackSize {
+				return "", errors.New("Fluent Forward compressed entries are too large")
+			}
+		}
+		packed := bufio.NewReader(bytes.NewReader(data))
+		for {
+			entry, err := readMsgpack(packed)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", err
+			}
+			pair, ok := entry.([]interface{})
+			if !ok || len(pair) < 2 {
+				return "", errors.New("invalid Fluent Forward entry")
+			}
+			handle(forwardLine(pair[0], pair[1]), tag)
+		}
+	default:
+		if len(fields) < 3 {
+			return "", errors.New("invalid Fluent Forward message")
+		}
+		handle(forwardLine(fields[1], fields[2]), tag)
+		if len(fields) > 3 {
+			option, _ = fields[3].(map[string]interface{})
+		}
+	}
+
+	chunk, _ := option["chunk"].(string)
+	return chunk, nil
+}
+
+// forwardLine turns a record into a JSON line, with its event time as
+// @timestamp unless the record has its own.
+func forwardLine(eventTime, record interface{}) string {
+	doc, ok := jsonCompatible(record).(map[string]interface{})
+	if !ok {
+		doc = map[string]interface{}{"message": fmt.Sprint(record)}
+	}
+	if _, ok := doc["@timestamp"]; !ok {
+		if t, ok := forwardTime(eventTime); ok {
+			doc["@timestamp"] = t.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Sprint(record)
+	}
+	return string(data)
+}
+
+// forwardTime decodes an event time: seconds since the epoch, or the
+// EventTime extension with nanoseconds.
+func forwardTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case int64:
+		return time.Unix(v, 0), true
+	case uint64:
+		return time.Unix(int64(v), 0), true
+	case float64:
+		return time.Unix(0, int64(v*1e9)), true
+	case msgpackExt:
+		if v.typ == 0 && len(v.data) == 8 {
+			sec := binary.BigEndian.Uint32(v.data[:4])
+			nsec := binary.BigEndian.Uint32(v.data[4:])
+			return time.Unix(int64(sec), int64(nsec)), true
+		}
+	}
+	return time.Time{}, false
+}
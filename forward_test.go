@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestForward(t *testing.T) {
+	eventTime := msgpackExt{typ: 0, data: []byte{0x65, 0xa5, 0x3a, 0x60, 0x00, 0x00, 0x00, 0x64}}
+	record := func(message string) map[string]interface{} {
+		return map[string]interface{}{"message": message}
+	}
+	packed := func(entries ...[]interface{}) []byte {
+		var b []byte
+		for _, entry := range entries {
+			b = append(b, packMsgpack(entry)...)
+		}
+		return b
+	}
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write(packed([]interface{}{int64(1705327200), record("gzipped")}))
+	zw.Close()
+
+	tests := []struct {
+		name    string
+		message []interface{}
+		want    []string
+		wantAck string
+	}{
+		{
+			name:    "message mode",
+			message: []interface{}{"app.web", int64(1705327200), record("one")},
+			want:    []string{`app.web {"@timestamp":"2024-01-15T14:00:00Z","message":"one"}`},
+		},
+		{
+			name:    "event time",
+			message: []interface{}{"app.web", eventTime, map[string]interface{}{"log": []byte("raw line\n")}},
+			want:    []string{`app.web {"@timestamp":"2024-01-15T14:00:00.0000001Z","log":"raw line\n"}`},
+		},
+		{
+			name:    "record timestamp kept",
+			message: []interface{}{"app", int64(0), map[string]interface{}{"@timestamp": "2024-01-15T14:00:00Z"}},
+			want:    []string{`app {"@timestamp":"2024-01-15T14:00:00Z"}`},
+		},
+		{
+			name: "forward mode with ack",
+			message: []interface{}{"app.worker", []interface{}{
+				[]interface{}{int64(1705327200), record("two")},
+				[]interface{}{int64(1705327201), record("three")},
+			}, map[string]interface{}{"chunk": "c1"}},
+			want: []string{
+				`app.worker {"@timestamp":"2024-01-15T14:00:00Z","message":"two"}`,
+				`app.worker {"@timestamp":"2024-01-15T14:00:01Z","message":"three"}`,
+			},
+			wantAck: "c1",
+		},
+		{
+			name: "packed forward",
+			message: []interface{}{"app", packed(
+				[]interface{}{int64(1705327200), record("four")},
+				[]interface{}{int64(1705327200), record("five")},
+			)},
+			want: []string{
+				`app {"@timestamp":"2024-01-15T14:00:00Z","message":"four"}`,
+				`app {"@timestamp":"2024-01-15T14:00:00Z","message":"five"}`,
+			},
+		},
+		{
+			name:    "compressed packed forward",
+			message: []interface{}{"app", compressed.Bytes(), map[string]interface{}{"compressed": "gzip", "chunk": "c2"}},
+			want:    []string{`app {"@timestamp":"2024-01-15T14:00:00Z","message":"gzipped"}`},
+			wantAck: "c2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			var mu sync.Mutex
+			var got []string
+			received := make(chan struct{})
+			done := make(chan error)
+			go func() {
+				done <- serveForward(ctx, ln, func(line, label string) {
+					mu.Lock()
+					defer mu.Unlock()
+					got = append(got, label+" "+line)
+					if len(got) == len(tt.want) {
+						close(received)
+					}
+				})
+			}()
+
+			conn, err := net.Dial("tcp", ln.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			if _, err := conn.Write(packMsgpack(tt.message)); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantAck != "" {
+				ack, err := readMsgpack(bufio.NewReader(conn))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := map[string]interface{}{"ack": tt.wantAck}; !reflect.DeepEqual(ack, want) {
+					t.Errorf("ack = %v, want %v", ack, want)
+				}
+			}
+			select {
+			case <-received:
+			case <-ctx.Done():
+				t.Fatal("timed out waiting for records")
+			}
+
+			cancel()
+			if err := <-done; err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
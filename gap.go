@@ -0,0 +1,45 @@
+package main
+
+import (
+	"time"
+)
+
+// gapMarker notes silent periods: entries logged longer than threshold
+// after the previous one, going by their timestamps.
+type gapMarker struct {
+	threshold time.Duration
+	last      time.Time
+}
+
+func newGapMarker(threshold time.Duration) *gapMarker {
+	return &gapMarker{threshold: threshold}
+}
+
+// check returns the marker to show in front of an entry logged at
+// timestamp, or "". Entries going back in time, as when merging inputs,
+// are compared with the latest one seen.
+func (g *gapMarker) check(timestamp string) string {
+	t, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return ""
+	}
+	gap := t.Sub(g.last)
+	if !t.After(g.last) {
+		return ""
+	}
+	first := g.last.IsZero()
+	g.last = t
+	if first || gap <= g.threshold {
+		return ""
+	}
+	return labelColor.Sprintf("— %s gap —", formatGap(gap))
+}
+
+// formatGap shortens a gap like formatIdle, keeping milliseconds below a
+// second.
+func formatGap(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return formatIdle(d)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+func TestGapMarker(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	tests := []struct {
+		name       string
+		threshold  time.Duration
+		timestamps []string
+		want       []string
+	}{
+		{
+			name:       "minutes",
+			threshold:  5 * time.Minute,
+			timestamps: []string{"2024-01-16T14:02:11Z", "2024-01-16T14:14:51Z", "2024-01-16T14:15:00Z", "", "2024-01-16T14:20:00Z"},
+			want:       []string{"", "— 12m40s gap —", "", "", ""},
+		},
+		{
+			name:       "back in time",
+			threshold:  time.Minute,
+			timestamps: []string{"2024-01-16T14:00:00Z", "2024-01-16T13:00:00Z", "2024-01-16T14:00:30Z", "2024-01-16T16:00:30Z"},
+			want:       []string{"", "", "", "— 2h gap —"},
+		},
+		{
+			name:       "milliseconds",
+			threshold:  100 * time.Millisecond,
+			timestamps: []string{"2024-01-16T14:00:00.000Z", "2024-01-16T14:00:00.350Z"},
+			want:       []string{"", "— 350ms gap —"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newGapMarker(tt.threshold)
+			for i, ts := range tt.timestamps {
+				if got := g.check(ts); got != tt.want[i] {
+					t.Errorf("check(%q) = %q, want %q", ts, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// gcpLoggingEndpoint is the Cloud Logging API address.
+var gcpLoggingEndpoint = "https://logging.googleapis.com"
+
+// gcpPageSize is how many entries each entries.list request asks for.
+var gcpPageSize = 1000
+
+// gcpPollInterval is how often --follow asks for new entries.
+var gcpPollInterval = 2 * time.Second
+
+// gcpLateWindow is how far behind the newest entry seen --follow keeps
+// asking, for entries written late.
+var gcpLateWindow = 2 * time.Minute
+
+// gcpDefaultSince is how far back reads start without --since.
+const gcpDefaultSince = time.Hour
+
+// gcpLabelNames are the resource labels an entry is labeled with, the first
+// one present winning.
+var gcpLabelNames = []string{"pod_name", "container_name", "service_name", "function_name", "instance_id"}
+
+// gcpOptions configures `logpipe gcp`.
+type gcpOptions struct {
+	project string
+	filter  string
+	limit   int
+	follow  bool
+}
+
+func (o *gcpOptions) register(fs *flag.FlagSet) {
+	project := os.Getenv("GOOGLE_CLOUD_PROJECT")
+	if project == "" {
+		project = os.Getenv("CLOUDSDK_CORE_PROJECT")
+	}
+	fs.StringVar(&o.project, "project", project, "Google Cloud project to read (default: $GOOGLE_CLOUD_PROJECT, or the credentials')")
+	fs.StringVar(&o.filter, "filter", "", `Logging query, e.g. 'resource.type="k8s_container" severity>=ERROR'`)
+	fs.IntVar(&o.limit, "limit", 0, "Most entries read before following (default: all in the time range)")
+	registerFollowSwitch(fs, &o.follow, "Keep polling for new entries after the time range")
+}
+
+// gcpEntry is the part of a LogEntry logpipe reads.
+type gcpEntry struct {
+	InsertID  string    `json:"insertId"`
+	Timestamp time.Time `json:"timestamp"`
+	Severity  string    `json:"severity"`
+	LogName   string    `json:"logName"`
+	Resource  struct {
+		Labels map[string]string `json:"labels"`
+	} `json:"resource"`
+	TextPayload  *string                `json:"textPayload"`
+	JSONPayload  map[string]interface{} `json:"jsonPayload"`
+	ProtoPayload map[string]interface{} `json:"protoPayload"`
+}
+
+// gcpLine turns an entry into an ECS log line: its JSON payload, with the
+// timestamp and severity added unless the payload has its own, or its text
+// payload as the message.
+func gcpLine(entry gcpEntry) string {
+	doc := entry.JSONPayload
+	if doc == nil {
+		doc = entry.ProtoPayload
+	}
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+	if entry.TextPayload != nil {
+		doc["message"] = strings.TrimRight(*entry.TextPayload, "\r\n")
+	}
+	if _, ok := doc["@timestamp"]; !ok && !entry.Timestamp.IsZero() {
+		doc["@timestamp"] = entry.Timestamp.Format(time.RFC3339Nano)
+	}
+	_, hasLevel := doc["log.level"]
+	_, hasSeverity := doc["severity"]
+	if !hasLevel && !hasSeverity && entry.Severity != "" && entry.Severity != "DEFAULT" {
+		doc["log.level"] = strings.ToLower(entry.Severity)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// gcpLabel names an entry's source by the first of gcpLabelNames its
+// resource has, or by its log.
+func gcpLabel(entry gcpEntry) string {
+	for _, name := range gcpLabelNames {
+		if value := entry.Resource.Labels[name]; value != "" {
+			return value
+		}
+	}
+	if entry.LogName == "" {
+		return ""
+	}
+	name := path.Base(entry.LogName)
+	if i := strings.LastIndex(name, "%2F"); i >= 0 {
+		name = name[i+3:]
+	}
+	return name
+}
+
+// gcpLoggingScope is the OAuth scope reading entries needs.
+const gcpLoggingScope = "https://www.googleapis.com/auth/logging.read"
+
+// gcpClient reads entries with the Cloud Logging API, authenticated with
+// the tokens of gcpCredentials.
+type gcpClient struct {
+	tokens oauth2.TokenSource
+	// static is set when the token comes from GOOGLE_OAUTH_ACCESS_TOKEN,
+	// which can't be refreshed.
+	static bool
+	opts   gcpOptions
+}
+
+// gcpCredentials finds the OAuth tokens to authenticate with: the access
+// token in GOOGLE_OAUTH_ACCESS_TOKEN as is, else Application Default
+// Credentials (GOOGLE_APPLICATION_CREDENTIALS, `gcloud auth
+// application-default login` or the metadata server), whose tokens are
+// refreshed as they expire. It also returns the project of the
+// credentials, if they name one.
+func gcpCredentials(ctx context.Context, scope string) (tokens oauth2.TokenSource, static bool, project string, err error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}), true, "", nil
+	}
+	creds, err := google.FindDefaultCredentials(ctx, scope)
+	if err != nil {
+		return nil, false, "", fmt.Errorf("no Google Cloud credentials found: run `gcloud auth application-default login`, "+
+			"set GOOGLE_APPLICATION_CREDENTIALS to a key file, or GOOGLE_OAUTH_ACCESS_TOKEN to a token (%w)", err)
+	}
+	return creds.TokenSource, false, creds.ProjectID, nil
+}
+
+func newGCPClient(ctx context.Context, opts gcpOptions) (*gcpClient, error) {
+	tokens, static, project, err := gcpCredentials(ctx, gcpLoggingScope)
+	if err != nil {
+		return nil, err
+	}
+	if opts.project == "" {
+		opts.project = project
+	}
+	if opts.project == "" {
+		return nil, errors.New("--project is required, e.g. --project my-project")
+	}
+	return &gcpClient{tokens: tokens, static: static, opts: opts}, nil
+}
+
+// list fetches a page of the entries from start (inclusive) to end
+// (exclusive, zero: open), oldest first, and the token of the next page if
+// there is one.
+func (c *gcpClient) list(ctx context.Context, start, end time.Time, size int, token string) ([]gcpEntry, string, error) {
+	filter := `timestamp>="` + start.UTC().Format(time.RFC3339Nano) + `"`
+	if !end.IsZero() {
+		filter += ` AND timestamp<"` + end.UTC().Format(time.RFC3339Nano) + `"`
+	}
+	if c.opts.filter != "" {
+		filter += " AND (" + c.opts.filter + ")"
+	}
+	params := map[string]interface{}{
+		"resourceNames": []string{"projects/" + c.opts.project},
+		"filter":        filter,
+		"orderBy":       "timestamp asc",
+		"pageSize":      size,
+	}
+	if token != "" {
+		params["pageToken"] = token
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, gcpLoggingEndpoint+"/v2/entries:list", bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	access, err := c.tokens.Token()
+	if err != nil {
+		return nil, "", fmt.Errorf("getting a Google Cloud access token: %w", err)
+	}
+	access.SetAuthHeader(req)
+	body, err := getObject(req)
+	if err != nil {
+		if c.static && strings.Contains(err.Error(), "401 Unauthorized") {
+			return nil, "", fmt.Errorf("%w (GOOGLE_OAUTH_ACCESS_TOKEN may have expired: unset it to use Application Default Credentials, which are refreshed)", err)
+		}
+		return nil, "", err
+	}
+	defer body.Close()
+
+	var resp struct {
+		Entries       []gcpEntry `json:"entries"`
+		NextPageToken string     `json:"nextPageToken"`
+	}
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, "", err
+	}
+	return resp.Entries, resp.NextPageToken, nil
+}
+
+// consumeGCP reads the entries of a project matching a query over a time
+// range, then with --follow polls for newer ones until ctx is cancelled.
+// Without --since, the range starts an hour ago.
+func consumeGCP(ctx context.Context, opts gcpOptions, timeFilter *timeRange, handle lineHandler) error {
+	client, err := newGCPClient(ctx, opts)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	start, end := now.Add(-gcpDefaultSince), now
+	if timeFilter != nil {
+		if !timeFilter.since.IsZero() {
+			start = timeFilter.since
+		}
+		if !timeFilter.until.IsZero() {
+			if opts.follow {
+				return errors.New("--until can't be used with --follow")
+			}
+			end = timeFilter.until
+		}
+	}
+
+	if err := client.readRange(ctx, start, end, handle); err != nil || !opts.follow {
+		return ignoreClosed(ctx, err)
+	}
+	return ignoreClosed(ctx, client.poll(ctx, end, handle))
+}
+
+// readRange hands over the entries of a time range, page by page.
+func (c *gcpClient) readRange(ctx context.Context, start, end time.Time, handle lineHandler) error {
+	read := 0
+	token := ""
+	for {
+		size := gcpPageSize
+		if c.opts.limit > 0 {
+			size = min(size, c.opts.limit-read)
+		}
+		entries, next, err := c.list(ctx, start, end, size, token)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if c.opts.limit > 0 && read == c.opts.limit {
+				return nil
+			}
+			handle(gcpLine(entry), gcpLabel(entry))
+			read++
+		}
+		if next == "" || (c.opts.limit > 0 && read >= c.opts.limit) {
+			return nil
+		}
+		token = next
+	}
+}
+
+// poll asks for entries from a time on every gcpPollInterval. Entries are
+// written late and out of order, so each request goes back gcpLateWindow
+// before the newest timestamp seen and skips the entries already handed
+// over.
+func (c *gcpClient) poll(ctx context.Context, from time.Time, handle lineHandler) error {
+	newest := from
+	seen := map[string]time.Time{}
+	ticker := time.NewTicker(gcpPollInterval)
+	defer ticker.Stop()
+	windowStart := func() time.Time {
+		if start := newest.Add(-gcpLateWindow); start.After(from) {
+			return start
+		}
+		return from
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		start := windowStart()
+		token := ""
+		for {
+			entries, next, err := c.list(ctx, start, time.Time{}, gcpPageSize, token)
+			if err != nil {
+				return err
+			}
+			for _, entry := range entries {
+				// Insert IDs are unique within a log
+				id := entry.LogName + "/" + entry.InsertID
+				if _, ok := seen[id]; ok {
+					continue
+				}
+				seen[id] = entry.Timestamp
+				if entry.Timestamp.After(newest) {
+					newest = entry.Timestamp
+				}
+				handle(gcpLine(entry), gcpLabel(entry))
+			}
+			if next == "" {
+				break
+			}
+			token = next
+		}
+		// Entries older than the next request's start can't be listed again
+		start = windowStart()
+		for id, timestamp := range seen {
+			if timestamp.Before(start) {
+				delete(seen, id)
+			}
+		}
+	}
+}
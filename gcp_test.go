@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCloudLogging serves entries.list over a fixed set of entries, two per
+// page, picking the timestamp bounds out of the filter. Polls find a new
+// entry, then another one written late with the same timestamp and a last
+// one.
+func fakeCloudLogging(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	entry := func(id string, at time.Time, payload string) string {
+		return fmt.Sprintf(`{"insertId":%q,"timestamp":%q,"logName":"projects/p/logs/stdout","resource":{"labels":{"pod_name":"api-1"}},%s}`,
+			id, at.Format(time.RFC3339Nano), payload)
+	}
+	type stored struct {
+		at   time.Time
+		json string
+	}
+	entries := []stored{
+		{time.Unix(1, 0), entry("a", time.Unix(1, 0), `"textPayload":"one"`)},
+		{time.Unix(2, 0), entry("b", time.Unix(2, 0), `"jsonPayload":{"message":"two"}`)},
+		{time.Unix(3, 0), entry("c", time.Unix(3, 0), `"textPayload":"three"`)},
+		{time.Unix(9, 0), entry("d", time.Unix(9, 0), `"textPayload":"after the range"`)},
+	}
+	polls := 0
+	future := time.Now().Add(time.Hour).Truncate(time.Second)
+	bounds := regexp.MustCompile(`timestamp(>=|<)"([^"]+)"`)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path != "/v2/entries:list" || r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, `{"error":{"status":"UNAUTHENTICATED"}}`, http.StatusUnauthorized)
+			return
+		}
+		var params struct {
+			ResourceNames []string `json:"resourceNames"`
+			Filter        string   `json:"filter"`
+			OrderBy       string   `json:"orderBy"`
+			PageSize      int      `json:"pageSize"`
+			PageToken     string   `json:"pageToken"`
+		}
+		json.NewDecoder(r.Body).Decode(&params)
+		if !reflect.DeepEqual(params.ResourceNames, []string{"projects/p"}) || params.OrderBy != "timestamp asc" {
+			http.Error(w, `{"error":{"status":"INVALID_ARGUMENT"}}`, http.StatusBadRequest)
+			return
+		}
+		var from, until time.Time
+		for _, m := range bounds.FindAllStringSubmatch(params.Filter, -1) {
+			at, _ := time.Parse(time.RFC3339Nano, m[2])
+			if m[1] == ">=" {
+				from = at
+			} else {
+				until = at
+			}
+		}
+		if until.IsZero() && params.PageToken == "" {
+			polls++
+			switch polls {
+			case 1:
+				entries = append(entries, stored{future, entry("e", future, `"textPayload":"tailed"`)})
+			case 2:
+				entries = append(entries, stored{future, entry("f", future, `"textPayload":"late at the same time"`)},
+					stored{future.Add(time.Second), entry("g", future.Add(time.Second), `"textPayload":"last"`)})
+			case 3:
+				older := future.Add(-30 * time.Second)
+				entries = append(entries, stored{older, entry("h", older, `"textPayload":"late and older"`)})
+			}
+		}
+
+		var matched []string
+		for _, e := range entries {
+			if !e.at.Before(from) && (until.IsZero() || e.at.Before(until)) &&
+				(!strings.Contains(params.Filter, `"two"`) || strings.Contains(e.json, `"two"`)) {
+				matched = append(matched, e.json)
+			}
+		}
+		offset, _ := strconv.Atoi(params.PageToken)
+		n := min(2, params.PageSize, len(matched)-offset)
+		next := ""
+		if offset+n < len(matched) {
+			next = strconv.Itoa(offset + n)
+		}
+		fmt.Fprintf(w, `{"entries":[%s],"nextPageToken":%q}`, strings.Join(matched[offset:offset+n], ","), next)
+	}))
+}
+
+func TestGCP(t *testing.T) {
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "token")
+	defer func(endpoint string, interval time.Duration) {
+		gcpLoggingEndpoint, gcpPollInterval = endpoint, interval
+	}(gcpLoggingEndpoint, gcpPollInterval)
+	gcpPollInterval = time.Millisecond
+
+	tests := []struct {
+		name    string
+		opts    gcpOptions
+		until   time.Time
+		want    []string
+		wantErr string
+	}{
+		{
+			name:  "pages",
+			opts:  gcpOptions{project: "p"},
+			until: time.Unix(5, 0),
+			want:  []string{"one", "two", "three"},
+		},
+		{
+			name:  "filter",
+			opts:  gcpOptions{project: "p", filter: `jsonPayload.message="two"`},
+			until: time.Unix(5, 0),
+			want:  []string{"two"},
+		},
+		{
+			name:  "limit",
+			opts:  gcpOptions{project: "p", limit: 3},
+			until: time.Unix(5, 0),
+			want:  []string{"one", "two", "three"},
+		},
+		{
+			name: "read then poll",
+			opts: gcpOptions{project: "p", follow: true},
+			want: []string{"one", "two", "three", "after the range", "tailed", "late at the same time", "last", "late and older"},
+		},
+		{
+			name:    "missing project",
+			opts:    gcpOptions{},
+			wantErr: "--project is required",
+		},
+		{
+			name:    "unknown project",
+			opts:    gcpOptions{project: "other"},
+			until:   time.Unix(5, 0),
+			wantErr: "INVALID_ARGUMENT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fakeCloudLogging(t)
+			defer server.Close()
+			gcpLoggingEndpoint = server.URL
+			timeFilter := &timeRange{since: time.Unix(1, 0), until: tt.until}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			var got []string
+			err := consumeGCP(ctx, tt.opts, timeFilter, func(line, label string) {
+				var doc map[string]interface{}
+				json.Unmarshal([]byte(line), &doc)
+				if label != "api-1" {
+					t.Errorf("label = %q, want api-1", label)
+				}
+				got = append(got, fmt.Sprint(doc["message"]))
+				if len(got) == len(tt.want) && tt.opts.follow {
+					cancel()
+				}
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGCPLine(t *testing.T) {
+	text := "started\n"
+	at := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		entry     gcpEntry
+		want      string
+		wantLabel string
+	}{
+		{
+			name:      "text payload",
+			entry:     gcpEntry{Timestamp: at, Severity: "WARNING", TextPayload: &text, LogName: "projects/p/logs/cloudaudit.googleapis.com%2Factivity"},
+			want:      `{"@timestamp":"2024-01-15T14:00:00Z","log.level":"warning","message":"started"}`,
+			wantLabel: "activity",
+		},
+		{
+			name:  "json payload keeps its own severity",
+			entry: gcpEntry{Timestamp: at, Severity: "ERROR", JSONPayload: map[string]interface{}{"msg": "x", "severity": "info"}},
+			want:  `{"@timestamp":"2024-01-15T14:00:00Z","msg":"x","severity":"info"}`,
+		},
+		{
+			name:  "default severity",
+			entry: gcpEntry{Timestamp: at, Severity: "DEFAULT", JSONPayload: map[string]interface{}{"message": "x"}},
+			want:  `{"@timestamp":"2024-01-15T14:00:00Z","message":"x"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gcpLine(tt.entry); got != tt.want {
+				t.Errorf("gcpLine() = %s, want %s", got, tt.want)
+			}
+			if got := gcpLabel(tt.entry); got != tt.wantLabel {
+				t.Errorf("gcpLabel() = %q, want %q", got, tt.wantLabel)
+			}
+		})
+	}
+}
+
+// withoutGCPCredentials hides the credentials gcloud or the environment
+// may provide.
+func withoutGCPCredentials(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "")
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	t.Setenv("CLOUDSDK_CONFIG", dir)
+	t.Setenv("HOME", dir)
+}
+
+func TestGCPCredentials(t *testing.T) {
+	defer func(endpoint string) { gcpLoggingEndpoint = endpoint }(gcpLoggingEndpoint)
+	var mu sync.Mutex
+	var authorizations []string
+	logging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") == "Bearer expired" {
+			http.Error(w, `{"error":{"status":"UNAUTHENTICATED"}}`, http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"entries":[]}`)
+	}))
+	defer logging.Close()
+	gcpLoggingEndpoint = logging.URL
+
+	t.Run("service account key, refreshed", func(t *testing.T) {
+		withoutGCPCredentials(t)
+		issued := 0
+		tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			issued++
+			// Tokens this close to expiry are refreshed on every use
+			fmt.Fprintf(w, `{"access_token":"t%d","token_type":"Bearer","expires_in":1}`, issued)
+		}))
+		defer tokens.Close()
+		t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", writeServiceAccountKey(t, tokens.URL))
+
+		client, err := newGCPClient(context.Background(), gcpOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if client.opts.project != "p" {
+			t.Errorf("project = %q, want the key's", client.opts.project)
+		}
+		for i := 0; i < 2; i++ {
+			if _, _, err := client.list(context.Background(), time.Unix(1, 0), time.Time{}, 10, ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if want := []string{"Bearer t1", "Bearer t2"}; !reflect.DeepEqual(authorizations, want) {
+			t.Errorf("Authorization = %q, want %q", authorizations, want)
+		}
+	})
+
+	t.Run("none", func(t *testing.T) {
+		withoutGCPCredentials(t)
+		if _, err := newGCPClient(context.Background(), gcpOptions{project: "p"}); err == nil || !strings.Contains(err.Error(), "no Google Cloud credentials found") {
+			t.Errorf("error = %v, want no Google Cloud credentials found", err)
+		}
+	})
+
+	t.Run("expired access token", func(t *testing.T) {
+		withoutGCPCredentials(t)
+		t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "expired")
+		client, err := newGCPClient(context.Background(), gcpOptions{project: "p"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = client.list(context.Background(), time.Unix(1, 0), time.Time{}, 10, "")
+		if err == nil || !strings.Contains(err.Error(), "GOOGLE_OAUTH_ACCESS_TOKEN may have expired") {
+			t.Errorf("error = %v, want a hint that the token expired", err)
+		}
+	})
+}
+
+// writeServiceAccountKey writes the key file of a service account of
+// project p whose tokens come from tokenURL.
+func writeServiceAccountKey(t *testing.T, tokenURL string) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "p",
+		"private_key_id": "key",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email":   "logpipe@p.iam.gserviceaccount.com",
+		"token_uri":      tokenURL,
+	})
+	file := filepath.Join(t.TempDir(), "key.json")
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// GoTestEvent is an event printed by `go test -json` (see `go doc test2json`).
+type GoTestEvent struct {
+	Time    string
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// goTestActions are the actions test2json and the go command emit.
+var goTestActions = map[string]bool{
+	"start": true, "run": true, "pause": true, "cont": true, "pass": true,
+	"bench": true, "fail": true, "output": true, "skip": true,
+	"build-output": true, "build-fail": true,
+}
+
+// parseGoTest recognizes a `go test -json` event, returning nil for other
+// log lines.
+func parseGoTest(line *lineFields) *GoTestEvent {
+	if !strings.Contains(line.text, `"Action"`) {
+		return nil
+	}
+	fields := line.object()
+	if fields == nil || !goTestActions[fieldString(fields, "Action")] {
+		return nil
+	}
+	event := &GoTestEvent{
+		Time:    fieldString(fields, "Time"),
+		Action:  fieldString(fields, "Action"),
+		Package: fieldString(fields, "Package", "ImportPath"),
+		Test:    fieldString(fields, "Test"),
+		Output:  fieldString(fields, "Output"),
+	}
+	if event.Package == "" {
+		return nil
+	}
+	event.Elapsed, _ = fieldFloat(fields, "Elapsed")
+	return event
+}
+
+var (
+	goTestPassColor = color.New(color.FgGreen)
+	goTestFailColor = color.New(color.FgRed, color.Bold)
+	goTestSkipColor = color.New(color.FgYellow)
+)
+
+// goTestPackage counts the results of one package's tests.
+type goTestPackage struct {
+	passed, failed, skipped int
+	coverage                string
+}
+
+// goTestView renders `go test -json` events as a compact progress view: one
+// line per finished test, output only for failed and skipped tests, and a
+// summary line per package.
+type goTestView struct {
+	output   map[string][]string
+	packages map[string]*goTestPackage
+	order    []string
+}
+
+func newGoTestView() *goTestView {
+	return &goTestView{
+		output:   make(map[string][]string),
+		packages: make(map[string]*goTestPackage),
+	}
+}
+
+// goTests holds the state of the test run being rendered.
+var goTests = newGoTestView()
+
+func (v *goTestView) pkg(name string) *goTestPackage {
+	p, ok := v.packages[name]
+	if !ok {
+		p = &goTestPackage{}
+		v.packages[name] = p
+		v.order = append(v.order, name)
+	}
+	return p
+}
+
+// write renders an event, buffering test output until the test finishes.
+func (v *goTestView) write(w io.Writer, event *GoTestEvent) {
+	p := v.pkg(event.Package)
+	key := event.Package + " " + event.Test
+
+	switch event.Action {
+	case "output", "build-output":
+		line := strings.TrimRight(event.Output, "\n")
+		if event.Test == "" {
+			v.writePackageOutput(w, p, line)
+			return
+		}
+		// test2json repeats the results we already render
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "=== ") || strings.HasPrefix(trimmed, "--- ") {
+			return
+		}
+		v.output[key] = append(v.output[key], line)
+	case "pass", "fail", "skip":
+		if event.Test == "" {
+			v.writePackageResult(w, event, p)
+			return
+		}
+		output := v.output[key]
+		delete(v.output, key)
+
+		indent := strings.Repeat("  ", strings.Count(event.Test, "/"))
+		switch event.Action {
+		case "pass":
+			p.passed++
+			fmt.Fprintf(w, "%s%s %s %s\n", indent, goTestPassColor.Sprint("✓"), event.Test, labelColor.Sprintf("%.2fs", event.Elapsed))
+		case "fail":
+			p.failed++
+			fmt.Fprintf(w, "%s%s %s %s\n", indent, goTestFailColor.Sprint("✗"), goTestFailColor.Sprint(event.Test), labelColor.Sprintf("%.2fs", event.Elapsed))
+			for _, line := range output {
+				fmt.Fprintf(w, "%s    %s\n", indent, strings.TrimLeft(line, " "))
+			}
+		case "skip":
+			p.skipped++
+			fmt.Fprintf(w, "%s%s %s %s\n", indent, goTestSkipColor.Sprint("○"), event.Test, goTestSkipColor.Sprint("skipped"))
+			for _, line := range output {
+				fmt.Fprintf(w, "%s    %s\n", indent, labelColor.Sprint(strings.TrimLeft(line, " ")))
+			}
+		}
+	case "build-fail":
+		fmt.Fprintf(w, "%s %s %s\n", goTestFailColor.Sprint("FAIL"), event.Package, goTestFailColor.Sprint("[build failed]"))
+	}
+}
+
+// writePackageOutput shows package-level output such as panics and build
+// errors, skipping the summary lines go test prints itself.
+func (v *goTestView) writePackageOutput(w io.Writer, p *goTestPackage, line string) {
+	trimmed := strings.TrimSpace(line)
+	switch {
+	case trimmed == "" || trimmed == "PASS" || trimmed == "FAIL":
+		return
+	case strings.HasPrefix(trimmed, "ok ") || strings.HasPrefix(trimmed, "FAIL\t") || strings.HasPrefix(trimmed, "?"):
+		return
+	case strings.HasPrefix(trimmed, "coverage:"):
+		p.coverage = strings.TrimPrefix(trimmed, "coverage: ")
+		return
+	case strings.HasPrefix(trimmed, "=== ") || strings.HasPrefix(trimmed, "--- "):
+		return
+	}
+	fmt.Fprintf(w, "  %s\n", line)
+}
+
+// writePackageResult prints the summary line of a finished package.
+func (v *goTestView) writePackageResult(w io.Writer, event *GoTestEvent, p *goTestPackage) {
+	counts := p.counts()
+	switch event.Action {
+	case "pass":
+		fmt.Fprintf(w, "%s  %s %s", goTestPassColor.Sprint("ok  "), event.Package, labelColor.Sprintf("%.2fs", event.Elapsed))
+	case "fail":
+		fmt.Fprintf(w, "%s  %s %s", goTestFailColor.Sprint("FAIL"), event.Package, labelColor.Sprintf("%.2fs", event.Elapsed))
+	case "skip":
+		fmt.Fprintf(w, "%s  %s %s\n", labelColor.Sprint("?   "), event.Package, labelColor.Sprint("[no test files]"))
+		return
+	}
+	if counts != "" {
+		fmt.Fprintf(w, "  %s", counts)
+	}
+	if p.coverage != "" {
+		fmt.Fprintf(w, "  %s", labelColor.Sprintf("coverage: %s", p.coverage))
+	}
+	fmt.Fprintln(w)
+}
+
+// counts formats the test results, e.g. "12 passed, 1 failed".
+func (p *goTestPackage) counts() string {
+	var parts []string
+	if p.passed > 0 {
+		parts = append(parts, goTestPassColor.Sprintf("%d passed", p.passed))
+	}
+	if p.failed > 0 {
+		parts = append(parts, goTestFailColor.Sprintf("%d failed", p.failed))
+	}
+	if p.skipped > 0 {
+		parts = append(parts, goTestSkipColor.Sprintf("%d skipped", p.skipped))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// summary prints the totals across packages once the input ends. It prints
+// nothing when no test events were seen.
+func (v *goTestView) summary(w io.Writer) {
+	if len(v.order) == 0 {
+		return
+	}
+	var total goTestPackage
+	for _, name := range v.order {
+		p := v.packages[name]
+		total.passed += p.passed
+		total.failed += p.failed
+		total.skipped += p.skipped
+	}
+	counts := total.counts()
+	if counts == "" {
+		counts = "no tests"
+	}
+	packages := "packages"
+	if len(v.order) == 1 {
+		packages = "package"
+	}
+	fmt.Fprintf(w, "\n%s %s in %d %s\n", color.New(color.Bold).Sprint("Tests:"), counts, len(v.order), packages)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseGoTest(t *testing.T) {
+	tests := []struct {
+		name       string
+		line       string
+		wantAction string
+	}{
+		{"pass", `{"Time":"2025-06-28T11:50:00Z","Action":"pass","Package":"example.com/app","Test":"TestA","Elapsed":0.01}`, "pass"},
+		{"build output", `{"ImportPath":"example.com/app","Action":"build-output","Output":"x.go:1: undefined: y\n"}`, "build-output"},
+		{"unknown action", `{"Action":"login","Package":"example.com/app"}`, ""},
+		{"no package", `{"Action":"pass"}`, ""},
+		{"log entry", `{"message":"hi","event":{"action":"pass"}}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := parseGoTest(newLineFields([]byte(tt.line)))
+			action := ""
+			if event != nil {
+				action = event.Action
+			}
+			if action != tt.wantAction {
+				t.Errorf("parseGoTest() action = %q, want %q", action, tt.wantAction)
+			}
+		})
+	}
+}
+
+func TestGoTestView(t *testing.T) {
+	lines := []string{
+		`{"Action":"start","Package":"example.com/app"}`,
+		`{"Action":"run","Package":"example.com/app","Test":"TestA"}`,
+		`{"Action":"output","Package":"example.com/app","Test":"TestA","Output":"=== RUN   TestA\n"}`,
+		`{"Action":"output","Package":"example.com/app","Test":"TestA","Output":"--- PASS: TestA (0.01s)\n"}`,
+		`{"Action":"pass","Package":"example.com/app","Test":"TestA","Elapsed":0.01}`,
+		`{"Action":"run","Package":"example.com/app","Test":"TestB"}`,
+		`{"Action":"output","Package":"example.com/app","Test":"TestB","Output":"    b_test.go:9: got 2, want 3\n"}`,
+		`{"Action":"fail","Package":"example.com/app","Test":"TestB","Elapsed":0.02}`,
+		`{"Action":"output","Package":"example.com/app","Output":"FAIL\n"}`,
+		`{"Action":"output","Package":"example.com/app","Output":"coverage: 81.5% of statements\n"}`,
+		`{"Action":"fail","Package":"example.com/app","Elapsed":0.3}`,
+		`{"Action":"skip","Package":"example.com/tools","Elapsed":0}`,
+	}
+
+	view := newGoTestView()
+	var buf bytes.Buffer
+	for _, line := range lines {
+		view.write(&buf, parseGoTest(newLineFields([]byte(line))))
+	}
+	view.summary(&buf)
+
+	want := []string{
+		"✓ TestA 0.01s",
+		"✗ TestB 0.02s",
+		"    b_test.go:9: got 2, want 3",
+		"FAIL  example.com/app 0.30s  1 passed, 1 failed  coverage: 81.5% of statements",
+		"?     example.com/tools [no test files]",
+		"",
+		"Tests: 1 passed, 1 failed in 2 packages",
+	}
+	if got := strings.TrimSpace(buf.String()); got != strings.Join(want, "\n") {
+		t.Errorf("output =\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/fatih/color"
+)
+
+// GRPCFields describes a gRPC call logged by interceptors such as
+// go-grpc-middleware (grpc.*) or as OpenTelemetry rpc.* attributes.
+type GRPCFields struct {
+	// Method is the full method name, e.g. "/helloworld.Greeter/SayHello".
+	Method string
+	// Code is the status code name, e.g. "OK" or "DEADLINE_EXCEEDED".
+	Code string
+	// DurationMs is the call duration in milliseconds, or -1 if unknown.
+	DurationMs float64
+}
+
+// grpcCodes are the gRPC status code names indexed by number.
+var grpcCodes = []string{
+	"OK", "CANCELLED", "UNKNOWN", "INVALID_ARGUMENT", "DEADLINE_EXCEEDED",
+	"NOT_FOUND", "ALREADY_EXISTS", "PERMISSION_DENIED", "RESOURCE_EXHAUSTED",
+	"FAILED_PRECONDITION", "ABORTED", "OUT_OF_RANGE", "UNIMPLEMENTED",
+	"INTERNAL", "UNAVAILABLE", "DATA_LOSS", "UNAUTHENTICATED",
+}
+
+// parseGRPC extracts gRPC call details from a JSON log line, returning nil
+// when the line does not describe a gRPC call.
+func parseGRPC(line *lineFields) *GRPCFields {
+	if !strings.Contains(line.text, "rpc") {
+		return nil
+	}
+	fields := line.object()
+	if fields == nil {
+		return nil
+	}
+
+	method := fieldString(fields, "grpc.method", "rpc.method")
+	if method == "" {
+		return nil
+	}
+	if system := fieldString(fields, "rpc.system"); system != "" && system != "grpc" && fieldString(fields, "grpc.method") == "" {
+		return nil
+	}
+	if !strings.HasPrefix(method, "/") {
+		if service := fieldString(fields, "grpc.service", "rpc.service"); service != "" {
+			method = "/" + service + "/" + method
+		}
+	}
+
+	call := &GRPCFields{
+		Method:     method,
+		Code:       grpcCodeName(fieldString(fields, "grpc.code", "rpc.grpc.status_code")),
+		DurationMs: -1,
+	}
+	if ms, ok := fieldFloat(fields, "grpc.time_ms", "rpc.duration_ms"); ok {
+		call.DurationMs = ms
+	} else if ns, ok := fieldFloat(fields, "event.duration"); ok {
+		call.DurationMs = ns / 1e6
+	}
+	return call
+}
+
+// grpcCodeName normalizes a status code given as a number, a Go name
+// ("DeadlineExceeded") or a canonical name ("DEADLINE_EXCEEDED").
+func grpcCodeName(code string) string {
+	if code == "" {
+		return ""
+	}
+	if n, err := strconv.Atoi(code); err == nil {
+		if n >= 0 && n < len(grpcCodes) {
+			return grpcCodes[n]
+		}
+		return code
+	}
+
+	// Go's codes.Code names are CamelCase
+	var name strings.Builder
+	for i, r := range code {
+		if i > 0 && unicode.IsUpper(r) && code != strings.ToUpper(code) {
+			name.WriteByte('_')
+		}
+		name.WriteRune(r)
+	}
+	upper := strings.ToUpper(name.String())
+	if upper == "CANCELED" {
+		return "CANCELLED"
+	}
+	return upper
+}
+
+var (
+	grpcOKPaint     = newPaint(color.FgGreen)
+	grpcServerPaint = newPaint(color.FgRed, color.Bold)
+	grpcNoCodePaint = newPaint(color.FgWhite)
+	grpcClientPaint = newPaint(color.FgYellow)
+)
+
+// grpcCodePaint colors successful calls green, client-side and
+// retryable failures yellow, and server failures red.
+func grpcCodePaint(code string) paint {
+	switch code {
+	case "OK":
+		return grpcOKPaint
+	case "UNKNOWN", "UNIMPLEMENTED", "INTERNAL", "UNAVAILABLE", "DATA_LOSS":
+		return grpcServerPaint
+	case "":
+		return grpcNoCodePaint
+	}
+	return grpcClientPaint
+}
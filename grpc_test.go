@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseGRPC(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  *GRPCFields
+	}{
+		{
+			name:  "go-grpc-middleware",
+			input: `{"grpc.service":"helloworld.Greeter","grpc.method":"SayHello","grpc.code":"DeadlineExceeded","grpc.time_ms":12.5}`,
+			want:  &GRPCFields{Method: "/helloworld.Greeter/SayHello", Code: "DEADLINE_EXCEEDED", DurationMs: 12.5},
+		},
+		{
+			name:  "full method",
+			input: `{"grpc.method":"/pkg.Svc/Get","grpc.code":"OK"}`,
+			want:  &GRPCFields{Method: "/pkg.Svc/Get", Code: "OK", DurationMs: -1},
+		},
+		{
+			name:  "otel nested attributes",
+			input: `{"rpc":{"system":"grpc","service":"a.B","method":"C","grpc":{"status_code":13}},"event":{"duration":3000000}}`,
+			want:  &GRPCFields{Method: "/a.B/C", Code: "INTERNAL", DurationMs: 3},
+		},
+		{
+			name:  "other rpc system",
+			input: `{"rpc.system":"jsonrpc","rpc.method":"eth_call"}`,
+		},
+		{
+			name:  "not grpc",
+			input: `{"message":"hello"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseGRPC(newLineFields([]byte(tt.input)))
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("parseGRPC() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGRPCCodeName(t *testing.T) {
+	tests := map[string]string{
+		"0":                  "OK",
+		"4":                  "DEADLINE_EXCEEDED",
+		"99":                 "99",
+		"DeadlineExceeded":   "DEADLINE_EXCEEDED",
+		"Canceled":           "CANCELLED",
+		"RESOURCE_EXHAUSTED": "RESOURCE_EXHAUSTED",
+		"ok":                 "OK",
+	}
+	for code, want := range tests {
+		if got := grpcCodeName(code); got != want {
+			t.Errorf("grpcCodeName(%q) = %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestLookupField(t *testing.T) {
+	var fields map[string]interface{}
+	input := `{"a.b":1,"c":{"d.e":{"f":"x"}},"g":{"h":null}}`
+	if err := json.Unmarshal([]byte(input), &fields); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := fieldString(fields, "a.b"); got != "1" {
+		t.Errorf("fieldString(a.b) = %q, want 1", got)
+	}
+	if got := fieldString(fields, "c.d.e.f"); got != "x" {
+		t.Errorf("fieldString(c.d.e.f) = %q, want x", got)
+	}
+	if got := fieldString(fields, "g.h", "missing", "c.d.e.f"); got != "x" {
+		t.Errorf("fieldString() fallback = %q, want x", got)
+	}
+	if _, ok := lookupField(fields, "c.d"); ok {
+		t.Error("lookupField(c.d) should not match a partial key")
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// highlightValues is cleared by --no-highlight.
+var highlightValues = true
+
+// messageValuePattern finds values substituted into messages, one group per
+// kind, tried in order.
+var messageValuePattern = regexp.MustCompile(strings.Join([]string{
+	`(https?://[^\s"'<>]+[^\s"'<>.,;:!?)\]])`,
+	`(\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b)`,
+	`("[^"]*"|` + "`[^`]*`" + `|'[^'\s][^']*')`,
+	`(\b(?:\d+(?:\.\d+)?(?:ns|µs|us|ms|s|m|h))+\b)`,
+	`(\b\d+(?:\.\d+)?\b)`,
+}, "|"))
+
+// messageValueColors are subtle colors for URLs, UUIDs, quoted strings,
+// durations and numbers, in the order of messageValuePattern groups.
+var messageValueColors = []*color.Color{
+	color.New(color.FgBlue, color.Underline),
+	color.New(color.FgMagenta),
+	color.New(color.FgGreen),
+	color.New(color.FgYellow),
+	color.New(color.FgCyan),
+}
+
+// messageValue is a value found in a message, with its kind as an index
+// into messageValueColors.
+type messageValue struct {
+	start, end, kind int
+}
+
+// findMessageValues returns the values inside a message, in order.
+func findMessageValues(message string) []messageValue {
+	var values []messageValue
+	for _, m := range messageValuePattern.FindAllStringSubmatchIndex(message, -1) {
+		start, end := m[0], m[1]
+		// Apostrophes inside words (don't, user's) are not quotes
+		if message[start] == '\'' && start > 0 && isWordByte(message[start-1]) {
+			continue
+		}
+		for g := 1; g*2 < len(m); g++ {
+			if m[g*2] >= 0 {
+				values = append(values, messageValue{start, end, g - 1})
+				break
+			}
+		}
+	}
+	return values
+}
+
+// messageValuePaints are messageValueColors as paints.
+var messageValuePaints = func() []paint {
+	paints := make([]paint, len(messageValueColors))
+	for i, c := range messageValueColors {
+		paints[i] = paintOf(c)
+	}
+	return paints
+}()
+
+// highlightMessage writes a message with the values inside it colored,
+// and the rest in base.
+func highlightMessage(b *bytes.Buffer, message string, base paint) {
+	if !highlightValues || color.NoColor {
+		base.write(b, message)
+		return
+	}
+
+	last := 0
+	for _, v := range findMessageValues(message) {
+		if v.start > last {
+			base.write(b, message[last:v.start])
+		}
+		messageValuePaints[v.kind].write(b, message[v.start:v.end])
+		last = v.end
+	}
+	if last < len(message) {
+		base.write(b, message[last:])
+	}
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
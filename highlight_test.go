@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestFindMessageValues(t *testing.T) {
+	kinds := []string{"url", "uuid", "string", "duration", "number"}
+
+	tests := []struct {
+		name    string
+		message string
+		want    []string
+	}{
+		{"number", "fetched 42 items", []string{"number:42"}},
+		{"float", "load 0.75", []string{"number:0.75"}},
+		{"identifier digits", "user42 on v2", nil},
+		{"quoted", `user "bob" and 'alice'`, []string{`string:"bob"`, `string:'alice'`}},
+		{"apostrophes", "it's the user's", nil},
+		{"duration", "took 1m30.5s and 250ms", []string{"duration:1m30.5s", "duration:250ms"}},
+		{"uuid", "req 123e4567-e89b-12d3-a456-426614174000 done", []string{"uuid:123e4567-e89b-12d3-a456-426614174000"}},
+		{"url", "see https://example.com/a?b=1.", []string{"url:https://example.com/a?b=1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, v := range findMessageValues(tt.message) {
+				got = append(got, kinds[v.kind]+":"+tt.message[v.start:v.end])
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("findMessageValues() = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("findMessageValues() = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestHighlightMessage(t *testing.T) {
+	noColor := color.NoColor
+	defer func() { color.NoColor = noColor }()
+	color.NoColor = false
+
+	base := color.New(color.FgWhite)
+	var b bytes.Buffer
+	want := base.Sprint("took ") + messageValueColors[3].Sprint("5ms")
+	if highlightMessage(&b, "took 5ms", paintOf(base)); b.String() != want {
+		t.Errorf("highlightMessage() = %q, want %q", b.String(), want)
+	}
+
+	highlightValues = false
+	defer func() { highlightValues = true }()
+	b.Reset()
+	if highlightMessage(&b, "took 5ms", paintOf(base)); b.String() != base.Sprint("took 5ms") {
+		t.Errorf("highlightMessage() with --no-highlight = %q, want %q", b.String(), base.Sprint("took 5ms"))
+	}
+}
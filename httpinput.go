@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// maxURLRetries bounds how often a download is resumed after the
+// connection drops.
+const maxURLRetries = 3
+
+// urlHeaders are extra request headers by URL prefix, from the config's
+// url_headers, e.g. an Authorization header for a log server.
+var urlHeaders = map[string]map[string]string{}
+
+// isHTTPURL reports whether an argument is an http:// or https:// URL.
+func isHTTPURL(arg string) bool {
+	return strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://")
+}
+
+// headersFor returns the configured headers for a URL, those of longer
+// prefixes winning. Values may refer to environment variables, e.g.
+// "Bearer ${LOGS_TOKEN}", to keep secrets out of the config.
+func headersFor(rawURL string) map[string]string {
+	headers := map[string]string{}
+	prefixes := sortedKeys(urlHeaders)
+	for _, prefix := range prefixes {
+		if !strings.HasPrefix(rawURL, prefix) {
+			continue
+		}
+		// Sorted keys put a prefix before its extensions
+		for name, value := range urlHeaders[prefix] {
+			headers[name] = os.ExpandEnv(value)
+		}
+	}
+	return headers
+}
+
+// urlFile returns a URL as a file to read, labeled by the last part of its
+// path.
+func urlFile(rawURL string) (inputFile, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return inputFile{}, err
+	}
+	label := path.Base(u.Path)
+	if label == "/" || label == "." {
+		label = u.Host
+	}
+	return inputFile{
+		path:  rawURL,
+		label: label,
+		open: func() (io.ReadCloser, error) {
+			r := &rangeReader{url: rawURL}
+			if err := r.connect(); err != nil {
+				return nil, err
+			}
+			return decompress(r)
+		},
+	}, nil
+}
+
+// rangeReader streams a download, resuming it with a Range request where
+// it stopped when the connection drops and the server supports ranges.
+type rangeReader struct {
+	url       string
+	body      io.ReadCloser
+	offset    int64
+	resumable bool
+	retries   int
+}
+
+func (r *rangeReader) connect() error {
+	req, err := http.NewRequest(http.MethodGet, r.url, nil)
+	if err != nil {
+		return err
+	}
+	for name, value := range headersFor(r.url) {
+		req.Header.Set(name, value)
+	}
+	// Asked for explicitly, compressed bodies are left compressed, so
+	// offsets count the bytes sent; decompress unpacks them.
+	req.Header.Set("Accept-Encoding", "gzip")
+	want := http.StatusOK
+	if r.offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", r.offset))
+		want = http.StatusPartialContent
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != want {
+		resp.Body.Close()
+		return fmt.Errorf("%s: %s", req.URL.Redacted(), resp.Status)
+	}
+	r.body = resp.Body
+	r.resumable = resp.Header.Get("Accept-Ranges") == "bytes"
+	return nil
+}
+
+func (r *rangeReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	if err != nil && err != io.EOF && r.resumable && r.retries < maxURLRetries {
+		r.body.Close()
+		r.retries++
+		if r.connect() == nil {
+			return n, nil
+		}
+	}
+	return n, err
+}
+
+func (r *rangeReader) Close() error {
+	return r.body.Close()
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHeadersFor(t *testing.T) {
+	urlHeaders = map[string]map[string]string{
+		"https://logs.example.com/":     {"Authorization": "Bearer ${LOGPIPE_TEST_TOKEN}", "X-Team": "all"},
+		"https://logs.example.com/api/": {"X-Team": "api"},
+	}
+	defer func() { urlHeaders = map[string]map[string]string{} }()
+	t.Setenv("LOGPIPE_TEST_TOKEN", "s3cret")
+
+	tests := []struct {
+		url  string
+		want map[string]string
+	}{
+		{"https://logs.example.com/web/app.log", map[string]string{"Authorization": "Bearer s3cret", "X-Team": "all"}},
+		{"https://logs.example.com/api/app.log", map[string]string{"Authorization": "Bearer s3cret", "X-Team": "api"}},
+		{"https://other.example.com/app.log", map[string]string{}},
+	}
+	for _, tt := range tests {
+		if got := headersFor(tt.url); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("headersFor(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestURLFile(t *testing.T) {
+	body := `{"message":"one"}` + "\n" + `{"message":"two"}` + "\n" + `{"message":"three"}` + "\n"
+	var drops atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/app.log.gz":
+			w.Header().Set("Content-Encoding", "gzip")
+			io.WriteString(w, gzipped(t, body))
+		case "/flaky.log":
+			w.Header().Set("Accept-Ranges", "bytes")
+			var start int
+			if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &start); err == nil {
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(body)-1, len(body)))
+				w.WriteHeader(http.StatusPartialContent)
+				io.WriteString(w, body[start:])
+				return
+			}
+			// Send the first entry, then drop the connection
+			w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+			io.WriteString(w, body[:20])
+			w.(http.Flusher).Flush()
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			drops.Add(1)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	urlHeaders = map[string]map[string]string{server.URL: {"Authorization": "Bearer token"}}
+	defer func() { urlHeaders = map[string]map[string]string{} }()
+
+	tests := []struct {
+		name    string
+		path    string
+		want    []string
+		wantErr string
+	}{
+		{name: "gzipped", path: "/app.log.gz", want: []string{"one", "two", "three"}},
+		{name: "resumed after a dropped connection", path: "/flaky.log", want: []string{"one", "two", "three"}},
+		{name: "missing", path: "/missing.log", wantErr: "404"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := listInputFiles([]string{server.URL + tt.path}, false, "name")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			err = readFiles(files, "name", func(line, label string) {
+				entry, _ := parseLine(line)
+				got = append(got, entry.Message)
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if drops.Load() != 1 {
+		t.Errorf("connection dropped %d times, want 1", drops.Load())
+	}
+}
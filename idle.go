@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// idleWatch tracks how long the input has been silent, for --idle markers
+// that tell a quiet source apart from a broken pipe.
+type idleWatch struct {
+	after time.Duration
+	last  time.Time
+	// reported is how many periods of silence were marked since the last
+	// line.
+	reported int
+	now      func() time.Time
+}
+
+func newIdleWatch(after time.Duration) *idleWatch {
+	return &idleWatch{after: after, last: time.Now(), now: time.Now}
+}
+
+// touch records that a line arrived.
+func (w *idleWatch) touch() {
+	w.last = w.now()
+	w.reported = 0
+}
+
+// check returns the marker due for the current silence, e.g.
+// "— 30s idle —", or "" if none is. One marker is due per period.
+func (w *idleWatch) check() string {
+	periods := int(w.now().Sub(w.last) / w.after)
+	if periods <= w.reported {
+		return ""
+	}
+	w.reported = periods
+	return fmt.Sprintf("— %s idle —", formatIdle(w.after*time.Duration(periods)))
+}
+
+// formatIdle shortens whole durations: "1m" rather than "1m0s".
+func formatIdle(d time.Duration) string {
+	s := d.Round(time.Second).String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
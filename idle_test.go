@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdleWatch(t *testing.T) {
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		steps []time.Duration // time since start of each check
+		touch time.Duration   // a line arrives at this time, if set
+		want  []string
+	}{
+		{"quiet", []time.Duration{10 * time.Second, 29 * time.Second}, 0, []string{"", ""}},
+		{"one period", []time.Duration{30 * time.Second, 40 * time.Second}, 0, []string{"— 30s idle —", ""}},
+		{"every period", []time.Duration{30 * time.Second, time.Minute, 90 * time.Second}, 0, []string{"— 30s idle —", "— 1m idle —", "— 1m30s idle —"}},
+		{"missed checks", []time.Duration{95 * time.Second}, 0, []string{"— 1m30s idle —"}},
+		{"line resets", []time.Duration{30 * time.Second, 50 * time.Second, 70 * time.Second}, 40 * time.Second, []string{"— 30s idle —", "", "— 30s idle —"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := start
+			w := &idleWatch{after: 30 * time.Second, last: start, now: func() time.Time { return now }}
+			for i, step := range tt.steps {
+				if tt.touch > 0 && step > tt.touch && now.Sub(start) < tt.touch {
+					now = start.Add(tt.touch)
+					w.touch()
+				}
+				now = start.Add(step)
+				if got := w.check(); got != tt.want[i] {
+					t.Errorf("check at %v = %q, want %q", step, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFormatIdle(t *testing.T) {
+	for _, tt := range []struct {
+		d    time.Duration
+		want string
+	}{
+		{30 * time.Second, "30s"},
+		{time.Minute, "1m"},
+		{90 * time.Second, "1m30s"},
+		{time.Hour, "1h"},
+		{90 * time.Minute, "1h30m"},
+	} {
+		if got := formatIdle(tt.d); got != tt.want {
+			t.Errorf("formatIdle(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rdnsTimeout bounds each reverse DNS lookup so slow resolvers don't stall
+// the stream.
+const rdnsTimeout = 500 * time.Millisecond
+
+// ipEnricher describes source addresses: their range (private, public,
+// ...), GeoIP location from a MaxMind DB and reverse DNS name.
+type ipEnricher struct {
+	geo  *mmdbReader
+	rdns bool
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+// ipEnrichment is set when --ip-info, --geoip or --rdns is used.
+var ipEnrichment *ipEnricher
+
+func newIPEnricher(geoipFile string, rdns bool) (*ipEnricher, error) {
+	e := &ipEnricher{rdns: rdns, cache: make(map[string]string)}
+	if geoipFile != "" {
+		geo, err := openMMDB(geoipFile)
+		if err != nil {
+			return nil, err
+		}
+		e.geo = geo
+	}
+	return e, nil
+}
+
+// describe returns the enrichment for an address, e.g.
+// "public, US/Seattle, host.example.com". Results are cached.
+func (e *ipEnricher) describe(addr string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if info, ok := e.cache[addr]; ok {
+		return info
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(addr))
+	if ip == nil {
+		e.cache[addr] = ""
+		return ""
+	}
+
+	class := ipClass(ip)
+	parts := []string{class}
+	if e.geo != nil && class == "public" {
+		if location := e.geoLocation(ip); location != "" {
+			parts = append(parts, location)
+		}
+	}
+	if e.rdns {
+		ctx, cancel := context.WithTimeout(context.Background(), rdnsTimeout)
+		names, err := net.DefaultResolver.LookupAddr(ctx, ip.String())
+		cancel()
+		if err == nil && len(names) > 0 {
+			parts = append(parts, strings.TrimSuffix(names[0], "."))
+		}
+	}
+
+	info := strings.Join(parts, ", ")
+	e.cache[addr] = info
+	return info
+}
+
+// geoLocation formats the country ISO code and English city name of a
+// GeoIP2/GeoLite2 record as "US/Seattle".
+func (e *ipEnricher) geoLocation(ip net.IP) string {
+	record, err := e.geo.lookup(ip)
+	if err != nil || record == nil {
+		return ""
+	}
+
+	var parts []string
+	for _, path := range [][]string{
+		{"country", "iso_code"},
+		{"registered_country", "iso_code"},
+		{"city", "names", "en"},
+	} {
+		if s, ok := mmdbPath(record, path...).(string); ok && s != "" {
+			if path[0] == "registered_country" && len(parts) > 0 {
+				continue
+			}
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func mmdbPath(value interface{}, path ...string) interface{} {
+	for _, key := range path {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = fields[key]
+	}
+	return value
+}
+
+// ipClass tags the address range an IP belongs to.
+func ipClass(ip net.IP) string {
+	switch {
+	case ip.IsLoopback():
+		return "loopback"
+	case ip.IsPrivate():
+		return "private"
+	case ip.IsLinkLocalUnicast(), ip.IsLinkLocalMulticast():
+		return "link-local"
+	case ip.IsMulticast():
+		return "multicast"
+	case ip.IsUnspecified():
+		return "unspecified"
+	case cgnatRange.Contains(ip):
+		return "cgnat"
+	}
+	return "public"
+}
+
+var cgnatRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// mmdbString, mmdbUint and mmdbMap encode MaxMind DB data fields.
+func mmdbString(s string) []byte {
+	return append([]byte{2<<5 | byte(len(s))}, s...)
+}
+
+func mmdbUint(n uint16) []byte {
+	return []byte{5<<5 | 2, byte(n >> 8), byte(n)}
+}
+
+func mmdbMap(pairs ...[]byte) []byte {
+	out := []byte{7<<5 | byte(len(pairs)/2)}
+	for _, p := range pairs {
+		out = append(out, p...)
+	}
+	return out
+}
+
+// testMMDB builds an IPv4 database with a single 24-bit node: addresses in
+// 0.0.0.0/1 resolve to a record located in Seattle, US; the rest are absent.
+// The city is stored behind a pointer to exercise pointer decoding.
+func testMMDB() []byte {
+	city := mmdbMap(mmdbString("names"), mmdbMap(mmdbString("en"), mmdbString("Seattle")))
+	record := mmdbMap(
+		mmdbString("country"), mmdbMap(mmdbString("iso_code"), mmdbString("US")),
+		mmdbString("city"), []byte{1 << 5, 0}, // pointer to offset 0
+	)
+	data := append(city, record...)
+
+	const nodeCount = 1
+	left := nodeCount + 16 + len(city)
+	tree := []byte{byte(left >> 16), byte(left >> 8), byte(left), 0, 0, nodeCount}
+
+	var buf bytes.Buffer
+	buf.Write(tree)
+	buf.Write(make([]byte, 16))
+	buf.Write(data)
+	buf.Write(mmdbMetadataMarker)
+	buf.Write(mmdbMap(
+		mmdbString("node_count"), mmdbUint(nodeCount),
+		mmdbString("record_size"), mmdbUint(24),
+		mmdbString("ip_version"), mmdbUint(4),
+	))
+	return buf.Bytes()
+}
+
+func TestMMDBLookup(t *testing.T) {
+	r, err := newMMDBReader(testMMDB())
+	if err != nil {
+		t.Fatalf("newMMDBReader() error = %v", err)
+	}
+
+	record, err := r.lookup(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		t.Fatalf("lookup() error = %v", err)
+	}
+	if got := mmdbPath(record, "city", "names", "en"); got != "Seattle" {
+		t.Errorf("city = %v, want Seattle", got)
+	}
+	if got := mmdbPath(record, "country", "iso_code"); got != "US" {
+		t.Errorf("country = %v, want US", got)
+	}
+
+	for _, ip := range []string{"200.1.1.1", "2001:db8::1"} {
+		if record, err := r.lookup(net.ParseIP(ip)); record != nil || err != nil {
+			t.Errorf("lookup(%s) = %v, %v, want nil", ip, record, err)
+		}
+	}
+
+	if _, err := newMMDBReader([]byte("not a database")); err == nil {
+		t.Error("newMMDBReader() expected an error for invalid data")
+	}
+}
+
+func TestMMDBCorrupt(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"pointer to itself", "\xab\xcd\xefMaxMind.com \x00"},
+		{"pointer to a pointer", "\xab\xcd\xefMaxMind.com \x02 \x00"},
+		{"map pointing back into itself", "\xab\xcd\xefMaxMind.com\xe1\x41a \x00"},
+		{"huge array", "\xab\xcd\xefMaxMind.com\x1f\xff\xff\xff\x04"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newMMDBReader([]byte(tt.data)); err == nil {
+				t.Error("newMMDBReader() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func FuzzMMDB(f *testing.F) {
+	f.Add(testMMDB())
+	f.Add([]byte("\xab\xcd\xefMaxMind.com \x00"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r, err := newMMDBReader(data)
+		if err != nil {
+			return
+		}
+		r.lookup(net.ParseIP("8.8.8.8"))
+		r.lookup(net.ParseIP("2001:db8::1"))
+	})
+}
+
+func TestIPEnricherDescribe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "geo.mmdb")
+	if err := os.WriteFile(path, testMMDB(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	e, err := newIPEnricher(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"8.8.8.8", "public, US/Seattle"},
+		{"200.1.1.1", "public"},
+		{"10.1.2.3", "private"},
+		{"192.168.0.5", "private"},
+		{"127.0.0.1", "loopback"},
+		{"100.72.0.1", "cgnat"},
+		{"fe80::1", "link-local"},
+		{"fd00::1", "private"},
+		{"not-an-ip", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := e.describe(tt.ip); got != tt.want {
+				t.Errorf("describe(%q) = %q, want %q", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"github.com/itchyny/gojq"
+)
+
+// jqFilter runs a jq expression over JSON entries, to filter them or to
+// transform them into other entries before they are rendered.
+type jqFilter struct {
+	code *gojq.Code
+}
+
+func newJQFilter(query string) (*jqFilter, error) {
+	parsed, err := gojq.Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	code, err := gojq.Compile(parsed)
+	if err != nil {
+		return nil, err
+	}
+	return &jqFilter{code: code}, nil
+}
+
+// apply returns the lines an entry turns into. Lines that aren't JSON
+// objects are kept as they are. Each result of the expression is a line:
+// objects as JSON entries, strings as plain text and other values as JSON,
+// except that true keeps the entry unchanged and false and null drop it, so
+// that comparisons filter as --where does. A runtime error stops the
+// expression and is returned with the lines produced before it.
+func (f *jqFilter) apply(line string) ([]string, error) {
+	fields := decodeJSONObject(line)
+	if fields == nil {
+		return []string{line}, nil
+	}
+	var lines []string
+	iter := f.code.Run(fields)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			return lines, nil
+		}
+		switch v := v.(type) {
+		case error:
+			if halt, ok := v.(*gojq.HaltError); ok && halt.Value() == nil {
+				// halt stops the expression without an error
+				return lines, nil
+			}
+			return lines, v
+		case nil:
+		case bool:
+			if v {
+				lines = append(lines, line)
+			}
+		case string:
+			lines = append(lines, v)
+		default:
+			data, err := gojq.Marshal(v)
+			if err != nil {
+				return lines, err
+			}
+			lines = append(lines, string(data))
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJQFilter(t *testing.T) {
+	const line = `{"message":"request","http":{"request":{"method":"GET","id":"a1","bytes":512}},"tags":["x","y"]}`
+	tests := []struct {
+		name    string
+		query   string
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "projection",
+			query: ".http.request | {method, id}",
+			line:  line,
+			want:  []string{`{"id":"a1","method":"GET"}`},
+		},
+		{
+			name:  "select",
+			query: `select(.http.request.method == "POST")`,
+			line:  line,
+		},
+		{
+			name:  "comparison keeps entry",
+			query: ".http.request.bytes > 100",
+			line:  line,
+			want:  []string{line},
+		},
+		{
+			name:  "comparison drops entry",
+			query: ".http.request.bytes > 1000",
+			line:  line,
+		},
+		{
+			name:  "several results",
+			query: ".tags[]",
+			line:  line,
+			want:  []string{"x", "y"},
+		},
+		{
+			name:  "other values as JSON",
+			query: ".http.request.bytes, .tags",
+			line:  line,
+			want:  []string{"512", `["x","y"]`},
+		},
+		{
+			name:  "not JSON",
+			query: ".message",
+			line:  "level=info msg=started",
+			want:  []string{"level=info msg=started"},
+		},
+		{
+			name:  "halt",
+			query: ".message, halt, .tags",
+			line:  line,
+			want:  []string{"request"},
+		},
+		{
+			name:    "runtime error",
+			query:   ".message, (.message | keys)",
+			line:    line,
+			want:    []string{"request"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newJQFilter(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := f.apply(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("apply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewJQFilterInvalid(t *testing.T) {
+	for _, query := range []string{".a |", "undefined_function(1)"} {
+		if _, err := newJQFilter(query); err == nil {
+			t.Errorf("newJQFilter(%q) succeeded", query)
+		}
+	}
+}
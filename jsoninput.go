@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	// byteOrderMark starts files written by some Windows tools.
+	byteOrderMark = "\uFEFF"
+	// maxJoinedLines and maxJoinedBytes bound how much of a pretty-printed
+	// object is held before giving up and showing the lines as they are.
+	maxJoinedLines = 10000
+	maxJoinedBytes = 16 * 1024 * 1024
+)
+
+// jsonJoiner turns JSON written over several lines into one line per
+// entry: pretty-printed objects, arrays of entries and Kubernetes lists,
+// whether on one line or spread over many. Other lines pass through
+// unchanged.
+type jsonJoiner struct {
+	emit func(line string)
+	// pending are the lines of the value being read.
+	pending []string
+	size    int
+	// object is the text of the current object, depth its nesting.
+	object   strings.Builder
+	depth    int
+	inString bool
+	escaped  bool
+	inArray  bool
+	// emitted counts objects emitted from the pending lines.
+	emitted int
+}
+
+func newJSONJoiner(emit func(line string)) *jsonJoiner {
+	return &jsonJoiner{emit: emit}
+}
+
+// startsJSON reports whether a line may open an object or an array of
+// objects. "[INFO] ..." and "{main} ..." lines don't.
+func startsJSON(trimmed string) bool {
+	switch {
+	case trimmed == "{" || trimmed == "[":
+		return true
+	case strings.HasPrefix(trimmed, "{"):
+		return strings.HasPrefix(strings.TrimSpace(trimmed[1:]), `"`) || strings.TrimSpace(trimmed[1:]) == "}"
+	case strings.HasPrefix(trimmed, "["):
+		rest := strings.TrimSpace(trimmed[1:])
+		return rest == "" || strings.HasPrefix(rest, "{")
+	}
+	return false
+}
+
+// continuesJSON reports whether a line may be part of a JSON value, so
+// the joiner gives up early on a stray opening line.
+func continuesJSON(trimmed string) bool {
+	return trimmed == "" || strings.ContainsRune(`"{}[],-0123456789tfn`, rune(trimmed[0]))
+}
+
+// add reads the next line.
+func (j *jsonJoiner) add(line string) {
+	line = strings.TrimPrefix(line, byteOrderMark)
+	trimmed := strings.TrimSpace(line)
+	if len(j.pending) == 0 && !j.inArray {
+		if !startsJSON(trimmed) {
+			j.emit(line)
+			return
+		}
+		// One object per line, by far the most common case
+		if trimmed[0] == '{' && json.Valid([]byte(trimmed)) {
+			j.emitEntries(line)
+			return
+		}
+	}
+	if len(j.pending) > 0 && !continuesJSON(trimmed) {
+		j.giveUp()
+		j.add(line)
+		return
+	}
+
+	j.pending = append(j.pending, line)
+	j.size += len(line)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if j.depth == 0 {
+			switch {
+			case c == ' ' || c == '\t' || c == '\r' || c == ',':
+				continue
+			case c == '[' && !j.inArray:
+				j.inArray = true
+				continue
+			case c == ']' && j.inArray:
+				j.inArray = false
+				continue
+			case c != '{':
+				j.giveUpAt(line[i:])
+				return
+			}
+		}
+		j.object.WriteByte(c)
+		switch {
+		case j.escaped:
+			j.escaped = false
+		case j.inString:
+			j.escaped = c == '\\'
+			j.inString = c != '"'
+		case c == '"':
+			j.inString = true
+		case c == '{' || c == '[':
+			j.depth++
+		case c == '}' || c == ']':
+			j.depth--
+			if j.depth == 0 && !j.emitObject() {
+				j.giveUpAt(line[i+1:])
+				return
+			}
+		}
+	}
+	if j.depth > 0 {
+		j.object.WriteByte('\n')
+	}
+	switch {
+	case j.depth == 0:
+		j.reset()
+	case len(j.pending) >= maxJoinedLines || j.size >= maxJoinedBytes:
+		j.giveUp()
+	}
+}
+
+// emitObject emits the object just read as one compact line, and reports
+// whether it was valid JSON.
+func (j *jsonJoiner) emitObject() bool {
+	text := j.object.String()
+	j.object.Reset()
+	var compact bytes.Buffer
+	if json.Compact(&compact, []byte(text)) != nil {
+		return false
+	}
+	j.emitEntries(compact.String())
+	j.emitted++
+	return true
+}
+
+// emitEntries emits an object, or each item of a Kubernetes list, as
+// printed by `kubectl get ... -o json`.
+func (j *jsonJoiner) emitEntries(line string) {
+	items, ok := kubernetesListItems(line)
+	if !ok {
+		j.emit(line)
+		return
+	}
+	for _, item := range items {
+		j.emit(item)
+	}
+}
+
+// giveUp shows the pending lines as they are, when they turn out not to
+// be JSON.
+func (j *jsonJoiner) giveUp() {
+	if j.emitted == 0 {
+		for _, line := range j.pending {
+			j.emit(line)
+		}
+	}
+	j.reset()
+	j.inArray = false
+}
+
+// giveUpAt is giveUp for a line whose start was read as JSON: objects
+// already emitted stay, and the rest of the line is shown as is.
+func (j *jsonJoiner) giveUpAt(rest string) {
+	emitted := j.emitted
+	j.giveUp()
+	if rest = strings.TrimSpace(rest); emitted > 0 && rest != "" {
+		j.emit(rest)
+	}
+}
+
+func (j *jsonJoiner) reset() {
+	j.pending = j.pending[:0]
+	j.size = 0
+	j.object.Reset()
+	j.depth = 0
+	j.inString = false
+	j.escaped = false
+	j.emitted = 0
+}
+
+// flush shows what is left of an unfinished value at the end of input.
+func (j *jsonJoiner) flush() {
+	if len(j.pending) > 0 {
+		j.giveUp()
+	}
+}
+
+// readConcatenated reads JSON values written back to back, with or without
+// newlines between them, handing each object to handle as one line.
+// Elements of arrays are handed over one by one. After a syntax error the
+// rest of the line is shown as is and reading resumes on the next one.
+func readConcatenated(r io.Reader, handle lineHandler) error {
+	reader := bufio.NewReader(r)
+	if bom, err := reader.Peek(len(byteOrderMark)); err == nil && string(bom) == byteOrderMark {
+		reader.Discard(len(byteOrderMark))
+	}
+	for {
+		decoder := json.NewDecoder(reader)
+		err := decodeConcatenated(decoder, handle)
+		if err == io.EOF {
+			return nil
+		}
+		var syntaxErr *json.SyntaxError
+		if !errors.As(err, &syntaxErr) && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Invalid JSON at byte %d: %v\n", decoder.InputOffset(), err)
+
+		// Resume after the line holding the error
+		rest := bufio.NewReader(io.MultiReader(decoder.Buffered(), reader))
+		line, readErr := rest.ReadString('\n')
+		if line = strings.TrimSpace(line); line != "" {
+			handle(line, "")
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+		reader = rest
+	}
+}
+
+// decodeConcatenated hands values from decoder to handle until an error,
+// io.EOF at the end of input.
+func decodeConcatenated(decoder *json.Decoder, handle lineHandler) error {
+	for {
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+		var elements []json.RawMessage
+		if value[0] != '[' || json.Unmarshal(value, &elements) != nil {
+			elements = []json.RawMessage{value}
+		}
+		for _, element := range elements {
+			var text string
+			if json.Unmarshal(element, &text) != nil {
+				var compact bytes.Buffer
+				json.Compact(&compact, element)
+				text = compact.String()
+			}
+			handle(text, "")
+		}
+	}
+}
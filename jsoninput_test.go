@@ -0,0 +1,144 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONJoiner(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "one object per line",
+			input: "{\"a\":1}\n{\"b\":2}",
+			want:  []string{`{"a":1}`, `{"b":2}`},
+		},
+		{
+			name:  "byte order mark",
+			input: "\uFEFF{\"a\":1}",
+			want:  []string{`{"a":1}`},
+		},
+		{
+			name:  "pretty-printed object",
+			input: "{\n  \"a\": 1,\n  \"b\": {\"c\": \"x}\"}\n}\n{\"d\":2}",
+			want:  []string{`{"a":1,"b":{"c":"x}"}}`, `{"d":2}`},
+		},
+		{
+			name:  "array on one line",
+			input: `[{"a":1}, {"b":[2]}]`,
+			want:  []string{`{"a":1}`, `{"b":[2]}`},
+		},
+		{
+			name:  "Kubernetes list",
+			input: "{\n  \"apiVersion\": \"v1\",\n  \"items\": [{\"kind\":\"Event\"}, {\"kind\":\"Event\",\"count\":2}],\n  \"kind\": \"List\"\n}",
+			want:  []string{`{"kind":"Event"}`, `{"kind":"Event","count":2}`},
+		},
+		{
+			name:  "array over several lines",
+			input: "[\n  {\n    \"a\": 1\n  },\n  {\"b\": 2}\n]\nafter",
+			want:  []string{`{"a":1}`, `{"b":2}`, "after"},
+		},
+		{
+			name:  "escaped quote in string",
+			input: "{\n\"a\": \"say \\\"}\\\"\"\n}",
+			want:  []string{`{"a":"say \"}\""}`},
+		},
+		{
+			name:  "bracketed level is not JSON",
+			input: "[INFO] started\n[ERROR] {\"a\":1}",
+			want:  []string{"[INFO] started", `[ERROR] {"a":1}`},
+		},
+		{
+			name:  "braces that are not JSON",
+			input: "{main} started\n{\"a\" is odd}",
+			want:  []string{"{main} started", `{"a" is odd}`},
+		},
+		{
+			name:  "stray opening brace gives up on plain text",
+			input: "{\nplain text\nmore",
+			want:  []string{"{", "plain text", "more"},
+		},
+		{
+			name:  "unfinished object at end of input",
+			input: "{\n  \"a\": 1,",
+			want:  []string{"{", `  "a": 1,`},
+		},
+		{
+			name:  "text after an object",
+			input: `[{"a":1}] trailing`,
+			want:  []string{`{"a":1}`, "trailing"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			joiner := newJSONJoiner(func(line string) { got = append(got, line) })
+			for _, line := range strings.Split(tt.input, "\n") {
+				joiner.add(line)
+			}
+			joiner.flush()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadConcatenated(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "objects back to back",
+			input: `{"a":1}{"b":2}  {"c":3}`,
+			want:  []string{`{"a":1}`, `{"b":2}`, `{"c":3}`},
+		},
+		{
+			name:  "objects over several lines",
+			input: "{\n  \"a\": 1\n}\n{\"b\":\n2}",
+			want:  []string{`{"a":1}`, `{"b":2}`},
+		},
+		{
+			name:  "byte order mark and array",
+			input: "\uFEFF[{\"a\":1},{\"b\":2}]{\"c\":3}",
+			want:  []string{`{"a":1}`, `{"b":2}`, `{"c":3}`},
+		},
+		{
+			name:  "strings are shown as text",
+			input: `"plain"{"a":1}`,
+			want:  []string{"plain", `{"a":1}`},
+		},
+		{
+			name:  "syntax error resumes on the next line",
+			input: "{\"a\":1}{bad} text\n{\"b\":2}",
+			want:  []string{`{"a":1}`, "{bad} text", `{"b":2}`},
+		},
+		{
+			name:  "unfinished object at end of input",
+			input: `{"a":1}{"b":`,
+			want:  []string{`{"a":1}`, `{"b":`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := readConcatenated(strings.NewReader(tt.input), func(line, label string) {
+				got = append(got, line)
+			})
+			if err != nil {
+				t.Fatalf("readConcatenated() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// kafkaOptions configures `logpipe kafka`.
+type kafkaOptions struct {
+	brokers       string
+	topic         string
+	group         string
+	fromBeginning bool
+	showOffsets   bool
+	showKey       bool
+	showHeaders   string
+}
+
+func (o *kafkaOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.brokers, "brokers", "", "Comma-separated list of Kafka brokers")
+	fs.StringVar(&o.topic, "topic", "", "Kafka topic to consume")
+	fs.StringVar(&o.group, "group", "", "Kafka consumer group to join and commit offsets for (default: none, read every partition)")
+	fs.BoolVar(&o.fromBeginning, "from-beginning", false, "Start from the oldest offset, unless the --group has offsets committed")
+	fs.BoolVar(&o.showOffsets, "show-offsets", false, "Show partition/offset in front of each entry")
+	fs.BoolVar(&o.showKey, "show-key", false, "Show the message key in front of each entry")
+	fs.StringVar(&o.showHeaders, "show-headers", "", "Show these message headers in front of each entry (comma-separated)")
+}
+
+// consumeKafka reads messages from a Kafka topic and hands each one to
+// handle until ctx is cancelled. With --group, logpipe joins the consumer
+// group and commits its offsets; otherwise it reads every partition on its
+// own, so that viewers neither split partitions nor move each other's
+// offsets.
+func consumeKafka(ctx context.Context, opts kafkaOptions, decoder recordDecoder, handle lineHandler) error {
+	if opts.brokers == "" || opts.topic == "" {
+		return errors.New("--brokers and --topic are required")
+	}
+	brokers := strings.Split(opts.brokers, ",")
+
+	startOffset := kafka.LastOffset
+	if opts.fromBeginning {
+		startOffset = kafka.FirstOffset
+	}
+	if opts.group == "" {
+		return consumeKafkaPartitions(ctx, opts, brokers, startOffset, decoder, handle)
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     brokers,
+		Topic:       opts.topic,
+		GroupID:     opts.group,
+		StartOffset: startOffset,
+	})
+	defer reader.Close()
+
+	for {
+		msg, err := reader.ReadMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		handleKafkaMessage(msg, opts, decoder, handle)
+	}
+}
+
+// consumeKafkaPartitions reads every partition of the topic from
+// startOffset, without a consumer group. Messages are handed to handle one
+// at a time, in the order they arrive from the partitions.
+func consumeKafkaPartitions(ctx context.Context, opts kafkaOptions, brokers []string, startOffset int64, decoder recordDecoder, handle lineHandler) error {
+	var partitions []kafka.Partition
+	var err error
+	for _, broker := range brokers {
+		if partitions, err = kafka.LookupPartitions(ctx, "tcp", broker, opts.topic); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(partitions) == 0 {
+		return fmt.Errorf("topic %q has no partitions", opts.topic)
+	}
+
+	readCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	messages := make(chan kafka.Message)
+	errs := make(chan error, len(partitions))
+	for _, partition := range partitions {
+		reader := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:   brokers,
+			Topic:     opts.topic,
+			Partition: partition.ID,
+		})
+		go func() {
+			defer reader.Close()
+			if err := reader.SetOffset(startOffset); err != nil {
+				errs <- err
+				return
+			}
+			for {
+				msg, err := reader.ReadMessage(readCtx)
+				if err != nil {
+					errs <- err
+					return
+				}
+				select {
+				case messages <- msg:
+				case <-readCtx.Done():
+					errs <- readCtx.Err()
+					return
+				}
+			}
+		}()
+	}
+
+	for {
+		select {
+		case msg := <-messages:
+			handleKafkaMessage(msg, opts, decoder, handle)
+		case err := <-errs:
+			// One partition failing stops the others
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// handleKafkaMessage hands the lines of a message to handle, labeled as
+// the options ask for.
+func handleKafkaMessage(msg kafka.Message, opts kafkaOptions, decoder recordDecoder, handle lineHandler) {
+	handlePayload(msg.Value, decoder, handle, kafkaLabel(msg, opts))
+}
+
+// kafkaLabel labels the entries of a message with its position, key and
+// headers, as far as the options ask for them.
+func kafkaLabel(msg kafka.Message, opts kafkaOptions) string {
+	var parts []string
+	if opts.showOffsets {
+		parts = append(parts, kafkaOffsetLabel(msg))
+	}
+	if opts.showKey {
+		parts = append(parts, string(msg.Key))
+	}
+	for _, name := range splitList(opts.showHeaders) {
+		for _, header := range msg.Headers {
+			if header.Key == name {
+				parts = append(parts, name+"="+string(header.Value))
+			}
+		}
+	}
+	return joinLabel(parts...)
+}
+
+// kafkaOffsetLabel formats a message position as partition@offset.
+func kafkaOffsetLabel(msg kafka.Message) string {
+	return fmt.Sprintf("%d@%d", msg.Partition, msg.Offset)
+}
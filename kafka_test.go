@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestHandleKafkaMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{"single line", `{"message":"started"}`, []string{`{"message":"started"}`}},
+		{"trailing newline", "{\"message\":\"started\"}\r\n", []string{`{"message":"started"}`}},
+		{"batched lines", "{\"message\":\"a\"}\n{\"message\":\"b\"}\r\n{\"message\":\"c\"}\n", []string{`{"message":"a"}`, `{"message":"b"}`, `{"message":"c"}`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			handleKafkaMessage(kafka.Message{Value: []byte(tt.value)}, kafkaOptions{}, nil, func(line, label string) {
+				got = append(got, line)
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKafkaLabel(t *testing.T) {
+	msg := kafka.Message{
+		Partition: 2,
+		Offset:    1042,
+		Key:       []byte("checkout"),
+		Headers: []kafka.Header{
+			{Key: "trace_id", Value: []byte("4bf92f35")},
+			{Key: "env", Value: []byte("prod")},
+		},
+	}
+
+	tests := []struct {
+		name string
+		opts kafkaOptions
+		want string
+	}{
+		{"nothing asked", kafkaOptions{}, ""},
+		{"offsets", kafkaOptions{showOffsets: true}, "2@1042"},
+		{"key", kafkaOptions{showKey: true}, "checkout"},
+		{"headers in the order asked", kafkaOptions{showHeaders: "env, trace_id"}, "env=prod trace_id=4bf92f35"},
+		{"missing header", kafkaOptions{showHeaders: "tenant"}, ""},
+		{"everything", kafkaOptions{showOffsets: true, showKey: true, showHeaders: "env"}, "2@1042 checkout env=prod"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := kafkaLabel(msg, tt.opts); got != tt.want {
+				t.Errorf("kafkaLabel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleKafkaMessageLabelsEveryLine(t *testing.T) {
+	msg := kafka.Message{Partition: 0, Offset: 7, Value: []byte("{\"message\":\"a\"}\n{\"message\":\"b\"}")}
+	var labels []string
+	handleKafkaMessage(msg, kafkaOptions{showOffsets: true}, nil, func(line, label string) {
+		labels = append(labels, label)
+	})
+	if want := []string{"0@7", "0@7"}; !reflect.DeepEqual(labels, want) {
+		t.Errorf("labels = %q, want %q", labels, want)
+	}
+}
+
+func TestConsumeKafkaWithoutGroupLooksUpPartitions(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = consumeKafka(ctx, kafkaOptions{brokers: addr, topic: "app-logs"}, nil, func(string, string) {})
+	if err == nil || ctx.Err() != nil {
+		t.Errorf("consumeKafka() error = %v, want the partition lookup to fail", err)
+	}
+}
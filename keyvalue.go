@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KeyValue is a key=value pair of a logfmt line with no field of its own
+// in LogEntry, shown after the message.
+type KeyValue struct {
+	Key, Value string
+}
+
+// logfmtFields maps common logfmt keys onto the fields LogEntry reads.
+var logfmtFields = map[string]string{
+	"time": "@timestamp", "ts": "@timestamp", "timestamp": "@timestamp",
+	"level": "log.level", "lvl": "log.level", "severity": "log.level",
+	"msg": "message", "message": "message",
+	"err": "error", "error": "error",
+	"logger": "log.logger",
+}
+
+var logfmtKeyRegex = regexp.MustCompile(`^[A-Za-z_@][\w.@/-]*$`)
+
+// splitLogfmt splits a line of key=value pairs, with values optionally
+// double-quoted. It fails unless the whole line is made of pairs.
+func splitLogfmt(line string) ([]KeyValue, bool) {
+	var pairs []KeyValue
+	rest := strings.TrimSpace(line)
+	for rest != "" {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok || !logfmtKeyRegex.MatchString(key) {
+			return nil, false
+		}
+		if strings.HasPrefix(value, `"`) {
+			quoted, err := strconv.QuotedPrefix(value)
+			if err != nil {
+				return nil, false
+			}
+			rest = value[len(quoted):]
+			value, _ = strconv.Unquote(quoted)
+		} else {
+			end := strings.IndexByte(value, ' ')
+			if end < 0 {
+				end = len(value)
+			}
+			value, rest = value[:end], value[end:]
+		}
+		if rest != "" && rest[0] != ' ' {
+			return nil, false
+		}
+		pairs = append(pairs, KeyValue{key, value})
+		rest = strings.TrimLeft(rest, " ")
+	}
+	return pairs, true
+}
+
+// parseKeyValueLine reads a logfmt line, e.g.
+// `time=2024-01-15T10:00:00Z level=info msg="user created" user=alice`.
+// It needs a message or a level, so prose that happens to contain "=" is
+// left alone.
+func parseKeyValueLine(line string) (LogEntry, bool) {
+	pairs, ok := splitLogfmt(line)
+	if !ok || len(pairs) < 2 {
+		return LogEntry{}, false
+	}
+	doc := make(map[string]interface{})
+	var rest []KeyValue
+	for _, kv := range pairs {
+		field, known := logfmtFields[strings.ToLower(kv.Key)]
+		value := kv.Value
+		if known && field == "@timestamp" {
+			at, ok := parseLooseTime(kv.Value)
+			known = ok
+			value = at.Format(time.RFC3339Nano)
+		}
+		if known && setEntryField(doc, field, value) {
+			continue
+		}
+		// Other pairs stay out of the document: a "user" or "event" pair
+		// would clash with the objects LogEntry expects there
+		rest = append(rest, kv)
+	}
+	if doc["message"] == nil && doc["log.level"] == nil {
+		return LogEntry{}, false
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return LogEntry{}, false
+	}
+	var entry LogEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return LogEntry{}, false
+	}
+	entry.KeyValues = rest
+	return entry, true
+}
+
+// looseTimeRegex matches the timestamps written in front of plain text
+// lines: 2024-01-15T10:00:00.123Z, 2024-01-15 10:00:00,123 or Go's
+// 2024/01/15 10:00:00, optionally in brackets.
+var looseTimeRegex = regexp.MustCompile(`^\[?(\d{4}[-/]\d{2}[-/]\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d{1,9})?(?:Z|[+-]\d{2}:?\d{2})?)\]?(?:\s+|$)`)
+
+// parseLooseTime parses a timestamp in one of the forms of looseTimeRegex.
+// Times without a zone are local.
+func parseLooseTime(s string) (time.Time, bool) {
+	s = strings.NewReplacer("/", "-", ",", ".", " ", "T").Replace(s)
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, true
+	}
+	// Zones written without a colon, e.g. +0100
+	if t, err := time.Parse("2006-01-02T15:04:05.999999999Z0700", s); err == nil {
+		return t, true
+	}
+	t, err := time.ParseInLocation("2006-01-02T15:04:05.999999999", s, time.Local)
+	return t, err == nil
+}
+
+// cutLeadingTime splits a timestamp off the start of line.
+func cutLeadingTime(line string) (time.Time, string, bool) {
+	m := looseTimeRegex.FindStringSubmatch(line)
+	if m == nil {
+		return time.Time{}, line, false
+	}
+	t, ok := parseLooseTime(m[1])
+	if !ok {
+		return time.Time{}, line, false
+	}
+	return t, line[len(m[0]):], true
+}
+
+// levelPrefixRegex matches a level word in brackets, capitalized and
+// followed by a colon, or in capitals, then the message. Lower-case words
+// with a colon are left alone: "panic: ..." is the message of a Go panic.
+var levelPrefixRegex = regexp.MustCompile(`^(?:\[(\pL+)\]|\((\pL+)\)|(\p{Lu}\pL*|\p{Lo}+):|(\p{Lu}+)(?:\s|$))\s*(.*)$`)
+
+// parseLevelPrefixLine reads plain text lines starting with a level, after
+// an optional timestamp: "ERROR: disk full", "[WARN] slow query",
+// "2024-01-15 10:00:00,123 INFO started".
+func parseLevelPrefixLine(line string) (LogEntry, bool) {
+	at, rest, hasTime := cutLeadingTime(line)
+	m := levelPrefixRegex.FindStringSubmatch(rest)
+	if m == nil {
+		return LogEntry{}, false
+	}
+	level := m[1] + m[2] + m[3] + m[4]
+	if _, ok := levelAliases[strings.ToLower(level)]; !ok {
+		return LogEntry{}, false
+	}
+	entry := LogEntry{Level: level, Message: m[5]}
+	if hasTime {
+		entry.Timestamp = at.Format(time.RFC3339Nano)
+	}
+	return entry, true
+}
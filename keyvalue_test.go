@@ -0,0 +1,137 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseKeyValueLine(t *testing.T) {
+	tests := []struct {
+		name          string
+		line          string
+		wantOK        bool
+		wantLevel     string
+		wantMessage   string
+		wantTimestamp string
+		wantRest      []KeyValue
+	}{
+		{
+			name:          "logfmt",
+			line:          `time=2024-01-15T10:00:00Z level=info msg="user created" user=alice id=42`,
+			wantOK:        true,
+			wantLevel:     "info",
+			wantMessage:   "user created",
+			wantTimestamp: "2024-01-15T10:00:00Z",
+			wantRest:      []KeyValue{{"user", "alice"}, {"id", "42"}},
+		},
+		{
+			name:        "escaped quotes",
+			line:        `lvl=warn msg="said \"hi\"" path=/x`,
+			wantOK:      true,
+			wantLevel:   "warn",
+			wantMessage: `said "hi"`,
+			wantRest:    []KeyValue{{"path", "/x"}},
+		},
+		{
+			name:      "level only",
+			line:      `level=error component=db err="connection refused"`,
+			wantOK:    true,
+			wantLevel: "error",
+			wantRest:  []KeyValue{{"component", "db"}},
+		},
+		{
+			name:     "unparsed time kept as a pair",
+			line:     `ts=yesterday msg=hello`,
+			wantOK:   true,
+			wantRest: []KeyValue{{"ts", "yesterday"}},
+
+			wantMessage: "hello",
+		},
+		{name: "pairs without message or level", line: `a=1 b=2`},
+		{name: "single pair", line: `msg=hello`},
+		{name: "prose", line: `the value x=1 is wrong`},
+		{name: "unterminated quote", line: `msg="oops level=info`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, ok := parseKeyValueLine(tt.line)
+			if ok != tt.wantOK {
+				t.Fatalf("parseKeyValueLine() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if entry.Level != tt.wantLevel || entry.Message != tt.wantMessage || entry.Timestamp != tt.wantTimestamp {
+				t.Errorf("got level %q message %q time %q, want %q %q %q", entry.Level, entry.Message, entry.Timestamp, tt.wantLevel, tt.wantMessage, tt.wantTimestamp)
+			}
+			if !reflect.DeepEqual(entry.KeyValues, tt.wantRest) {
+				t.Errorf("KeyValues = %v, want %v", entry.KeyValues, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestParseLevelPrefixLine(t *testing.T) {
+	local := func(s string) string {
+		at, _ := time.ParseInLocation("2006-01-02 15:04:05.000", s, time.Local)
+		return at.Format(time.RFC3339Nano)
+	}
+	tests := []struct {
+		line          string
+		wantOK        bool
+		wantLevel     string
+		wantMessage   string
+		wantTimestamp string
+	}{
+		{"ERROR: disk full", true, "ERROR", "disk full", ""},
+		{"Warning: low memory", true, "Warning", "low memory", ""},
+		{"[warn] slow query", true, "warn", "slow query", ""},
+		{"(debug) cache miss", true, "debug", "cache miss", ""},
+		{"INFO starting server", true, "INFO", "starting server", ""},
+		{"2024-01-15T10:00:00Z [ERROR] boom", true, "ERROR", "boom", "2024-01-15T10:00:00Z"},
+		{"2024-01-15 10:00:00,123 INFO started", true, "INFO", "started", local("2024-01-15 10:00:00.123")},
+		{"[2024-01-15 10:00:00.123] WARN retrying", true, "WARN", "retrying", local("2024-01-15 10:00:00.123")},
+		{"警告: 容量不足", true, "警告", "容量不足", ""},
+		{"panic: something broke", false, "", "", ""},
+		{"Error connecting to db", false, "", "", ""},
+		{"HELLO world", false, "", "", ""},
+		{"2024/01/15 10:00:00 no level here", false, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			entry, ok := parseLevelPrefixLine(tt.line)
+			if ok != tt.wantOK {
+				t.Fatalf("parseLevelPrefixLine() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if entry.Level != tt.wantLevel || entry.Message != tt.wantMessage || entry.Timestamp != tt.wantTimestamp {
+				t.Errorf("got level %q message %q time %q, want %q %q %q", entry.Level, entry.Message, entry.Timestamp, tt.wantLevel, tt.wantMessage, tt.wantTimestamp)
+			}
+		})
+	}
+}
+
+func TestParseLooseTime(t *testing.T) {
+	tests := []struct {
+		s    string
+		want time.Time
+	}{
+		{"2024-01-15T10:00:00Z", time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)},
+		{"2024-01-15T10:00:00.5+01:00", time.Date(2024, 1, 15, 9, 0, 0, 5e8, time.UTC)},
+		{"2024-01-15 10:00:00+0100", time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)},
+		{"2024/01/15 10:00:00", time.Date(2024, 1, 15, 10, 0, 0, 0, time.Local)},
+		{"2024-01-15 10:00:00,250", time.Date(2024, 1, 15, 10, 0, 0, 25e7, time.Local)},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseLooseTime(tt.s)
+		if !ok || !got.Equal(tt.want) {
+			t.Errorf("parseLooseTime(%q) = %v, %v, want %v", tt.s, got, ok, tt.want)
+		}
+	}
+	if _, ok := parseLooseTime("yesterday"); ok {
+		t.Error("expected parseLooseTime to fail on yesterday")
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// KubeEvent is a Kubernetes Event object, as printed by
+// `kubectl get events -w -o json`, in the core v1 or events.k8s.io/v1
+// shape.
+type KubeEvent struct {
+	// Type is "Normal" or "Warning".
+	Type   string
+	Reason string
+	// Object is the involved object, e.g. "pod/web-1", in Namespace.
+	Object    string
+	Namespace string
+	// Count is how many times the event was seen, 0 when not logged.
+	Count int
+	// Source is the component that reported the event, e.g. "kubelet".
+	Source string
+	// Message and Time fill in entries without a message or timestamp.
+	Message, Time string
+}
+
+// parseKubeEvent recognizes a Kubernetes Event, bare or wrapped in a watch
+// event by --output-watch-events, returning nil for other log lines.
+func parseKubeEvent(line *lineFields) *KubeEvent {
+	if !strings.Contains(line.text, `"Event"`) {
+		return nil
+	}
+	fields := line.object()
+	if fields == nil {
+		return nil
+	}
+	if object, ok := fields["object"].(map[string]interface{}); ok && fieldString(fields, "kind") == "" {
+		fields = object
+	}
+	apiVersion := fieldString(fields, "apiVersion")
+	if fieldString(fields, "kind") != "Event" || apiVersion != "v1" && apiVersion != "events.k8s.io/v1" {
+		return nil
+	}
+	event := &KubeEvent{
+		Type:    fieldString(fields, "type"),
+		Reason:  fieldString(fields, "reason"),
+		Source:  fieldString(fields, "source.component", "reportingController", "reportingComponent"),
+		Message: strings.TrimSpace(fieldString(fields, "message", "note")),
+		Time:    fieldString(fields, "lastTimestamp", "series.lastObservedTime", "eventTime", "firstTimestamp", "deprecatedLastTimestamp", "metadata.creationTimestamp"),
+	}
+	object := "involvedObject"
+	if _, ok := fields["regarding"]; ok {
+		object = "regarding"
+	}
+	if kind, name := fieldString(fields, object+".kind"), fieldString(fields, object+".name"); name != "" {
+		event.Object = strings.ToLower(kind) + "/" + name
+	}
+	event.Namespace = fieldString(fields, object+".namespace", "metadata.namespace")
+	event.Count, _ = strconv.Atoi(fieldString(fields, "count", "series.count", "deprecatedCount"))
+	return event
+}
+
+// kubeEventLevel maps an event type onto a level, for entries without one.
+func kubeEventLevel(eventType string) string {
+	if eventType == "Warning" {
+		return "warn"
+	}
+	return "info"
+}
+
+// kubernetesListItems returns the items of a Kubernetes list, as printed
+// by `kubectl get events -o json` without --watch, one compact line each.
+func kubernetesListItems(line string) ([]string, bool) {
+	if !strings.Contains(line, `"items"`) || !strings.Contains(line, `List"`) {
+		return nil, false
+	}
+	var list struct {
+		Kind  string            `json:"kind"`
+		Items []json.RawMessage `json:"items"`
+	}
+	if json.Unmarshal([]byte(line), &list) != nil || !strings.HasSuffix(list.Kind, "List") {
+		return nil, false
+	}
+	items := make([]string, len(list.Items))
+	for i, item := range list.Items {
+		items[i] = string(item)
+	}
+	return items, true
+}
+
+var (
+	kubeNormalPaint  = newPaint(color.FgGreen, color.Bold)
+	kubeWarningPaint = newPaint(color.FgYellow, color.Bold)
+	kubeObjectPaint  = newPaint(color.FgMagenta)
+	kubeCountPaint   = newPaint(color.FgYellow)
+)
+
+// kubeEventLine renders the body of an event line: its reason, colored by
+// type, the object it is about, how many times it was seen, its message
+// and the component that reported it, e.g.
+// "BackOff default pod/web-1 ×12 Back-off restarting failed container (kubelet)".
+func kubeEventLine(event *KubeEvent, message string) string {
+	reason := kubeNormalPaint
+	if event.Type == "Warning" {
+		reason = kubeWarningPaint
+	}
+	var b strings.Builder
+	b.WriteString(reason.sprint(event.Reason))
+	if event.Namespace != "" {
+		b.WriteString(" " + labelPaint.sprint(event.Namespace))
+	}
+	if event.Object != "" {
+		b.WriteString(" " + kubeObjectPaint.sprint(event.Object))
+	}
+	if event.Count > 1 {
+		b.WriteString(" " + kubeCountPaint.sprint("×"+strconv.Itoa(event.Count)))
+	}
+	if message != "" {
+		b.WriteString(" " + messagePaint.sprint(message))
+	}
+	if event.Source != "" {
+		b.WriteString(" " + labelPaint.sprint("("+event.Source+")"))
+	}
+	return b.String()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestParseKubeEvent(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want *KubeEvent
+	}{
+		{
+			"core v1",
+			`{"apiVersion":"v1","kind":"Event","type":"Warning","reason":"BackOff","message":"Back-off restarting failed container","count":12,"involvedObject":{"kind":"Pod","name":"web-1","namespace":"default"},"source":{"component":"kubelet"},"firstTimestamp":"2024-01-15T14:00:00Z","lastTimestamp":"2024-01-15T14:02:11Z"}`,
+			&KubeEvent{Type: "Warning", Reason: "BackOff", Object: "pod/web-1", Namespace: "default", Count: 12, Source: "kubelet", Message: "Back-off restarting failed container", Time: "2024-01-15T14:02:11Z"},
+		},
+		{
+			"events.k8s.io in a watch event",
+			`{"type":"ADDED","object":{"apiVersion":"events.k8s.io/v1","kind":"Event","type":"Normal","reason":"Scheduled","note":"Successfully assigned default/web-2 to node-1","regarding":{"kind":"Pod","name":"web-2","namespace":"default"},"reportingController":"default-scheduler","eventTime":"2024-01-15T14:03:00.000000Z","series":{"count":3}}}`,
+			&KubeEvent{Type: "Normal", Reason: "Scheduled", Object: "pod/web-2", Namespace: "default", Count: 3, Source: "default-scheduler", Message: "Successfully assigned default/web-2 to node-1", Time: "2024-01-15T14:03:00.000000Z"},
+		},
+		{"audit event", `{"apiVersion":"audit.k8s.io/v1","kind":"Event","verb":"get"}`, nil},
+		{"other object", `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"Event"}}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseKubeEvent(newLineFields([]byte(tt.line)))
+			if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+				t.Errorf("parseKubeEvent() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKubeEventLine(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	tests := []struct {
+		event KubeEvent
+		want  string
+	}{
+		{KubeEvent{Type: "Warning", Reason: "BackOff", Object: "pod/web-1", Namespace: "default", Count: 12, Source: "kubelet"}, "BackOff default pod/web-1 ×12 restarting (kubelet)"},
+		{KubeEvent{Type: "Normal", Reason: "Pulled", Object: "pod/web-3", Count: 1}, "Pulled pod/web-3 restarting"},
+	}
+	for _, tt := range tests {
+		if got := kubeEventLine(&tt.event, "restarting"); got != tt.want {
+			t.Errorf("kubeEventLine() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestKubeEventEntry(t *testing.T) {
+	entry, ok := parseLine(`{"apiVersion":"v1","kind":"Event","type":"Warning","reason":"BackOff","message":"Back-off","lastTimestamp":"2024-01-15T14:02:11Z"}`)
+	if !ok || entry.KubeEvent == nil {
+		t.Fatal("event not recognized")
+	}
+	if entry.Level != "warn" || entry.Message != "Back-off" || entry.Timestamp != "2024-01-15T14:02:11Z" {
+		t.Errorf("entry = level %q, message %q, timestamp %q", entry.Level, entry.Message, entry.Timestamp)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// kvOutput is set by --kv to render entries as one key=value line each.
+var kvOutput = false
+
+// kvLeadingKeys are the fields written first by --kv, under shorter
+// names, in this order. The other fields follow under their dotted path.
+var kvLeadingKeys = []struct{ field, key string }{
+	{"@timestamp", "ts"},
+	{"log.level", "level"},
+	{"label", "label"},
+	{"stream", "stream"},
+	{"http.request.method", "method"},
+	{"http.response.status_code", "status"},
+	{"url.path", "path"},
+	{"duration_ms", "duration_ms"},
+	{"message", "msg"},
+}
+
+var (
+	kvKeyColor = color.New(color.FgBlue)
+	kvKeyPaint = paintOf(kvKeyColor)
+)
+
+// kvLine renders all the fields of an entry, normalized as for
+// --output-format jsonl-normalized, as one logfmt line, e.g.
+// ts=2024-01-15T14:00:00Z level=info method=GET path=/api msg=served
+// user.id=42.
+func kvLine(line string, entry LogEntry) string {
+	fields := normalizedFields(line, entry, entry.InputLabel)
+	var b strings.Builder
+	write := func(key string, value interface{}) {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		formatted := logfmtValue(value)
+		switch key {
+		case "level":
+			formatted = getLevelColor(entry.Level).Sprint(formatted)
+		case "msg":
+			formatted = messagePaint.sprint(formatted)
+		}
+		b.WriteString(kvKeyPaint.sprint(key+"=") + formatted)
+	}
+	for _, leading := range kvLeadingKeys {
+		if value, ok := fields[leading.field]; ok {
+			write(leading.key, value)
+			delete(fields, leading.field)
+		}
+	}
+	for _, key := range sortedKeys(fields) {
+		write(key, fields[key])
+	}
+	return b.String()
+}
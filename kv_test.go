@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestKVLine(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	tests := []struct {
+		name  string
+		line  string
+		label string
+		want  string
+	}{
+		{
+			name: "http",
+			line: `{"@timestamp":"2024-01-15T15:00:00+01:00","log.level":"INFO","http":{"request":{"method":"GET"},"response":{"status_code":200}},"url":{"path":"/api"},"message":"served ok","user":{"id":42},"event":{"duration":12000000}}`,
+			want: `ts=2024-01-15T14:00:00Z level=info method=GET status=200 path=/api duration_ms=12 msg="served ok" event.duration=12000000 user.id=42`,
+		},
+		{
+			name:  "labelled logfmt",
+			line:  `level=warn msg="slow thing" took=3`,
+			label: "worker.log",
+			want:  `level=warn label=worker.log duration_ms=3 msg="slow thing" took=3`,
+		},
+		{
+			name: "arrays and nulls",
+			line: `{"message":"x","tags":["a","b"],"user":null}`,
+			want: `msg=x tags="[\"a\",\"b\"]" user=`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, ok := parseLine(tt.line)
+			if !ok {
+				t.Fatalf("parseLine(%q) failed", tt.line)
+			}
+			entry.InputLabel = tt.label
+			if got := kvLine(tt.line, entry); got != tt.want {
+				t.Errorf("kvLine() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// latencyBarWidth is the width of --latency-bar bars in cells.
+const latencyBarWidth = 8
+
+// latencyWindow is how many recent durations the bar scale follows.
+const latencyWindow = 100
+
+// showLatencyBars is set by --latency-bar.
+var showLatencyBars = false
+
+// latencyScales keep the recent durations of each kind of entry (HTTP,
+// gRPC, SQL), since their typical latencies differ.
+var latencyScales = map[string]*latencyScale{}
+
+// latencyScale tracks the maximum of the recent durations.
+type latencyScale struct {
+	recent [latencyWindow]float64
+	next   int
+}
+
+// add records a duration and returns its ratio to the rolling maximum.
+func (s *latencyScale) add(ms float64) float64 {
+	s.recent[s.next] = ms
+	s.next = (s.next + 1) % latencyWindow
+	peak := 0.0
+	for _, d := range s.recent {
+		peak = max(peak, d)
+	}
+	if peak <= 0 {
+		return 0
+	}
+	return ms / peak
+}
+
+// eighthBlocks draw the partial cell of a bar, from 1/8 to 7/8.
+var eighthBlocks = []string{"▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+// latencyBar draws a duration as a bar relative to the slowest of the
+// recent entries of the same kind, padded to latencyBarWidth.
+func latencyBar(kind string, ms float64) string {
+	scale, ok := latencyScales[kind]
+	if !ok {
+		scale = &latencyScale{}
+		latencyScales[kind] = scale
+	}
+	ratio := scale.add(ms)
+
+	eighths := int(ratio*latencyBarWidth*8 + 0.5)
+	if eighths == 0 && ms > 0 {
+		eighths = 1
+	}
+	bar := strings.Repeat("█", eighths/8)
+	if eighths%8 > 0 {
+		bar += eighthBlocks[eighths%8-1]
+	}
+	cells := (eighths + 7) / 8
+	return latencyBarPaint(ratio).sprint(bar) + strings.Repeat(" ", latencyBarWidth-cells)
+}
+
+var (
+	latencyHighPaint   = newPaint(color.FgRed)
+	latencyMediumPaint = newPaint(color.FgYellow)
+	latencyLowPaint    = newPaint(color.FgGreen)
+)
+
+// latencyBarPaint colors bars green up to half the recent maximum, yellow
+// up to 80% and red beyond.
+func latencyBarPaint(ratio float64) paint {
+	switch {
+	case ratio > 0.8:
+		return latencyHighPaint
+	case ratio > 0.5:
+		return latencyMediumPaint
+	}
+	return latencyLowPaint
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestLatencyBar(t *testing.T) {
+	saved := latencyScales
+	latencyScales = map[string]*latencyScale{}
+	defer func() { latencyScales = saved }()
+
+	tests := []struct {
+		kind string
+		ms   float64
+		want string
+	}{
+		{"http", 100, "████████"},
+		{"http", 50, "████    "},
+		{"http", 10, "▊       "},
+		{"http", 0.01, "▏       "},
+		{"http", 0, "        "},
+		{"http", 200, "████████"},
+		{"http", 100, "████    "},
+		// Each kind has its own scale
+		{"sql", 5, "████████"},
+	}
+	for _, tt := range tests {
+		got := latencyBar(tt.kind, tt.ms)
+		if got != tt.want {
+			t.Errorf("latencyBar(%s, %g) = %q, want %q", tt.kind, tt.ms, got, tt.want)
+		}
+		if n := utf8.RuneCountInString(got); n != latencyBarWidth {
+			t.Errorf("latencyBar(%s, %g) is %d cells wide", tt.kind, tt.ms, n)
+		}
+	}
+}
+
+func TestLatencyScaleWindow(t *testing.T) {
+	var s latencyScale
+	s.add(1000)
+	for i := 0; i < latencyWindow-1; i++ {
+		s.add(10)
+	}
+	if ratio := s.add(10); ratio != 1 {
+		t.Errorf("ratio after the peak left the window = %g, want 1", ratio)
+	}
+}
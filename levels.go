@@ -0,0 +1,311 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Canonical levels that every level name or number is mapped onto for
+// coloring and filtering.
+var canonicalLevels = []string{"trace", "debug", "info", "notice", "warn", "error", "fatal"}
+
+// levelNames lists the names recognized for each canonical level, including
+// common non-English ones.
+var levelNames = map[string][]string{
+	"trace": {"trace", "trc", "verbose", "finest", "finer"},
+	"debug": {"debug", "dbg", "fine",
+		"depuración", "depuracion", "调试"},
+	"info": {"info", "inf", "information", "informational",
+		"información", "informacion", "informação", "informacao", "информация", "信息", "情報"},
+	"notice": {"notice", "hinweis"},
+	"warn": {"warn", "wrn", "warning",
+		"warnung", "avertissement", "advertencia", "avviso", "aviso", "waarschuwing", "предупреждение", "警告"},
+	"error": {"error", "err", "eror",
+		"fehler", "erreur", "errore", "erro", "fout", "ошибка", "错误", "エラー"},
+	"fatal": {"fatal", "ftl", "panic", "critical", "crit", "crt", "alert", "emergency", "emerg", "severe",
+		"kritisch", "critique", "crítico", "critico"},
+}
+
+// levelAliases maps lower-cased level names onto canonical levels. Custom
+// entries from the config file are added at startup.
+var levelAliases = make(map[string]string)
+
+func init() {
+	for canonical, names := range levelNames {
+		for _, name := range names {
+			levelAliases[name] = canonical
+		}
+	}
+}
+
+// numericLevelScheme selects how numeric levels are read: "syslog" (0-7),
+// "pino" (10-60), "otel" (SeverityNumber 1-24), or "auto".
+var numericLevelScheme = "auto"
+
+var numericLevelSchemes = []string{"auto", "syslog", "pino", "otel"}
+
+// normalizeLevel maps a level name or number onto a canonical level. Unknown
+// levels are returned lower-cased.
+func normalizeLevel(level string) string {
+	key := strings.ToLower(strings.TrimSpace(level))
+	if canonical, ok := levelAliases[key]; ok {
+		return canonical
+	}
+	if n, err := strconv.ParseFloat(key, 64); err == nil && n == float64(int(n)) {
+		if canonical := numericLevel(int(n)); canonical != "" {
+			return canonical
+		}
+	}
+	return key
+}
+
+// isNumericLevel reports whether level is a bare number.
+func isNumericLevel(level string) bool {
+	_, err := strconv.ParseFloat(strings.TrimSpace(level), 64)
+	return err == nil
+}
+
+func numericLevel(n int) string {
+	switch numericLevelScheme {
+	case "syslog":
+		return syslogLevel(n)
+	case "pino":
+		return pinoLevel(n)
+	case "otel":
+		return otelLevel(n)
+	}
+
+	// auto: syslog's 0-7 first, pino's multiples of ten next, and
+	// OTel SeverityNumber for the rest
+	switch {
+	case n >= 0 && n <= 7:
+		return syslogLevel(n)
+	case n%10 == 0 && n >= 10 && n <= 60:
+		return pinoLevel(n)
+	}
+	return otelLevel(n)
+}
+
+func syslogLevel(n int) string {
+	if n < 0 || n > 7 {
+		return ""
+	}
+	return normalizeLevel(syslogSeverityLevels[n])
+}
+
+func pinoLevel(n int) string {
+	switch {
+	case n <= 0:
+		return ""
+	case n < 20:
+		return "trace"
+	case n < 30:
+		return "debug"
+	case n < 40:
+		return "info"
+	case n < 50:
+		return "warn"
+	case n < 60:
+		return "error"
+	}
+	return "fatal"
+}
+
+func otelLevel(n int) string {
+	switch {
+	case n < 1 || n > 24:
+		return ""
+	case n <= 4:
+		return "trace"
+	case n <= 8:
+		return "debug"
+	case n <= 12:
+		return "info"
+	case n <= 16:
+		return "warn"
+	case n <= 20:
+		return "error"
+	}
+	return "fatal"
+}
+
+// displayLevel returns the level as shown in output. Names are kept as
+// written, numbers are replaced by their canonical level.
+func displayLevel(level string) string {
+	if isNumericLevel(level) {
+		return normalizeLevel(level)
+	}
+	return level
+}
+
+// addLevelAliases registers custom level names or numbers from the config.
+func addLevelAliases(aliases map[string]string) error {
+	for name, target := range aliases {
+		canonical := strings.ToLower(strings.TrimSpace(target))
+		if !slices.Contains(canonicalLevels, canonical) {
+			return fmt.Errorf("level alias %q: unknown level %q (expected one of %s)",
+				name, target, strings.Join(canonicalLevels, ", "))
+		}
+		levelAliases[strings.ToLower(strings.TrimSpace(name))] = canonical
+	}
+	return nil
+}
+
+// LevelStyle customizes how a level is shown, keyed in the config by
+// canonical level or by any level name (e.g. "audit").
+type LevelStyle struct {
+	Abbrev    string `json:"abbrev"`
+	Icon      string `json:"icon"`
+	Fg        string `json:"fg"`
+	Bg        string `json:"bg"`
+	Bold      bool   `json:"bold"`
+	Faint     bool   `json:"faint"`
+	Underline bool   `json:"underline"`
+}
+
+type compiledLevelStyle struct {
+	abbrev string
+	icon   string
+	color  *color.Color
+}
+
+// levelStyles holds the styles from the config, keyed by lower-cased level.
+var levelStyles = make(map[string]compiledLevelStyle)
+
+var styleColors = map[string][2]color.Attribute{
+	"black":      {color.FgBlack, color.BgBlack},
+	"red":        {color.FgRed, color.BgRed},
+	"green":      {color.FgGreen, color.BgGreen},
+	"yellow":     {color.FgYellow, color.BgYellow},
+	"blue":       {color.FgBlue, color.BgBlue},
+	"magenta":    {color.FgMagenta, color.BgMagenta},
+	"cyan":       {color.FgCyan, color.BgCyan},
+	"white":      {color.FgWhite, color.BgWhite},
+	"hi-black":   {color.FgHiBlack, color.BgHiBlack},
+	"hi-red":     {color.FgHiRed, color.BgHiRed},
+	"hi-green":   {color.FgHiGreen, color.BgHiGreen},
+	"hi-yellow":  {color.FgHiYellow, color.BgHiYellow},
+	"hi-blue":    {color.FgHiBlue, color.BgHiBlue},
+	"hi-magenta": {color.FgHiMagenta, color.BgHiMagenta},
+	"hi-cyan":    {color.FgHiCyan, color.BgHiCyan},
+	"hi-white":   {color.FgHiWhite, color.BgHiWhite},
+}
+
+// styleAttributes returns the color attributes of a style from the config.
+func styleAttributes(fg, bg string, bold, faint, underline bool) ([]color.Attribute, error) {
+	var attrs []color.Attribute
+	for i, name := range []string{fg, bg} {
+		if name == "" {
+			continue
+		}
+		pair, ok := styleColors[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown color %q", name)
+		}
+		attrs = append(attrs, pair[i])
+	}
+	if bold {
+		attrs = append(attrs, color.Bold)
+	}
+	if faint {
+		attrs = append(attrs, color.Faint)
+	}
+	if underline {
+		attrs = append(attrs, color.Underline)
+	}
+	return attrs, nil
+}
+
+// addLevelStyles registers level styles from the config.
+func addLevelStyles(styles map[string]LevelStyle) error {
+	for level, style := range styles {
+		attrs, err := styleAttributes(style.Fg, style.Bg, style.Bold, style.Faint, style.Underline)
+		if err != nil {
+			return fmt.Errorf("level style %q: %w", level, err)
+		}
+
+		compiled := compiledLevelStyle{abbrev: style.Abbrev, icon: style.Icon}
+		if len(attrs) > 0 {
+			compiled.color = color.New(attrs...)
+		}
+		levelStyles[strings.ToLower(strings.TrimSpace(level))] = compiled
+	}
+	return nil
+}
+
+// lookupLevelStyle finds the configured style for a level, trying the name
+// as written before its canonical level.
+func lookupLevelStyle(level string) (compiledLevelStyle, bool) {
+	if len(levelStyles) == 0 {
+		return compiledLevelStyle{}, false
+	}
+	if style, ok := levelStyles[strings.ToLower(strings.TrimSpace(level))]; ok {
+		return style, true
+	}
+	style, ok := levelStyles[normalizeLevel(level)]
+	return style, ok
+}
+
+// levelLabel returns the short level tag shown between brackets.
+func levelLabel(level string) string {
+	if style, ok := lookupLevelStyle(level); ok && style.abbrev != "" {
+		return style.abbrev
+	}
+	return truncateWidth(displayLevel(level), 4)
+}
+
+// iconSets are the built-in level icons for --icons.
+var iconSets = map[string]map[string]string{
+	"emoji": {
+		"trace": "🔍", "debug": "🐛", "info": "ℹ️", "notice": "✅",
+		"warn": "⚠️", "error": "❌", "fatal": "🔥",
+	},
+	// Nerd Font glyphs: search, debug, info_circle, bell, warning,
+	// times_circle, fire
+	"nerd": {
+		"trace": "\uea6d", "debug": "\uead8", "info": "\uf05a", "notice": "\uf0f3",
+		"warn": "\uf071", "error": "\uf057", "fatal": "\uf06d",
+	},
+}
+
+// iconSet is the icon set used when icons are enabled, or "" when disabled.
+var iconSet = ""
+
+// levelIcon returns the icon shown in front of an entry, preferring an icon
+// configured for the level over the built-in set.
+func levelIcon(level string) string {
+	if iconSet == "" {
+		return ""
+	}
+	if style, ok := lookupLevelStyle(level); ok && style.icon != "" {
+		return style.icon
+	}
+	return iconSets[iconSet][normalizeLevel(level)]
+}
+
+// levelIconPrefix returns the icon and a separating space, or blanks of the
+// same width when the level has no icon, so entries stay aligned.
+func levelIconPrefix(level string) string {
+	if iconSet == "" {
+		return ""
+	}
+	if icon := levelIcon(level); icon != "" {
+		return icon + " "
+	}
+	if iconSet == "nerd" {
+		return "  "
+	}
+	return "   "
+}
+
+func setIconSet(name string) error {
+	if _, ok := iconSets[name]; !ok {
+		return fmt.Errorf("unknown icon set %q (expected emoji or nerd)", name)
+	}
+	iconSet = name
+	return nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+)
+
+func TestNormalizeLevel(t *testing.T) {
+	tests := []struct {
+		level string
+		want  string
+	}{
+		{"error", "error"},
+		{"ERROR", "error"},
+		{" Warning ", "warn"},
+		{"CRITICAL", "fatal"},
+		{"PANIC", "fatal"},
+		{"TRACE", "trace"},
+		{"NOTICE", "notice"},
+		{"Fehler", "error"},
+		{"avertissement", "warn"},
+		{"3", "error"},  // syslog err
+		{"7", "debug"},  // syslog debug
+		{"30", "info"},  // pino info
+		{"50", "error"}, // pino error
+		{"60", "fatal"}, // pino fatal
+		{"9", "info"},   // OTel INFO
+		{"17", "error"}, // OTel ERROR
+		{"custom", "custom"},
+		{"999", "999"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			if got := normalizeLevel(tt.level); got != tt.want {
+				t.Errorf("normalizeLevel(%q) = %q, want %q", tt.level, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNumericLevelScheme(t *testing.T) {
+	defer func() { numericLevelScheme = "auto" }()
+
+	numericLevelScheme = "otel"
+	if got := normalizeLevel("5"); got != "debug" {
+		t.Errorf("otel 5 = %q, want debug", got)
+	}
+	numericLevelScheme = "pino"
+	if got := normalizeLevel("20"); got != "debug" {
+		t.Errorf("pino 20 = %q, want debug", got)
+	}
+	if err := setNumericLevelScheme("bogus"); err == nil {
+		t.Error("Expected unknown scheme to fail")
+	}
+}
+
+func TestAddLevelAliases(t *testing.T) {
+	defer delete(levelAliases, "sev3")
+	if err := addLevelAliases(map[string]string{"SEV3": "error"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := normalizeLevel("sev3"); got != "error" {
+		t.Errorf("normalizeLevel(sev3) = %q, want error", got)
+	}
+	if err := addLevelAliases(map[string]string{"x": "loud"}); err == nil {
+		t.Error("Expected alias to unknown level to fail")
+	}
+}
+
+func TestNumericLevelUnmarshal(t *testing.T) {
+	var entry LogEntry
+	if err := json.Unmarshal([]byte(`{"log.level":50,"message":"boom"}`), &entry); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if entry.Level != "50" || entry.Message != "boom" {
+		t.Errorf("entry = %+v", entry)
+	}
+	if displayLevel(entry.Level) != "error" {
+		t.Errorf("displayLevel(50) = %q, want error", displayLevel(entry.Level))
+	}
+	if err := json.Unmarshal([]byte(`{"log.level":{"x":1}}`), &entry); err == nil {
+		t.Error("Expected object level to fail")
+	}
+}
+
+func TestLevelMatches(t *testing.T) {
+	re := regexp.MustCompile("error|fatal")
+	for _, level := range []string{"error", "ERR", "50", "CRITICAL"} {
+		if !levelMatches(re, level) {
+			t.Errorf("levelMatches(%q) = false, want true", level)
+		}
+	}
+	if levelMatches(re, "info") {
+		t.Error("levelMatches(info) = true, want false")
+	}
+}
+
+func TestLevelStyles(t *testing.T) {
+	defer func() { levelStyles = make(map[string]compiledLevelStyle) }()
+
+	err := addLevelStyles(map[string]LevelStyle{
+		"error": {Abbrev: "ERR", Fg: "white", Bg: "red", Bold: true},
+		"audit": {Abbrev: "AUD", Fg: "hi-magenta"},
+		"warn":  {Fg: "yellow"},
+	})
+	if err != nil {
+		t.Fatalf("addLevelStyles() error = %v", err)
+	}
+
+	tests := []struct {
+		level string
+		label string
+	}{
+		{"error", "ERR"},
+		{"ERR", "ERR"},
+		{"50", "ERR"},
+		{"audit", "AUD"},
+		{"warning", "warn"},
+		{"info", "info"},
+	}
+	for _, tt := range tests {
+		if got := levelLabel(tt.level); got != tt.label {
+			t.Errorf("levelLabel(%q) = %q, want %q", tt.level, got, tt.label)
+		}
+	}
+
+	if style, ok := lookupLevelStyle("audit"); !ok || style.color == nil {
+		t.Error("Expected a color for the custom audit level")
+	}
+	if getLevelColor("audit") != levelStyles["audit"].color {
+		t.Error("Expected getLevelColor to use the configured style")
+	}
+
+	if err := addLevelStyles(map[string]LevelStyle{"x": {Fg: "mauve"}}); err == nil {
+		t.Error("Expected unknown color to fail")
+	}
+}
+
+func TestLevelIcons(t *testing.T) {
+	defer func() {
+		iconSet = ""
+		levelStyles = make(map[string]compiledLevelStyle)
+	}()
+
+	if levelIconPrefix("error") != "" {
+		t.Error("Expected no icon when icons are disabled")
+	}
+
+	if err := setIconSet("emoji"); err != nil {
+		t.Fatal(err)
+	}
+	if got := levelIconPrefix("ERROR"); got != "❌ " {
+		t.Errorf("levelIconPrefix(ERROR) = %q", got)
+	}
+	if got := levelIconPrefix("custom"); got != "   " {
+		t.Errorf("levelIconPrefix(custom) = %q, want padding", got)
+	}
+
+	addLevelStyles(map[string]LevelStyle{"audit": {Icon: "🛡"}})
+	if got := levelIcon("audit"); got != "🛡" {
+		t.Errorf("levelIcon(audit) = %q, want configured icon", got)
+	}
+
+	if err := setIconSet("nerd"); err != nil {
+		t.Fatal(err)
+	}
+	if got := levelIcon("warn"); got != "\uf071" {
+		t.Errorf("levelIcon(warn) = %q", got)
+	}
+	if err := setIconSet("ascii"); err == nil {
+		t.Error("Expected unknown icon set to fail")
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// lintOptions configures `logpipe lint`.
+type lintOptions struct {
+	// schema is from the shared --schema flag, ecs by default.
+	schema string
+	// require lists fields every entry must have in addition to the
+	// schema's, from the shared --require flag.
+	require string
+}
+
+// lintSchema declares expected field types and required fields.
+type lintSchema struct {
+	types    map[string]string
+	required []string
+}
+
+// lintSchemas are the built-in schemas. Types follow Elasticsearch mapping
+// names: keyword and text are strings, long an integer, date an RFC 3339
+// timestamp, ip an IPv4/IPv6 address.
+var lintSchemas = map[string]lintSchema{
+	"ecs": {
+		required: []string{"@timestamp"},
+		types: map[string]string{
+			"@timestamp":                "date",
+			"message":                   "text",
+			"tags":                      "keyword",
+			"labels":                    "object",
+			"ecs.version":               "keyword",
+			"log.level":                 "keyword",
+			"log.logger":                "keyword",
+			"log.origin.file.name":      "keyword",
+			"log.origin.file.line":      "long",
+			"log.origin.function":       "keyword",
+			"log.original":              "keyword",
+			"event.dataset":             "keyword",
+			"event.duration":            "long",
+			"event.kind":                "keyword",
+			"event.category":            "keyword",
+			"event.outcome":             "keyword",
+			"event.created":             "date",
+			"error":                     "object",
+			"error.message":             "text",
+			"error.type":                "keyword",
+			"error.stack_trace":         "keyword",
+			"http.version":              "keyword",
+			"http.request.id":           "keyword",
+			"http.request.method":       "keyword",
+			"http.request.body.bytes":   "long",
+			"http.response.status_code": "long",
+			"http.response.body.bytes":  "long",
+			"http.response.mime_type":   "keyword",
+			"url.original":              "keyword",
+			"url.domain":                "keyword",
+			"url.path":                  "keyword",
+			"url.port":                  "long",
+			"url.query":                 "keyword",
+			"url.scheme":                "keyword",
+			"source.ip":                 "ip",
+			"source.port":               "long",
+			"client.ip":                 "ip",
+			"destination.ip":            "ip",
+			"destination.port":          "long",
+			"destination.domain":        "keyword",
+			"host.name":                 "keyword",
+			"host.hostname":             "keyword",
+			"host.ip":                   "ip",
+			"process.name":              "keyword",
+			"process.pid":               "long",
+			"process.thread.id":         "long",
+			"process.thread.name":       "keyword",
+			"service.name":              "keyword",
+			"service.version":           "keyword",
+			"service.environment":       "keyword",
+			"trace.id":                  "keyword",
+			"span.id":                   "keyword",
+			"transaction.id":            "keyword",
+			"user.id":                   "keyword",
+			"user.name":                 "keyword",
+			"user.email":                "keyword",
+			"user_agent.original":       "keyword",
+		},
+	},
+}
+
+// lintIssue groups violations of one kind on one field.
+type lintIssue struct {
+	field     string
+	problem   string
+	count     int
+	firstLine int
+}
+
+// lintReport collects the violations found in one input.
+type lintReport struct {
+	name    string
+	entries int
+	issues  map[[2]string]*lintIssue
+}
+
+func (r *lintReport) add(field, problem string, line int) {
+	key := [2]string{field, problem}
+	if issue, ok := r.issues[key]; ok {
+		issue.count++
+		return
+	}
+	r.issues[key] = &lintIssue{field: field, problem: problem, count: 1, firstLine: line}
+}
+
+func (r *lintReport) violations() int {
+	n := 0
+	for _, issue := range r.issues {
+		n += issue.count
+	}
+	return n
+}
+
+// write prints the violations per field, most frequent first.
+func (r *lintReport) write(w io.Writer) {
+	fmt.Fprintf(w, "%s: %d entries, %d violations\n", r.name, r.entries, r.violations())
+	issues := make([]*lintIssue, 0, len(r.issues))
+	width := 0
+	for _, issue := range r.issues {
+		issues = append(issues, issue)
+		width = max(width, len(issue.field))
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].count != issues[j].count {
+			return issues[i].count > issues[j].count
+		}
+		return issues[i].field+issues[i].problem < issues[j].field+issues[j].problem
+	})
+	for _, issue := range issues {
+		fmt.Fprintf(w, "  %-*s %6d  %s (first at line %d)\n", width, issue.field, issue.count, issue.problem, issue.firstLine)
+	}
+}
+
+// lintInput validates each line of r against the schema.
+func lintInput(name string, r io.Reader, schema lintSchema) (*lintReport, error) {
+	report := &lintReport{name: name, issues: make(map[[2]string]*lintIssue)}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		report.entries++
+
+		fields := decodeJSONObject(line)
+		if fields == nil {
+			report.add("(line)", "not a JSON object", lineNumber)
+			continue
+		}
+		for _, path := range schema.required {
+			if value, ok := lookupField(fields, path); !ok || value == nil || value == "" {
+				report.add(path, "missing required field", lineNumber)
+			}
+		}
+		for path, value := range flattenFields(fields, schema.types) {
+			expected, ok := schema.types[path]
+			if !ok {
+				continue
+			}
+			if problem := checkFieldType(value, expected); problem != "" {
+				report.add(path, problem, lineNumber)
+			}
+		}
+	}
+	return report, scanner.Err()
+}
+
+// flattenFields maps dotted paths to leaf values. Objects are descended
+// into unless the schema declares them as objects.
+func flattenFields(fields map[string]interface{}, types map[string]string) map[string]interface{} {
+	flat := make(map[string]interface{})
+	var walk func(prefix string, value interface{})
+	walk = func(prefix string, value interface{}) {
+		nested, ok := value.(map[string]interface{})
+		if !ok || types[prefix] == "object" {
+			flat[prefix] = value
+			return
+		}
+		for key, item := range nested {
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			walk(key, item)
+		}
+	}
+	walk("", fields)
+	return flat
+}
+
+// checkFieldType describes how value fails to match the expected type, or
+// returns "" when it matches. Arrays match when all their items do.
+func checkFieldType(value interface{}, expected string) string {
+	if items, ok := value.([]interface{}); ok && expected != "object" {
+		for _, item := range items {
+			if problem := checkFieldType(item, expected); problem != "" {
+				return problem
+			}
+		}
+		return ""
+	}
+
+	got := jsonTypeName(value)
+	switch expected {
+	case "keyword", "text":
+		if got == "string" {
+			return ""
+		}
+	case "long":
+		if n, ok := value.(json.Number); ok {
+			if _, err := n.Int64(); err == nil {
+				return ""
+			}
+			return "expected long, got decimal number"
+		}
+	case "date":
+		if s, ok := value.(string); ok {
+			if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+				return fmt.Sprintf("invalid timestamp %q (expected RFC 3339)", s)
+			}
+			return ""
+		}
+	case "ip":
+		if s, ok := value.(string); ok {
+			if net.ParseIP(s) == nil {
+				return fmt.Sprintf("invalid IP address %q", s)
+			}
+			return ""
+		}
+	case "object":
+		if got == "object" {
+			return ""
+		}
+	}
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("expected %s, got %s", expected, got)
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case json.Number, float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// runLint validates the given files, or stdin when there are none, and
+// returns the process exit code: 1 when violations were found.
+func runLint(opts lintOptions, files []string, w io.Writer) int {
+	schema, ok := lintSchemas[opts.schema]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown schema: %s (expected %s)\n", opts.schema, strings.Join(sortedKeys(lintSchemas), ", "))
+		return 2
+	}
+	schema.required = append(append([]string(nil), schema.required...), splitList(opts.require)...)
+
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	code := 0
+	for _, name := range files {
+		report, err := lintFile(name, schema)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", name, err)
+			return 2
+		}
+		report.write(w)
+		if report.violations() > 0 {
+			code = 1
+		}
+	}
+	return code
+}
+
+// lintFile validates a file, or stdin when name is "-".
+func lintFile(name string, schema lintSchema) (*lintReport, error) {
+	if name == "-" {
+		return lintInput("stdin", os.Stdin, schema)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return lintInput(name, f, schema)
+}
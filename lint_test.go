@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckFieldType(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+		want     string
+	}{
+		{"keyword", `"info"`, "keyword", ""},
+		{"keyword number", `5`, "keyword", "expected keyword, got number"},
+		{"long", `200`, "long", ""},
+		{"long string", `"200"`, "long", "expected long, got string"},
+		{"long decimal", `1.5`, "long", "expected long, got decimal number"},
+		{"date", `"2025-06-28T11:50:00.123Z"`, "date", ""},
+		{"bad date", `"28/06/2025"`, "date", `invalid timestamp "28/06/2025" (expected RFC 3339)`},
+		{"ip", `"2001:db8::1"`, "ip", ""},
+		{"bad ip", `"localhost"`, "ip", `invalid IP address "localhost"`},
+		{"array", `["a","b"]`, "keyword", ""},
+		{"bad array item", `["a",1]`, "keyword", "expected keyword, got number"},
+		{"object", `{"code":1}`, "object", ""},
+		{"object string", `"boom"`, "object", "expected object, got string"},
+		{"null", `null`, "long", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := decodeJSONObject(`{"v":` + tt.value + `}`)
+			if got := checkFieldType(fields["v"], tt.expected); got != tt.want {
+				t.Errorf("checkFieldType(%s, %s) = %q, want %q", tt.value, tt.expected, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunLint(t *testing.T) {
+	input := strings.Join([]string{
+		`{"@timestamp":"2025-06-28T11:50:00Z","log.level":"info","message":"ok"}`,
+		`{"@timestamp":"2025-06-28T11:50:01Z","http":{"response":{"status_code":"500"}}}`,
+		`{"@timestamp":"2025-06-28T11:50:02Z","http.response.status_code":"404","message":"x"}`,
+		`not json`,
+		``,
+	}, "\n")
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	code := runLint(lintOptions{schema: "ecs", require: "message"}, []string{path}, &out)
+	if code != 1 {
+		t.Errorf("runLint() = %d, want 1", code)
+	}
+
+	want := []string{
+		path + ": 4 entries, 4 violations",
+		"http.response.status_code      2  expected long, got string (first at line 2)",
+		"(line)                         1  not a JSON object (first at line 4)",
+		"message                        1  missing required field (first at line 2)",
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("output =\n%s", out.String())
+	}
+	for i := range want {
+		if strings.TrimSpace(lines[i]) != want[i] {
+			t.Errorf("line %d = %q, want %q", i, strings.TrimSpace(lines[i]), want[i])
+		}
+	}
+
+	if code := runLint(lintOptions{schema: "nope"}, nil, &out); code != 2 {
+		t.Errorf("runLint() with an unknown schema = %d, want 2", code)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// listenOptions configures `logpipe listen`.
+type listenOptions struct {
+	forward  string
+	otlpGRPC string
+	otlpHTTP string
+}
+
+func (o *listenOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.forward, "forward", "", "Address to receive the Fluent Forward protocol on, e.g. :24224 (localhost only)")
+	fs.StringVar(&o.otlpGRPC, "otlp-grpc", "", "Address to receive OTLP logs over gRPC on, e.g. :4317 (localhost only)")
+	fs.StringVar(&o.otlpHTTP, "otlp-http", "", "Address to receive OTLP logs over HTTP on, e.g. :4318 (localhost only)")
+}
+
+// consumeListen receives logs pushed by agents and SDKs on the configured
+// addresses until ctx is cancelled or one of the receivers fails. handle
+// is called from a goroutine per connection.
+func consumeListen(ctx context.Context, opts listenOptions, handle lineHandler) error {
+	type receiver struct {
+		name, addr string
+		serve      func(ctx context.Context, ln net.Listener) error
+	}
+	var receivers []receiver
+	if opts.forward != "" {
+		receivers = append(receivers, receiver{"Fluent Forward", opts.forward, func(ctx context.Context, ln net.Listener) error {
+			return serveForward(ctx, ln, handle)
+		}})
+	}
+	if opts.otlpGRPC != "" {
+		// gRPC clients speak HTTP/2 without TLS to a plain address
+		handler := h2c.NewHandler(otlpGRPCHandler(handle), &http2.Server{})
+		receivers = append(receivers, receiver{"OTLP/gRPC", opts.otlpGRPC, func(ctx context.Context, ln net.Listener) error {
+			return serveHTTP(ctx, ln, handler)
+		}})
+	}
+	if opts.otlpHTTP != "" {
+		receivers = append(receivers, receiver{"OTLP/HTTP", opts.otlpHTTP, func(ctx context.Context, ln net.Listener) error {
+			return serveHTTP(ctx, ln, otlpHTTPHandler(handle))
+		}})
+	}
+	if len(receivers) == 0 {
+		return errors.New("nothing to listen on, e.g. --forward :24224, --otlp-grpc :4317 or --otlp-http :4318")
+	}
+
+	listeners := make([]net.Listener, len(receivers))
+	for i, r := range receivers {
+		ln, err := net.Listen("tcp", listenAddr(r.addr))
+		if err != nil {
+			for _, open := range listeners[:i] {
+				open.Close()
+			}
+			return err
+		}
+		listeners[i] = ln
+		fmt.Fprintf(os.Stderr, "Receiving %s on %s\n", r.name, ln.Addr())
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, len(receivers))
+	for i, r := range receivers {
+		go func(r receiver, ln net.Listener) {
+			err := r.serve(ctx, ln)
+			if err != nil {
+				err = fmt.Errorf("%s: %w", r.name, err)
+			}
+			// One receiver failing stops the others
+			cancel()
+			errs <- err
+		}(r, listeners[i])
+	}
+	var first error
+	for range receivers {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// listenAddr returns the address a server given addr listens on: the
+// loopback interface unless a host is given, e.g. 0.0.0.0:24224 to receive
+// from other machines.
+func listenAddr(addr string) string {
+	if strings.HasPrefix(addr, ":") {
+		return "127.0.0.1" + addr
+	}
+	return addr
+}
+
+// serveHTTP serves handler on ln until ctx is cancelled.
+func serveHTTP(ctx context.Context, ln net.Listener, handler http.Handler) error {
+	server := &http.Server{Handler: handler}
+	stop := context.AfterFunc(ctx, func() { server.Close() })
+	defer stop()
+	if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
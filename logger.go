@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// showLogger is set by --logger-column to show log.logger in front of the
+// message.
+var showLogger = false
+
+// shortenLogger abbreviates the packages of a Java-style logger name to
+// their first letter, keeping the class, e.g. com.example.service.UserService
+// as c.e.s.UserService.
+func shortenLogger(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts[:len(parts)-1] {
+		_, size := utf8.DecodeRuneInString(part)
+		parts[i] = part[:size]
+	}
+	return strings.Join(parts, ".")
+}
+
+// loggerColumn renders the shortened logger of an entry in a color picked
+// by its full name, followed by a space, or returns "" without one.
+func loggerColumn(log LogEntry) string {
+	if log.Log.Logger == "" {
+		return ""
+	}
+	return sourcePaint(log.Log.Logger).sprint(shortenLogger(log.Log.Logger)) + " "
+}
+
+// loggerFilter keeps the entries of loggers under the include prefixes,
+// if any, and not under the exclude ones.
+type loggerFilter struct {
+	include, exclude []string
+}
+
+func newLoggerFilter(include, exclude []string) *loggerFilter {
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil
+	}
+	return &loggerFilter{include: include, exclude: exclude}
+}
+
+func (f *loggerFilter) keep(logger string) bool {
+	for _, prefix := range f.exclude {
+		if underLogger(logger, prefix) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, prefix := range f.include {
+		if underLogger(logger, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// underLogger reports whether logger is prefix or one of its descendants:
+// com.example covers com.example.db but not com.examples.
+func underLogger(logger, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, ".")
+	rest, ok := strings.CutPrefix(logger, prefix)
+	return ok && (rest == "" || rest[0] == '.')
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestShortenLogger(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"com.example.service.UserService", "c.e.s.UserService"},
+		{"UserService", "UserService"},
+		{"app.db", "a.db"},
+		{"été.Main", "é.Main"},
+		{"a..B", "a..B"},
+	}
+	for _, tt := range tests {
+		if got := shortenLogger(tt.name); got != tt.want {
+			t.Errorf("shortenLogger(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoggerColumn(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	var log LogEntry
+	if got := loggerColumn(log); got != "" {
+		t.Errorf("no logger = %q", got)
+	}
+	log.Log.Logger = "org.hibernate.SQL"
+	if got, want := loggerColumn(log), "o.h.SQL "; got != want {
+		t.Errorf("loggerColumn() = %q, want %q", got, want)
+	}
+}
+
+func TestLoggerFilter(t *testing.T) {
+	if newLoggerFilter(nil, nil) != nil {
+		t.Error("no prefixes should need no filter")
+	}
+
+	tests := []struct {
+		name             string
+		include, exclude []string
+		logger           string
+		want             bool
+	}{
+		{"included", []string{"com.example"}, nil, "com.example.db.Pool", true},
+		{"included exactly", []string{"com.example"}, nil, "com.example", true},
+		{"not a child", []string{"com.example"}, nil, "com.examples.Main", false},
+		{"trailing dot", []string{"com.example."}, nil, "com.example.Main", true},
+		{"no logger", []string{"com.example"}, nil, "", false},
+		{"excluded", nil, []string{"org.hibernate"}, "org.hibernate.SQL", false},
+		{"not excluded", nil, []string{"org.hibernate"}, "com.example.Main", true},
+		{"no logger not excluded", nil, []string{"org.hibernate"}, "", true},
+		{"exclusion wins", []string{"com.example"}, []string{"com.example.health"}, "com.example.health.Check", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newLoggerFilter(tt.include, tt.exclude).keep(tt.logger); got != tt.want {
+				t.Errorf("keep(%q) = %v, want %v", tt.logger, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lokiBatchSize is how many entries each query_range request asks for.
+var lokiBatchSize = 1000
+
+// lokiDefaultSince is how far back queries start without --since.
+const lokiDefaultSince = time.Hour
+
+// lokiLabelNames are the stream labels an entry is labeled with, the first
+// one present winning.
+var lokiLabelNames = []string{"pod", "container", "instance", "host", "filename", "job"}
+
+// lokiOptions configures `logpipe loki`.
+type lokiOptions struct {
+	addr   string
+	query  string
+	limit  int
+	follow bool
+	orgID  string
+}
+
+func (o *lokiOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.addr, "addr", os.Getenv("LOKI_ADDR"), "Loki server address, e.g. http://loki:3100 (default: $LOKI_ADDR)")
+	fs.StringVar(&o.query, "query", "", `LogQL log query, e.g. '{app="api"} |= "error"'`)
+	fs.IntVar(&o.limit, "limit", 0, "Most entries read before following (default: all in the time range)")
+	fs.StringVar(&o.orgID, "org-id", os.Getenv("LOKI_ORG_ID"), "Tenant sent as X-Scope-OrgID (default: $LOKI_ORG_ID)")
+	registerFollowSwitch(fs, &o.follow, "Keep streaming new entries after the time range")
+}
+
+// lokiStream is a stream of a query result or tail message.
+type lokiStream struct {
+	Labels map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// lokiEntry is an entry of a stream, with its timestamp in nanoseconds.
+type lokiEntry struct {
+	ns    int64
+	line  string
+	label string
+}
+
+// lokiEntries flattens streams into entries in timestamp order.
+func lokiEntries(streams []lokiStream) []lokiEntry {
+	var entries []lokiEntry
+	for _, stream := range streams {
+		label := lokiLabel(stream.Labels)
+		for _, value := range stream.Values {
+			ns, err := strconv.ParseInt(value[0], 10, 64)
+			if err != nil {
+				continue
+			}
+			entries = append(entries, lokiEntry{ns: ns, line: value[1], label: label})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].ns < entries[j].ns })
+	return entries
+}
+
+// lokiLabel names an entry's source by the first of lokiLabelNames its
+// stream has.
+func lokiLabel(labels map[string]string) string {
+	for _, name := range lokiLabelNames {
+		if value := labels[name]; value != "" {
+			if name == "filename" {
+				return path.Base(value)
+			}
+			return value
+		}
+	}
+	return ""
+}
+
+// lokiClient queries a Loki server's HTTP API.
+type lokiClient struct {
+	addr   *url.URL
+	header http.Header
+}
+
+func newLokiClient(opts lokiOptions) (*lokiClient, error) {
+	if opts.addr == "" {
+		return nil, errors.New("--addr is required, e.g. --addr http://loki:3100")
+	}
+	u, err := url.Parse(strings.TrimSuffix(opts.addr, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported address scheme %q (expected http or https)", u.Scheme)
+	}
+	header := http.Header{}
+	for name, value := range headersFor(opts.addr) {
+		header.Set(name, value)
+	}
+	if opts.orgID != "" {
+		header.Set("X-Scope-OrgID", opts.orgID)
+	}
+	if user := os.Getenv("LOKI_USERNAME"); user != "" && u.User == nil {
+		u.User = url.UserPassword(user, os.Getenv("LOKI_PASSWORD"))
+	}
+	return &lokiClient{addr: u, header: header}, nil
+}
+
+// endpoint returns the URL of an API path with a query.
+func (c *lokiClient) endpoint(apiPath string, query url.Values) *url.URL {
+	u := *c.addr
+	u.Path += apiPath
+	u.RawQuery = query.Encode()
+	return &u
+}
+
+// queryRange fetches up to limit entries from start (inclusive) to end
+// (exclusive), oldest first.
+func (c *lokiClient) queryRange(ctx context.Context, query string, start, end int64, limit int) ([]lokiEntry, error) {
+	u := c.endpoint("/loki/api/v1/query_range", url.Values{
+		"query":     {query},
+		"start":     {strconv.FormatInt(start, 10)},
+		"end":       {strconv.FormatInt(end, 10)},
+		"limit":     {strconv.Itoa(limit)},
+		"direction": {"forward"},
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range c.header {
+		req.Header[name] = values
+	}
+	body, err := getObject(req)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var resp struct {
+		Data struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, err
+	}
+	if resp.Data.ResultType != "streams" {
+		return nil, fmt.Errorf("query returns %s, not log lines: logpipe loki only runs log queries", resp.Data.ResultType)
+	}
+	var streams []lokiStream
+	if err := json.Unmarshal(resp.Data.Result, &streams); err != nil {
+		return nil, err
+	}
+	return lokiEntries(streams), nil
+}
+
+// consumeLoki runs a log query over a time range, in batches, then with
+// --follow tails new entries until ctx is cancelled. Without --since, the
+// range starts an hour ago.
+func consumeLoki(ctx context.Context, opts lokiOptions, timeFilter *timeRange, handle lineHandler) error {
+	if opts.query == "" {
+		return errors.New(`--query is required, e.g. --query '{app="api"}'`)
+	}
+	client, err := newLokiClient(opts)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	start, end := now.Add(-lokiDefaultSince), now
+	if timeFilter != nil {
+		if !timeFilter.since.IsZero() {
+			start = timeFilter.since
+		}
+		if !timeFilter.until.IsZero() {
+			if opts.follow {
+				return errors.New("--until can't be used with --follow")
+			}
+			end = timeFilter.until
+		}
+	}
+
+	err = client.readRange(ctx, opts.query, start.UnixNano(), end.UnixNano(), opts.limit, handle)
+	if err != nil || !opts.follow {
+		return ignoreClosed(ctx, err)
+	}
+	return ignoreClosed(ctx, client.tail(ctx, opts.query, end.UnixNano(), handle))
+}
+
+// readRange hands over the entries of a time range, batch by batch.
+func (c *lokiClient) readRange(ctx context.Context, query string, start, end int64, limit int, handle lineHandler) error {
+	var last int64
+	// Entries at the last timestamp of a batch may continue in the next
+	// one, which starts at that timestamp again
+	seen := map[lokiEntry]bool{}
+	read := 0
+	for start < end && (limit == 0 || read < limit) {
+		batch := lokiBatchSize
+		if limit > 0 {
+			batch = min(batch, limit-read)
+		}
+		entries, err := c.queryRange(ctx, query, start, end, batch)
+		if err != nil {
+			return err
+		}
+		fresh := 0
+		for _, entry := range entries {
+			if seen[entry] {
+				continue
+			}
+			if entry.ns != last {
+				clear(seen)
+			}
+			seen[entry] = true
+			handle(entry.line, entry.label)
+			last = entry.ns
+			fresh++
+			read++
+		}
+		if len(entries) < batch || fresh == 0 {
+			break
+		}
+		if last == start {
+			// A whole batch at one timestamp: move on rather than loop
+			start++
+		} else {
+			start = last
+		}
+	}
+	return nil
+}
+
+// tail streams entries from start on with Loki's tail API, a WebSocket.
+func (c *lokiClient) tail(ctx context.Context, query string, start int64, handle lineHandler) error {
+	u := c.endpoint("/loki/api/v1/tail", url.Values{
+		"query": {query},
+		"start": {strconv.FormatInt(start, 10)},
+	})
+	defaultPort := "80"
+	u.Scheme = "ws"
+	if c.addr.Scheme == "https" {
+		defaultPort = "443"
+		u.Scheme = "wss"
+	}
+	conn, r, err := dialWebSocket(ctx, u, defaultPort, c.header)
+	if conn == nil {
+		return err
+	}
+	defer conn.Close()
+
+	for {
+		message, err := readWSMessage(r, conn, true)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		var tailed struct {
+			Streams []lokiStream `json:"streams"`
+			Dropped []struct{}   `json:"dropped_entries"`
+		}
+		if err := json.Unmarshal(message, &tailed); err != nil {
+			return fmt.Errorf("invalid tail message: %w", err)
+		}
+		for _, entry := range lokiEntries(tailed.Streams) {
+			handle(entry.line, entry.label)
+		}
+		if len(tailed.Dropped) > 0 {
+			fmt.Fprintf(os.Stderr, "Loki dropped %d entries while tailing\n", len(tailed.Dropped))
+		}
+	}
+}
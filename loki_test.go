@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeLoki serves query_range over a fixed set of entries, at most
+// lokiBatchSize at a time, and tails one message.
+func fakeLoki(t *testing.T) *httptest.Server {
+	type entry struct {
+		ns     int64
+		stream string
+		line   string
+	}
+	entries := []entry{
+		{1, `{"pod":"api-1"}`, "one"},
+		{2, `{"pod":"api-1"}`, "two"},
+		{2, `{"pod":"api-2"}`, "two again"},
+		{3, `{"filename":"/var/log/app.log"}`, "three"},
+		{9, `{"pod":"api-1"}`, "after the range"},
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Scope-OrgID") != "tenant" {
+			http.Error(w, "no org id", http.StatusUnauthorized)
+			return
+		}
+		query := r.URL.Query()
+		switch r.URL.Path {
+		case "/loki/api/v1/query_range":
+			if query.Get("query") == "rate({app=\"api\"}[1m])" {
+				fmt.Fprint(w, `{"data":{"resultType":"matrix","result":[]}}`)
+				return
+			}
+			start, _ := strconv.ParseInt(query.Get("start"), 10, 64)
+			end, _ := strconv.ParseInt(query.Get("end"), 10, 64)
+			limit, _ := strconv.Atoi(query.Get("limit"))
+			var streams []string
+			for _, e := range entries {
+				if e.ns >= start && e.ns < end && len(streams) < limit {
+					streams = append(streams, fmt.Sprintf(`{"stream":%s,"values":[["%d",%q]]}`, e.stream, e.ns, e.line))
+				}
+			}
+			fmt.Fprintf(w, `{"status":"success","data":{"resultType":"streams","result":[%s]}}`, strings.Join(streams, ","))
+		case "/loki/api/v1/tail":
+			conn, rw, _ := w.(http.Hijacker).Hijack()
+			defer conn.Close()
+			fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+				wsAcceptKey(r.Header.Get("Sec-WebSocket-Key")))
+			rw.Flush()
+			message := fmt.Sprintf(`{"streams":[{"stream":{"pod":"api-3"},"values":[["%s","tailed"]]}],"dropped_entries":[]}`, query.Get("start"))
+			writeWSFrame(conn, wsText, []byte(message), false)
+			writeWSFrame(conn, wsClose, nil, false)
+			bufio.NewReader(conn).ReadByte()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestLoki(t *testing.T) {
+	server := fakeLoki(t)
+	defer server.Close()
+	defer func(size int) { lokiBatchSize = size }(lokiBatchSize)
+	lokiBatchSize = 2
+
+	tests := []struct {
+		name    string
+		opts    lokiOptions
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "range in batches",
+			opts: lokiOptions{query: `{app="api"}`},
+			want: []string{"api-1 one", "api-1 two", "api-2 two again", "app.log three"},
+		},
+		{
+			name: "limit",
+			opts: lokiOptions{query: `{app="api"}`, limit: 2},
+			want: []string{"api-1 one", "api-1 two"},
+		},
+		{
+			name: "range then tail",
+			opts: lokiOptions{query: `{app="api"}`, limit: 1, follow: true},
+			want: []string{"api-1 one", "api-3 tailed"},
+		},
+		{
+			name:    "metric query",
+			opts:    lokiOptions{query: `rate({app="api"}[1m])`},
+			wantErr: "only runs log queries",
+		},
+		{
+			name:    "missing query",
+			opts:    lokiOptions{},
+			wantErr: "--query is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.addr = server.URL
+			tt.opts.orgID = "tenant"
+			timeFilter := &timeRange{since: time.Unix(0, 1), until: time.Unix(0, 5)}
+			if tt.opts.follow {
+				timeFilter.until = time.Time{}
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			var got []string
+			err := consumeLoki(ctx, tt.opts, timeFilter, func(line, label string) {
+				got = append(got, label+" "+line)
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLokiEntries(t *testing.T) {
+	streams := []lokiStream{
+		{Labels: map[string]string{"job": "api", "container": "web"}, Values: [][2]string{{"3", "c"}, {"1", "a"}}},
+		{Labels: map[string]string{"app": "db"}, Values: [][2]string{{"2", "b"}, {"bad", "skipped"}}},
+	}
+	var got []string
+	for _, entry := range lokiEntries(streams) {
+		got = append(got, fmt.Sprintf("%d %s %s", entry.ns, entry.label, entry.line))
+	}
+	want := []string{"1 web a", "2  b", "3 web c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSwitchValueReplacesFollow(t *testing.T) {
+	fs := newFlagSet()
+	glob := fs.String("follow", "", "")
+	fs.StringVar(glob, "f", "", "")
+	var opts lokiOptions
+	opts.register(fs)
+	if err := fs.Parse([]string{"-f", "--query", "{}"}); err != nil {
+		t.Fatal(err)
+	}
+	if !opts.follow || opts.query != "{}" || *glob != "" {
+		t.Errorf("follow = %v, query = %q, glob = %q", opts.follow, opts.query, *glob)
+	}
+}
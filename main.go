@@ -2,12 +2,21 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/fatih/color"
@@ -32,10 +41,14 @@ type LogEntry struct {
 	Event struct {
 		Duration int64 `json:"duration"`
 	} `json:"event"`
+	Host struct {
+		Hostname string `json:"hostname"`
+	} `json:"host"`
 	HTTP struct {
 		Request struct {
 			Body struct {
-				Bytes int `json:"bytes"`
+				Bytes   int         `json:"bytes"`
+				Content interface{} `json:"content"`
 			} `json:"body"`
 			ID     string `json:"id"`
 			Method string `json:"method"`
@@ -43,7 +56,8 @@ type LogEntry struct {
 		} `json:"request"`
 		Response struct {
 			Body struct {
-				Bytes int `json:"bytes"`
+				Bytes   int         `json:"bytes"`
+				Content interface{} `json:"content"`
 			} `json:"body"`
 			MimeType   string `json:"mime_type"`
 			StatusCode int    `json:"status_code"`
@@ -60,6 +74,7 @@ type LogEntry struct {
 			} `json:"file"`
 			Function string `json:"function"`
 		} `json:"origin"`
+		Syslog *SyslogFields `json:"syslog"`
 	} `json:"log"`
 	Process struct {
 		Name   string `json:"name"`
@@ -75,9 +90,10 @@ type LogEntry struct {
 	Source struct {
 		IP string `json:"ip"`
 	} `json:"source"`
-	Span  interface{} `json:"span"`
-	Trace interface{} `json:"trace"`
-	URL   struct {
+	Span   interface{} `json:"span"`
+	Stream string      `json:"stream"`
+	Trace  interface{} `json:"trace"`
+	URL    struct {
 		Domain       string `json:"domain"`
 		Path         string `json:"path"`
 		PathTemplate string `json:"path_template"`
@@ -85,34 +101,363 @@ type LogEntry struct {
 		Query        string `json:"query"`
 		Scheme       string `json:"scheme"`
 	} `json:"url"`
+	User struct {
+		Name string `json:"name"`
+	} `json:"user"`
 	UserAgent struct {
 		Original string `json:"original"`
 	} `json:"user_agent"`
 	Version string `json:"version"`
+
+	// GRPC is set when the entry describes a gRPC call.
+	GRPC *GRPCFields `json:"-"`
+	// SQL is set when the entry logs a database query.
+	SQL *SQLFields `json:"-"`
+	// GoTest is set when the entry is a `go test -json` event.
+	GoTest *GoTestEvent `json:"-"`
+
+	// Audit is set when the entry is an audit event (see parseAudit).
+	Audit *AuditEvent `json:"-"`
+
+	// KubeEvent is set when the entry is a Kubernetes Event object.
+	KubeEvent *KubeEvent `json:"-"`
+
+	// Terraform, BuildKit and Actions are set for the output of these dev
+	// tools: Terraform's -json UI, BuildKit's rawjson progress and
+	// GitHub Actions runner commands.
+	Terraform *TerraformEvent `json:"-"`
+	BuildKit  *BuildKitStatus `json:"-"`
+	Actions   *ActionsCommand `json:"-"`
+
+	// Security is set when the entry is a security alert, authentication
+	// or intrusion detection event (see parseSecurity).
+	Security *SecurityEvent `json:"-"`
+	// Embedded holds a JSON object found in message or log.original that
+	// has no message of its own, to be pretty-printed under the entry.
+	Embedded map[string]interface{} `json:"-"`
+
+	// KeyValues holds the pairs of a logfmt line that have no field of
+	// their own, shown after the message.
+	KeyValues []KeyValue `json:"-"`
+
+	// Lookups holds the labels --enrich found for the entry's fields,
+	// shown after the message.
+	Lookups []Lookup `json:"-"`
+
+	// Promoted holds the members of the entry's labels picked by
+	// --promote, shown after the message.
+	Promoted []PromotedLabel `json:"-"`
+
+	// RequestTime is the timestamp of the request entry a response was
+	// paired with by --pair-requests.
+	RequestTime string `json:"-"`
+
+	// RowStyle is the escape sequence of the style rule matching the
+	// entry, applied to the whole rendered entry.
+	RowStyle string `json:"-"`
+	// InputLabel describes where the entry was read from (e.g. a Kafka
+	// partition and offset) and is shown in front of it when set.
+	InputLabel string `json:"-"`
+
+	// fields is the line the entry was unmarshaled from, decoded once
+	// for its parsers and entryFields.
+	fields *lineFields
 }
 
+// labelColor dims metadata shown around entries, such as input positions
+var (
+	labelColor = color.New(color.Faint)
+	labelPaint = paintOf(labelColor)
+)
+
 func main() {
-	// Check for help flags before parsing
-	for _, arg := range os.Args[1:] {
-		if arg == "-h" || arg == "--help" || arg == "help" {
-			printHelp()
-			return
-		}
-		if arg == "-v" || arg == "--version" || arg == "version" {
-			printVersion()
-			return
-		}
-	}
+	// Flags are parsed by parseCommandLine, which explains its errors
+	flag.CommandLine = newFlagSet()
+	enableConsoleColors()
 
 	var levelFilter = flag.String("level", "", "PERL regex to filter log levels")
 	var messageFilter = flag.String("message", "", "PERL regex to filter messages")
 	var noLevelFilter = flag.String("no-level", "", "PERL regex to exclude log levels")
 	var noMessageFilter = flag.String("no-message", "", "PERL regex to exclude messages")
-	flag.Parse()
+	var loggerFlag = flag.String("logger", "", "Comma-separated logger name prefixes to include, e.g. com.example")
+	var noLoggerFlag = flag.String("no-logger", "", "Comma-separated logger name prefixes to exclude, e.g. org.hibernate")
+	var quietPaths = flag.String("quiet-paths", "", "Comma-separated URL paths (or patterns like /static/*) of noisy entries to hide")
+	var quietMatch = flag.String("quiet-match", "", "Regex of noisy lines to hide")
+	var routeRules = flag.String("route", "", "Comma-separated rules copying input lines of some levels elsewhere, e.g. level>=error:stderr")
+	var statusFlag = flag.Bool("status", false, "Keep a status line with rolling request and error rates at the bottom of the terminal")
+	var durationUnitFlag = flag.String("duration-unit", "", "Unit of duration fields that don't name one: auto (default), ns, us, ms or s")
+	var outliersFlag = flag.Bool("latency-outliers", false, "Flag HTTP requests slower than their endpoint's recent p99")
+	var outlierWindow = flag.Duration("outlier-window", 10*time.Minute, "How far back --latency-outliers baselines go")
+	var latencyBarFlag = flag.Bool("latency-bar", false, "Show durations as bars relative to the slowest recent entries")
+	var timelineFlag = flag.Bool("timeline", false, "Print a chart of entries per minute by level when the input ends or on Ctrl-C")
+	var sinceFlag = flag.String("since", "", "Only show entries from this time: RFC 3339, a time of day like 14:00, or a duration ago like 1h")
+	var untilFlag = flag.String("until", "", "Only show entries before this time (same formats as --since)")
+	var idleAfter = flag.Duration("idle", 0, "Print a marker when no lines arrive for this long, e.g. 30s")
+	var shedLevel = flag.String("shed", "", "When the output can't keep up, drop entries at or below this level instead of slowing the input")
+	var headEntries = flag.Int("head", 0, "Show only the first N entries, then exit")
+	var tailEntries = flag.Int("tail", 0, "Show only the last N entries, once the input ends")
+	var bufferFlag = flag.String("buffer", defaultBufferLimit, "Most entries (or bytes, e.g. 64MB) kept in memory for --tail")
+	var memoryLimitFlag = flag.String("memory-limit", "", "Memory budget, e.g. 256MB, shared by --tail, --pair-requests, --latency-outliers and patterns; --tail spills to disk beyond it")
+	var skipEntries = flag.Int("skip", 0, "Skip the first N entries")
+	var whereFilter = flag.String("where", "", "Include entries for which an expression over their fields is true, e.g. 'http.response.status_code >= 500'")
+	var jqQuery = flag.String("jq", "", "jq expression filtering or transforming JSON entries, e.g. '.http.request | {method, id}'")
+	var diffFields = flag.String("diff-fields", "", "Show how these comma-separated fields (or all) differ from the previous entry")
+	var protoSchemaFile = flag.String("proto-schema", "", "Protobuf schema (.proto) describing binary input records")
+	var protoMessage = flag.String("proto-message", "", "Protobuf message type of each binary input record")
+	var avroSchemaFile = flag.String("avro-schema", "", "Avro schema (.avsc) describing binary input records")
+	var outputFormat = flag.String("output-format", "pretty", "How entries are written: pretty, or jsonl-normalized (one flat JSON object per entry, for scripts)")
+	var streamMode = flag.String("stream", "lines", "How JSON entries are framed on stdin: lines (one per line, or pretty-printed) or concat (back to back)")
+	var lengthPrefix = flag.String("length-prefix", "uint32", "Length prefix of binary input records: uint32 or varint")
+	var configFile = flag.String("config", "", "Path to the JSON config file")
+	var profileName = flag.String("profile", "", "Named bundle of flags from the config file's profiles")
+	var numericLevels = flag.String("numeric-levels", "", "How to read numeric levels: auto, syslog, pino or otel")
+	var icons = flag.Bool("icons", false, "Prefix entries with level icons")
+	var iconSetName = flag.String("icon-set", "", "Level icon set: emoji (default) or nerd (Nerd Font glyphs)")
+	var redactFields = flag.String("redact", "", "Comma-separated field paths to mask, e.g. user.email")
+	var redactDetectorNames = flag.String("redact-detectors", "", "Comma-separated detectors to mask: emails, tokens, cards, ips or all")
+	var ipInfo = flag.Bool("ip-info", false, "Show the range of source IPs (private, public, ...) on HTTP lines")
+	var promoteKeys = flag.String("promote", "", "Show these keys of labels, tags, attributes or baggage after the message (comma-separated)")
+	var enrichSpecs = flag.String("enrich", "", "Label field values from a lookup file, e.g. user_id=users.csv:id:name (comma-separated)")
+	var geoipFile = flag.String("geoip", "", "MaxMind DB (GeoLite2/GeoIP2) used to locate public source IPs")
+	var rdns = flag.Bool("rdns", false, "Resolve source IPs with cached reverse DNS lookups")
+	var debugParseFlag = flag.Bool("debug-parse", false, "Explain why lines could not be parsed and print a summary at the end")
+	var strictFlag = flag.Bool("strict", false, "Exit non-zero when lines fail to parse or lack required fields")
+	var schemaName = flag.String("schema", "", "Render entries with a schema's profile: audit")
+	var requireFields = flag.String("require", "", "Comma-separated fields every JSON entry must have in --strict mode, e.g. @timestamp,log.level")
+	var maxErrors = flag.Int("max-errors", 0, "Violations tolerated in --strict mode before exiting")
+	var followGlob = flag.String("follow", "", "Comma-separated files or globs to follow, including files created later")
+	flag.StringVar(followGlob, "f", "", "Comma-separated files or globs to follow, including files created later")
+	var recursive = flag.Bool("recursive", false, "Read directories named on the command line with all their subdirectories")
+	var fileOrder = flag.String("order", "name", "Order files named on the command line are read in: name, mtime or timestamp (merged by entry time)")
+	var stdoutFile = flag.String("stdout", "-", "File or pipe with the stdout stream when --stderr is used (default: stdin)")
+	var stderrFile = flag.String("stderr", "", "File or pipe with the stderr stream, interleaved with --stdout and marked")
+	var unwrapField = flag.String("unwrap", "", "Field of a shipper's wrapper document holding the real event, e.g. message or log")
+	var unwrapKeep = flag.String("unwrap-keep", "", "Comma-separated wrapper fields shown with unwrapped events (default: host.name,host.hostname,log.file.path,tag)")
+	var sanitizeMode = flag.String("sanitize", "strip", "What to do with terminal escape sequences in input: strip, escape (show them) or off")
+	var noHighlight = flag.Bool("no-highlight", false, "Don't highlight numbers, strings, UUIDs, durations and URLs in messages")
+	var sqlFullFlag = flag.Bool("sql-full", false, "Show SQL statements in full instead of truncating them")
+	var loggerColumnFlag = flag.Bool("logger-column", false, "Show log.logger in front of the message, shortened like c.e.s.UserService")
+	var contextHeader = flag.Bool("context-header", false, "Show the service, version, process and Kubernetes pod of entries in a header when they change, e.g. after a deploy")
+	var correlationFlag = flag.String("correlation", "", "Fill a correlation field from these comma-separated fields, or auto for request/correlation IDs and traceparent")
+	var pairRequests = flag.Bool("pair-requests", false, "Complete responses with the request logged before them under the same http.request.id")
+	var verticalFlag = flag.Bool("vertical", false, "Write each entry as a block of aligned field: value lines, like psql's \\x")
+	var kvFlag = flag.Bool("kv", false, "Write each entry as one key=value line with all its fields, e.g. for grepping")
+	var density = flag.String("density", "normal", "Preset of what is shown: compact (time, level and message), normal or detailed (all optional columns)")
+	var gapFlag = flag.Duration("gap", 0, "Note gaps longer than this between the timestamps of consecutive entries, e.g. 5m")
+	var separatorsFlag = flag.String("separators", "", "Show a separator where entries cross into a new day, hour or interval (day, hour or e.g. 15m)")
+	var restartsFlag = flag.Bool("restarts", false, "Draw a rule where a service is deployed or restarted: a new version or PID, or a startup message")
+	var processFlag = flag.Bool("process", false, "Show the host, process, PID and thread of entries in a column, e.g. api[1234]/worker-3")
+	var bytesColumn = flag.Bool("bytes", false, "Show response sizes and content types on HTTP lines")
+	var bodies = flag.Bool("bodies", false, "Show HTTP request/response bodies indented under the entry")
+	var bodyLimit = flag.Int("body-limit", defaultBodyLimit, "Maximum bytes of each body shown with --bodies")
+	var traceURLFlag = flag.String("trace-url", "", "Link trace IDs to a tracing UI, e.g. https://jaeger.local/trace/{trace.id}")
+	var anonymizeFlag = flag.Bool("anonymize", false, "Replace users, hosts, emails and IPs with stable pseudonyms before display or capture")
+	var anonymizeKey = flag.String("anonymize-key", "", "Secret making --anonymize pseudonyms stable across runs (default: random per run)")
+	var escalateSpec = flag.String("escalate", "", "Mark bursts of errors, e.g. 20/1m for 20 errors within a minute")
+	var escalateWebhook = flag.String("escalate-webhook", "", "URL to POST a JSON alert to when an --escalate burst starts")
+	var captureFile = flag.String("capture", "", "Record input lines into a session bundle to replay with logpipe open")
+	var curlFlag = flag.Bool("curl", false, "Show a curl command reproducing each HTTP request, with secrets redacted")
+	var serveWS = flag.String("serve-ws", "", "Address to serve a live-tail page and WebSocket stream on, e.g. :8081")
+	var serveWSRaw = flag.Bool("serve-ws-raw", false, "Broadcast raw input lines instead of rendered entries")
+	var serveWSOrigins = flag.String("serve-ws-origins", "", "Comma-separated origins of other pages allowed to connect to --serve-ws, e.g. https://dash.example.com")
+
+	// Subcommands read from another source than stdin and add their own flags
+	var kafkaOpts kafkaOptions
+	var lintOpts lintOptions
+	var patternOpts patternOptions
+	var flattenOpts flattenOptions
+	var sloOpts sloOptions
+	var lokiOpts lokiOptions
+	var esOpts esOptions
+	var cloudwatchOpts cloudwatchOptions
+	var gcpOpts gcpOptions
+	var listenOpts listenOptions
+	commands := []command{
+		{name: "pipe", usage: "logpipe [pipe] [OPTIONS] [FILE|DIR|URL...]", maxArgs: -1},
+		{name: "kafka", usage: "logpipe kafka --brokers HOSTS --topic TOPIC [--group GROUP] [OPTIONS]", register: kafkaOpts.register},
+		{name: "sub", usage: "logpipe sub URL [OPTIONS]", minArgs: 1, maxArgs: 1},
+		{name: "loki", usage: "logpipe loki --addr URL --query LOGQL [--since TIME] [--follow] [OPTIONS]", register: lokiOpts.register},
+		{name: "es", usage: "logpipe es --index PATTERN [--query QUERY] [--since TIME] [--follow] [OPTIONS]", register: esOpts.register},
+		{name: "cloudwatch", usage: "logpipe cloudwatch --log-group GROUP [--filter PATTERN] [--since TIME] [--follow] [OPTIONS]", register: cloudwatchOpts.register},
+		{name: "gcp", usage: "logpipe gcp --project PROJECT [--filter QUERY] [--since TIME] [--follow] [OPTIONS]", register: gcpOpts.register},
+		{name: "listen", usage: "logpipe listen [--forward ADDR] [--otlp-grpc ADDR] [--otlp-http ADDR] [OPTIONS]", register: listenOpts.register},
+		{name: "ws", usage: "logpipe ws URL [OPTIONS]", minArgs: 1, maxArgs: 1},
+		{name: "lint", usage: "logpipe lint [--schema ecs] [--require FIELDS] [FILE...]", maxArgs: -1},
+		{name: "run", usage: "logpipe run [OPTIONS] -- COMMAND [ARGS...]", minArgs: 1, maxArgs: -1, passthrough: true},
+		{name: "trace", usage: "logpipe trace TRACE_ID [FILE...]", minArgs: 1, maxArgs: -1},
+		{name: "open", usage: "logpipe open SESSION [OPTIONS]", aliases: []string{"replay"}, minArgs: 1, maxArgs: 1},
+		{name: "patterns", usage: "logpipe patterns [--top N] [FILE...]", register: patternOpts.register, maxArgs: -1},
+		{name: "schema", usage: "logpipe schema [FILE...]", maxArgs: -1},
+		{name: "flatten", usage: "logpipe flatten [--format json|logfmt] [FILE...]", register: flattenOpts.register, maxArgs: -1},
+		{name: "slo", usage: "logpipe slo --objective OBJECTIVES [--by FIELD] [FILE...]", register: sloOpts.register, maxArgs: -1},
+	}
+	cmd, positional, err := parseCommandLine(flag.CommandLine, commands, os.Args[1:])
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		printHelp()
+		return
+	case errors.Is(err, errVersion):
+		printVersion()
+		return
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "logpipe: %v\nRun 'logpipe --help' for usage.\n", err)
+		os.Exit(2)
+	}
+	mode := cmd.name
+	if mode == defaultCommand {
+		mode = ""
+	}
+	var subURL string
+	if mode == "sub" || mode == "ws" {
+		subURL = positional[0]
+	}
+
+	// Load the config file and the selected profile, then let flags
+	// override them
+	config, err := loadConfig(*configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid config: %v\n", err)
+		os.Exit(1)
+	}
+	if *profileName != "" {
+		if err := applyProfile(flag.CommandLine, config.Profiles, *profileName, commands); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid profile: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if mode == "lint" {
+		lintOpts.schema = *schemaName
+		if lintOpts.schema == "" {
+			lintOpts.schema = "ecs"
+		}
+		lintOpts.require = *requireFields
+		os.Exit(runLint(lintOpts, positional, os.Stdout))
+	}
+
+	if *numericLevels != "" {
+		config.NumericLevels = *numericLevels
+	}
+	if *durationUnitFlag != "" {
+		config.DurationUnit = *durationUnitFlag
+	}
+	if *icons || *iconSetName != "" {
+		config.Icons = true
+	}
+	if *iconSetName != "" {
+		config.IconSet = *iconSetName
+	}
+	config.Redact = append(config.Redact, splitList(*redactFields)...)
+	config.Enrich = append(config.Enrich, splitList(*enrichSpecs)...)
+	config.Promote = append(config.Promote, splitList(*promoteKeys)...)
+	config.CorrelationRules = append(parseCorrelationFlag(*correlationFlag), config.CorrelationRules...)
+	config.RedactDetectors = append(config.RedactDetectors, splitList(*redactDetectorNames)...)
+	if err := config.apply(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid config: %v\n", err)
+		os.Exit(1)
+	}
+	if *memoryLimitFlag != "" {
+		limit, err := parseMemoryLimit(*memoryLimitFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --memory-limit: %v\n", err)
+			os.Exit(1)
+		}
+		memoryLimit = newMemoryBudget(limit)
+	}
+	if mode == "trace" {
+		os.Exit(runTrace(positional[0], positional[1:], os.Stdout))
+	}
+	if mode == "patterns" {
+		os.Exit(runPatterns(patternOpts, positional, os.Stdout))
+	}
+	if mode == "schema" {
+		os.Exit(runSchema(positional, os.Stdout))
+	}
+	if mode == "flatten" {
+		os.Exit(runFlatten(flattenOpts, positional, os.Stdout))
+	}
+	if mode == "slo" {
+		os.Exit(runSLO(sloOpts, positional, os.Stdout))
+	}
+	redact, err := newRedactor(config.Redact, config.RedactDetectors)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid redaction: %v\n", err)
+		os.Exit(1)
+	}
+	var anonymize *redactor
+	if *anonymizeFlag || *anonymizeKey != "" {
+		if anonymize, err = newAnonymizer(*anonymizeKey); err != nil {
+			fmt.Fprintf(os.Stderr, "Error setting up --anonymize: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *unwrapField != "" {
+		config.Unwrap = *unwrapField
+	}
+	if *unwrapKeep != "" {
+		config.UnwrapKeep = splitList(*unwrapKeep)
+	}
+	unwrap := newUnwrapper(config.Unwrap, config.UnwrapKeep)
+	debugParse = *debugParseFlag
+	if err := checkDensity(*density); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --density: %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkSchema(*schemaName); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --schema: %v\n", err)
+		os.Exit(1)
+	}
+	renderSchema = *schemaName
+	compactOutput = *density == "compact"
+	kvOutput = *kvFlag
+	verticalOutput = *verticalFlag
+	detailed := *density == "detailed"
+	showBytes = *bytesColumn || detailed
+	showProcess = *processFlag || detailed
+	showLogger = *loggerColumnFlag || detailed
+	showLatencyBars = *latencyBarFlag
+	if *outliersFlag {
+		latencyOutliers = newLatencyBaseline(*outlierWindow)
+	}
+	sqlFull = *sqlFullFlag
+	highlightValues = !*noHighlight
+	if *bodies {
+		showBodies = true
+		maxBodyBytes = *bodyLimit
+	}
+	if *geoipFile != "" {
+		config.GeoIP = *geoipFile
+	}
+	if *traceURLFlag != "" {
+		config.TraceURL = *traceURLFlag
+	}
+	if config.TraceURL != "" {
+		if err := checkTraceURL(config.TraceURL); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid trace URL: %v\n", err)
+			os.Exit(1)
+		}
+		traceURL = config.TraceURL
+	}
+	if *ipInfo || *rdns || config.GeoIP != "" || detailed {
+		ipEnrichment, err = newIPEnricher(config.GeoIP, *rdns)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening GeoIP database: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	for _, spec := range config.Enrich {
+		table, err := parseLookupSpec(spec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --enrich: %v\n", err)
+			os.Exit(1)
+		}
+		lookupTables = append(lookupTables, table)
+	}
+	promotedKeys = config.Promote
 
 	// Compile regex patterns if provided
 	var levelRegex, messageRegex, noLevelRegex, noMessageRegex *regexp.Regexp
-	var err error
 	if *levelFilter != "" {
 		levelRegex, err = regexp.Compile(*levelFilter)
 		if err != nil {
@@ -142,136 +487,1056 @@ func main() {
 		}
 	}
 
-	// Check if stdin has data
-	stat, err := os.Stdin.Stat()
+	quiet, err := newQuietFilter(splitList(*quietPaths), *quietMatch)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid quiet filter: %v\n", err)
+		os.Exit(1)
+	}
+
+	routes, err := newOutputRoutes(splitList(*routeRules))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid route: %v\n", err)
+		os.Exit(1)
+	}
+
+	timeFilter, err := newTimeRange(*sinceFlag, *untilFlag, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid time range: %v\n", err)
+		os.Exit(1)
+	}
+
+	limit, err := parseBufferLimit(*bufferFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --buffer: %v\n", err)
+		os.Exit(1)
+	}
+	slice, err := newOutputSlice(*skipEntries, *headEntries, *tailEntries, limit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid slice: %v\n", err)
+		os.Exit(1)
+	}
+
+	var diff *entryDiff
+	if *diffFields != "" {
+		diff = newEntryDiff(splitList(*diffFields))
+	}
+
+	var contexts *contextTracker
+	if *contextHeader {
+		contexts = newContextTracker()
+	}
+	var separators *timeSeparator
+	if *separatorsFlag != "" {
+		separators, err = newTimeSeparator(*separatorsFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --separators: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	var gaps *gapMarker
+	if *gapFlag > 0 {
+		gaps = newGapMarker(*gapFlag)
+	}
+	var pairs *requestPairer
+	if *pairRequests {
+		pairs = newRequestPairer()
+	}
+	var restarts *restartDetector
+	if *restartsFlag {
+		restarts = newRestartDetector()
+	}
+	loggers := newLoggerFilter(splitList(*loggerFlag), splitList(*noLoggerFlag))
+
+	var where *expr
+	if *whereFilter != "" {
+		where, err = compileExpr(*whereFilter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid where expression: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var jq *jqFilter
+	if *jqQuery != "" {
+		jq, err = newJQFilter(*jqQuery)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --jq: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Set up a record decoder if binary input was requested
+	decoder, err := newRecordDecoder(*protoSchemaFile, *protoMessage, *avroSchemaFile)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error checking stdin: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Invalid schema: %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkSanitizeMode(*sanitizeMode); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --sanitize: %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkFileOrder(*fileOrder); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --order: %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkOutputFormat(*outputFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --output-format: %v\n", err)
+		os.Exit(1)
+	}
+	normalizedOutput := *outputFormat == "jsonl-normalized"
+	if *streamMode != "lines" && *streamMode != "concat" {
+		fmt.Fprintf(os.Stderr, "Invalid stream mode: %s (expected lines or concat)\n", *streamMode)
+		os.Exit(1)
+	}
+	if decoder != nil && *lengthPrefix != "uint32" && *lengthPrefix != "varint" {
+		fmt.Fprintf(os.Stderr, "Invalid length prefix: %s (expected uint32 or varint)\n", *lengthPrefix)
 		os.Exit(1)
 	}
 
 	// If no pipe input and no args, show help
-	if (stat.Mode()&os.ModeCharDevice) != 0 && len(os.Args) == 1 {
+	if isTerminal(os.Stdin) && len(os.Args) == 1 {
 		printHelp()
 		return
 	}
 
-	scanner := bufio.NewScanner(os.Stdin)
+	// Broadcast entries to browser clients if requested
+	var hub *wsHub
+	if *serveWS != "" {
+		hub = newWSHub(splitList(*serveWSOrigins))
+		ln, err := hub.listenAndServe(*serveWS)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error serving WebSocket: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Serving live tail on http://%s/\n", ln.Addr())
+	}
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	var status *statusLine
+	if *statusFlag {
+		status = newStatusLine()
+	}
+	if status != nil {
+		status.start()
+	}
+
+	var entryTimeline *timeline
+	if *timelineFlag {
+		entryTimeline = newTimeline()
+	}
+
+	stats := newParseStats()
+	// Stop once stdout is closed (e.g. `logpipe | head`): with SIGPIPE
+	// ignored, writes fail with EPIPE instead of killing logpipe mid-entry
+	signal.Ignore(syscall.SIGPIPE)
+	stdout := closingWriter{w: os.Stdout, closed: func() { os.Exit(0) }}
+
+	// Watch error rates for --escalate
+	var escalate *escalation
+	var alerts *webhookNotifier
+	if *escalateSpec != "" {
+		if escalate, err = parseEscalation(*escalateSpec); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --escalate: %v\n", err)
+			os.Exit(1)
+		}
+		if *escalateWebhook != "" {
+			alerts = &webhookNotifier{url: *escalateWebhook}
+		}
+	} else if *escalateWebhook != "" {
+		fmt.Fprintln(os.Stderr, "--escalate-webhook needs --escalate")
+		os.Exit(1)
+	}
+
+	// Record the raw input for --capture
+	var capture *sessionWriter
+	if *captureFile != "" {
+		capture, err = newSessionWriter(*captureFile, os.Args[1:])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating capture: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// finish prints the summaries due once the input ends
+	finish := func() {
+		if status != nil {
+			status.stop()
+		}
+		if slice != nil {
+			slice.flush(stdout)
+		}
+		if entryTimeline != nil {
+			entryTimeline.write(stdout)
+		}
+		goTests.summary(stdout)
+		if quiet != nil {
+			quiet.flush(stdout)
+		}
+		if debugParse {
+			stats.report(os.Stderr)
+		}
+		memoryLimit.report(os.Stderr)
+		if err := capture.close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing capture: %v\n", err)
+		}
+		alerts.wait()
+	}
+	defer finish()
+	var strict *strictChecker
+	if *strictFlag {
+		strict = newStrictChecker(splitList(*requireFields), *maxErrors, os.Stderr)
+	}
+	// failStrict exits once the --strict error budget is exhausted
+	failStrict := func() {
+		if debugParse {
+			stats.report(os.Stderr)
+		}
+		capture.close()
+		os.Exit(1)
+	}
+
+	// entryOutput returns where to render the next entry: stdout, or a
+	// buffer kept for --tail
+	entryOutput := func() (io.Writer, *bytes.Buffer) {
+		if slice != nil && slice.tail > 0 {
+			buffer := &bytes.Buffer{}
+			return buffer, buffer
+		}
+		return stdout, nil
+	}
+
+	var processing sync.Mutex
+	// records numbers the entries shown with --vertical
+	records := 0
+	var idle *idleWatch
+	if *idleAfter > 0 {
+		idle = newIdleWatch(*idleAfter)
+	}
+	renderLine := func(line, label, stream string) {
+		// Windows programs and files end lines with CRLF
+		line = strings.TrimSuffix(line, "\r")
+		line = sanitizeLine(line, *sanitizeMode)
+
+		if unwrap != nil {
+			var extras string
+			line, extras = unwrap.unwrapLine(line)
+			label = joinLabel(label, extras)
+		}
 
-		var logEntry LogEntry
-		if err := json.Unmarshal([]byte(line), &logEntry); err != nil {
-			// If not valid JSON, print the line truncated to fit terminal
-			if len(line) > 120 {
-				fmt.Printf("%s...\n", line[:120])
+		logEntry, ok := parseLine(line)
+		if !ok {
+			kind, detail := "unparseable", ""
+			if debugParse || strict != nil {
+				kind, detail = diagnoseLine(line)
+			}
+			stats.record(kind)
+			if timeFilter != nil && !timeFilter.contains("") {
+				return
+			}
+			if quiet != nil && quiet.quiet(line, nil) {
+				quiet.hide(stdout)
+				return
+			}
+
+			if slice != nil && !slice.admit() {
+				return
+			}
+
+			// If not a known format, print the line truncated to fit terminal,
+			// with any timestamp and level word picked out
+			out, rendered := entryOutput()
+			if normalizedOutput {
+				fmt.Fprintln(out, unparsedLine(line, label, stream))
 			} else {
-				fmt.Println(line)
+				fmt.Fprint(out, streamMarker(stream))
+				if label != "" {
+					fmt.Fprintf(out, "%s ", formatLabel(label))
+				}
+				fmt.Fprintln(out, highlightPlainLine(abbreviate(line, 120)))
+			}
+			if debugParse && !normalizedOutput {
+				fmt.Fprintf(out, "  %s\n", parseErrorColor.Sprintf("parse error: %s", detail))
 			}
-			continue
+			if rendered != nil {
+				slice.keep(rendered.Bytes())
+			}
+			if hub != nil {
+				hub.broadcast(line)
+			}
+			if strict != nil && !strict.violation(stats.lines, "parse error: %s", detail) {
+				failStrict()
+			}
+			return
+		}
+
+		stats.record("")
+		if status != nil {
+			status.record(logEntry)
+		}
+		if escalate != nil {
+			if b := escalate.record(logEntry); b != nil {
+				fmt.Fprintln(stdout, b.banner())
+				if alerts != nil {
+					alerts.notify(b)
+				}
+			}
+		}
+		if strict != nil {
+			if missing := strict.missingFields(line); len(missing) > 0 {
+				if !strict.violation(stats.lines, "missing required fields: %s", strings.Join(missing, ", ")) {
+					failStrict()
+				}
+			}
+		}
+
+		if pairs != nil {
+			pairs.pair(&logEntry)
 		}
 
 		// Apply filters
-		if levelRegex != nil && !levelRegex.MatchString("^"+logEntry.Level+"$") {
-			continue
+		if timeFilter != nil && !timeFilter.contains(logEntry.Timestamp) {
+			return
+		}
+		if levelRegex != nil && !levelMatches(levelRegex, logEntry.Level) {
+			return
 		}
 		if messageRegex != nil && !messageRegex.MatchString("^"+logEntry.Message+"$") {
-			continue
+			return
 		}
-		if noLevelRegex != nil && noLevelRegex.MatchString("^"+logEntry.Level+"$") {
-			continue
+		if noLevelRegex != nil && levelMatches(noLevelRegex, logEntry.Level) {
+			return
 		}
 		if noMessageRegex != nil && noMessageRegex.MatchString("^"+logEntry.Message+"$") {
-			continue
+			return
+		}
+		if loggers != nil && !loggers.keep(logEntry.Log.Logger) {
+			return
+		}
+		if where != nil && !truthy(where.evaluate(entryFields(line, logEntry))) {
+			return
+		}
+		if quiet != nil && quiet.quiet(line, &logEntry) {
+			quiet.hide(stdout)
+			return
+		}
+		if slice != nil && !slice.admit() {
+			return
+		}
+		if len(routes) > 0 {
+			routeLine(routes, line, logEntry.Level)
+		}
+		if entryTimeline != nil {
+			entryTimeline.record(logEntry)
 		}
 
-		printPrettyLog(logEntry)
+		logEntry.InputLabel = label
+		if len(lookupTables) > 0 {
+			logEntry.Lookups = entryLookups(entryFields(line, logEntry))
+		}
+		if len(promotedKeys) > 0 {
+			logEntry.Promoted = promotedLabels(entryFields(line, logEntry))
+		}
+		if len(styleRules) > 0 {
+			logEntry.RowStyle = matchRowStyle(entryFields(line, logEntry))
+		}
+		if stream != "" {
+			logEntry.Stream = stream
+		}
+		out, buffered := entryOutput()
+		if normalizedOutput {
+			fmt.Fprintln(out, normalizedLine(line, logEntry, label))
+			if buffered != nil {
+				slice.keep(buffered.Bytes())
+			}
+			return
+		}
+		if gaps != nil {
+			if marker := gaps.check(logEntry.Timestamp); marker != "" {
+				fmt.Fprintln(out, marker)
+			}
+		}
+		if separators != nil {
+			if separator := separators.check(logEntry.Timestamp); separator != "" {
+				fmt.Fprintln(out, separator)
+			}
+		}
+		if restarts != nil {
+			if rule := restarts.check(label, logEntry, entryFields(line, logEntry)); rule != "" {
+				fmt.Fprintln(out, rule)
+			}
+		}
+		if contexts != nil {
+			if header := contexts.header(label, entryFields(line, logEntry)); header != "" {
+				fmt.Fprintln(out, header)
+			}
+		}
+		render := writePrettyLog
+		if kvOutput {
+			render = func(w io.Writer, log LogEntry) { fmt.Fprintln(w, kvLine(line, log)) }
+		}
+		if verticalOutput {
+			records++
+			render = func(w io.Writer, log LogEntry) { fmt.Fprint(w, verticalBlock(line, log, records)) }
+		}
+		if hub == nil {
+			render(out, logEntry)
+		} else {
+			var rendered bytes.Buffer
+			render(&rendered, logEntry)
+			out.Write(rendered.Bytes())
+			if *serveWSRaw {
+				hub.broadcast(line)
+			} else {
+				hub.broadcast(strings.TrimSuffix(rendered.String(), "\n"))
+			}
+		}
+		if *curlFlag && logEntry.HTTP.Request.Method != "" {
+			if cmd, ok := curlCommand(entryFields(line, logEntry)); ok {
+				writeBlock(out, "curl", cmd)
+			}
+		}
+		if diff != nil {
+			writeDiff(out, diff.next(entryFields(line, logEntry)))
+		}
+		if buffered != nil {
+			slice.keep(buffered.Bytes())
+		}
+	}
+
+	// processStreamLine renders a line read from the given output stream
+	// ("stdout", "stderr", or "" when unknown)
+	processStreamLine := func(line, label, stream string) {
+		processing.Lock()
+		defer processing.Unlock()
+		if idle != nil {
+			idle.touch()
+		}
+		if status != nil {
+			status.pause()
+		}
+		renderLine(line, label, stream)
+		if status != nil {
+			status.resume()
+		}
+		if slice != nil && slice.done() {
+			finish()
+			os.Exit(0)
+		}
+	}
+
+	// Render from a queue that drops low-severity entries when the output
+	// falls behind
+	var shed *loadShedder
+	if *shedLevel != "" {
+		shed, err = newLoadShedder(*shedLevel, processStreamLine, func(dropped int) {
+			processing.Lock()
+			defer processing.Unlock()
+			fmt.Fprintln(stdout, droppedNotice(dropped))
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --shed: %v\n", err)
+			os.Exit(1)
+		}
+		processStreamLine = shed.add
+	}
+	defer shed.close()
+	// Run --jq on the lines as read, so captures keep the original entries
+	if jq != nil {
+		render := processStreamLine
+		processStreamLine = func(line, label, stream string) {
+			lines, err := jq.apply(line)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "jq: error: %v\n", err)
+			}
+			for _, line := range lines {
+				render(line, label, stream)
+			}
+		}
+	}
+	// Mask sensitive values and pseudonymize lines before they are
+	// displayed, forwarded or captured
+	if redact != nil || anonymize != nil || capture != nil {
+		processStreamLine = protectLines(processStreamLine, redact, anonymize, capture)
+	}
+	processLine := func(line, label string) {
+		processStreamLine(line, label, "")
+	}
+
+	// Mark silences, so a quiet source can be told apart from a broken pipe
+	if idle != nil {
+		go func() {
+			ticker := time.NewTicker(min(idle.after, time.Second))
+			defer ticker.Stop()
+			for range ticker.C {
+				processing.Lock()
+				if marker := idle.check(); marker != "" {
+					if status != nil {
+						status.pause()
+					}
+					fmt.Fprintln(stdout, labelColor.Sprint(marker))
+					if status != nil {
+						status.resume()
+					}
+				}
+				processing.Unlock()
+			}
+		}()
+	}
+
+	// Spawn a command and render its output, exiting with its exit code
+	if mode == "run" {
+		code, err := runCommand(positional, func(line, stream string) {
+			processStreamLine(line, "", stream)
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error running command: %v\n", err)
+		}
+		shed.close()
+		finish()
+		os.Exit(code)
+	}
+
+	// Replay a session bundle recorded with --capture
+	if mode == "open" {
+		session, err := openSession(positional[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening session: %v\n", err)
+			os.Exit(1)
+		}
+		defer session.close()
+		fmt.Fprintln(stdout, labelColor.Sprint(session.header.describe()))
+		if err := session.replay(processStreamLine); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading session: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if mode != "" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		switch mode {
+		case "kafka":
+			err = consumeKafka(ctx, kafkaOpts, decoder, processLine)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error consuming from Kafka: %v\n", err)
+			}
+		case "sub":
+			err = subscribe(ctx, subURL, decoder, processLine)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error subscribing: %v\n", err)
+			}
+		case "ws":
+			err = consumeWebSocket(ctx, subURL, decoder, processLine)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading WebSocket: %v\n", err)
+			}
+		case "loki":
+			err = consumeLoki(ctx, lokiOpts, timeFilter, processLine)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error querying Loki: %v\n", err)
+			}
+		case "es":
+			err = consumeElasticsearch(ctx, esOpts, timeFilter, processLine)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error searching Elasticsearch: %v\n", err)
+			}
+		case "cloudwatch":
+			err = consumeCloudWatch(ctx, cloudwatchOpts, timeFilter, processLine)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading CloudWatch Logs: %v\n", err)
+			}
+		case "gcp":
+			err = consumeGCP(ctx, gcpOpts, timeFilter, processLine)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading Cloud Logging: %v\n", err)
+			}
+		case "listen":
+			err = consumeListen(ctx, listenOpts, processLine)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error receiving logs: %v\n", err)
+			}
+		}
+		if err != nil {
+			os.Exit(1)
+		}
+		return
 	}
 
+	// Print the end-of-input summaries on Ctrl-C too, between entries
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupts
+		processing.Lock()
+		finish()
+		os.Exit(130)
+	}()
+
+	// Separate stdout and stderr inputs, interleaved into one view
+	if *stderrFile != "" {
+		err := readStreams(*stdoutFile, *stderrFile, func(line, stream string) {
+			processStreamLine(line, "", stream)
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading streams: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Follow files, labeling lines with their file name
+	if *followGlob != "" {
+		if err := followFiles(context.Background(), splitList(*followGlob), processLine); err != nil {
+			fmt.Fprintf(os.Stderr, "Error following files: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Files and directories named on the command line
+	if len(positional) > 0 {
+		files, err := listInputFiles(positional, *recursive, *fileOrder)
+		if err == nil {
+			err = readFiles(files, *fileOrder, processLine)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading files: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Binary input: length-prefixed records decoded with a schema
+	if decoder != nil {
+		if err := readRecords(os.Stdin, *lengthPrefix, decoder, processLine); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading from stdin: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// JSON objects written back to back, without newlines between them
+	if *streamMode == "concat" {
+		if err := readConcatenated(os.Stdin, processLine); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading from stdin: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	joiner := newJSONJoiner(func(line string) { processLine(line, "") })
+
+	for scanner.Scan() {
+		joiner.add(scanner.Text())
+	}
+	joiner.flush()
+
 	if err := scanner.Err(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading from stdin: %v\n", err)
 		os.Exit(1)
 	}
 }
 
+// levelMatches applies a level filter to both the level as written and its
+// canonical name, so "error" also matches "ERR" or 50.
+func levelMatches(re *regexp.Regexp, level string) bool {
+	return re.MatchString("^"+level+"$") || re.MatchString("^"+normalizeLevel(level)+"$")
+}
+
+// UnmarshalJSON decodes a log line, accepting numeric levels (pino, syslog,
+// OTel SeverityNumber) as well as level names.
+func (l *LogEntry) UnmarshalJSON(data []byte) error {
+	line := newLineFields(data)
+	// Durations are read before aliases scale them, so --duration-unit
+	// only applies to values as logged
+	duration, hasDuration := parseEntryDuration(line)
+	if normalized := normalizeEntry(line); normalized != nil {
+		data = normalized
+	}
+	type plainEntry LogEntry
+	aux := struct {
+		*plainEntry
+		Level json.RawMessage `json:"log.level"`
+	}{plainEntry: (*plainEntry)(l)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		// Retry with mistyped fields (e.g. a status code as a string)
+		// converted instead of giving up on the whole entry
+		var typeErr *json.UnmarshalTypeError
+		coerced, ok := coerceFields(data)
+		if !errors.As(err, &typeErr) || !ok {
+			return err
+		}
+		*l = LogEntry{}
+		if err := json.Unmarshal(coerced, &aux); err != nil {
+			return err
+		}
+	}
+	l.fields = line
+	l.GRPC = parseGRPC(line)
+	l.SQL = parseSQL(line)
+	if hasDuration {
+		l.Event.Duration = duration.Nanoseconds()
+		if l.GRPC != nil {
+			l.GRPC.DurationMs = float64(duration) / 1e6
+		}
+		if l.SQL != nil {
+			l.SQL.DurationMs = float64(duration) / 1e6
+		}
+	}
+	l.GoTest = parseGoTest(line)
+	l.Audit = parseAudit(line)
+	if l.Audit != nil && l.Timestamp == "" {
+		l.Timestamp = l.Audit.Time
+	}
+	l.Security = parseSecurity(line)
+	l.KubeEvent = parseKubeEvent(line)
+	if l.KubeEvent != nil {
+		if l.Timestamp == "" {
+			l.Timestamp = l.KubeEvent.Time
+		}
+		if l.Message == "" {
+			l.Message = l.KubeEvent.Message
+		}
+	}
+
+	l.Terraform = parseTerraform(line)
+	if l.Terraform != nil {
+		if l.Timestamp == "" {
+			l.Timestamp = l.Terraform.Time
+		}
+		if l.Message == "" {
+			l.Message = l.Terraform.Message
+		}
+	}
+	l.BuildKit = parseBuildKit(line)
+
+	l.Level = ""
+	if len(aux.Level) == 0 || string(aux.Level) == "null" {
+		l.Level = impliedLevel(l)
+		return nil
+	}
+	if aux.Level[0] == '"' {
+		return json.Unmarshal(aux.Level, &l.Level)
+	}
+	var number json.Number
+	if err := json.Unmarshal(aux.Level, &number); err != nil {
+		return fmt.Errorf("log.level: expected a string or number, got %s", aux.Level)
+	}
+	l.Level = number.String()
+	return nil
+}
+
+// impliedLevel is the level of an entry without log.level whose format
+// has its own notion of one.
+func impliedLevel(l *LogEntry) string {
+	switch {
+	case l.KubeEvent != nil:
+		return kubeEventLevel(l.KubeEvent.Type)
+	case l.Terraform != nil:
+		return l.Terraform.Level
+	}
+	return ""
+}
+
+// lineHandler receives raw input lines along with an optional label
+// describing where they came from.
+type lineHandler func(line, label string)
+
+// parseLine decodes a single input line, trying JSON first and then the
+// plain-text formats LogPipe knows how to recognize.
+func parseLine(line string) (LogEntry, bool) {
+	var logEntry LogEntry
+	if err := json.Unmarshal([]byte(line), &logEntry); err == nil {
+		return unwrapEmbeddedJSON(line, logEntry, 0), true
+	}
+	for _, parser := range lineParsers {
+		if logEntry, ok := parser.parse(line); ok {
+			return logEntry, true
+		}
+	}
+	return LogEntry{}, false
+}
+
+// lineParsers are the formats parseLine tries, in order, for lines that
+// are not JSON. They are set in init, as the Docker parser parses the
+// lines it unwraps with parseLine.
+var lineParsers []lineParser
+
+type lineParser struct {
+	name  string
+	parse func(string) (LogEntry, bool)
+}
+
+func init() {
+	lineParsers = []lineParser{
+		{"a GitHub Actions line", parseActionsLine},
+		{"a Docker json-file line", parseDockerLog},
+		{"syslog", parseSyslog},
+		{"an access log", parseAccessLog},
+		{"logfmt", parseKeyValueLine},
+		{"a level-prefixed line", parseLevelPrefixLine},
+	}
+}
+
 func printPrettyLog(log LogEntry) {
+	writePrettyLog(os.Stdout, log)
+}
+
+// writePrettyLog renders a single entry to w, in one write.
+func writePrettyLog(w io.Writer, log LogEntry) {
+	b := renderBuffers.Get().(*bytes.Buffer)
+	b.Reset()
+	renderPrettyLog(b, log)
+	w.Write(b.Bytes())
+	if b.Cap() <= maxPooledBuffer {
+		renderBuffers.Put(b)
+	}
+}
+
+// renderPrettyLog renders a single entry into w.
+func renderPrettyLog(w *bytes.Buffer, log LogEntry) {
 	// Parse timestamp
 	timestamp, err := time.Parse(time.RFC3339, log.Timestamp)
 	if err != nil {
 		timestamp = time.Now()
 	}
 
-	// Color setup
-	timestampColor := color.New(color.FgCyan)
-	levelColor := getLevelColor(log.Level)
-	methodColor := color.New(color.FgMagenta, color.Bold)
-	statusColor := getStatusColor(log.HTTP.Response.StatusCode)
-	durationColor := color.New(color.FgYellow)
-	pathColor := color.New(color.FgGreen)
-	messageColor := color.New(color.FgWhite)
+	// Style rules apply to everything rendered for the entry
+	if log.RowStyle != "" {
+		var rendered bytes.Buffer
+		style := log.RowStyle
+		log.RowStyle = ""
+		renderPrettyLog(&rendered, log)
+		w.WriteString(styleRow(rendered.String(), style))
+		return
+	}
+
+	// go test -json events have their own compact view
+	if log.GoTest != nil {
+		goTests.write(w, log.GoTest)
+		return
+	}
+
+	// So do BuildKit builds, and the ends of runner log groups are hidden
+	if log.BuildKit != nil {
+		buildKit.write(w, log.BuildKit)
+		return
+	}
+	if log.Actions != nil && log.Actions.Command == "endgroup" {
+		return
+	}
+
+	fmt.Fprint(w, levelIconPrefix(log.Level))
+	fmt.Fprint(w, streamMarker(log.Stream))
+
+	if log.InputLabel != "" {
+		fmt.Fprintf(w, "%s ", formatLabel(log.InputLabel))
+	}
+	if showProcess {
+		fmt.Fprint(w, processColumn(log))
+	}
 
-	// Check if this is an HTTP access log
-	if log.Category == "http" && log.HTTP.Request.Method != "" {
+	// Security events stand out from the entries around them
+	if log.Security != nil {
+		writeTimeLevel(w, timestamp, log.Level)
+		w.WriteString(" " + securityHeadline(log.Security))
+		if log.Message != "" && log.Message != log.Security.Rule {
+			w.WriteByte(' ')
+			messagePaint.write(w, log.Message)
+		}
+		fmt.Fprintln(w, securityDetails(log.Security)+promotedSuffix(log)+lookupSuffix(log)+traceSuffix(log))
+	} else if log.Category == "http" && log.HTTP.Request.Method != "" {
 		// Format HTTP access log
-		userAgent := log.UserAgent.Original
-		if len(userAgent) > 50 {
-			userAgent = userAgent[:50]
-		}
-		fmt.Printf("%s [%s] %s %s %s %s %s %s\n",
-			timestampColor.Sprintf(timestamp.Format("15:04:05.000")),
-			levelColor.Sprintf("%-4s", log.Level[:min(4, len(log.Level))]),
-			methodColor.Sprintf("%-4s", log.HTTP.Request.Method),
-			statusColor.Sprintf("%d", log.HTTP.Response.StatusCode),
-			pathColor.Sprintf("%s", log.URL.Path),
-			durationColor.Sprintf("%dms", log.Event.Duration/1000000), // Convert to milliseconds
-			color.New(color.FgBlue).Sprintf("ua=%s", userAgent),
-			messageColor.Sprintf("%s", log.Message),
-		)
+		writeTimeLevel(w, timestamp, log.Level)
+		w.WriteByte(' ')
+		methodPaint.write(w, padWidth(log.HTTP.Request.Method, 4))
+		w.WriteByte(' ')
+		statusPaint(log.HTTP.Response.StatusCode).write(w, strconv.Itoa(log.HTTP.Response.StatusCode))
+		w.WriteByte(' ')
+		pathPaint.write(w, log.URL.Path)
+		w.WriteByte(' ')
+		durationPaint.write(w, strconv.FormatInt(log.Event.Duration/1000000, 10)+"ms") // Convert to milliseconds
+		if showLatencyBars {
+			w.WriteString(" " + latencyBar("http", float64(log.Event.Duration)/1e6))
+		}
+		if latencyOutliers != nil {
+			w.WriteString(outlierMarker(log, timestamp))
+		}
+		if showBytes {
+			w.WriteString(" " + formatResponseSize(log))
+		}
+		if !compactOutput {
+			w.WriteByte(' ')
+			keyValuePaint.write(w, "ua="+truncateWidth(log.UserAgent.Original, 50))
+		}
+		w.WriteByte(' ')
+		messagePaint.write(w, log.Message)
+
+		// Dimmed suffix describing where the request came from
+		if ipEnrichment != nil && log.Source.IP != "" {
+			suffix := "ip=" + log.Source.IP
+			if info := ipEnrichment.describe(log.Source.IP); info != "" {
+				suffix += " (" + info + ")"
+			}
+			w.WriteByte(' ')
+			labelPaint.write(w, suffix)
+		}
+		fmt.Fprintln(w, pairSuffix(log)+promotedSuffix(log)+lookupSuffix(log)+traceSuffix(log))
+	} else if log.GRPC != nil {
+		// Format gRPC call like an HTTP access log
+		writeTimeLevel(w, timestamp, log.Level)
+		w.WriteByte(' ')
+		methodPaint.write(w, "gRPC")
+		w.WriteByte(' ')
+		grpcCodePaint(log.GRPC.Code).write(w, log.GRPC.Code)
+		w.WriteByte(' ')
+		pathPaint.write(w, log.GRPC.Method)
+		if log.GRPC.DurationMs >= 0 {
+			w.WriteByte(' ')
+			durationPaint.write(w, strconv.FormatFloat(log.GRPC.DurationMs, 'g', -1, 64)+"ms")
+			if showLatencyBars {
+				w.WriteString(" " + latencyBar("grpc", log.GRPC.DurationMs))
+			}
+		}
+		w.WriteByte(' ')
+		messagePaint.write(w, log.Message)
+		fmt.Fprintln(w, promotedSuffix(log)+lookupSuffix(log)+traceSuffix(log))
+	} else if log.Terraform != nil {
+		// Format Terraform UI message with the symbol of its change
+		writeTimeLevel(w, timestamp, log.Level)
+		w.WriteString(" " + terraformLine(log.Terraform, log.Message))
+		fmt.Fprintln(w, promotedSuffix(log)+lookupSuffix(log)+traceSuffix(log))
+	} else if log.Actions != nil {
+		// Format GitHub Actions runner command
+		writeTimeLevel(w, timestamp, log.Level)
+		w.WriteString(" " + actionsLine(log.Actions, log.Message))
+		fmt.Fprintln(w, promotedSuffix(log)+lookupSuffix(log)+traceSuffix(log))
+	} else if log.KubeEvent != nil {
+		// Format Kubernetes event compactly, like kubectl get events
+		writeTimeLevel(w, timestamp, log.Level)
+		w.WriteString(" " + kubeEventLine(log.KubeEvent, log.Message))
+		fmt.Fprintln(w, promotedSuffix(log)+lookupSuffix(log)+traceSuffix(log))
+	} else if log.Audit != nil {
+		// Format audit event as who did what to what
+		writeTimeLevel(w, timestamp, log.Level)
+		w.WriteString(" " + auditSentence(log.Audit))
+		if log.Message != "" && log.Message != log.Audit.Action {
+			w.WriteByte(' ')
+			messagePaint.write(w, log.Message)
+		}
+		fmt.Fprintln(w, promotedSuffix(log)+lookupSuffix(log)+traceSuffix(log))
+	} else if log.SQL != nil {
+		// Format database query with its duration and row count
+		writeTimeLevel(w, timestamp, log.Level)
+		w.WriteByte(' ')
+		methodPaint.write(w, "SQL")
+		w.WriteByte(' ')
+		if log.SQL.DurationMs >= 0 {
+			sqlDurationPaint(log.SQL.DurationMs).write(w, strconv.FormatFloat(log.SQL.DurationMs, 'g', -1, 64)+"ms")
+			w.WriteByte(' ')
+			if showLatencyBars {
+				w.WriteString(latencyBar("sql", log.SQL.DurationMs) + " ")
+			}
+		}
+		if log.SQL.Rows >= 0 {
+			durationPaint.write(w, strconv.FormatInt(log.SQL.Rows, 10)+" rows")
+			w.WriteByte(' ')
+		}
+		w.WriteString(formatSQL(log.SQL.Statement))
+		if log.Message != "" {
+			w.WriteByte(' ')
+			messagePaint.write(w, log.Message)
+		}
+		fmt.Fprintln(w, promotedSuffix(log)+lookupSuffix(log)+traceSuffix(log))
 	} else {
 		// Format general log entry
-		fmt.Printf("%s [%s] %s",
-			timestampColor.Sprintf(timestamp.Format("15:04:05.000")),
-			levelColor.Sprintf("%-4s", log.Level[:min(4, len(log.Level))]),
-			messageColor.Sprintf("%s", log.Message),
-		)
+		writeTimeLevel(w, timestamp, log.Level)
+		w.WriteByte(' ')
+
+		// Syslog lines carry their origin in front of the message
+		if log.Log.Syslog != nil {
+			keyValuePaint.write(w, syslogOrigin(log))
+			w.WriteByte(' ')
+		}
+
+		if showLogger {
+			w.WriteString(loggerColumn(log))
+		}
+		highlightMessage(w, log.Message, messagePaint)
+		keyValues := log.KeyValues
+		if compactOutput {
+			keyValues = nil
+		}
+		for _, kv := range keyValues {
+			value := kv.Value
+			if value == "" || strings.ContainsAny(value, " \"=") {
+				value = strconv.Quote(value)
+			}
+			w.WriteByte(' ')
+			keyValuePaint.write(w, kv.Key+"="+value)
+		}
+
+		if log.Log.Syslog != nil && !compactOutput {
+			for _, id := range sortedKeys(log.Log.Syslog.StructuredData) {
+				params := log.Log.Syslog.StructuredData[id]
+				for _, name := range sortedKeys(params) {
+					w.WriteByte(' ')
+					keyValuePaint.write(w, id+"."+name+"="+params[name])
+				}
+			}
+		}
 
 		// Add error information if present
 		if log.Error != nil {
-			errorColor := color.New(color.FgRed, color.Bold)
-			fmt.Printf(" %s", errorColor.Sprintf("error=%v", log.Error))
+			w.WriteByte(' ')
+			errorPaint.write(w, fmt.Sprintf("error=%v", log.Error))
 		}
 
-		fmt.Println()
+		fmt.Fprintln(w, promotedSuffix(log)+lookupSuffix(log)+traceSuffix(log))
+	}
+
+	if log.Embedded != nil && !compactOutput {
+		writeBlock(w, "embedded", formatEmbedded(log.Embedded))
+	}
+	if showBodies {
+		writeBodies(w, log)
 	}
 }
 
+// sortedKeys returns the keys of m in a stable order for display.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func getLevelColor(level string) *color.Color {
-	switch strings.ToLower(strings.TrimSpace(level)) {
-	case "error":
-		return color.New(color.FgRed, color.Bold)
-	case "warn", "warning":
-		return color.New(color.FgYellow, color.Bold)
-	case "info":
-		return color.New(color.FgBlue)
-	case "debug":
-		return color.New(color.FgWhite)
-	default:
-		return color.New(color.FgWhite)
+	if style, ok := lookupLevelStyle(level); ok && style.color != nil {
+		return style.color
+	}
+	if c, ok := levelColors[normalizeLevel(level)]; ok {
+		return c
 	}
+	return defaultLevelColor
 }
 
 func getStatusColor(status int) *color.Color {
 	switch {
 	case status >= 200 && status < 300:
-		return color.New(color.FgGreen)
+		return successStatusColor
 	case status >= 300 && status < 400:
-		return color.New(color.FgYellow)
+		return redirectStatusColor
 	case status >= 400 && status < 500:
-		return color.New(color.FgRed)
+		return clientErrorStatusColor
 	case status >= 500:
-		return color.New(color.FgRed, color.Bold)
+		return serverErrorStatusColor
 	default:
-		return color.New(color.FgWhite)
+		return otherStatusColor
 	}
 }
 
@@ -279,7 +1544,23 @@ func printHelp() {
 	fmt.Println("LogPipe - Pretty-print structured JSON logs")
 	fmt.Println()
 	fmt.Println("USAGE:")
-	fmt.Println("  logpipe [OPTIONS]")
+	fmt.Println("  logpipe [OPTIONS] [FILE|DIR|URL...]")
+	fmt.Println("  logpipe kafka --brokers HOSTS --topic TOPIC [--group GROUP] [OPTIONS]")
+	fmt.Println("  logpipe sub URL [OPTIONS]")
+	fmt.Println("  logpipe loki --addr URL --query LOGQL [--since TIME] [--follow] [OPTIONS]")
+	fmt.Println("  logpipe es --index PATTERN [--query QUERY] [--since TIME] [--follow] [OPTIONS]")
+	fmt.Println("  logpipe cloudwatch --log-group GROUP [--filter PATTERN] [--since TIME] [--follow] [OPTIONS]")
+	fmt.Println("  logpipe gcp --project PROJECT [--filter QUERY] [--since TIME] [--follow] [OPTIONS]")
+	fmt.Println("  logpipe listen [--forward ADDR] [--otlp-grpc ADDR] [--otlp-http ADDR] [OPTIONS]")
+	fmt.Println("  logpipe ws URL [OPTIONS]")
+	fmt.Println("  logpipe lint [--schema ecs] [--require FIELDS] [FILE...]")
+	fmt.Println("  logpipe run [OPTIONS] -- COMMAND [ARGS...]")
+	fmt.Println("  logpipe trace TRACE_ID [FILE...]")
+	fmt.Println("  logpipe open SESSION [OPTIONS]         (or: logpipe replay)")
+	fmt.Println("  logpipe patterns [--top N] [FILE...]")
+	fmt.Println("  logpipe schema [FILE...]")
+	fmt.Println("  logpipe flatten [--format json|logfmt] [FILE...]")
+	fmt.Println("  logpipe slo --objective OBJECTIVES [--by FIELD] [FILE...]")
 	fmt.Println()
 	fmt.Println("DESCRIPTION:")
 	fmt.Println("  LogPipe reads JSON logs from stdin and displays them in a readable format.")
@@ -292,6 +1573,155 @@ func printHelp() {
 	fmt.Println("  --message REGEX         Include logs matching message regex")
 	fmt.Println("  --no-level REGEX        Exclude logs matching level regex")
 	fmt.Println("  --no-message REGEX      Exclude logs matching message regex")
+	fmt.Println("  --correlation FIELDS    Fill a correlation field for filters from these fields, or auto (request IDs, traceparent)")
+	fmt.Println("  --logger PREFIXES       Include logs of these loggers and their children (e.g. com.example)")
+	fmt.Println("  --no-logger PREFIXES    Exclude logs of these loggers and their children (e.g. org.hibernate)")
+	fmt.Println("  --quiet-paths PATHS     Hide entries for these URL paths (e.g. /healthz,/static/*), counting them")
+	fmt.Println("  --quiet-match REGEX     Hide lines matching a regex, counting them")
+	fmt.Println("  --route RULES           Also copy input lines of some levels to stderr, stdout or a file,")
+	fmt.Println("                          e.g. 'level>=error:stderr,level=warn:warnings.log'")
+	fmt.Println("  --status                Show rolling 1m/5m request and error rates on a status line (terminal only)")
+	fmt.Println("  --idle DURATION         Print a dim marker (— 30s idle —) when no lines arrive for DURATION")
+	fmt.Println("  --timeline              Chart entries per minute by level when the input ends or on Ctrl-C")
+	fmt.Println("  --since TIME            Only show entries from TIME: 2024-01-15T14:00:00Z, 14:00 or a duration ago (1h, 2d)")
+	fmt.Println("  --until TIME            Only show entries before TIME (same formats; a time of day is on the --since day)")
+	fmt.Println("  --shed LEVEL            Drop entries at or below LEVEL when the output can't keep up, counting them")
+	fmt.Println("  --head N                Show only the first N entries (after filtering), then exit")
+	fmt.Println("  --tail N                Show only the last N entries (after filtering) once the input ends")
+	fmt.Println("  --buffer N|SIZE         Most entries, or memory like 64MB, kept for --tail (default: 100000)")
+	fmt.Println("  --memory-limit SIZE     Memory budget, e.g. 256MB, for --tail, --pair-requests, --latency-outliers and patterns only")
+	fmt.Println("  --skip N                Skip the first N entries (after filtering)")
+	fmt.Println("  --where EXPR            Include entries matching an expression, e.g. 'url.path =~ \"^/api\" && event.duration > 1e9'")
+	fmt.Println("  --jq EXPR               Filter or transform JSON entries with a jq expression, e.g. '.http.request | {method, id}'")
+	fmt.Println("  --diff-fields FIELDS    Show which of these fields (or all) changed since the previous entry shown")
+	fmt.Println("  --output-format FORMAT  pretty (default), or jsonl-normalized: one flat JSON object per entry for scripts")
+	fmt.Println("  --config FILE           JSON config file (default: logpipe/config.json in the user config dir)")
+	fmt.Println("  --profile NAME          Use the flags of a named profile from the config file")
+	fmt.Println("  --numeric-levels TYPE   Read numeric levels as auto (default), syslog, pino or otel")
+	fmt.Println("  --icons                 Prefix entries with level icons")
+	fmt.Println("  --icon-set NAME         Icon set: emoji (default) or nerd (Nerd Font glyphs)")
+	fmt.Println("  --redact FIELDS         Mask comma-separated field paths, e.g. user.email")
+	fmt.Println("  --redact-detectors LIST Mask detected emails, tokens, cards, ips (or all)")
+	fmt.Println("  --anonymize             Replace users, hosts, emails and IPs with stable pseudonyms")
+	fmt.Println("  --anonymize-key SECRET  Keep --anonymize pseudonyms the same across runs")
+	fmt.Println("  --ip-info               Tag source IPs of HTTP lines as private, public, ...")
+	fmt.Println("  --geoip FILE            Locate public source IPs with a MaxMind DB (implies --ip-info)")
+	fmt.Println("  --rdns                  Show reverse DNS names of source IPs (implies --ip-info)")
+	fmt.Println("  --promote KEYS          Show these labels/tags/attributes/baggage keys after the message, e.g. env,tenant")
+	fmt.Println("  --enrich SPECS          Label field values from CSV/JSON lookup files, e.g. user_id=users.csv:id:name")
+	fmt.Println("  --debug-parse           Explain why lines render raw and summarize parse failures")
+	fmt.Println("  --strict                Exit non-zero when lines fail to parse or lack required fields")
+	fmt.Println("  --require FIELDS        Fields every JSON entry must have in --strict mode")
+	fmt.Println("  --max-errors N          Violations tolerated in --strict mode (default: 0)")
+	fmt.Println("  --recursive             Read directories given as arguments with their subdirectories")
+	fmt.Println("  --order ORDER           Read files given as arguments by name (default), mtime, or timestamp (merged)")
+	fmt.Println("  -f, --follow GLOBS      Follow files matching comma-separated globs, discovering new ones, labeled by name")
+	fmt.Println("  --stderr FILE           Read a stderr stream from FILE (e.g. /dev/fd/3) and mark its entries")
+	fmt.Println("  --stdout FILE           Read the stdout stream from FILE instead of stdin when using --stderr")
+	fmt.Println("  --unwrap FIELD          Read events from a field of a shipper's wrapper (e.g. message, log)")
+	fmt.Println("  --unwrap-keep FIELDS    Wrapper fields shown in front of unwrapped events")
+	fmt.Println("  --sanitize MODE         Escape sequences in input: strip (default), escape (show as ␛[31m) or off")
+	fmt.Println("  --no-highlight          Don't highlight numbers, strings, UUIDs, durations and URLs in messages")
+	fmt.Println("  --sql-full              Show SQL statements in full instead of truncating them")
+	fmt.Println("  --bytes                 Show response sizes (1.2 KB) and content types on HTTP lines")
+	fmt.Println("  --logger-column         Show the logger in front of the message, shortened (c.e.s.UserService)")
+	fmt.Println("  --kv                    Write each entry as one colored key=value line with all its fields")
+	fmt.Println("  --pair-requests         Show responses with their request's method, path and latency (by http.request.id)")
+	fmt.Println("  --vertical              Write each entry as a block of aligned field: value lines")
+	fmt.Println("  --density PRESET        compact (time, level, message), normal or detailed (all optional columns)")
+	fmt.Println("  --schema audit          Render every entry with an action as an audit event")
+	fmt.Println("  --gap DURATION          Note gaps longer than DURATION between entries' timestamps, e.g. 5m")
+	fmt.Println("  --separators INTERVAL   Show a dim line where entries cross into a new day, hour or interval (e.g. 15m)")
+	fmt.Println("  --restarts              Draw a rule at deploys and restarts (new version or PID, startup messages)")
+	fmt.Println("  --context-header        Show service, version, process and pod in a header when they change")
+	fmt.Println("  --process               Show host, process, PID and thread in a column (web-1 api[1234]/worker-3)")
+	fmt.Println("  --duration-unit UNIT    Unit of durations in fields like event.duration or duration: auto, ns, us, ms, s")
+	fmt.Println("  --latency-outliers      Flag HTTP requests slower than the p99 of their endpoint's recent requests")
+	fmt.Println("  --outlier-window DUR    How far back --latency-outliers looks (default: 10m)")
+	fmt.Println("  --latency-bar           Show durations as bars relative to the slowest recent entries")
+	fmt.Println("  --bodies                Show HTTP request/response bodies under the entry")
+	fmt.Println("  --body-limit BYTES      Maximum bytes shown per body (default: 2048)")
+	fmt.Println("  --trace-url URL         Link trace IDs to a tracing UI; {trace.id} and {span.id} are filled in")
+	fmt.Println("  --escalate N/DURATION   Show a banner when N errors occur within DURATION, e.g. 20/1m")
+	fmt.Println("  --escalate-webhook URL  POST a JSON alert to URL when an --escalate burst starts")
+	fmt.Println("  --capture FILE          Record input lines into a session bundle (.lpz) for logpipe open")
+	fmt.Println("  --curl                  Show a curl command reproducing each HTTP request (secrets redacted)")
+	fmt.Println("  --stream MODE           Framing of JSON input: lines (default) or concat (objects back to back)")
+	fmt.Println("  --proto-schema FILE     Decode binary input records with a .proto schema")
+	fmt.Println("  --proto-message NAME    Protobuf message type of each record")
+	fmt.Println("  --avro-schema FILE      Decode binary input records with an .avsc schema")
+	fmt.Println("  --length-prefix TYPE    Record length prefix: uint32 (default) or varint")
+	fmt.Println("  --serve-ws ADDR         Serve a live-tail page and WebSocket stream on ADDR (:8081 is localhost only)")
+	fmt.Println("  --serve-ws-raw          Broadcast raw input lines instead of rendered entries")
+	fmt.Println("  --serve-ws-origins LIST Origins of other pages allowed to connect to --serve-ws")
+	fmt.Println()
+	fmt.Println("KAFKA OPTIONS:")
+	fmt.Println("  --brokers HOSTS         Comma-separated list of Kafka brokers")
+	fmt.Println("  --topic TOPIC           Topic to consume log messages from")
+	fmt.Println("  --group GROUP           Join a consumer group and commit its offsets (default: none)")
+	fmt.Println("  --from-beginning        Start from the oldest offset, unless the group has some committed")
+	fmt.Println("  --show-offsets          Show partition/offset in front of each entry")
+	fmt.Println("  --show-key              Show the message key in front of each entry")
+	fmt.Println("  --show-headers NAMES    Show these message headers in front of each entry, e.g. trace_id,env")
+	fmt.Println()
+	fmt.Println("LOKI OPTIONS:")
+	fmt.Println("  --addr URL              Loki server address (default: $LOKI_ADDR)")
+	fmt.Println("  --query LOGQL           Log query, e.g. '{app=\"api\"} |= \"error\"'")
+	fmt.Println("  --since, --until TIME   Time range of the query (default: the last hour)")
+	fmt.Println("  --limit N               Most entries read before following (default: all)")
+	fmt.Println("  -f, --follow            Keep streaming new entries")
+	fmt.Println("  --org-id TENANT         Tenant sent as X-Scope-OrgID (default: $LOKI_ORG_ID)")
+	fmt.Println()
+	fmt.Println("ELASTICSEARCH OPTIONS:")
+	fmt.Println("  --addr URL              Elasticsearch address (default: $ELASTICSEARCH_URL or http://localhost:9200)")
+	fmt.Println("  --index PATTERN         Index, alias or pattern to search, e.g. 'logs-*'")
+	fmt.Println("  --query QUERY           Query string, e.g. 'service.name:api AND log.level:error' (default: *)")
+	fmt.Println("  --time-field FIELD      Timestamp field to sort and filter on (default: @timestamp)")
+	fmt.Println("  --since, --until TIME   Time range of the search (default: the last hour)")
+	fmt.Println("  --limit N               Most entries read before following (default: all)")
+	fmt.Println("  -f, --follow            Keep polling for new entries")
+	fmt.Println()
+	fmt.Println("CLOUDWATCH OPTIONS:")
+	fmt.Println("  --log-group GROUP       Log group to read, e.g. /aws/lambda/my-fn")
+	fmt.Println("  --filter PATTERN        CloudWatch filter pattern, e.g. '{ $.level = \"error\" }'")
+	fmt.Println("  --stream-prefix PREFIX  Only read log streams whose name starts with PREFIX")
+	fmt.Println("  --since, --until TIME   Time range to read (default: the last hour)")
+	fmt.Println("  --limit N               Most events read before following (default: all)")
+	fmt.Println("  -f, --follow            Keep polling for new events")
+	fmt.Println()
+	fmt.Println("GCP OPTIONS:")
+	fmt.Println("  --project PROJECT       Google Cloud project to read (default: $GOOGLE_CLOUD_PROJECT, or the credentials')")
+	fmt.Println("  --filter QUERY          Logging query, e.g. 'resource.type=\"k8s_container\" severity>=ERROR'")
+	fmt.Println("  --since, --until TIME   Time range to read (default: the last hour)")
+	fmt.Println("  --limit N               Most entries read before following (default: all)")
+	fmt.Println("  -f, --follow            Keep polling for new entries")
+	fmt.Println()
+	fmt.Println("LISTEN OPTIONS:")
+	fmt.Println("  --forward ADDR          Receive the Fluent Forward protocol on ADDR (:24224 is localhost only)")
+	fmt.Println("  --otlp-grpc ADDR        Receive OpenTelemetry logs over gRPC (:4317 is localhost only)")
+	fmt.Println("  --otlp-http ADDR        Receive OpenTelemetry logs over HTTP, protobuf or JSON (:4318 is localhost only)")
+	fmt.Println()
+	fmt.Println("LINT OPTIONS:")
+	fmt.Println("  --schema NAME           Schema to validate entries against (default: ecs)")
+	fmt.Println("  --require FIELDS        Extra fields every entry must have")
+	fmt.Println("  Violations are counted per field; the exit status is 1 when any are found.")
+	fmt.Println()
+	fmt.Println("PATTERNS OPTIONS:")
+	fmt.Println("  --top N                 Number of patterns to show, most frequent first (default: 20, 0 for all)")
+	fmt.Println()
+	fmt.Println("FLATTEN OPTIONS:")
+	fmt.Println("  --format FORMAT         Write flattened entries as json or logfmt (default: json)")
+	fmt.Println()
+	fmt.Println("SLO OPTIONS:")
+	fmt.Println("  --objective LIST        Objectives such as 'p99<500ms, error_rate<1%' or 'availability>=99.9%'")
+	fmt.Println("  --by FIELD              Field to group requests by (default: url.path_template)")
+	fmt.Println("  The exit status is 1 when any group misses an objective.")
+	fmt.Println()
+	fmt.Println("SUB URLS:")
+	fmt.Println("  nats://[user:pass@]host[:4222]?subject=SUBJECTS     NATS (tls:// for TLS)")
+	fmt.Println("  redis://[user:pass@]host[:6379]?channel=CHANNELS    Redis pub/sub (rediss:// for TLS)")
+	fmt.Println("  Several subjects or channels can be given comma-separated; Redis glob")
+	fmt.Println("  patterns use PSUBSCRIBE.")
 	fmt.Println()
 	fmt.Println("EXAMPLES:")
 	fmt.Println("  # Kubernetes logs")
@@ -315,6 +1745,28 @@ func printHelp() {
 	fmt.Println("  # Exclude debug messages")
 	fmt.Println("  cat app.log | logpipe --no-message \"debug.*\"")
 	fmt.Println()
+	fmt.Println("  # Run a command and render its stdout and stderr, keeping its exit code")
+	fmt.Println("  logpipe run -- go run ./cmd/server")
+	fmt.Println()
+	fmt.Println("  # Consume JSON logs directly from Kafka")
+	fmt.Println("  logpipe kafka --brokers localhost:9092 --topic app-logs --group logpipe-dev")
+	fmt.Println()
+	fmt.Println("  # Tail logs published on a message bus")
+	fmt.Println("  logpipe sub 'nats://localhost:4222?subject=logs.>'")
+	fmt.Println("  logpipe sub 'redis://localhost:6379?channel=logs'")
+	fmt.Println()
+	fmt.Println("  # Consume a WebSocket log stream")
+	fmt.Println("  logpipe ws ws://localhost:8081/ws")
+	fmt.Println()
+	fmt.Println("  # Share a live tail with browsers on http://localhost:8081/")
+	fmt.Println("  kubectl logs -f my-pod | logpipe --serve-ws :8081")
+	fmt.Println()
+	fmt.Println("  # Binary protobuf records dumped from Kafka")
+	fmt.Printf("  kcat -b broker:9092 -t app-logs -C -f '%%R%%s' | logpipe --proto-schema logs.proto --proto-message LogRecord\n")
+	fmt.Println()
+	fmt.Println("  # Mask sensitive values before screen-sharing")
+	fmt.Println("  cat app.log | logpipe --redact user.email,http.request.headers.authorization --redact-detectors all")
+	fmt.Println()
 	fmt.Println("  # JSON log example")
 	fmt.Println(`  echo '{"@timestamp":"2024-01-15T14:25:13.458Z","log.level":"info","message":"Server started"}' | logpipe`)
 	fmt.Println()
@@ -325,6 +1777,9 @@ func printHelp() {
 	fmt.Println("  Application Logs:")
 	fmt.Println("    14:25:13 [error] Database connection failed error=map[code:TIMEOUT]")
 	fmt.Println()
+	fmt.Println("  --output-format jsonl-normalized:")
+	fmt.Println(`    {"@timestamp":"2024-01-15T14:25:13Z","duration_ms":850,"http.request.method":"GET","log.level":"info",...}`)
+	fmt.Println()
 	fmt.Println("For more information, visit: https://github.com/kabooboo/logpipe")
 }
 
@@ -151,7 +151,8 @@ func TestPrintPrettyLog(t *testing.T) {
 		HTTP: struct {
 			Request struct {
 				Body struct {
-					Bytes int `json:"bytes"`
+					Bytes   int         `json:"bytes"`
+					Content interface{} `json:"content"`
 				} `json:"body"`
 				ID     string `json:"id"`
 				Method string `json:"method"`
@@ -159,7 +160,8 @@ func TestPrintPrettyLog(t *testing.T) {
 			} `json:"request"`
 			Response struct {
 				Body struct {
-					Bytes int `json:"bytes"`
+					Bytes   int         `json:"bytes"`
+					Content interface{} `json:"content"`
 				} `json:"body"`
 				MimeType   string `json:"mime_type"`
 				StatusCode int    `json:"status_code"`
@@ -168,7 +170,8 @@ func TestPrintPrettyLog(t *testing.T) {
 		}{
 			Request: struct {
 				Body struct {
-					Bytes int `json:"bytes"`
+					Bytes   int         `json:"bytes"`
+					Content interface{} `json:"content"`
 				} `json:"body"`
 				ID     string `json:"id"`
 				Method string `json:"method"`
@@ -176,7 +179,8 @@ func TestPrintPrettyLog(t *testing.T) {
 			}{Method: "GET"},
 			Response: struct {
 				Body struct {
-					Bytes int `json:"bytes"`
+					Bytes   int         `json:"bytes"`
+					Content interface{} `json:"content"`
 				} `json:"body"`
 				MimeType   string `json:"mime_type"`
 				StatusCode int    `json:"status_code"`
@@ -366,7 +370,8 @@ func BenchmarkPrintPrettyLog(b *testing.B) {
 		HTTP: struct {
 			Request struct {
 				Body struct {
-					Bytes int `json:"bytes"`
+					Bytes   int         `json:"bytes"`
+					Content interface{} `json:"content"`
 				} `json:"body"`
 				ID     string `json:"id"`
 				Method string `json:"method"`
@@ -374,7 +379,8 @@ func BenchmarkPrintPrettyLog(b *testing.B) {
 			} `json:"request"`
 			Response struct {
 				Body struct {
-					Bytes int `json:"bytes"`
+					Bytes   int         `json:"bytes"`
+					Content interface{} `json:"content"`
 				} `json:"body"`
 				MimeType   string `json:"mime_type"`
 				StatusCode int    `json:"status_code"`
@@ -383,7 +389,8 @@ func BenchmarkPrintPrettyLog(b *testing.B) {
 		}{
 			Request: struct {
 				Body struct {
-					Bytes int `json:"bytes"`
+					Bytes   int         `json:"bytes"`
+					Content interface{} `json:"content"`
 				} `json:"body"`
 				ID     string `json:"id"`
 				Method string `json:"method"`
@@ -391,7 +398,8 @@ func BenchmarkPrintPrettyLog(b *testing.B) {
 			}{Method: "GET"},
 			Response: struct {
 				Body struct {
-					Bytes int `json:"bytes"`
+					Bytes   int         `json:"bytes"`
+					Content interface{} `json:"content"`
 				} `json:"body"`
 				MimeType   string `json:"mime_type"`
 				StatusCode int    `json:"status_code"`
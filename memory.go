@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// memoryLimit is set by --memory-limit, and nil without it.
+var memoryLimit *memoryBudget
+
+// memoryBudget bounds the memory shared by the state that grows with the
+// input: the --tail buffer, the requests --pair-requests waits on, the
+// --latency-outliers baselines and the patterns of `logpipe patterns`.
+// Other state, such as the groups of the summary commands, isn't counted.
+// Each of them grows the budget with what it keeps and, once the budget is
+// exceeded, gives memory back: the --tail buffer spills its oldest entries
+// to a temporary file, the others forget their oldest or rarest state.
+// Sizes are estimates of the data kept, not of the whole process.
+type memoryBudget struct {
+	limit int
+	used  int
+	// shed counts what was spilled or forgotten, by kind, e.g.
+	// "pending requests forgotten".
+	shed map[string]int
+}
+
+func newMemoryBudget(limit int) *memoryBudget {
+	return &memoryBudget{limit: limit, shed: make(map[string]int)}
+}
+
+// parseMemoryLimit reads --memory-limit: a size like 256MB.
+func parseMemoryLimit(s string) (int, error) {
+	limit, err := parseBufferLimit(s)
+	if err != nil || limit.bytes == 0 {
+		return 0, fmt.Errorf("expected a size like 256MB, got %q", s)
+	}
+	return limit.bytes, nil
+}
+
+// grow accounts for n more bytes kept. A nil budget is no budget.
+func (m *memoryBudget) grow(n int) {
+	if m != nil {
+		m.used += n
+	}
+}
+
+// shrink accounts for n bytes given back.
+func (m *memoryBudget) shrink(n int) {
+	if m != nil {
+		m.used = max(m.used-n, 0)
+	}
+}
+
+// exceeded reports whether more is kept than the budget allows.
+func (m *memoryBudget) exceeded() bool {
+	return m != nil && m.used > m.limit
+}
+
+// record counts n things spilled or forgotten to stay within the budget.
+func (m *memoryBudget) record(what string, n int) {
+	if m != nil && n > 0 {
+		m.shed[what] += n
+	}
+}
+
+// report notes what was spilled or forgotten, if anything, so that it is
+// clear when results are partial.
+func (m *memoryBudget) report(w io.Writer) {
+	if m == nil || len(m.shed) == 0 {
+		return
+	}
+	var parts []string
+	for _, what := range sortedKeys(m.shed) {
+		parts = append(parts, fmt.Sprintf("%d %s", m.shed[what], what))
+	}
+	fmt.Fprintf(w, "%s\n", labelPaint.sprint("· --memory-limit: "+strings.Join(parts, ", ")))
+}
+
+// entryOverhead is the size of a LogEntry without the text it points to.
+var entryOverhead = int(reflect.TypeOf(LogEntry{}).Size())
+
+// entrySize estimates the memory an entry kept aside takes, from its
+// overhead and its longest texts.
+func entrySize(e *LogEntry) int {
+	return entryOverhead + len(e.Timestamp) + len(e.Message) + len(e.URL.Path) + len(e.UserAgent.Original) + len(e.HTTP.Request.ID)
+}
+
+// tailSpill is the file the --tail buffer spills its oldest entries to
+// when the memory budget is exceeded. The live entries are the last ones
+// written, from offset; the ones before were dropped since.
+type tailSpill struct {
+	file    *os.File
+	lengths []int
+	first   int
+	offset  int64
+	end     int64
+}
+
+// spillCompactBytes is how much of a spill file may be dropped entries
+// before they are removed from it.
+const spillCompactBytes = 16 << 20
+
+func newTailSpill() (*tailSpill, error) {
+	file, err := os.CreateTemp("", "logpipe-tail-*")
+	if err != nil {
+		return nil, err
+	}
+	return &tailSpill{file: file}, nil
+}
+
+// count returns the number of live entries in the file.
+func (s *tailSpill) count() int {
+	return len(s.lengths) - s.first
+}
+
+func (s *tailSpill) write(entry []byte) error {
+	if _, err := s.file.WriteAt(entry, s.end); err != nil {
+		return err
+	}
+	s.end += int64(len(entry))
+	s.lengths = append(s.lengths, len(entry))
+	return nil
+}
+
+// dropOldest drops the oldest live entry, rewriting the file without the
+// dropped ones when they take more room than the live ones.
+func (s *tailSpill) dropOldest() error {
+	s.offset += int64(s.lengths[s.first])
+	s.first++
+	if s.first > 1024 && s.first > len(s.lengths)/2 {
+		s.lengths = append([]int(nil), s.lengths[s.first:]...)
+		s.first = 0
+	}
+	if s.offset < spillCompactBytes || s.offset < s.end-s.offset {
+		return nil
+	}
+	compacted, err := os.CreateTemp("", "logpipe-tail-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(compacted, io.NewSectionReader(s.file, s.offset, s.end-s.offset)); err != nil {
+		compacted.Close()
+		os.Remove(compacted.Name())
+		return err
+	}
+	s.close()
+	s.file, s.end, s.offset = compacted, s.end-s.offset, 0
+	return nil
+}
+
+// copyTo writes the live entries, oldest first.
+func (s *tailSpill) copyTo(w io.Writer) error {
+	_, err := io.Copy(w, io.NewSectionReader(s.file, s.offset, s.end-s.offset))
+	return err
+}
+
+// close removes the file.
+func (s *tailSpill) close() error {
+	err := s.file.Close()
+	return errors.Join(err, os.Remove(s.file.Name()))
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseMemoryLimit(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{"256MB", 256 << 20, false},
+		{"1.5gb", 3 << 29, false},
+		{"512KB", 512 << 10, false},
+		{"1000", 0, true},
+		{"-1MB", 0, true},
+		{"lots", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseMemoryLimit(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseMemoryLimit(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// withMemoryLimit sets --memory-limit for the duration of a test.
+func withMemoryLimit(t *testing.T, limit int) *memoryBudget {
+	memoryLimit = newMemoryBudget(limit)
+	t.Cleanup(func() { memoryLimit = nil })
+	return memoryLimit
+}
+
+func TestOutputSliceSpill(t *testing.T) {
+	budget := withMemoryLimit(t, 6)
+	slice, _ := newOutputSlice(0, 0, 8, bufferLimit{entries: 100})
+	for i := 1; i <= 10; i++ {
+		slice.admit()
+		slice.keep([]byte(fmt.Sprintf("%d ", i)))
+		if budget.used > budget.limit {
+			t.Fatalf("entry %d: %d bytes kept in memory, over the %d byte budget", i, budget.used, budget.limit)
+		}
+	}
+	if slice.spill == nil || slice.evicted() != 0 {
+		t.Fatalf("spill = %v, evicted() = %d, want spilled entries and none dropped", slice.spill, slice.evicted())
+	}
+
+	var out bytes.Buffer
+	slice.flush(&out)
+	if got, want := strings.TrimSpace(out.String()), "3 4 5 6 7 8 9 10"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	if budget.used != 0 || slice.spill != nil {
+		t.Errorf("after flush: %d bytes used, spill %v", budget.used, slice.spill)
+	}
+	if budget.shed["--tail entries spilled to disk"] == 0 {
+		t.Errorf("shed = %v, want spilled entries counted", budget.shed)
+	}
+}
+
+func TestPendingRequestsMemoryLimit(t *testing.T) {
+	request := func(id string) *LogEntry {
+		var e LogEntry
+		e.HTTP.Request.ID = id
+		e.HTTP.Request.Method = "GET"
+		return &e
+	}
+	budget := withMemoryLimit(t, 3*entrySize(request("r1")))
+
+	p := newRequestPairer()
+	for i := 1; i <= 5; i++ {
+		p.pair(request(fmt.Sprintf("r%d", i)))
+	}
+	if len(p.pending) != 3 {
+		t.Fatalf("pending = %d, want 3", len(p.pending))
+	}
+	if _, ok := p.pending["r1"]; ok {
+		t.Error("oldest request kept, want it forgotten first")
+	}
+	if got := budget.shed["pending requests forgotten"]; got != 2 {
+		t.Errorf("forgotten = %d, want 2", got)
+	}
+
+	response := request("r5")
+	response.HTTP.Response.StatusCode = 200
+	p.pair(response)
+	if want := 2 * entrySize(request("r1")); budget.used != want {
+		t.Errorf("used = %d after a response, want %d", budget.used, want)
+	}
+}
+
+func TestPatternMinerMemoryLimit(t *testing.T) {
+	budget := withMemoryLimit(t, 2048)
+	m := newPatternMiner()
+	for i := 0; i < 50; i++ {
+		m.add("info", "cache warmed")
+	}
+	for i := 0; i < 200; i++ {
+		m.add("info", fmt.Sprintf("%c%c queue drained", 'a'+i%26, 'a'+i/26))
+	}
+
+	if budget.used > budget.limit {
+		t.Errorf("used = %d, over the %d byte budget", budget.used, budget.limit)
+	}
+	if budget.shed["rare patterns forgotten"] == 0 {
+		t.Error("no patterns forgotten")
+	}
+	if m.entries != 250 {
+		t.Errorf("entries = %d, want all of them counted", m.entries)
+	}
+	if len(m.patterns) == 0 || strings.Join(m.patterns[0].tokens, " ") != "cache warmed" {
+		t.Error("most frequent pattern forgotten")
+	}
+}
+
+func TestMemoryBudgetReport(t *testing.T) {
+	var out bytes.Buffer
+	var unset *memoryBudget
+	unset.report(&out)
+	budget := newMemoryBudget(1 << 20)
+	budget.report(&out)
+	if out.Len() != 0 {
+		t.Errorf("report without anything shed = %q, want nothing", out.String())
+	}
+
+	budget.record("latency samples forgotten", 3)
+	budget.record("--tail entries spilled to disk", 2)
+	budget.report(&out)
+	if got, want := out.String(), "· --memory-limit: 2 --tail entries spilled to disk, 3 latency samples forgotten\n"; got != want {
+		t.Errorf("report = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// showBytes is set by --bytes to add the response size and content type to
+// HTTP lines.
+var showBytes = false
+
+// largeResponseBytes is the size above which binary responses are bolded.
+const largeResponseBytes = 1 << 20
+
+// humanBytes formats a byte count with binary units, e.g. "512 B",
+// "1.2 KB" or "3.4 MB".
+func humanBytes(n int) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	value := float64(n)
+	for _, unit := range []string{"KB", "MB", "GB", "TB"} {
+		value /= 1024
+		if value < 1024 || unit == "TB" {
+			return fmt.Sprintf("%.1f %s", value, unit)
+		}
+	}
+	return ""
+}
+
+// mimeKind sorts a content type into json, html, xml, text or binary, and
+// returns "" when there is none.
+func mimeKind(mimeType string) string {
+	mimeType, _, _ = strings.Cut(strings.ToLower(strings.TrimSpace(mimeType)), ";")
+	mimeType = strings.TrimSpace(mimeType)
+	kind, sub, _ := strings.Cut(mimeType, "/")
+
+	switch {
+	case mimeType == "":
+		return ""
+	case sub == "json" || strings.HasSuffix(sub, "+json") || sub == "x-ndjson":
+		return "json"
+	case sub == "html" || sub == "xhtml+xml":
+		return "html"
+	case sub == "xml" || strings.HasSuffix(sub, "+xml"):
+		return "xml"
+	case kind == "text" || sub == "javascript" || sub == "x-www-form-urlencoded":
+		return "text"
+	}
+	return "binary"
+}
+
+var (
+	mimeJSONPaint        = newPaint(color.FgGreen)
+	mimeMarkupPaint      = newPaint(color.FgMagenta)
+	mimeTextPaint        = newPaint(color.FgWhite)
+	mimeLargeBinaryPaint = newPaint(color.FgRed, color.Bold)
+	mimeBinaryPaint      = newPaint(color.FgRed)
+	mimeUnknownPaint     = newPaint(color.FgWhite, color.Faint)
+)
+
+// mimePaint colors content types so large binary responses stand out.
+func mimePaint(kind string, size int) paint {
+	switch kind {
+	case "json":
+		return mimeJSONPaint
+	case "html", "xml":
+		return mimeMarkupPaint
+	case "text":
+		return mimeTextPaint
+	case "binary":
+		if size >= largeResponseBytes {
+			return mimeLargeBinaryPaint
+		}
+		return mimeBinaryPaint
+	}
+	return mimeUnknownPaint
+}
+
+// formatResponseSize renders the --bytes column, e.g. "1.2 KB json".
+func formatResponseSize(log LogEntry) string {
+	size := log.HTTP.Response.Body.Bytes
+	kind := mimeKind(log.HTTP.Response.MimeType)
+	text := humanBytes(size)
+	if kind != "" {
+		text += " " + kind
+	}
+	return mimePaint(kind, size).sprint(text)
+}
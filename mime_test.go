@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestHumanBytes(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "0 B"},
+		{512, "512 B"},
+		{1024, "1.0 KB"},
+		{1229, "1.2 KB"},
+		{3565158, "3.4 MB"},
+		{5 << 30, "5.0 GB"},
+		{3 << 50, "3072.0 TB"},
+	}
+
+	for _, tt := range tests {
+		if got := humanBytes(tt.n); got != tt.want {
+			t.Errorf("humanBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestMimeKind(t *testing.T) {
+	tests := []struct {
+		mimeType string
+		want     string
+	}{
+		{"", ""},
+		{"application/json; charset=utf-8", "json"},
+		{"application/problem+json", "json"},
+		{"text/html", "html"},
+		{"application/atom+xml", "xml"},
+		{"text/plain", "text"},
+		{"application/javascript", "text"},
+		{"image/png", "binary"},
+		{"application/octet-stream", "binary"},
+	}
+
+	for _, tt := range tests {
+		if got := mimeKind(tt.mimeType); got != tt.want {
+			t.Errorf("mimeKind(%q) = %q, want %q", tt.mimeType, got, tt.want)
+		}
+	}
+}
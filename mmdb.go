@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+)
+
+// mmdbMetadataMarker precedes the metadata map at the end of a MaxMind DB.
+var mmdbMetadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// mmdbReader looks up addresses in a MaxMind DB file (GeoLite2/GeoIP2
+// City or Country), following the MaxMind DB format spec: a binary search
+// tree over address bits whose leaves point into a data section.
+type mmdbReader struct {
+	tree       []byte
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	ipv4Start  uint
+}
+
+func openMMDB(path string) (*mmdbReader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newMMDBReader(buf)
+}
+
+func newMMDBReader(buf []byte) (*mmdbReader, error) {
+	start := bytes.LastIndex(buf, mmdbMetadataMarker)
+	if start < 0 {
+		return nil, errors.New("not a MaxMind DB file")
+	}
+	meta, _, err := decodeMMDB(buf[start+len(mmdbMetadataMarker):], 0)
+	if err != nil {
+		return nil, fmt.Errorf("reading MaxMind DB metadata: %w", err)
+	}
+	fields, ok := meta.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("invalid MaxMind DB metadata")
+	}
+
+	r := &mmdbReader{}
+	for name, dst := range map[string]*uint{"node_count": &r.nodeCount, "record_size": &r.recordSize, "ip_version": &r.ipVersion} {
+		v, ok := fields[name].(uint64)
+		if !ok {
+			return nil, fmt.Errorf("MaxMind DB metadata is missing %s", name)
+		}
+		*dst = uint(v)
+	}
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("unsupported MaxMind DB record size %d", r.recordSize)
+	}
+
+	if r.nodeCount > uint(start) {
+		return nil, errors.New("truncated MaxMind DB file")
+	}
+	treeSize := int(r.nodeCount * r.recordSize * 2 / 8)
+	if treeSize+16 > start {
+		return nil, errors.New("truncated MaxMind DB file")
+	}
+	r.tree = buf[:treeSize]
+	r.data = buf[treeSize+16 : start]
+
+	// IPv4 addresses live under ::/96 in IPv6 trees
+	if r.ipVersion == 6 {
+		for i := 0; i < 96 && r.ipv4Start < r.nodeCount; i++ {
+			r.ipv4Start = r.readNode(r.ipv4Start, 0)
+		}
+	}
+	return r, nil
+}
+
+// lookup returns the data record for ip, or nil when it is not in the
+// database.
+func (r *mmdbReader) lookup(ip net.IP) (interface{}, error) {
+	addr, node := ip.To16(), uint(0)
+	if ip4 := ip.To4(); ip4 != nil {
+		addr, node = ip4, r.ipv4Start
+	} else if r.ipVersion == 4 {
+		return nil, nil
+	}
+	if addr == nil {
+		return nil, nil
+	}
+
+	for i := 0; i < len(addr)*8 && node < r.nodeCount; i++ {
+		bit := (addr[i/8] >> (7 - uint(i%8))) & 1
+		node = r.readNode(node, uint(bit))
+	}
+	if node <= r.nodeCount {
+		return nil, nil
+	}
+	value, _, err := decodeMMDB(r.data, int(node-r.nodeCount-16))
+	return value, err
+}
+
+func (r *mmdbReader) readNode(node, index uint) uint {
+	b := r.tree
+	switch r.recordSize {
+	case 24:
+		off := node*6 + index*3
+		return uint(b[off])<<16 | uint(b[off+1])<<8 | uint(b[off+2])
+	case 28:
+		off := node * 7
+		if index == 0 {
+			return uint(b[off+3]&0xf0)<<20 | uint(b[off])<<16 | uint(b[off+1])<<8 | uint(b[off+2])
+		}
+		return uint(b[off+3]&0x0f)<<24 | uint(b[off+4])<<16 | uint(b[off+5])<<8 | uint(b[off+6])
+	}
+	off := node*8 + index*4
+	return uint(binary.BigEndian.Uint32(b[off:]))
+}
+
+// mmdbMaxDepth bounds the nesting of maps, arrays and pointers, so that a
+// corrupt database pointing back into a value it is decoding fails rather
+// than recursing forever. Real databases nest a few levels deep.
+const mmdbMaxDepth = 32
+
+// decodeMMDB decodes the data field at offset, returning it with the offset
+// just past it. Strings, maps and arrays decode to string,
+// map[string]interface{} and []interface{}; unsigned integers to uint64,
+// int32 to int64, doubles and floats to float64.
+func decodeMMDB(buf []byte, offset int) (interface{}, int, error) {
+	return decodeMMDBValue(buf, offset, 0)
+}
+
+func decodeMMDBValue(buf []byte, offset, depth int) (interface{}, int, error) {
+	errTruncated := errors.New("truncated MaxMind DB data")
+	if depth > mmdbMaxDepth {
+		return nil, 0, errors.New("MaxMind DB data nested too deep")
+	}
+	if offset < 0 || offset >= len(buf) {
+		return nil, 0, errTruncated
+	}
+	ctrl := buf[offset]
+	offset++
+	typ := int(ctrl >> 5)
+
+	if typ == 1 {
+		// Pointer: the value lives elsewhere in the data section
+		n := int(ctrl>>3)&3 + 1
+		if offset+n > len(buf) {
+			return nil, 0, errTruncated
+		}
+		p := int(ctrl & 7)
+		if n == 4 {
+			p = 0
+		}
+		for _, b := range buf[offset : offset+n] {
+			p = p<<8 | int(b)
+		}
+		p += [...]int{0, 2048, 526336, 0}[n-1]
+		// The spec forbids pointers to pointers
+		if p >= 0 && p < len(buf) && buf[p]>>5 == 1 {
+			return nil, 0, errors.New("MaxMind DB pointer to a pointer")
+		}
+		value, _, err := decodeMMDBValue(buf, p, depth+1)
+		return value, offset + n, err
+	}
+
+	if typ == 0 {
+		if offset >= len(buf) {
+			return nil, 0, errTruncated
+		}
+		typ = 7 + int(buf[offset])
+		offset++
+	}
+
+	size := int(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > len(buf) {
+			return nil, 0, errTruncated
+		}
+		extra := 0
+		for _, b := range buf[offset : offset+n] {
+			extra = extra<<8 | int(b)
+		}
+		size = [...]int{29, 285, 65821}[n-1] + extra
+		offset += n
+	}
+
+	switch typ {
+	case 7, 11:
+		// Map or array: size counts entries, not bytes
+		if typ == 11 {
+			// Every item takes a byte or more
+			items := make([]interface{}, 0, min(size, len(buf)-offset))
+			for i := 0; i < size; i++ {
+				item, next, err := decodeMMDBValue(buf, offset, depth+1)
+				if err != nil {
+					return nil, 0, err
+				}
+				items = append(items, item)
+				offset = next
+			}
+			return items, offset, nil
+		}
+		fields := make(map[string]interface{}, min(size, len(buf)-offset))
+		for i := 0; i < size; i++ {
+			key, next, err := decodeMMDBValue(buf, offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			value, next, err := decodeMMDBValue(buf, next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			name, _ := key.(string)
+			fields[name] = value
+			offset = next
+		}
+		return fields, offset, nil
+	case 14:
+		return size != 0, offset, nil
+	}
+
+	if offset+size > len(buf) {
+		return nil, 0, errTruncated
+	}
+	raw := buf[offset : offset+size]
+	offset += size
+
+	switch typ {
+	case 2:
+		return string(raw), offset, nil
+	case 3:
+		if size != 8 {
+			return nil, 0, errors.New("invalid MaxMind DB double")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), offset, nil
+	case 4, 10:
+		// Bytes and uint128 are kept raw
+		return append([]byte(nil), raw...), offset, nil
+	case 5, 6, 9:
+		var n uint64
+		for _, b := range raw {
+			n = n<<8 | uint64(b)
+		}
+		return n, offset, nil
+	case 8:
+		var n uint32
+		for _, b := range raw {
+			n = n<<8 | uint32(b)
+		}
+		return int64(int32(n)), offset, nil
+	case 15:
+		if size != 4 {
+			return nil, 0, errors.New("invalid MaxMind DB float")
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(raw))), offset, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported MaxMind DB data type %d", typ)
+}
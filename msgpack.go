@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// maxMsgpackSize bounds the strings, binaries and containers a msgpack
+// value may declare, so a garbled stream can't exhaust memory.
+const maxMsgpackSize = 64 << 20
+
+// msgpackMaxDepth bounds the nesting of arrays and maps, so that a stream
+// of nested containers fails rather than exhausting the stack. Fluentd
+// events nest a few levels deep.
+const msgpackMaxDepth = 64
+
+// msgpackExt is a msgpack extension value, e.g. Fluentd's EventTime.
+type msgpackExt struct {
+	typ  int8
+	data []byte
+}
+
+// readMsgpack reads one msgpack value. Integers are returned as int64 or
+// uint64, floats as float64, str as string, bin as []byte, arrays as
+// []interface{} and maps as map[string]interface{}, keys being formatted
+// as strings.
+func readMsgpack(r *bufio.Reader) (interface{}, error) {
+	return readMsgpackValue(r, 0)
+}
+
+// readMsgpackValue reads one msgpack value nested depth containers deep.
+func readMsgpackValue(r *bufio.Reader, depth int) (interface{}, error) {
+	if depth > msgpackMaxDepth {
+		return nil, errors.New("msgpack value nested too deep")
+	}
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case b <= 0x7f:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b&0xf0 == 0x80:
+		return readMsgpackMap(r, int(b&0x0f), depth)
+	case b&0xf0 == 0x90:
+		return readMsgpackArray(r, int(b&0x0f), depth)
+	case b&0xe0 == 0xa0:
+		return readMsgpackString(r, int(b&0x1f))
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := readMsgpackLength(r, 1<<(b-0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackBytes(r, n)
+	case 0xc7, 0xc8, 0xc9:
+		n, err := readMsgpackLength(r, 1<<(b-0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackExt(r, n)
+	case 0xca:
+		data, err := readMsgpackBytes(r, 4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data))), nil
+	case 0xcb:
+		data, err := readMsgpackBytes(r, 8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		data, err := readMsgpackBytes(r, 1<<(b-0xcc))
+		if err != nil {
+			return nil, err
+		}
+		n := bigEndianUint(data)
+		if n <= math.MaxInt64 {
+			return int64(n), nil
+		}
+		return n, nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (b - 0xd0)
+		data, err := readMsgpackBytes(r, size)
+		if err != nil {
+			return nil, err
+		}
+		// Sign-extend from the value's width
+		shift := 64 - 8*size
+		return int64(bigEndianUint(data)<<shift) >> shift, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return readMsgpackExt(r, 1<<(b-0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := readMsgpackLength(r, 1<<(b-0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackString(r, n)
+	case 0xdc, 0xdd:
+		n, err := readMsgpackLength(r, 2<<(b-0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackArray(r, n, depth)
+	case 0xde, 0xdf:
+		n, err := readMsgpackLength(r, 2<<(b-0xde))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackMap(r, n, depth)
+	}
+	return nil, fmt.Errorf("invalid msgpack type 0x%02x", b)
+}
+
+func bigEndianUint(data []byte) uint64 {
+	var n uint64
+	for _, b := range data {
+		n = n<<8 | uint64(b)
+	}
+	return n
+}
+
+// readMsgpackLength reads a big-endian length of size bytes.
+func readMsgpackLength(r *bufio.Reader, size int) (int, error) {
+	data, err := readMsgpackBytes(r, size)
+	if err != nil {
+		return 0, err
+	}
+	n := bigEndianUint(data)
+	if n > maxMsgpackSize {
+		return 0, fmt.Errorf("msgpack value of %d bytes is too large", n)
+	}
+	return int(n), nil
+}
+
+func readMsgpackBytes(r *bufio.Reader, n int) ([]byte, error) {
+	return readSized(r, n)
+}
+
+func readMsgpackString(r *bufio.Reader, n int) (interface{}, error) {
+	data, err := readMsgpackBytes(r, n)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func readMsgpackExt(r *bufio.Reader, n int) (interface{}, error) {
+	typ, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	data, err := readMsgpackBytes(r, n)
+	if err != nil {
+		return nil, err
+	}
+	return msgpackExt{typ: int8(typ), data: data}, nil
+}
+
+func readMsgpackArray(r *bufio.Reader, n, depth int) (interface{}, error) {
+	// Grown as elements arrive rather than sized by the declared length
+	var values []interface{}
+	for i := 0; i < n; i++ {
+		value, err := readMsgpackValue(r, depth+1)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		values = append(values, value)
+	}
+	if values == nil {
+		values = []interface{}{}
+	}
+	return values, nil
+}
+
+func readMsgpackMap(r *bufio.Reader, n, depth int) (interface{}, error) {
+	values := map[string]interface{}{}
+	for i := 0; i < n; i++ {
+		key, err := readMsgpackValue(r, depth+1)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		value, err := readMsgpackValue(r, depth+1)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		switch k := key.(type) {
+		case string:
+			values[k] = value
+		case []byte:
+			values[string(k)] = value
+		default:
+			values[fmt.Sprint(k)] = value
+		}
+	}
+	return values, nil
+}
+
+// unexpectedEOF turns the end of the stream within a value into an error.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// jsonCompatible converts a decoded msgpack value for encoding/json: bin
+// values become strings, as most agents send text as bin.
+func jsonCompatible(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = jsonCompatible(v[i])
+		}
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = jsonCompatible(v[key])
+		}
+	case msgpackExt:
+		return v.data
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil
+		}
+	}
+	return value
+}
+
+// appendMsgpackString appends s encoded as a msgpack str.
+func appendMsgpackString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xda, byte(n>>8), byte(n))
+	default:
+		b = append(b, 0xdb, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(b, s...)
+}
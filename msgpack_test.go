@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// packMsgpack encodes the value types readMsgpack returns, for tests.
+func packMsgpack(value interface{}) []byte {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		b = append(b, 0xc0)
+	case bool:
+		b = append(b, map[bool]byte{false: 0xc2, true: 0xc3}[v])
+	case int:
+		return packMsgpack(int64(v))
+	case int64:
+		if v >= 0 && v <= 0x7f {
+			b = append(b, byte(v))
+		} else {
+			b = append(b, 0xd3)
+			for shift := 56; shift >= 0; shift -= 8 {
+				b = append(b, byte(v>>shift))
+			}
+		}
+	case float64:
+		bits := math.Float64bits(v)
+		b = append(b, 0xcb)
+		for shift := 56; shift >= 0; shift -= 8 {
+			b = append(b, byte(bits>>shift))
+		}
+	case string:
+		b = appendMsgpackString(b, v)
+	case []byte:
+		b = append(b, 0xc4, byte(len(v)))
+		b = append(b, v...)
+	case msgpackExt:
+		b = append(b, 0xc7, byte(len(v.data)), byte(v.typ))
+		b = append(b, v.data...)
+	case []interface{}:
+		b = append(b, 0xdc, byte(len(v)>>8), byte(len(v)))
+		for _, item := range v {
+			b = append(b, packMsgpack(item)...)
+		}
+	case map[string]interface{}:
+		b = append(b, 0xde, byte(len(v)>>8), byte(len(v)))
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			b = append(b, packMsgpack(key)...)
+			b = append(b, packMsgpack(v[key])...)
+		}
+	default:
+		panic("packMsgpack: unsupported type")
+	}
+	return b
+}
+
+func TestReadMsgpack(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    interface{}
+		wantErr string
+	}{
+		{name: "positive fixint", data: []byte{0x05}, want: int64(5)},
+		{name: "negative fixint", data: []byte{0xff}, want: int64(-1)},
+		{name: "uint16", data: []byte{0xcd, 0x01, 0x00}, want: int64(256)},
+		{name: "uint64 above int64", data: []byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, want: uint64(math.MaxUint64)},
+		{name: "int8", data: []byte{0xd0, 0x80}, want: int64(-128)},
+		{name: "int32", data: []byte{0xd2, 0xff, 0xff, 0xff, 0xfe}, want: int64(-2)},
+		{name: "float32", data: []byte{0xca, 0x3f, 0xc0, 0x00, 0x00}, want: 1.5},
+		{name: "float64", data: packMsgpack(2.25), want: 2.25},
+		{name: "nil and bools", data: packMsgpack([]interface{}{nil, true, false}), want: []interface{}{nil, true, false}},
+		{name: "fixstr", data: []byte{0xa2, 'h', 'i'}, want: "hi"},
+		{name: "str8", data: packMsgpack(strings.Repeat("x", 40)), want: strings.Repeat("x", 40)},
+		{name: "bin", data: []byte{0xc4, 0x02, 'o', 'k'}, want: []byte("ok")},
+		{name: "fixext", data: []byte{0xd4, 0x01, 0x2a}, want: msgpackExt{typ: 1, data: []byte{0x2a}}},
+		{name: "fixarray", data: []byte{0x92, 0x01, 0xa1, 'a'}, want: []interface{}{int64(1), "a"}},
+		{name: "empty array", data: []byte{0x90}, want: []interface{}{}},
+		{name: "fixmap", data: []byte{0x81, 0xa1, 'k', 0xa1, 'v'}, want: map[string]interface{}{"k": "v"}},
+		{name: "integer key", data: []byte{0x81, 0x07, 0xc3}, want: map[string]interface{}{"7": true}},
+		{name: "truncated string", data: []byte{0xa3, 'a'}, wantErr: "unexpected EOF"},
+		{name: "truncated array", data: []byte{0x92, 0x01}, wantErr: "unexpected EOF"},
+		{name: "too large", data: []byte{0xdb, 0xff, 0xff, 0xff, 0xff}, wantErr: "too large"},
+		{name: "truncated large binary", data: []byte{0xc6, 0x04, 0x00, 0x00, 0x00, 'a'}, wantErr: "unexpected EOF"},
+		{name: "nested too deep", data: bytes.Repeat([]byte{0x91}, msgpackMaxDepth+2), wantErr: "nested too deep"},
+		{name: "reserved type", data: []byte{0xc1}, wantErr: "invalid msgpack type 0xc1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readMsgpack(bufio.NewReader(bytes.NewReader(tt.data)))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readMsgpack() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := readMsgpack(bufio.NewReader(bytes.NewReader(nil))); err != io.EOF {
+		t.Errorf("empty input: error = %v, want EOF", err)
+	}
+}
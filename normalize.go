@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// outputFormats are the values --output-format accepts.
+var outputFormats = []string{"pretty", "jsonl-normalized"}
+
+func checkOutputFormat(format string) error {
+	for _, f := range outputFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown format %q (expected pretty or jsonl-normalized)", format)
+}
+
+// normalizedLine renders an entry as a flat JSON object with a stable
+// schema, whichever input format it was read from: every field under its
+// dotted path, @timestamp in RFC 3339 UTC, log.level canonical, the
+// duration in milliseconds as duration_ms, and the input's label and
+// stream as label and stream.
+func normalizedLine(line string, entry LogEntry, label string) string {
+	return marshalNormalized(normalizedFields(line, entry, label))
+}
+
+// normalizedFields returns the fields normalizedLine writes.
+func normalizedFields(line string, entry LogEntry, label string) map[string]interface{} {
+	flat := flattenFields(entryFields(line, entry), nil)
+	delete(flat, "")
+	if decodeJSONObject(line) == nil {
+		// The fields of other formats come from LogEntry, unset ones too
+		for key, value := range flat {
+			if isZeroField(value) {
+				delete(flat, key)
+			}
+		}
+	}
+	if entry.Timestamp != "" {
+		if t, err := time.Parse(time.RFC3339Nano, entry.Timestamp); err == nil {
+			entry.Timestamp = t.UTC().Format(time.RFC3339Nano)
+		}
+		flat["@timestamp"] = entry.Timestamp
+	}
+	if entry.Level != "" {
+		flat["log.level"] = normalizeLevel(entry.Level)
+	}
+	if d, ok := entryDuration(flat); ok {
+		flat["duration_ms"] = float64(d) / float64(time.Millisecond)
+	}
+	if entry.Stream != "" {
+		flat["stream"] = entry.Stream
+	}
+	if label != "" {
+		flat["label"] = label
+	}
+	return flat
+}
+
+func isZeroField(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case bool:
+		return !v
+	case json.Number:
+		return v == "0"
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// unparsedLine renders a line logpipe couldn't parse in the same schema,
+// as a message flagged unparsed.
+func unparsedLine(line, label, stream string) string {
+	flat := map[string]interface{}{"message": line, "unparsed": true}
+	if stream != "" {
+		flat["stream"] = stream
+	}
+	if label != "" {
+		flat["label"] = label
+	}
+	return marshalNormalized(flat)
+}
+
+func marshalNormalized(flat map[string]interface{}) string {
+	data, err := json.Marshal(flat)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestNormalizedLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		label string
+		want  string
+	}{
+		{
+			name: "nested JSON",
+			line: `{"@timestamp":"2024-01-15T15:25:13.458+01:00","log.level":"WARNING","message":"slow","http":{"request":{"method":"GET"}},"event":{"duration":850000000}}`,
+			want: `{"@timestamp":"2024-01-15T14:25:13.458Z","duration_ms":850,"event.duration":850000000,"http.request.method":"GET","log.level":"warn","message":"slow"}`,
+		},
+		{
+			name:  "duration in another field",
+			line:  `{"@timestamp":"2024-01-15T14:25:13Z","message":"done","took":12}`,
+			label: "api-1",
+			want:  `{"@timestamp":"2024-01-15T14:25:13Z","duration_ms":12,"label":"api-1","message":"done","took":12}`,
+		},
+		{
+			name: "logfmt",
+			line: `time=2024-01-15T14:25:13Z level=error msg="payment failed" order=42`,
+			want: `{"@timestamp":"2024-01-15T14:25:13Z","log.level":"error","message":"payment failed","order":42}`,
+		},
+		{
+			name: "unreadable timestamp kept",
+			line: `{"@timestamp":"yesterday","message":"x"}`,
+			want: `{"@timestamp":"yesterday","message":"x"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, ok := parseLine(tt.line)
+			if !ok {
+				t.Fatalf("parseLine(%q) failed", tt.line)
+			}
+			if got := normalizedLine(tt.line, entry, tt.label); got != tt.want {
+				t.Errorf("normalizedLine() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnparsedLine(t *testing.T) {
+	got := unparsedLine("panic: oops", "worker", "stderr")
+	want := `{"label":"worker","message":"panic: oops","stream":"stderr","unparsed":true}`
+	if got != want {
+		t.Errorf("unparsedLine() = %s, want %s", got, want)
+	}
+}
+
+func TestCheckOutputFormat(t *testing.T) {
+	for _, format := range outputFormats {
+		if err := checkOutputFormat(format); err != nil {
+			t.Errorf("checkOutputFormat(%q) = %v", format, err)
+		}
+	}
+	if err := checkOutputFormat("json"); err == nil {
+		t.Error("checkOutputFormat(json) succeeded")
+	}
+}
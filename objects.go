@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// objectStore lists and reads objects of a bucket in S3 or GCS.
+type objectStore interface {
+	// list returns the objects whose key starts with prefix, only those
+	// directly below it unless recursive.
+	list(bucket, prefix string, recursive bool) ([]storedObject, error)
+	open(bucket, key string) (io.ReadCloser, error)
+}
+
+type storedObject struct {
+	key     string
+	modTime time.Time
+}
+
+// isObjectURL reports whether an argument names objects in a bucket, e.g.
+// s3://bucket/prefix/, gs://bucket/prefix/ or az://account/container/prefix.
+func isObjectURL(arg string) bool {
+	return strings.HasPrefix(arg, "s3://") || strings.HasPrefix(arg, "gs://") || strings.HasPrefix(arg, "az://")
+}
+
+// newObjectStore returns the client for a bucket URL, configured from the
+// environment, along with the bucket and key prefix the URL names. Azure
+// URLs name the storage account first, then the container.
+func newObjectStore(u *url.URL) (store objectStore, bucket, prefix string, err error) {
+	bucket, prefix = u.Host, strings.TrimPrefix(u.Path, "/")
+	switch u.Scheme {
+	case "gs":
+		return newGCSClient(), bucket, prefix, nil
+	case "az":
+		container, prefix, _ := strings.Cut(prefix, "/")
+		if container == "" {
+			return nil, "", "", errors.New("missing container name")
+		}
+		return newAzureClient(u.Host), container, prefix, nil
+	}
+	client, err := newS3Client()
+	if err != nil {
+		return nil, "", "", err
+	}
+	return client, bucket, prefix, nil
+}
+
+// listObjectFiles lists the objects below an s3://, gs:// or az:// URL as
+// files to read, labeled by their key below the last "/" of the prefix. A
+// URL naming a single object reads just that object.
+func listObjectFiles(rawURL string, recursive bool) ([]inputFile, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%s: missing bucket name", rawURL)
+	}
+	store, bucket, prefix, err := newObjectStore(u)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", rawURL, err)
+	}
+	objects, err := store.list(bucket, prefix, recursive)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", rawURL, err)
+	}
+	if len(objects) == 0 {
+		hint := ""
+		if !recursive {
+			hint = " (use --recursive to read subdirectories)"
+		}
+		return nil, fmt.Errorf("%s: no objects found%s", rawURL, hint)
+	}
+	for _, object := range objects {
+		if object.key == prefix {
+			objects = []storedObject{object}
+			break
+		}
+	}
+
+	root := u.Scheme + "://" + u.Host + "/"
+	if u.Scheme == "az" {
+		root += bucket + "/"
+	}
+	dir := prefix[:strings.LastIndex(prefix, "/")+1]
+	files := make([]inputFile, 0, len(objects))
+	for _, object := range objects {
+		key := object.key
+		label := strings.TrimPrefix(key, dir)
+		if label == "" {
+			continue
+		}
+		files = append(files, inputFile{
+			path:    root + key,
+			label:   label,
+			modTime: object.modTime,
+			open: func() (io.ReadCloser, error) {
+				r, err := store.open(bucket, key)
+				if err != nil {
+					return nil, err
+				}
+				return decompress(r)
+			},
+		})
+	}
+	return files, nil
+}
+
+// getObject sends a GET request and returns the response body, or an error
+// for a response other than 200 OK.
+func getObject(req *http.Request) (io.ReadCloser, error) {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %s %s", req.URL.Redacted(), resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp.Body, nil
+}
+
+// s3Client talks to S3 or a compatible store with the REST API, signing
+// requests with the AWS credentials of loadAWSConfig when they resolve.
+// With AWS_ENDPOINT_URL (MinIO, R2, ...), buckets are addressed path-style.
+type s3Client struct {
+	awsSigner
+}
+
+func newS3Client() (*s3Client, error) {
+	signer, err := newAWSSigner(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return &s3Client{signer}, nil
+}
+
+// request builds a signed GET request for a key of a bucket ("" for the
+// bucket itself).
+func (c *s3Client) request(bucket, key string, query url.Values) (*http.Request, error) {
+	base := "https://" + bucket + ".s3." + c.region + ".amazonaws.com"
+	path := "/" + escapeKey(key)
+	if c.endpoint != "" {
+		base = c.endpoint
+		path = "/" + bucket + path
+	}
+	target := base + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.sign(req, "s3", nil); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// get fetches an object or listing, telling why a failed request went
+// unsigned.
+func (c *s3Client) get(req *http.Request) (io.ReadCloser, error) {
+	body, err := getObject(req)
+	if err != nil && c.unsigned != nil {
+		return nil, fmt.Errorf("%w (sent unsigned, as %v)", err, c.unsigned)
+	}
+	return body, err
+}
+
+// s3ListResult is the part of a ListObjectsV2 response logpipe reads.
+type s3ListResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (c *s3Client) list(bucket, prefix string, recursive bool) ([]storedObject, error) {
+	var objects []storedObject
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if !recursive {
+			query.Set("delimiter", "/")
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := c.request(bucket, "", query)
+		if err != nil {
+			return nil, err
+		}
+		body, err := c.get(req)
+		if err != nil {
+			return nil, err
+		}
+		var result s3ListResult
+		err = xml.NewDecoder(body).Decode(&result)
+		body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, content := range result.Contents {
+			if !strings.HasSuffix(content.Key, "/") {
+				objects = append(objects, storedObject{key: content.Key, modTime: content.LastModified})
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+func (c *s3Client) open(bucket, key string) (io.ReadCloser, error) {
+	req, err := c.request(bucket, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.get(req)
+}
+
+// gcsClient talks to Google Cloud Storage with the JSON API, authenticated
+// with an OAuth access token (e.g. from gcloud auth print-access-token)
+// when GOOGLE_OAUTH_ACCESS_TOKEN is set.
+type gcsClient struct {
+	endpoint string
+	token    string
+}
+
+func newGCSClient() *gcsClient {
+	endpoint := "https://storage.googleapis.com"
+	if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+		endpoint = strings.TrimSuffix(host, "/")
+		if !strings.Contains(endpoint, "://") {
+			endpoint = "http://" + endpoint
+		}
+	}
+	return &gcsClient{endpoint: endpoint, token: os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")}
+}
+
+func (c *gcsClient) request(path string, query url.Values) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodGet, c.endpoint+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// gcsListResult is the part of an objects.list response logpipe reads.
+type gcsListResult struct {
+	Items []struct {
+		Name    string    `json:"name"`
+		Updated time.Time `json:"updated"`
+	} `json:"items"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+func (c *gcsClient) list(bucket, prefix string, recursive bool) ([]storedObject, error) {
+	var objects []storedObject
+	token := ""
+	for {
+		query := url.Values{"prefix": {prefix}, "fields": {"items(name,updated),nextPageToken"}}
+		if !recursive {
+			query.Set("delimiter", "/")
+		}
+		if token != "" {
+			query.Set("pageToken", token)
+		}
+		req, err := c.request("/storage/v1/b/"+url.PathEscape(bucket)+"/o", query)
+		if err != nil {
+			return nil, err
+		}
+		body, err := getObject(req)
+		if err != nil {
+			return nil, err
+		}
+		var result gcsListResult
+		err = json.NewDecoder(body).Decode(&result)
+		body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range result.Items {
+			if !strings.HasSuffix(item.Name, "/") {
+				objects = append(objects, storedObject{key: item.Name, modTime: item.Updated})
+			}
+		}
+		if result.NextPageToken == "" {
+			return objects, nil
+		}
+		token = result.NextPageToken
+	}
+}
+
+func (c *gcsClient) open(bucket, key string) (io.ReadCloser, error) {
+	req, err := c.request("/storage/v1/b/"+url.PathEscape(bucket)+"/o/"+url.PathEscape(key), url.Values{"alt": {"media"}})
+	if err != nil {
+		return nil, err
+	}
+	return getObject(req)
+}
+
+// azureClient talks to Azure Blob Storage with the REST API, authorized
+// with a SAS token from AZURE_STORAGE_SAS_TOKEN when it is set.
+type azureClient struct {
+	endpoint string
+	sasToken url.Values
+}
+
+func newAzureClient(account string) *azureClient {
+	endpoint := "https://" + account + ".blob.core.windows.net"
+	if override := os.Getenv("AZURE_STORAGE_ENDPOINT"); override != "" {
+		endpoint = strings.TrimSuffix(override, "/")
+	}
+	sas, _ := url.ParseQuery(strings.TrimPrefix(os.Getenv("AZURE_STORAGE_SAS_TOKEN"), "?"))
+	return &azureClient{endpoint: endpoint, sasToken: sas}
+}
+
+func (c *azureClient) request(path string, query url.Values) (*http.Request, error) {
+	for name, values := range c.sasToken {
+		query[name] = values
+	}
+	req, err := http.NewRequest(http.MethodGet, c.endpoint+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-ms-version", "2021-08-06")
+	return req, nil
+}
+
+// azureListResult is the part of a List Blobs response logpipe reads.
+type azureListResult struct {
+	Blobs []struct {
+		Name         string `xml:"Name"`
+		LastModified string `xml:"Properties>Last-Modified"`
+	} `xml:"Blobs>Blob"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+func (c *azureClient) list(container, prefix string, recursive bool) ([]storedObject, error) {
+	var objects []storedObject
+	marker := ""
+	for {
+		query := url.Values{"restype": {"container"}, "comp": {"list"}, "prefix": {prefix}}
+		if !recursive {
+			query.Set("delimiter", "/")
+		}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		req, err := c.request("/"+url.PathEscape(container), query)
+		if err != nil {
+			return nil, err
+		}
+		body, err := getObject(req)
+		if err != nil {
+			return nil, err
+		}
+		var result azureListResult
+		err = xml.NewDecoder(body).Decode(&result)
+		body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, blob := range result.Blobs {
+			modTime, _ := http.ParseTime(blob.LastModified)
+			objects = append(objects, storedObject{key: blob.Name, modTime: modTime})
+		}
+		if result.NextMarker == "" {
+			return objects, nil
+		}
+		marker = result.NextMarker
+	}
+}
+
+func (c *azureClient) open(container, key string) (io.ReadCloser, error) {
+	req, err := c.request("/"+url.PathEscape(container)+"/"+escapeKey(key), url.Values{})
+	if err != nil {
+		return nil, err
+	}
+	return getObject(req)
+}
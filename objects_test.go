@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func gzipped(t *testing.T, s string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	io.WriteString(gz, s)
+	gz.Close()
+	return buf.String()
+}
+
+// bucketObjects are the objects served by the fake stores below.
+func bucketObjects(t *testing.T) map[string]string {
+	return map[string]string{
+		"logs/2024-01-15/api.log":       `{"message":"api"}` + "\n",
+		"logs/2024-01-15/web.log.gz":    gzipped(t, `{"message":"web"}`+"\n"),
+		"logs/2024-01-15/old/batch.log": `{"message":"batch"}` + "\n",
+		"logs/2024-01-16/api.log":       `{"message":"next day"}` + "\n",
+	}
+}
+
+// listKeys returns the keys of objects under prefix, stopping at the next
+// "/" when delimited, sorted as stores do.
+func listKeys(objects map[string]string, prefix string, delimited bool) []string {
+	var keys []string
+	for _, key := range sortedKeys(objects) {
+		if !strings.HasPrefix(key, prefix) || delimited && strings.Contains(key[len(prefix):], "/") {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestS3Objects(t *testing.T) {
+	objects := bucketObjects(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") {
+			http.Error(w, "unsigned", http.StatusForbidden)
+			return
+		}
+		if r.URL.Path == "/bucket/" {
+			query := r.URL.Query()
+			keys := listKeys(objects, query.Get("prefix"), query.Get("delimiter") == "/")
+			// One key per page, to follow continuation tokens
+			start := 0
+			fmt.Sscan(query.Get("continuation-token"), &start)
+			fmt.Fprint(w, "<ListBucketResult>")
+			if start < len(keys) {
+				fmt.Fprintf(w, "<Contents><Key>%s</Key><LastModified>2024-01-15T10:00:00.000Z</LastModified></Contents>", keys[start])
+			}
+			if start+1 < len(keys) {
+				fmt.Fprintf(w, "<IsTruncated>true</IsTruncated><NextContinuationToken>%d</NextContinuationToken>", start+1)
+			}
+			fmt.Fprint(w, "</ListBucketResult>")
+			return
+		}
+		content, ok := objects[strings.TrimPrefix(r.URL.Path, "/bucket/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, content)
+	}))
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL", server.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	testObjectFiles(t, "s3://bucket")
+}
+
+func TestGCSObjects(t *testing.T) {
+	objects := bucketObjects(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthenticated", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/storage/v1/b/bucket/o" {
+			query := r.URL.Query()
+			keys := listKeys(objects, query.Get("prefix"), query.Get("delimiter") == "/")
+			var items []string
+			for _, key := range keys {
+				items = append(items, fmt.Sprintf(`{"name":%q,"updated":"2024-01-15T10:00:00Z"}`, key))
+			}
+			fmt.Fprintf(w, `{"items":[%s]}`, strings.Join(items, ","))
+			return
+		}
+		content, ok := objects[strings.TrimPrefix(r.URL.Path, "/storage/v1/b/bucket/o/")]
+		if !ok || r.URL.Query().Get("alt") != "media" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, content)
+	}))
+	defer server.Close()
+	t.Setenv("STORAGE_EMULATOR_HOST", server.URL)
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "token")
+
+	testObjectFiles(t, "gs://bucket")
+}
+
+func TestAzureObjects(t *testing.T) {
+	objects := bucketObjects(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("sig") != "secret" || r.Header.Get("x-ms-version") == "" {
+			http.Error(w, "unauthorized", http.StatusForbidden)
+			return
+		}
+		if r.URL.Path == "/bucket" && query.Get("comp") == "list" {
+			fmt.Fprint(w, "<EnumerationResults><Blobs>")
+			for _, key := range listKeys(objects, query.Get("prefix"), query.Get("delimiter") == "/") {
+				fmt.Fprintf(w, "<Blob><Name>%s</Name><Properties><Last-Modified>Mon, 15 Jan 2024 10:00:00 GMT</Last-Modified></Properties></Blob>", key)
+			}
+			fmt.Fprint(w, "</Blobs><NextMarker/></EnumerationResults>")
+			return
+		}
+		content, ok := objects[strings.TrimPrefix(r.URL.Path, "/bucket/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, content)
+	}))
+	defer server.Close()
+	t.Setenv("AZURE_STORAGE_ENDPOINT", server.URL)
+	t.Setenv("AZURE_STORAGE_SAS_TOKEN", "?sv=2021-08-06&sig=secret")
+
+	testObjectFiles(t, "az://account/bucket")
+
+	if _, err := listInputFiles([]string{"az://account"}, false, "name"); err == nil || !strings.Contains(err.Error(), "missing container") {
+		t.Errorf("listInputFiles() without container: error = %v", err)
+	}
+}
+
+// testObjectFiles lists and reads the bucket of a fake store, at root.
+func testObjectFiles(t *testing.T, root string) {
+	tests := []struct {
+		name      string
+		url       string
+		recursive bool
+		want      []string
+	}{
+		{
+			name: "prefix",
+			url:  "/logs/2024-01-15/",
+			want: []string{"api.log api", "web.log.gz web"},
+		},
+		{
+			name:      "recursive prefix",
+			url:       "/logs/2024-01-15/",
+			recursive: true,
+			want:      []string{"api.log api", "old/batch.log batch", "web.log.gz web"},
+		},
+		{
+			name:      "partial name, recursive",
+			url:       "/logs/2024-01-1",
+			recursive: true,
+			want:      []string{"2024-01-15/api.log api", "2024-01-15/old/batch.log batch", "2024-01-15/web.log.gz web", "2024-01-16/api.log next day"},
+		},
+		{
+			name: "single object",
+			url:  "/logs/2024-01-16/api.log",
+			want: []string{" next day"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := listInputFiles([]string{root + tt.url}, tt.recursive, "name")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			err = readFiles(files, "name", func(line, label string) {
+				entry, _ := parseLine(line)
+				got = append(got, label+" "+entry.Message)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := listInputFiles([]string{strings.Replace(root, "bucket", "other", 1) + "/"}, false, "name"); err == nil {
+		t.Error("listInputFiles() accepted a missing bucket")
+	}
+	_, err := listInputFiles([]string{root + "/logs/2024-01-1"}, false, "name")
+	if err == nil || !strings.Contains(err.Error(), "no objects found (use --recursive") {
+		t.Errorf("listInputFiles() without matches: error = %v", err)
+	}
+}
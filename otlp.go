@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxOTLPRequestSize bounds the export requests the OTLP receivers read.
+const maxOTLPRequestSize = 64 << 20
+
+// otlpGRPCPath is the gRPC method OpenTelemetry SDKs export logs with.
+const otlpGRPCPath = "/opentelemetry.proto.collector.logs.v1.LogsService/Export"
+
+// otlpLogsSchema is the part of the OTLP logs protocol logpipe reads. The
+// JSON names are those of OTLP/JSON, so both encodings decode to the same
+// shape.
+const otlpLogsSchema = `
+syntax = "proto3";
+package opentelemetry.proto;
+
+message ExportLogsServiceRequest {
+  repeated ResourceLogs resource_logs = 1 [json_name = "resourceLogs"];
+}
+
+message ResourceLogs {
+  Resource resource = 1;
+  repeated ScopeLogs scope_logs = 2 [json_name = "scopeLogs"];
+}
+
+message Resource {
+  repeated KeyValue attributes = 1;
+}
+
+message ScopeLogs {
+  InstrumentationScope scope = 1;
+  repeated LogRecord log_records = 2 [json_name = "logRecords"];
+}
+
+message InstrumentationScope {
+  string name = 1;
+  string version = 2;
+}
+
+message LogRecord {
+  fixed64 time_unix_nano = 1 [json_name = "timeUnixNano"];
+  fixed64 observed_time_unix_nano = 11 [json_name = "observedTimeUnixNano"];
+  int32 severity_number = 2 [json_name = "severityNumber"];
+  string severity_text = 3 [json_name = "severityText"];
+  AnyValue body = 5;
+  repeated KeyValue attributes = 6;
+  bytes trace_id = 9 [json_name = "traceId"];
+  bytes span_id = 10 [json_name = "spanId"];
+  string event_name = 12 [json_name = "eventName"];
+}
+
+message KeyValue {
+  string key = 1;
+  AnyValue value = 2;
+}
+
+message AnyValue {
+  oneof value {
+    string string_value = 1 [json_name = "stringValue"];
+    bool bool_value = 2 [json_name = "boolValue"];
+    int64 int_value = 3 [json_name = "intValue"];
+    double double_value = 4 [json_name = "doubleValue"];
+    ArrayValue array_value = 5 [json_name = "arrayValue"];
+    KeyValueList kvlist_value = 6 [json_name = "kvlistValue"];
+    bytes bytes_value = 7 [json_name = "bytesValue"];
+  }
+}
+
+message ArrayValue {
+  repeated AnyValue values = 1;
+}
+
+message KeyValueList {
+  repeated KeyValue values = 1;
+}
+`
+
+// otlpDecoder decodes protobuf export requests, parsed from
+// otlpLogsSchema on first use.
+var otlpDecoder = sync.OnceValues(func() (*protoDecoder, error) {
+	schema, err := parseProtoSchema(otlpLogsSchema)
+	if err != nil {
+		return nil, err
+	}
+	return schema.decoderFor("ExportLogsServiceRequest")
+})
+
+// otlpSeverities names the ranges of OTLP severity numbers, four numbers
+// each from TRACE (1) on.
+var otlpSeverities = []string{"trace", "debug", "info", "warn", "error", "fatal"}
+
+// decodeOTLPRequest decodes a protobuf or, with isJSON, an OTLP/JSON
+// export request.
+func decodeOTLPRequest(data []byte, isJSON bool) (map[string]interface{}, error) {
+	if isJSON {
+		var request map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&request); err != nil {
+			return nil, err
+		}
+		return request, nil
+	}
+	decoder, err := otlpDecoder()
+	if err != nil {
+		return nil, err
+	}
+	return decoder.Decode(data)
+}
+
+// handleOTLPRequest hands over the log records of an export request as ECS
+// JSON lines, labeled with their service name.
+func handleOTLPRequest(request map[string]interface{}, handle lineHandler) {
+	for _, resourceLogs := range otlpList(request["resourceLogs"]) {
+		resourceLogs, _ := resourceLogs.(map[string]interface{})
+		resource, _ := resourceLogs["resource"].(map[string]interface{})
+		resourceAttributes := otlpAttributes(resource["attributes"])
+		label, _ := resourceAttributes["service.name"].(string)
+		for _, scopeLogs := range otlpList(resourceLogs["scopeLogs"]) {
+			scopeLogs, _ := scopeLogs.(map[string]interface{})
+			scope, _ := scopeLogs["scope"].(map[string]interface{})
+			scopeName, _ := scope["name"].(string)
+			for _, record := range otlpList(scopeLogs["logRecords"]) {
+				record, _ := record.(map[string]interface{})
+				handle(otlpLine(record, resourceAttributes, scopeName), label)
+			}
+		}
+	}
+}
+
+// otlpLine turns a log record into an ECS JSON line: resource attributes,
+// then the record's attributes and body, with its time, severity, trace
+// context and scope mapped onto their ECS fields.
+func otlpLine(record, resourceAttributes map[string]interface{}, scopeName string) string {
+	doc := map[string]interface{}{}
+	for _, key := range sortedKeys(resourceAttributes) {
+		setEntryField(doc, key, resourceAttributes[key])
+	}
+	attributes := otlpAttributes(record["attributes"])
+	for _, key := range sortedKeys(attributes) {
+		setEntryField(doc, key, attributes[key])
+	}
+
+	switch body := otlpValue(record["body"]).(type) {
+	case nil:
+	case map[string]interface{}:
+		for _, key := range sortedKeys(body) {
+			setEntryField(doc, key, body[key])
+		}
+	case string:
+		doc["message"] = body
+	default:
+		doc["message"] = fmt.Sprint(body)
+	}
+
+	ns := otlpUint(record["timeUnixNano"])
+	if ns == 0 {
+		ns = otlpUint(record["observedTimeUnixNano"])
+	}
+	if ns > 0 {
+		doc["@timestamp"] = time.Unix(0, int64(ns)).UTC().Format(time.RFC3339Nano)
+	}
+	if text, _ := record["severityText"].(string); text != "" {
+		doc["log.level"] = strings.ToLower(text)
+	} else if n := otlpUint(record["severityNumber"]); n >= 1 && int(n-1)/4 < len(otlpSeverities) {
+		doc["log.level"] = otlpSeverities[(n-1)/4]
+	}
+	if id := otlpID(record["traceId"]); id != "" {
+		setEntryField(doc, "trace.id", id)
+	}
+	if id := otlpID(record["spanId"]); id != "" {
+		setEntryField(doc, "span.id", id)
+	}
+	if scopeName != "" {
+		if _, ok := lookupField(doc, "log.logger"); !ok {
+			setEntryField(doc, "log.logger", scopeName)
+		}
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func otlpList(value interface{}) []interface{} {
+	list, _ := value.([]interface{})
+	return list
+}
+
+// otlpAttributes turns a list of KeyValues into a map.
+func otlpAttributes(value interface{}) map[string]interface{} {
+	attributes := map[string]interface{}{}
+	for _, kv := range otlpList(value) {
+		kv, _ := kv.(map[string]interface{})
+		if key, _ := kv["key"].(string); key != "" {
+			attributes[key] = otlpValue(kv["value"])
+		}
+	}
+	return attributes
+}
+
+// otlpValue unpacks an AnyValue. OTLP/JSON sends 64-bit integers as strings
+// and bytes as base64.
+func otlpValue(value interface{}) interface{} {
+	anyValue, _ := value.(map[string]interface{})
+	for key, v := range anyValue {
+		switch key {
+		case "stringValue", "boolValue":
+			return v
+		case "intValue":
+			if s, ok := v.(string); ok {
+				if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+					return n
+				}
+			}
+			return v
+		case "doubleValue":
+			return v
+		case "bytesValue":
+			if b, ok := v.([]byte); ok {
+				return base64.StdEncoding.EncodeToString(b)
+			}
+			return v
+		case "arrayValue":
+			array, _ := v.(map[string]interface{})
+			values := []interface{}{}
+			for _, item := range otlpList(array["values"]) {
+				values = append(values, otlpValue(item))
+			}
+			return values
+		case "kvlistValue":
+			kvlist, _ := v.(map[string]interface{})
+			return otlpAttributes(kvlist["values"])
+		}
+	}
+	return nil
+}
+
+// otlpUint reads an integer decoded from protobuf or, as a string or
+// number, from JSON.
+func otlpUint(value interface{}) uint64 {
+	switch v := value.(type) {
+	case uint64:
+		return v
+	case int32:
+		if v > 0 {
+			return uint64(v)
+		}
+	case json.Number:
+		n, _ := strconv.ParseUint(v.String(), 10, 64)
+		return n
+	case string:
+		n, _ := strconv.ParseUint(v, 10, 64)
+		return n
+	}
+	return 0
+}
+
+// otlpID formats a trace or span ID: raw bytes in protobuf, already hex in
+// OTLP/JSON.
+func otlpID(value interface{}) string {
+	switch v := value.(type) {
+	case []byte:
+		if len(v) > 0 && !bytes.Equal(v, make([]byte, len(v))) {
+			return hex.EncodeToString(v)
+		}
+	case string:
+		return strings.ToLower(v)
+	}
+	return ""
+}
+
+// readOTLPBody reads a request body, gunzipping it when its
+// Content-Encoding says so.
+func readOTLPBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	body := io.Reader(http.MaxBytesReader(w, r.Body, maxOTLPRequestSize))
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		body = io.LimitReader(zr, maxOTLPRequestSize)
+	}
+	return io.ReadAll(body)
+}
+
+// otlpHTTPHandler receives OTLP/HTTP exports on /v1/logs, in protobuf or
+// JSON.
+func otlpHTTPHandler(handle lineHandler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/logs", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		isJSON := strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+		data, err := readOTLPBody(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		request, err := decodeOTLPRequest(data, isJSON)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		handleOTLPRequest(request, handle)
+		// An empty ExportLogsServiceResponse: full success
+		if isJSON {
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, "{}")
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	})
+	return mux
+}
+
+// otlpGRPCHandler receives OTLP/gRPC exports. It speaks just enough gRPC
+// for the unary Export call, and must be served over HTTP/2.
+func otlpGRPCHandler(handle lineHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != otlpGRPCPath {
+			writeGRPCStatus(w, 12, "unknown method "+r.URL.Path)
+			return
+		}
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxOTLPRequestSize))
+		if err == nil {
+			data, err = unframeGRPC(data, r.Header.Get("Grpc-Encoding"))
+		}
+		var request map[string]interface{}
+		if err == nil {
+			request, err = decodeOTLPRequest(data, false)
+		}
+		if err != nil {
+			writeGRPCStatus(w, 3, err.Error())
+			return
+		}
+		handleOTLPRequest(request, handle)
+
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		// An empty ExportLogsServiceResponse, uncompressed
+		w.Write([]byte{0, 0, 0, 0, 0})
+		w.Header().Set("Grpc-Status", "0")
+		w.Header().Set("Grpc-Message", "")
+	})
+}
+
+// unframeGRPC returns the message of a unary gRPC request body: a
+// compressed flag and a length before it.
+func unframeGRPC(data []byte, encoding string) ([]byte, error) {
+	if len(data) < 5 {
+		return nil, errors.New("truncated gRPC message")
+	}
+	size := binary.BigEndian.Uint32(data[1:5])
+	if uint32(len(data)-5) < size {
+		return nil, errors.New("truncated gRPC message")
+	}
+	message := data[5 : 5+size]
+	if data[0] == 0 {
+		return message, nil
+	}
+	if encoding != "gzip" {
+		return nil, fmt.Errorf("unsupported gRPC encoding %q", encoding)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(message))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(io.LimitReader(zr, maxOTLPRequestSize))
+}
+
+// writeGRPCStatus fails a call with a trailers-only response.
+func writeGRPCStatus(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	w.Header().Set("Grpc-Message", url.PathEscape(message))
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// otlpTestLine is the line otlpTestRequest and otlpTestJSON render to.
+const otlpTestLine = `{"@timestamp":"2024-01-15T14:00:00Z","http":{"response":{"status_code":500}},` +
+	`"log":{"logger":"checkout"},"log.level":"error","message":"payment failed","service":{"name":"api"},` +
+	`"span":{"id":"0102030405060708"},"trace":{"id":"0af7651916cd43dd8448eb211c80319c"}}`
+
+const otlpTestJSON = `{"resourceLogs":[{
+	"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"api"}}]},
+	"scopeLogs":[{"scope":{"name":"checkout"},"logRecords":[{
+		"timeUnixNano":"1705327200000000000","severityNumber":17,
+		"body":{"stringValue":"payment failed"},
+		"attributes":[{"key":"http.response.status_code","value":{"intValue":"500"}}],
+		"traceId":"0AF7651916CD43DD8448EB211C80319C","spanId":"0102030405060708"
+	}]}]
+}]}`
+
+// otlpTestRequest encodes the protobuf ExportLogsServiceRequest that
+// otlpTestJSON is the JSON form of.
+func otlpTestRequest() []byte {
+	keyValue := func(key string, value []byte) []byte {
+		return protoAppend(protoAppend(nil, 1, wireBytes, key), 2, wireBytes, value)
+	}
+	stringValue := func(s string) []byte { return protoAppend(nil, 1, wireBytes, s) }
+
+	record := binary.AppendUvarint(nil, 1<<3|wireFixed64)
+	record = binary.LittleEndian.AppendUint64(record, 1705327200000000000)
+	record = protoAppend(record, 2, wireVarint, uint64(17))
+	record = protoAppend(record, 5, wireBytes, stringValue("payment failed"))
+	record = protoAppend(record, 6, wireBytes, keyValue("http.response.status_code", protoAppend(nil, 3, wireVarint, uint64(500))))
+	record = protoAppend(record, 9, wireBytes, []byte{0x0a, 0xf7, 0x65, 0x19, 0x16, 0xcd, 0x43, 0xdd, 0x84, 0x48, 0xeb, 0x21, 0x1c, 0x80, 0x31, 0x9c})
+	record = protoAppend(record, 10, wireBytes, []byte{1, 2, 3, 4, 5, 6, 7, 8})
+
+	scopeLogs := protoAppend(nil, 1, wireBytes, protoAppend(nil, 1, wireBytes, "checkout"))
+	scopeLogs = protoAppend(scopeLogs, 2, wireBytes, record)
+	resource := protoAppend(nil, 1, wireBytes, keyValue("service.name", stringValue("api")))
+	resourceLogs := protoAppend(protoAppend(nil, 1, wireBytes, resource), 2, wireBytes, scopeLogs)
+	return protoAppend(nil, 1, wireBytes, resourceLogs)
+}
+
+func TestOTLPLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		record string
+		want   string
+	}{
+		{
+			name:   "severity number",
+			record: `{"severityNumber":10,"body":{"stringValue":"ready"}}`,
+			want:   `{"log.level":"info","message":"ready"}`,
+		},
+		{
+			name:   "severity text wins",
+			record: `{"severityNumber":10,"severityText":"NOTICE","body":{"stringValue":"ready"}}`,
+			want:   `{"log.level":"notice","message":"ready"}`,
+		},
+		{
+			name:   "observed time",
+			record: `{"observedTimeUnixNano":"1705327200500000000"}`,
+			want:   `{"@timestamp":"2024-01-15T14:00:00.5Z"}`,
+		},
+		{
+			name:   "structured body",
+			record: `{"body":{"kvlistValue":{"values":[{"key":"message","value":{"stringValue":"hi"}},{"key":"tags","value":{"arrayValue":{"values":[{"stringValue":"a"},{"boolValue":true}]}}}]}}}`,
+			want:   `{"message":"hi","tags":["a",true]}`,
+		},
+		{
+			name:   "other body",
+			record: `{"body":{"doubleValue":1.5}}`,
+			want:   `{"message":"1.5"}`,
+		},
+		{
+			name:   "zero trace ID",
+			record: `{"traceId":"","body":{"stringValue":"x"}}`,
+			want:   `{"message":"x"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := decodeOTLPRequest([]byte(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[`+tt.record+`]}]}]}`), true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			handleOTLPRequest(request, func(line, label string) { got = append(got, line) })
+			if !reflect.DeepEqual(got, []string{tt.want}) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOTLPHTTP(t *testing.T) {
+	var gzipped bytes.Buffer
+	zw := gzip.NewWriter(&gzipped)
+	zw.Write(otlpTestRequest())
+	zw.Close()
+
+	tests := []struct {
+		name        string
+		contentType string
+		encoding    string
+		body        []byte
+		wantStatus  int
+		wantBody    string
+	}{
+		{name: "protobuf", contentType: "application/x-protobuf", body: otlpTestRequest(), wantStatus: http.StatusOK},
+		{name: "gzipped protobuf", contentType: "application/x-protobuf", encoding: "gzip", body: gzipped.Bytes(), wantStatus: http.StatusOK},
+		{name: "json", contentType: "application/json", body: []byte(otlpTestJSON), wantStatus: http.StatusOK, wantBody: "{}"},
+		{name: "invalid protobuf", contentType: "application/x-protobuf", body: []byte{0x0a, 0x05}, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			server := httptest.NewServer(otlpHTTPHandler(func(line, label string) {
+				got = append(got, label+" "+line)
+			}))
+			defer server.Close()
+
+			req, _ := http.NewRequest(http.MethodPost, server.URL+"/v1/logs", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", resp.StatusCode, tt.wantStatus, body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+			if want := []string{"api " + otlpTestLine}; !reflect.DeepEqual(got, want) {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestOTLPGRPC(t *testing.T) {
+	frame := func(message []byte) []byte {
+		return append(binary.BigEndian.AppendUint32([]byte{0}, uint32(len(message))), message...)
+	}
+	tests := []struct {
+		name        string
+		path        string
+		body        []byte
+		wantStatus  string
+		wantMessage string
+	}{
+		{name: "export", path: otlpGRPCPath, body: frame(otlpTestRequest()), wantStatus: "0"},
+		{name: "unknown method", path: "/opentelemetry.proto.collector.trace.v1.TraceService/Export", body: frame(nil), wantStatus: "12"},
+		{name: "truncated message", path: otlpGRPCPath, body: []byte{0, 0, 0, 0, 9}, wantStatus: "3", wantMessage: "truncated gRPC message"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			server := httptest.NewServer(h2c.NewHandler(otlpGRPCHandler(func(line, label string) {
+				got = append(got, label+" "+line)
+			}), &http2.Server{}))
+			defer server.Close()
+			client := &http.Client{Transport: &http2.Transport{
+				AllowHTTP: true,
+				DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+					return net.Dial(network, addr)
+				},
+			}}
+
+			req, _ := http.NewRequest(http.MethodPost, server.URL+tt.path, bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/grpc")
+			req.Header.Set("TE", "trailers")
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.ProtoMajor != 2 {
+				t.Fatalf("protocol = %s, want HTTP/2", resp.Proto)
+			}
+
+			status, message := resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+			if status == "" {
+				// A trailers-only response
+				status, message = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+			}
+			if status != tt.wantStatus {
+				t.Fatalf("grpc-status = %q (%s), want %q", status, message, tt.wantStatus)
+			}
+			if !strings.Contains(message, strings.ReplaceAll(tt.wantMessage, " ", "%20")) {
+				t.Errorf("grpc-message = %q, want %q", message, tt.wantMessage)
+			}
+			if tt.wantStatus != "0" {
+				return
+			}
+			if !bytes.Equal(body, []byte{0, 0, 0, 0, 0}) {
+				t.Errorf("body = %v, want an empty message", body)
+			}
+			if want := []string{"api " + otlpTestLine}; !reflect.DeepEqual(got, want) {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestConsumeListen(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := consumeListen(ctx, listenOptions{}, func(line, label string) {}); err == nil || !strings.Contains(err.Error(), "nothing to listen on") {
+		t.Errorf("no addresses: error = %v", err)
+	}
+
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+	opts := listenOptions{forward: "127.0.0.1:0", otlpHTTP: taken.Addr().String()}
+	if err := consumeListen(ctx, opts, func(line, label string) {}); err == nil || !strings.Contains(err.Error(), "address already in use") {
+		t.Errorf("address in use: error = %v", err)
+	}
+}
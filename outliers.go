@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// outlierMinSamples is how many recent requests an endpoint needs
+	// before its entries can be flagged.
+	outlierMinSamples = 20
+	// outlierMaxSamples caps the samples kept per endpoint.
+	outlierMaxSamples = 2000
+	// outlierSampleSize is the memory a sample takes.
+	outlierSampleSize = 32
+)
+
+// latencyOutliers is set by --latency-outliers.
+var latencyOutliers *latencyBaseline
+
+// latencyBaseline keeps the recent durations of each endpoint to flag
+// requests slower than their 99th percentile.
+type latencyBaseline struct {
+	window    time.Duration
+	endpoints map[string]*latencySamples
+}
+
+// latencySamples are the durations of an endpoint's recent requests, in
+// the order they were seen.
+type latencySamples struct {
+	at []time.Time
+	ms []float64
+}
+
+func newLatencyBaseline(window time.Duration) *latencyBaseline {
+	return &latencyBaseline{window: window, endpoints: make(map[string]*latencySamples)}
+}
+
+// endpointKey groups HTTP entries by method and url.path_template, or by
+// path when no template is logged.
+func endpointKey(log LogEntry) string {
+	path := log.URL.PathTemplate
+	if path == "" {
+		path = log.URL.Path
+	}
+	return log.HTTP.Request.Method + " " + path
+}
+
+// check compares a duration with the 99th percentile of the endpoint's
+// requests within the window before at, then adds it to them. It returns
+// the percentile and whether the duration is above it.
+func (b *latencyBaseline) check(key string, at time.Time, ms float64) (float64, bool) {
+	s := b.endpoints[key]
+	if s == nil {
+		s = &latencySamples{}
+		b.endpoints[key] = s
+	}
+	start := 0
+	for start < len(s.at) && at.Sub(s.at[start]) > b.window {
+		start++
+	}
+	if drop := len(s.at) - start + 1 - outlierMaxSamples; drop > 0 {
+		start += drop
+	}
+	// Under --memory-limit, endpoints keep fewer samples, down to the
+	// minimum needed to flag entries
+	for memoryLimit.exceeded() && len(s.at)-start > outlierMinSamples {
+		memoryLimit.record("latency samples forgotten", 1)
+		start++
+	}
+	memoryLimit.shrink(start * outlierSampleSize)
+	s.at, s.ms = s.at[start:], s.ms[start:]
+
+	var p99 float64
+	outlier := false
+	if len(s.ms) >= outlierMinSamples {
+		p99 = percentile(s.ms, 0.99)
+		outlier = ms > p99
+	}
+	s.at = append(s.at, at)
+	s.ms = append(s.ms, ms)
+	memoryLimit.grow(outlierSampleSize)
+	return p99, outlier
+}
+
+// percentile returns the q-th quantile of values, by nearest rank.
+func percentile(values []float64, q float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := int(q*float64(len(sorted))+0.5) - 1
+	return sorted[min(max(rank, 0), len(sorted)-1)]
+}
+
+// outlierMarker flags a request slower than its endpoint's recent p99.
+func outlierMarker(log LogEntry, at time.Time) string {
+	ms := float64(log.Event.Duration) / 1e6
+	p99, outlier := latencyOutliers.check(endpointKey(log), at, ms)
+	if !outlier {
+		return ""
+	}
+	return " " + errorPaint.sprint("▲ slow (p99 "+formatMillis(p99)+")")
+}
+
+// formatMillis shows a duration in milliseconds without needless decimals.
+func formatMillis(ms float64) string {
+	if ms >= 10 {
+		return fmt.Sprintf("%.0fms", ms)
+	}
+	return fmt.Sprintf("%.3gms", ms)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyBaseline(t *testing.T) {
+	b := newLatencyBaseline(10 * time.Minute)
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	// Too few samples to judge
+	for i := 0; i < outlierMinSamples; i++ {
+		if _, outlier := b.check("GET /users/{id}", start.Add(time.Duration(i)*time.Second), float64(10+i%5)); outlier {
+			t.Fatalf("sample %d flagged before the baseline was known", i)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		at      time.Duration
+		ms      float64
+		outlier bool
+	}{
+		{"typical", "GET /users/{id}", time.Minute, 12, false},
+		{"slow", "GET /users/{id}", time.Minute, 250, true},
+		{"other endpoint", "POST /users", time.Minute, 250, false},
+		{"baseline expired", "GET /users/{id}", time.Hour, 900, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p99, outlier := b.check(tt.key, start.Add(tt.at), tt.ms)
+			if outlier != tt.outlier {
+				t.Errorf("check(%v) = %v, %v, want outlier %v", tt.ms, p99, outlier, tt.outlier)
+			}
+		})
+	}
+}
+
+func TestLatencyBaselineCap(t *testing.T) {
+	b := newLatencyBaseline(time.Hour)
+	at := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < outlierMaxSamples+50; i++ {
+		b.check("GET /", at, 1)
+	}
+	if n := len(b.endpoints["GET /"].ms); n != outlierMaxSamples {
+		t.Errorf("kept %d samples, want %d", n, outlierMaxSamples)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := make([]float64, 100)
+	for i := range values {
+		values[i] = float64(100 - i)
+	}
+	tests := []struct {
+		q    float64
+		want float64
+	}{
+		{0.5, 50},
+		{0.99, 99},
+		{1, 100},
+		{0, 1},
+	}
+	for _, tt := range tests {
+		if got := percentile(values, tt.q); got != tt.want {
+			t.Errorf("percentile(%v) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+	if values[0] != 100 {
+		t.Error("percentile() sorted its input")
+	}
+}
+
+func TestEndpointKey(t *testing.T) {
+	var log LogEntry
+	log.HTTP.Request.Method = "GET"
+	log.URL.Path = "/users/42"
+	if got := endpointKey(log); got != "GET /users/42" {
+		t.Errorf("endpointKey() = %q", got)
+	}
+	log.URL.PathTemplate = "/users/{id}"
+	if got := endpointKey(log); got != "GET /users/{id}" {
+		t.Errorf("endpointKey() = %q", got)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"time"
+)
+
+// maxPendingRequests bounds the requests kept waiting for their response;
+// the oldest are forgotten first, and sooner under --memory-limit.
+const maxPendingRequests = 10000
+
+// requestPairer links HTTP responses to the requests logged before them
+// as separate entries with the same http.request.id.
+type requestPairer struct {
+	pending map[string]LogEntry
+	order   []string
+}
+
+func newRequestPairer() *requestPairer {
+	return &requestPairer{pending: make(map[string]LogEntry)}
+}
+
+// pair remembers an entry logging a request (a method but no status), or
+// completes an entry logging the response to a remembered one: the
+// method, path and user agent come from the request, and the duration,
+// when not logged, is the time between the two. The response is then
+// rendered as an HTTP access line linked to its request.
+func (p *requestPairer) pair(entry *LogEntry) {
+	id := entry.HTTP.Request.ID
+	if id == "" {
+		return
+	}
+	if entry.HTTP.Response.StatusCode == 0 {
+		if entry.HTTP.Request.Method != "" {
+			p.remember(id, *entry)
+		}
+		return
+	}
+	request, ok := p.pending[id]
+	if !ok {
+		return
+	}
+	p.forget(id)
+
+	if entry.HTTP.Request.Method == "" {
+		entry.HTTP.Request.Method = request.HTTP.Request.Method
+	}
+	if entry.URL.Path == "" {
+		entry.URL.Path = request.URL.Path
+	}
+	if entry.UserAgent.Original == "" {
+		entry.UserAgent.Original = request.UserAgent.Original
+	}
+	if entry.Source.IP == "" {
+		entry.Source.IP = request.Source.IP
+	}
+	if entry.Event.Duration == 0 {
+		start, err1 := time.Parse(time.RFC3339Nano, request.Timestamp)
+		end, err2 := time.Parse(time.RFC3339Nano, entry.Timestamp)
+		if err1 == nil && err2 == nil && end.After(start) {
+			entry.Event.Duration = int64(end.Sub(start))
+		}
+	}
+	entry.Category = "http"
+	entry.RequestTime = request.Timestamp
+}
+
+func (p *requestPairer) remember(id string, request LogEntry) {
+	if _, ok := p.pending[id]; ok {
+		p.forget(id)
+	} else {
+		p.order = append(p.order, id)
+	}
+	// Only the request's own fields are needed to pair it, not its line
+	request.fields = nil
+	p.pending[id] = request
+	memoryLimit.grow(entrySize(&request))
+	for len(p.pending) > maxPendingRequests || len(p.pending) > 1 && memoryLimit.exceeded() {
+		if _, ok := p.pending[p.order[0]]; ok && len(p.pending) <= maxPendingRequests {
+			memoryLimit.record("pending requests forgotten", 1)
+		}
+		p.forget(p.order[0])
+		p.order = p.order[1:]
+	}
+	// Forget the IDs of requests answered since
+	if len(p.order) > 2*maxPendingRequests {
+		order := p.order[:0]
+		for _, id := range p.order {
+			if _, ok := p.pending[id]; ok {
+				order = append(order, id)
+			}
+		}
+		p.order = order
+	}
+}
+
+// forget stops waiting on the request with the id, if any.
+func (p *requestPairer) forget(id string) {
+	if request, ok := p.pending[id]; ok {
+		memoryLimit.shrink(entrySize(&request))
+		delete(p.pending, id)
+	}
+}
+
+// pairSuffix links a paired response to the time of its request.
+func pairSuffix(log LogEntry) string {
+	if log.RequestTime == "" {
+		return ""
+	}
+	if t, err := time.Parse(time.RFC3339Nano, log.RequestTime); err == nil {
+		return " " + labelPaint.sprint("↩ request "+t.Format("15:04:05.000"))
+	}
+	return " " + labelPaint.sprint("↩ request "+log.RequestTime)
+}
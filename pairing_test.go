@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestRequestPairer(t *testing.T) {
+	entry := func(line string) LogEntry {
+		var e LogEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+	p := newRequestPairer()
+	request := entry(`{"@timestamp":"2024-01-15T14:00:00.100Z","http":{"request":{"id":"a1","method":"POST"}},"url":{"path":"/api/pay"},"user_agent":{"original":"curl/8.0"}}`)
+	p.pair(&request)
+	if request.RequestTime != "" || request.Category != "" {
+		t.Errorf("request changed: %+v", request)
+	}
+
+	tests := []struct {
+		name         string
+		line         string
+		wantMethod   string
+		wantPath     string
+		wantDuration int64
+		wantPaired   bool
+	}{
+		{
+			name:         "response",
+			line:         `{"@timestamp":"2024-01-15T14:00:00.450Z","http":{"request":{"id":"a1"},"response":{"status_code":502}}}`,
+			wantMethod:   "POST",
+			wantPath:     "/api/pay",
+			wantDuration: 350e6,
+			wantPaired:   true,
+		},
+		{
+			name: "answered already",
+			line: `{"@timestamp":"2024-01-15T14:00:00.500Z","http":{"request":{"id":"a1"},"response":{"status_code":200}}}`,
+		},
+		{
+			name: "unknown request",
+			line: `{"@timestamp":"2024-01-15T14:00:00.500Z","http":{"request":{"id":"b2"},"response":{"status_code":200}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := entry(tt.line)
+			p.pair(&response)
+			if paired := response.RequestTime != ""; paired != tt.wantPaired {
+				t.Fatalf("paired = %v, want %v", paired, tt.wantPaired)
+			}
+			if response.HTTP.Request.Method != tt.wantMethod || response.URL.Path != tt.wantPath || response.Event.Duration != tt.wantDuration {
+				t.Errorf("response = %s %s %d, want %s %s %d", response.HTTP.Request.Method, response.URL.Path, response.Event.Duration,
+					tt.wantMethod, tt.wantPath, tt.wantDuration)
+			}
+			if tt.wantPaired && (response.Category != "http" || response.UserAgent.Original != "curl/8.0") {
+				t.Errorf("response category %q, user agent %q", response.Category, response.UserAgent.Original)
+			}
+		})
+	}
+
+	// A logged duration is kept
+	p.pair(&request)
+	response := entry(`{"@timestamp":"2024-01-15T14:00:01Z","http":{"request":{"id":"a1"},"response":{"status_code":200}},"event":{"duration":5000}}`)
+	p.pair(&response)
+	if response.Event.Duration != 5000 {
+		t.Errorf("duration = %d, want the logged 5000", response.Event.Duration)
+	}
+}
+
+func TestPendingRequestsBounded(t *testing.T) {
+	p := newRequestPairer()
+	for i := 0; i < maxPendingRequests+10; i++ {
+		var request LogEntry
+		request.HTTP.Request.ID = strconv.Itoa(i)
+		request.HTTP.Request.Method = "GET"
+		p.pair(&request)
+	}
+	if len(p.pending) != maxPendingRequests {
+		t.Errorf("pending = %d, want %d", len(p.pending), maxPendingRequests)
+	}
+}
+
+func TestPairSuffix(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+	if got := pairSuffix(LogEntry{}); got != "" {
+		t.Errorf("unpaired suffix = %q", got)
+	}
+	if got, want := pairSuffix(LogEntry{RequestTime: "2024-01-15T14:00:00.1Z"}), " ↩ request 14:00:00.100"; got != want {
+		t.Errorf("pairSuffix() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// patternWildcard stands for the variable tokens of a pattern.
+const patternWildcard = "<*>"
+
+// patternSimilarity is the share of tokens a message must have in common
+// with a pattern to join it.
+const patternSimilarity = 0.5
+
+// patternOptions configures `logpipe patterns`.
+type patternOptions struct {
+	top int
+}
+
+func (o *patternOptions) register(fs *flag.FlagSet) {
+	fs.IntVar(&o.top, "top", 20, "Number of patterns to show (0 for all)")
+}
+
+// logPattern is a message template, with variable tokens replaced by
+// patternWildcard, and the number of entries matching it.
+type logPattern struct {
+	level  string
+	tokens []string
+	count  int
+}
+
+// patternMiner clusters messages into patterns the way Drain does: messages
+// are grouped by level, length and first token, then join the most similar
+// pattern of their group, turning the tokens they differ on into
+// wildcards.
+type patternMiner struct {
+	groups   map[string][]*logPattern
+	patterns []*logPattern
+	entries  int
+}
+
+func newPatternMiner() *patternMiner {
+	return &patternMiner{groups: make(map[string][]*logPattern)}
+}
+
+// variableTokenRegex matches tokens that are variables whatever the
+// message: anything with a digit, hex strings and quoted values.
+var variableTokenRegex = regexp.MustCompile(`\d|^(0x)?[0-9a-fA-F]{8,}$|^["'].*["']$`)
+
+// patternTokens splits a message into tokens, with obvious variables such
+// as numbers, IDs and durations already replaced by wildcards.
+func patternTokens(message string) []string {
+	tokens := strings.Fields(message)
+	for i, token := range tokens {
+		word := strings.TrimFunc(token, func(r rune) bool {
+			return unicode.IsPunct(r) && r != '"' && r != '\''
+		})
+		if variableTokenRegex.MatchString(word) {
+			tokens[i] = patternWildcard
+		}
+	}
+	return tokens
+}
+
+// add files a message under its pattern.
+func (m *patternMiner) add(level, message string) {
+	tokens := patternTokens(message)
+	if len(tokens) == 0 {
+		return
+	}
+	m.entries++
+	key := fmt.Sprintf("%s\x00%d\x00%s", normalizeLevel(level), len(tokens), tokens[0])
+
+	var best *logPattern
+	bestScore := 0.0
+	for _, p := range m.groups[key] {
+		if score := similarity(p.tokens, tokens); score > bestScore {
+			best, bestScore = p, score
+		}
+	}
+	if best == nil || bestScore < patternSimilarity {
+		p := &logPattern{level: level, tokens: tokens, count: 1}
+		m.groups[key] = append(m.groups[key], p)
+		m.patterns = append(m.patterns, p)
+		memoryLimit.grow(patternSize(p))
+		if memoryLimit.exceeded() && len(m.patterns) > 1 {
+			m.forgetRarest()
+		}
+		return
+	}
+	best.count++
+	for i, token := range tokens {
+		if best.tokens[i] != token {
+			best.tokens[i] = patternWildcard
+		}
+	}
+}
+
+// forgetRarest forgets the least frequent quarter of the patterns found so
+// far, to give memory back. The entries matching them still count in the
+// totals.
+func (m *patternMiner) forgetRarest() {
+	patterns := append([]*logPattern(nil), m.patterns...)
+	sort.SliceStable(patterns, func(i, j int) bool {
+		return patterns[i].count < patterns[j].count
+	})
+	forget := make(map[*logPattern]bool)
+	for _, p := range patterns[:max(len(patterns)/4, 1)] {
+		forget[p] = true
+		memoryLimit.shrink(patternSize(p))
+	}
+	memoryLimit.record("rare patterns forgotten", len(forget))
+
+	kept := m.patterns[:0]
+	for _, p := range m.patterns {
+		if !forget[p] {
+			kept = append(kept, p)
+		}
+	}
+	m.patterns = kept
+	for key, group := range m.groups {
+		kept := group[:0]
+		for _, p := range group {
+			if !forget[p] {
+				kept = append(kept, p)
+			}
+		}
+		if len(kept) == 0 {
+			delete(m.groups, key)
+		} else {
+			m.groups[key] = kept
+		}
+	}
+}
+
+// patternSize estimates the memory a pattern takes.
+func patternSize(p *logPattern) int {
+	size := 64 + len(p.level)
+	for _, token := range p.tokens {
+		size += 16 + len(token)
+	}
+	return size
+}
+
+// similarity is the share of a pattern's tokens a message has in common
+// with it. Wildcards match anything.
+func similarity(pattern, tokens []string) float64 {
+	same := 0
+	for i, token := range pattern {
+		if token == tokens[i] || token == patternWildcard {
+			same++
+		}
+	}
+	return float64(same) / float64(len(pattern))
+}
+
+// write prints the top patterns, most frequent first.
+func (m *patternMiner) write(w io.Writer, name string, top int) {
+	patterns := append([]*logPattern(nil), m.patterns...)
+	sort.SliceStable(patterns, func(i, j int) bool {
+		return patterns[i].count > patterns[j].count
+	})
+	noun := "patterns"
+	if len(patterns) == 1 {
+		noun = "pattern"
+	}
+	fmt.Fprintf(w, "%s: %d entries, %d %s\n", name, m.entries, len(patterns), noun)
+	if top > 0 && len(patterns) > top {
+		patterns = patterns[:top]
+	}
+	for _, p := range patterns {
+		level := ""
+		if p.level != "" {
+			level = getLevelColor(p.level).Sprintf("[%s] ", padWidth(levelLabel(p.level), 4))
+		}
+		fmt.Fprintf(w, "  %7d %5.1f%%  %s%s\n", p.count, 100*float64(p.count)/float64(m.entries), level,
+			strings.ReplaceAll(strings.Join(p.tokens, " "), patternWildcard, labelColor.Sprint(patternWildcard)))
+	}
+}
+
+// patternMessage returns the text of a line to cluster: the message of
+// parsed entries, or the line without its leading timestamp.
+func patternMessage(line string) (level, message string) {
+	if entry, ok := parseLine(line); ok {
+		message = entry.Message
+		if message == "" && entry.HTTP.Request.Method != "" {
+			message = fmt.Sprintf("%s %d %s", entry.HTTP.Request.Method, entry.HTTP.Response.StatusCode, entry.URL.Path)
+		}
+		return entry.Level, message
+	}
+	_, rest, _ := cutLeadingTime(line)
+	return "", rest
+}
+
+// runPatterns reports the most common message patterns of log files, or of
+// stdin when none are given.
+func runPatterns(opts patternOptions, files []string, w io.Writer) int {
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	for _, name := range files {
+		miner := newPatternMiner()
+		err := readLines(name, func(line string) {
+			miner.add(patternMessage(line))
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", name, err)
+			return 2
+		}
+		if name == "-" {
+			name = "stdin"
+		}
+		miner.write(w, name, opts.top)
+		for _, p := range miner.patterns {
+			memoryLimit.shrink(patternSize(p))
+		}
+	}
+	memoryLimit.report(os.Stderr)
+	return 0
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestPatternTokens(t *testing.T) {
+	tests := []struct {
+		message string
+		want    []string
+	}{
+		{"request handled in 12ms", []string{"request", "handled", "in", "<*>"}},
+		{"user 42 logged in.", []string{"user", "<*>", "logged", "in."}},
+		{`lookup "alice" failed (deadbeefcafe)`, []string{"lookup", "<*>", "failed", "<*>"}},
+		{"connection to db-1 refused", []string{"connection", "to", "<*>", "refused"}},
+		{"  ", []string{}},
+	}
+	for _, tt := range tests {
+		if got := patternTokens(tt.message); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("patternTokens(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestPatternMiner(t *testing.T) {
+	m := newPatternMiner()
+	for _, msg := range []string{
+		"session opened for alice",
+		"session opened for bob",
+		"session closed for bob",
+		"cache warmed",
+	} {
+		m.add("info", msg)
+	}
+	m.add("error", "session opened for alice")
+	m.add("info", "")
+
+	var got []string
+	for _, p := range m.patterns {
+		got = append(got, strings.Join(p.tokens, " "))
+	}
+	want := []string{"session <*> for <*>", "cache warmed", "session opened for alice"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("patterns = %q, want %q", got, want)
+	}
+	if m.entries != 5 || m.patterns[0].count != 3 {
+		t.Errorf("entries = %d, first count = %d", m.entries, m.patterns[0].count)
+	}
+}
+
+func TestRunPatterns(t *testing.T) {
+	defer func(noColor bool) { color.NoColor = noColor }(color.NoColor)
+	color.NoColor = true
+
+	input := strings.Join([]string{
+		`{"log.level":"info","message":"request handled in 12ms for user alice"}`,
+		`{"log.level":"info","message":"request handled in 15ms for user bob"}`,
+		`{"log.level":"error","message":"connection to 10.0.0.3:5432 refused"}`,
+		`2024-01-15 10:00:00 worker started`,
+	}, "\n")
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if code := runPatterns(patternOptions{top: 2}, []string{path}, &out); code != 0 {
+		t.Fatalf("runPatterns() = %d", code)
+	}
+	want := []string{
+		path + ": 4 entries, 3 patterns",
+		"2  50.0%  [info] request handled in <*> for user <*>",
+		"1  25.0%  [erro] connection to <*> refused",
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("output =\n%s", out.String())
+	}
+	for i := range want {
+		if strings.TrimSpace(lines[i]) != want[i] {
+			t.Errorf("line %d = %q, want %q", i, strings.TrimSpace(lines[i]), want[i])
+		}
+	}
+
+	if code := runPatterns(patternOptions{}, []string{filepath.Join(t.TempDir(), "missing")}, &out); code != 2 {
+		t.Errorf("runPatterns() for a missing file = %d, want 2", code)
+	}
+}
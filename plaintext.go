@@ -0,0 +1,62 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// plainLevelRegex finds level words in plain text: in brackets, or in
+// capitals so that "failed with error" in prose isn't taken for a level.
+var plainLevelRegex = regexp.MustCompile(`\[(\pL+)\]|\b(\p{Lu}{3,})\b`)
+
+var plainTimestampColor = color.New(color.FgCyan)
+
+// highlightPlainLine colors the leading timestamp and the first level word
+// of a line no parser recognized, and dims the rest, so unstructured lines
+// in a mixed stream stay readable. Lines with neither are left as they are.
+func highlightPlainLine(line string) string {
+	if color.NoColor {
+		return line
+	}
+	timestamp, rest := "", line
+	if _, after, ok := cutLeadingTime(line); ok {
+		timestamp, rest = line[:len(line)-len(after)], after
+	}
+
+	levelStart, levelEnd := -1, -1
+	for _, m := range plainLevelRegex.FindAllStringSubmatchIndex(rest, -1) {
+		word := m[2:4]
+		if word[0] < 0 {
+			word = m[4:6]
+		}
+		if _, ok := levelAliases[strings.ToLower(rest[word[0]:word[1]])]; ok {
+			levelStart, levelEnd = m[0], m[1]
+			break
+		}
+	}
+	if timestamp == "" && levelStart < 0 {
+		return line
+	}
+
+	var b strings.Builder
+	if timestamp != "" {
+		trimmed := strings.TrimRight(timestamp, " \t")
+		b.WriteString(plainTimestampColor.Sprint(trimmed))
+		b.WriteString(timestamp[len(trimmed):])
+	}
+	if levelStart < 0 {
+		b.WriteString(labelColor.Sprint(rest))
+		return b.String()
+	}
+	if levelStart > 0 {
+		b.WriteString(labelColor.Sprint(rest[:levelStart]))
+	}
+	level := rest[levelStart:levelEnd]
+	b.WriteString(getLevelColor(strings.Trim(level, "[]")).Sprint(level))
+	if levelEnd < len(rest) {
+		b.WriteString(labelColor.Sprint(rest[levelEnd:]))
+	}
+	return b.String()
+}
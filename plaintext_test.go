@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestHighlightPlainLine(t *testing.T) {
+	noColor := color.NoColor
+	defer func() { color.NoColor = noColor }()
+	color.NoColor = false
+
+	timestamp := plainTimestampColor.Sprint
+	dim := labelColor.Sprint
+	tests := []struct {
+		name, line, want string
+	}{
+		{"nothing found", "at main.go:12", "at main.go:12"},
+		{"prose", "failed with error", "failed with error"},
+		{"timestamp", "2024/01/15 10:00:00 listening on :8080", timestamp("2024/01/15 10:00:00") + " " + dim("listening on :8080")},
+		{"level", "worker 3 FATAL out of memory", dim("worker 3 ") + getLevelColor("fatal").Sprint("FATAL") + dim(" out of memory")},
+		{"bracketed level", "job [warn] retrying", dim("job ") + getLevelColor("warn").Sprint("[warn]") + dim(" retrying")},
+		{"both", "2024-01-15 10:00:00 app ERROR boom", timestamp("2024-01-15 10:00:00") + " " + dim("app ") + getLevelColor("error").Sprint("ERROR") + dim(" boom")},
+		{"first level word", "HTTP WARN then ERROR", dim("HTTP ") + getLevelColor("warn").Sprint("WARN") + dim(" then ERROR")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlightPlainLine(tt.line); got != tt.want {
+				t.Errorf("highlightPlainLine(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+
+	color.NoColor = true
+	if line := "2024/01/15 10:00:00 ERROR x"; highlightPlainLine(line) != line {
+		t.Error("expected lines to be unchanged without colors")
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/fatih/color"
+)
+
+// showProcess is set by --process to show where entries come from in a
+// column in front of them.
+var showProcess = false
+
+// processColumnMax caps the width the process column grows to.
+const processColumnMax = 32
+
+// processColumnWidth is the widest process column shown so far, so that
+// entries of the same processes stay aligned.
+var processColumnWidth = 0
+
+var processPaint = newPaint(color.FgBlue)
+
+// processOrigin describes the host, process and thread of an entry, e.g.
+// "web-1 api[1234]/worker-3", or returns "" when none are logged.
+func processOrigin(log LogEntry) string {
+	origin := log.Process.Name
+	if log.Process.PID != 0 {
+		origin += "[" + strconv.Itoa(log.Process.PID) + "]"
+	}
+	if log.Process.Thread.Name != "" {
+		origin += "/" + log.Process.Thread.Name
+	}
+	if log.Host.Hostname != "" {
+		if origin == "" {
+			return log.Host.Hostname
+		}
+		origin = log.Host.Hostname + " " + origin
+	}
+	return origin
+}
+
+// processColumn returns the padded process column of an entry, followed by
+// a space. Syslog entries show their origin in front of the message
+// already.
+func processColumn(log LogEntry) string {
+	if log.Log.Syslog != nil {
+		return ""
+	}
+	origin := truncateWidth(processOrigin(log), processColumnMax)
+	processColumnWidth = max(processColumnWidth, displayWidth(origin))
+	if processColumnWidth == 0 {
+		return ""
+	}
+	return processPaint.sprint(padWidth(origin, processColumnWidth)) + " "
+}
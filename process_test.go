@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestProcessOrigin(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"all", `{"host":{"hostname":"web-1"},"process":{"name":"api","pid":1234,"thread":{"name":"worker-3"}}}`, "web-1 api[1234]/worker-3"},
+		{"process only", `{"process":{"name":"api"}}`, "api"},
+		{"pid and thread", `{"process":{"pid":7,"thread":{"id":3,"name":"main"}}}`, "[7]/main"},
+		{"host only", `{"host":{"hostname":"web-1"}}`, "web-1"},
+		{"none", `{"message":"hi"}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var log LogEntry
+			if err := json.Unmarshal([]byte(tt.line), &log); err != nil {
+				t.Fatal(err)
+			}
+			if got := processOrigin(log); got != tt.want {
+				t.Errorf("processOrigin() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessColumn(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+	defer func() { processColumnWidth = 0 }()
+	processColumnWidth = 0
+
+	var short, long, none, syslog LogEntry
+	short.Process.Name = "api"
+	long.Process.Name = "api"
+	long.Process.PID = 1234
+	syslog.Process.Name = "sshd"
+	syslog.Log.Syslog = &SyslogFields{}
+
+	if got := processColumn(none); got != "" {
+		t.Errorf("no process before any: %q", got)
+	}
+	if got, want := processColumn(short), "api "; got != want {
+		t.Errorf("first = %q, want %q", got, want)
+	}
+	if got, want := processColumn(long), "api[1234] "; got != want {
+		t.Errorf("wider = %q, want %q", got, want)
+	}
+	if got, want := processColumn(short), "api       "; got != want {
+		t.Errorf("narrower = %q, want %q", got, want)
+	}
+	if got, want := processColumn(none), "          "; got != want {
+		t.Errorf("no process = %q, want %q", got, want)
+	}
+	if got := processColumn(syslog); got != "" {
+		t.Errorf("syslog = %q, want none", got)
+	}
+}
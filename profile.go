@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Profile is a named bundle of flag values in the config file, selected
+// with --profile, e.g. {"level": "warn|error", "unwrap": "log"}. Values are
+// strings, numbers, booleans or lists, which are joined with commas.
+type Profile map[string]interface{}
+
+// applyProfile sets the flags of the named profile that weren't given on
+// the command line, so explicit flags still win. Flags that belong to
+// another command are skipped, letting one profile serve e.g. both logpipe
+// and logpipe lint.
+func applyProfile(fs *flag.FlagSet, profiles map[string]Profile, name string, commands []command) error {
+	profile, ok := profiles[name]
+	if !ok {
+		available := sortedKeys(profiles)
+		if len(available) == 0 {
+			return fmt.Errorf("unknown profile %q (the config file defines none)", name)
+		}
+		return fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(available, ", "))
+	}
+
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	for _, key := range sortedKeys(profile) {
+		if key == "profile" || key == "config" {
+			return fmt.Errorf("profile %s: %q can't be set in a profile", name, key)
+		}
+		if fs.Lookup(key) == nil {
+			if flagOwner(commands, key) == nil {
+				return fmt.Errorf("profile %s: unknown flag %q", name, key)
+			}
+			continue
+		}
+		if given[key] {
+			continue
+		}
+		value, err := profileValue(profile[key])
+		if err != nil {
+			return fmt.Errorf("profile %s: %s: %w", name, key, err)
+		}
+		if err := fs.Set(key, value); err != nil {
+			return fmt.Errorf("profile %s: %s: %w", name, key, err)
+		}
+	}
+	return nil
+}
+
+// profileValue formats a JSON value the way it would be given as a flag.
+func profileValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, err := profileValue(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), nil
+	}
+	return "", fmt.Errorf("expected a string, number, boolean or list, got %s", jsonTypeName(value))
+}
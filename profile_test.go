@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestApplyProfile(t *testing.T) {
+	var config Config
+	err := json.Unmarshal([]byte(`{"profiles":{
+		"prod-api": {"level": "warn|error", "icons": true, "redact": ["user.email", "user.phone"], "max-errors": 5, "topic": "api-logs"},
+		"broken": {"nope": "x"},
+		"nested": {"level": {"x": 1}}
+	}}`), &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var kafkaOpts kafkaOptions
+	commands := []command{
+		{name: "pipe"},
+		{name: "kafka", register: kafkaOpts.register},
+	}
+	newFlags := func() *flagValues {
+		fs := newFlagSet()
+		v := &flagValues{
+			level:     fs.String("level", "", ""),
+			icons:     fs.Bool("icons", false, ""),
+			redact:    fs.String("redact", "", ""),
+			maxErrors: fs.Int("max-errors", 0, ""),
+		}
+		v.fs = fs
+		return v
+	}
+
+	flags := newFlags()
+	flags.fs.Parse([]string{"--level", "error"})
+	if err := applyProfile(flags.fs, config.Profiles, "prod-api", commands); err != nil {
+		t.Fatalf("applyProfile() error = %v", err)
+	}
+	if *flags.level != "error" {
+		t.Errorf("--level = %q, the command line should win", *flags.level)
+	}
+	if !*flags.icons || *flags.redact != "user.email,user.phone" || *flags.maxErrors != 5 {
+		t.Errorf("profile flags = %v %q %d", *flags.icons, *flags.redact, *flags.maxErrors)
+	}
+
+	tests := []struct {
+		profile string
+		wantErr string
+	}{
+		{"missing", `unknown profile "missing" (available: broken, nested, prod-api)`},
+		{"broken", `profile broken: unknown flag "nope"`},
+		{"nested", "profile nested: level: expected a string, number, boolean or list, got object"},
+	}
+	for _, tt := range tests {
+		err := applyProfile(newFlags().fs, config.Profiles, tt.profile, commands)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("applyProfile(%s) error = %v, want %q", tt.profile, err, tt.wantErr)
+		}
+	}
+}
+
+type flagValues struct {
+	fs        *flag.FlagSet
+	level     *string
+	icons     *bool
+	redact    *string
+	maxErrors *int
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+// promoteMaps are the maps a promoted key is looked up in, in order: ECS
+// labels and tags, OpenTelemetry attributes, and W3C baggage.
+var promoteMaps = []string{"labels", "tags", "attributes", "resource.attributes", "baggage"}
+
+// baggageHeaders are where a raw W3C baggage header may be logged.
+var baggageHeaders = []string{"baggage", "http.request.headers.baggage", "http.request.headers.Baggage"}
+
+// promotedKeys are set with --promote or the config's promote.
+var promotedKeys []string
+
+// PromotedLabel is a member of an entry's labels shown on its main line.
+type PromotedLabel struct {
+	Key, Value string
+}
+
+// promotedLabels returns the values of promotedKeys found in the label
+// maps of an entry, in the order the keys were given. A key not in any of
+// them may be the dotted path of a field, e.g. cloud.region.
+func promotedLabels(fields map[string]interface{}) []PromotedLabel {
+	baggage := parseBaggage(fieldString(fields, baggageHeaders...))
+	var labels []PromotedLabel
+	for _, key := range promotedKeys {
+		value := ""
+		for _, m := range promoteMaps {
+			if value = fieldString(fields, m+"."+key); value != "" {
+				break
+			}
+		}
+		if value == "" {
+			value = baggage[key]
+		}
+		if value == "" {
+			value = fieldString(fields, key)
+		}
+		if value != "" {
+			labels = append(labels, PromotedLabel{Key: key, Value: value})
+		}
+	}
+	return labels
+}
+
+// parseBaggage reads the members of a W3C baggage header, e.g.
+// "tenant=acme,plan=pro;ttl=60", leaving out their properties.
+func parseBaggage(header string) map[string]string {
+	if header == "" {
+		return nil
+	}
+	members := make(map[string]string)
+	for _, member := range strings.Split(header, ",") {
+		member, _, _ = strings.Cut(member, ";")
+		key, value, ok := strings.Cut(member, "=")
+		if !ok {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(strings.TrimSpace(value)); err == nil {
+			value = unescaped
+		}
+		members[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return members
+}
+
+// promotedSuffix shows the promoted labels of an entry after its message,
+// e.g. "env=prod tenant=acme", each in the color of its key.
+func promotedSuffix(log LogEntry) string {
+	var b strings.Builder
+	for _, l := range log.Promoted {
+		b.WriteString(" " + sourcePaint(l.Key).sprint(l.Key+"="+l.Value))
+	}
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestPromotedLabels(t *testing.T) {
+	saved := promotedKeys
+	defer func() { promotedKeys = saved }()
+
+	tests := []struct {
+		name string
+		keys []string
+		line string
+		want []PromotedLabel
+	}{
+		{"ECS labels in key order", []string{"tenant", "env"}, `{"labels":{"env":"prod","tenant":"acme","team":"core"}}`, []PromotedLabel{{"tenant", "acme"}, {"env", "prod"}}},
+		{"tags map", []string{"env"}, `{"tags":{"env":"staging"}}`, []PromotedLabel{{"env", "staging"}}},
+		{"OTel dotted attribute", []string{"deployment.environment"}, `{"resource":{"attributes":{"deployment.environment":"prod"}}}`, []PromotedLabel{{"deployment.environment", "prod"}}},
+		{"labels before attributes", []string{"env"}, `{"attributes":{"env":"b"},"labels":{"env":"a"}}`, []PromotedLabel{{"env", "a"}}},
+		{"baggage header", []string{"tenant", "plan"}, `{"http":{"request":{"headers":{"baggage":"tenant=acme%20corp,plan=pro;ttl=60"}}}}`, []PromotedLabel{{"tenant", "acme corp"}, {"plan", "pro"}}},
+		{"field path", []string{"cloud.region"}, `{"cloud":{"region":"eu-west-1"}}`, []PromotedLabel{{"cloud.region", "eu-west-1"}}},
+		{"numbers", []string{"shard"}, `{"labels":{"shard":3}}`, []PromotedLabel{{"shard", "3"}}},
+		{"missing", []string{"env"}, `{"labels":{"team":"core"}}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			promotedKeys = tt.keys
+			if got := promotedLabels(decodeJSONObject(tt.line)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("promotedLabels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromotedSuffix(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	log := LogEntry{Promoted: []PromotedLabel{{"env", "prod"}, {"tenant", "acme"}}}
+	if got, want := promotedSuffix(log), " env=prod tenant=acme"; got != want {
+		t.Errorf("promotedSuffix() = %q, want %q", got, want)
+	}
+	if got := promotedSuffix(LogEntry{}); got != "" {
+		t.Errorf("promotedSuffix() = %q, want empty", got)
+	}
+}
@@ -0,0 +1,612 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// protoSchema is the subset of a .proto file needed to decode messages:
+// message and enum definitions, indexed by fully-qualified name.
+type protoSchema struct {
+	messages map[string]*protoMessage
+	enums    map[string]map[int]string
+}
+
+type protoMessage struct {
+	name   string
+	fields map[int]*protoField
+}
+
+type protoField struct {
+	name     string
+	number   int
+	typeName string
+	scope    string
+	repeated bool
+	mapKey   *protoField
+	mapValue *protoField
+}
+
+// protoDecoder decodes records as one message type of a schema.
+type protoDecoder struct {
+	schema  *protoSchema
+	message *protoMessage
+}
+
+func (d *protoDecoder) Decode(data []byte) (map[string]interface{}, error) {
+	return d.schema.decodeMessage(d.message, data)
+}
+
+func (s *protoSchema) decoderFor(name string) (*protoDecoder, error) {
+	name = strings.TrimPrefix(name, ".")
+	if msg, ok := s.messages[name]; ok {
+		return &protoDecoder{schema: s, message: msg}, nil
+	}
+	// Accept the short name when it is unambiguous
+	var found *protoMessage
+	for fq, msg := range s.messages {
+		if fq == name || strings.HasSuffix(fq, "."+name) {
+			if found != nil {
+				return nil, fmt.Errorf("message %q is ambiguous", name)
+			}
+			found = msg
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("message %q not found in schema", name)
+	}
+	return &protoDecoder{schema: s, message: found}, nil
+}
+
+// parseProtoSchema parses message and enum definitions from .proto source.
+// Services, options and imports are skipped.
+func parseProtoSchema(src string) (*protoSchema, error) {
+	p := &protoParser{
+		tokens: tokenizeProto(src),
+		schema: &protoSchema{
+			messages: make(map[string]*protoMessage),
+			enums:    make(map[string]map[int]string),
+		},
+	}
+
+	pkg := ""
+	for !p.done() {
+		switch tok := p.next(); tok {
+		case "syntax", "edition", "import", "option":
+			p.skipStatement()
+		case "package":
+			pkg = p.next()
+			if err := p.expect(";"); err != nil {
+				return nil, err
+			}
+		case "message":
+			if err := p.parseMessage(pkg); err != nil {
+				return nil, err
+			}
+		case "enum":
+			if err := p.parseEnum(pkg); err != nil {
+				return nil, err
+			}
+		case "service", "extend":
+			p.skipBlock()
+		case ";":
+		default:
+			return nil, fmt.Errorf("unexpected %q", tok)
+		}
+	}
+	return p.schema, nil
+}
+
+type protoParser struct {
+	tokens []string
+	pos    int
+	schema *protoSchema
+}
+
+func (p *protoParser) done() bool { return p.pos >= len(p.tokens) }
+
+func (p *protoParser) next() string {
+	if p.done() {
+		return ""
+	}
+	p.pos++
+	return p.tokens[p.pos-1]
+}
+
+func (p *protoParser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *protoParser) expect(tok string) error {
+	if got := p.next(); got != tok {
+		return fmt.Errorf("expected %q, got %q", tok, got)
+	}
+	return nil
+}
+
+func (p *protoParser) skipStatement() {
+	for !p.done() && p.next() != ";" {
+	}
+}
+
+func (p *protoParser) skipBlock() {
+	for !p.done() && p.next() != "{" {
+	}
+	depth := 1
+	for !p.done() && depth > 0 {
+		switch p.next() {
+		case "{":
+			depth++
+		case "}":
+			depth--
+		}
+	}
+}
+
+func (p *protoParser) parseMessage(scope string) error {
+	name := qualify(scope, p.next())
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	msg := &protoMessage{name: name, fields: make(map[int]*protoField)}
+	p.schema.messages[name] = msg
+
+	for {
+		switch tok := p.peek(); tok {
+		case "":
+			return fmt.Errorf("unterminated message %s", name)
+		case "}":
+			p.next()
+			return nil
+		case "message":
+			p.next()
+			if err := p.parseMessage(name); err != nil {
+				return err
+			}
+		case "enum":
+			p.next()
+			if err := p.parseEnum(name); err != nil {
+				return err
+			}
+		case "oneof":
+			p.next()
+			p.next()
+			if err := p.expect("{"); err != nil {
+				return err
+			}
+			for p.peek() != "}" && !p.done() {
+				if p.peek() == "option" {
+					p.skipStatement()
+					continue
+				}
+				if err := p.parseField(msg, name); err != nil {
+					return err
+				}
+			}
+			p.next()
+		case "option", "reserved", "extensions":
+			p.skipStatement()
+		case "extend":
+			p.skipBlock()
+		case ";":
+			p.next()
+		default:
+			if err := p.parseField(msg, name); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (p *protoParser) parseField(msg *protoMessage, scope string) error {
+	field := &protoField{scope: scope}
+
+	switch p.peek() {
+	case "repeated":
+		field.repeated = true
+		p.next()
+	case "optional", "required":
+		p.next()
+	}
+
+	field.typeName = p.next()
+	if field.typeName == "map" {
+		if err := p.expect("<"); err != nil {
+			return err
+		}
+		field.mapKey = &protoField{name: "key", typeName: p.next(), scope: scope}
+		if err := p.expect(","); err != nil {
+			return err
+		}
+		field.mapValue = &protoField{name: "value", typeName: p.next(), scope: scope}
+		if err := p.expect(">"); err != nil {
+			return err
+		}
+	}
+	if field.typeName == "group" {
+		return errors.New("proto2 groups are not supported")
+	}
+
+	field.name = p.next()
+	if err := p.expect("="); err != nil {
+		return err
+	}
+	number, err := strconv.Atoi(p.next())
+	if err != nil {
+		return fmt.Errorf("field %s: invalid number", field.name)
+	}
+	field.number = number
+
+	if p.peek() == "[" {
+		p.next()
+		for !p.done() && p.peek() != "]" {
+			if p.next() == "json_name" && p.peek() == "=" {
+				p.next()
+				field.name = unquoteProto(p.next())
+			}
+		}
+		p.next()
+	}
+	if err := p.expect(";"); err != nil {
+		return err
+	}
+
+	msg.fields[field.number] = field
+	return nil
+}
+
+func (p *protoParser) parseEnum(scope string) error {
+	name := qualify(scope, p.next())
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	values := make(map[int]string)
+	p.schema.enums[name] = values
+
+	for {
+		switch tok := p.next(); tok {
+		case "":
+			return fmt.Errorf("unterminated enum %s", name)
+		case "}":
+			return nil
+		case "option", "reserved":
+			p.skipStatement()
+		case ";":
+		default:
+			if err := p.expect("="); err != nil {
+				return err
+			}
+			number, err := strconv.Atoi(p.next())
+			if err != nil {
+				return fmt.Errorf("enum %s: invalid value for %s", name, tok)
+			}
+			// With allow_alias the first name wins
+			if _, ok := values[number]; !ok {
+				values[number] = tok
+			}
+			p.skipStatement()
+		}
+	}
+}
+
+// tokenizeProto splits .proto source into identifiers, numbers, strings and
+// single-character punctuation, dropping comments.
+func tokenizeProto(src string) []string {
+	var tokens []string
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				i = len(src)
+			} else {
+				i += end + 4
+			}
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != c {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			j = min(j+1, len(src))
+			tokens = append(tokens, src[i:j])
+			i = j
+		case isProtoIdentChar(c):
+			j := i
+			for j < len(src) && isProtoIdentChar(src[j]) {
+				j++
+			}
+			tokens = append(tokens, src[i:j])
+			i = j
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens
+}
+
+func isProtoIdentChar(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || c == '+' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func unquoteProto(s string) string {
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		return unquoted
+	}
+	return strings.Trim(s, `"'`)
+}
+
+func qualify(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+// resolve finds a message or enum type following protobuf scoping rules:
+// the innermost enclosing scope wins.
+func (s *protoSchema) resolve(typeName, scope string) (string, bool) {
+	if strings.HasPrefix(typeName, ".") {
+		typeName = typeName[1:]
+		return typeName, s.isType(typeName)
+	}
+	for {
+		candidate := qualify(scope, typeName)
+		if s.isType(candidate) {
+			return candidate, true
+		}
+		if scope == "" {
+			return typeName, false
+		}
+		if i := strings.LastIndex(scope, "."); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+}
+
+func (s *protoSchema) isType(name string) bool {
+	_, isMessage := s.messages[name]
+	_, isEnum := s.enums[name]
+	return isMessage || isEnum
+}
+
+// Protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var protoPackableTypes = map[string]bool{
+	"double": true, "float": true, "int32": true, "int64": true, "uint32": true,
+	"uint64": true, "sint32": true, "sint64": true, "fixed32": true, "fixed64": true,
+	"sfixed32": true, "sfixed64": true, "bool": true,
+}
+
+func (s *protoSchema) decodeMessage(msg *protoMessage, data []byte) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("%s: invalid tag", msg.name)
+		}
+		data = data[n:]
+		number, wireType := int(tag>>3), int(tag&7)
+
+		var scalar uint64
+		var raw []byte
+		switch wireType {
+		case wireVarint:
+			scalar, n = binary.Uvarint(data)
+			if n <= 0 {
+				return nil, fmt.Errorf("%s: truncated varint", msg.name)
+			}
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return nil, fmt.Errorf("%s: truncated fixed64", msg.name)
+			}
+			scalar = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return nil, fmt.Errorf("%s: truncated fixed32", msg.name)
+			}
+			scalar = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < size {
+				return nil, fmt.Errorf("%s: truncated field %d", msg.name, number)
+			}
+			raw = data[n : n+int(size)]
+			data = data[n+int(size):]
+		default:
+			return nil, fmt.Errorf("%s: unsupported wire type %d", msg.name, wireType)
+		}
+
+		field, ok := msg.fields[number]
+		if !ok {
+			continue
+		}
+
+		if field.mapKey != nil {
+			entry, err := s.decodeMapEntry(field, raw)
+			if err != nil {
+				return nil, err
+			}
+			m, _ := result[field.name].(map[string]interface{})
+			if m == nil {
+				m = make(map[string]interface{})
+				result[field.name] = m
+			}
+			for k, v := range entry {
+				m[k] = v
+			}
+			continue
+		}
+
+		// Packed repeated scalars arrive as a single length-delimited blob
+		if wireType == wireBytes && field.repeated && s.isPackable(field) {
+			values, err := s.decodePacked(field, raw)
+			if err != nil {
+				return nil, err
+			}
+			list, _ := result[field.name].([]interface{})
+			result[field.name] = append(list, values...)
+			continue
+		}
+
+		value, err := s.decodeValue(field, wireType, scalar, raw)
+		if err != nil {
+			return nil, err
+		}
+		if field.repeated {
+			list, _ := result[field.name].([]interface{})
+			result[field.name] = append(list, value)
+		} else {
+			result[field.name] = value
+		}
+	}
+	return result, nil
+}
+
+func (s *protoSchema) isPackable(field *protoField) bool {
+	if protoPackableTypes[field.typeName] {
+		return true
+	}
+	name, ok := s.resolve(field.typeName, field.scope)
+	_, isEnum := s.enums[name]
+	return ok && isEnum
+}
+
+func (s *protoSchema) decodePacked(field *protoField, data []byte) ([]interface{}, error) {
+	var values []interface{}
+	for len(data) > 0 {
+		var scalar uint64
+		wireType := wireVarint
+		switch field.typeName {
+		case "double", "fixed64", "sfixed64":
+			if len(data) < 8 {
+				return nil, fmt.Errorf("%s: truncated packed field", field.name)
+			}
+			wireType = wireFixed64
+			scalar = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case "float", "fixed32", "sfixed32":
+			if len(data) < 4 {
+				return nil, fmt.Errorf("%s: truncated packed field", field.name)
+			}
+			wireType = wireFixed32
+			scalar = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		default:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return nil, fmt.Errorf("%s: truncated packed field", field.name)
+			}
+			scalar = v
+			data = data[n:]
+		}
+		value, err := s.decodeValue(field, wireType, scalar, nil)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func (s *protoSchema) decodeMapEntry(field *protoField, data []byte) (map[string]interface{}, error) {
+	entryMsg := &protoMessage{
+		name:   field.name,
+		fields: map[int]*protoField{1: field.mapKey, 2: field.mapValue},
+	}
+	entry, err := s.decodeMessage(entryMsg, data)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{fmt.Sprint(entry["key"]): entry["value"]}, nil
+}
+
+func (s *protoSchema) decodeValue(field *protoField, wireType int, v uint64, raw []byte) (interface{}, error) {
+	switch field.typeName {
+	case "double":
+		return math.Float64frombits(v), nil
+	case "float":
+		return math.Float32frombits(uint32(v)), nil
+	case "int32", "sfixed32":
+		return int32(v), nil
+	case "int64", "sfixed64":
+		return int64(v), nil
+	case "uint32", "fixed32":
+		return uint32(v), nil
+	case "uint64", "fixed64":
+		return v, nil
+	case "sint32":
+		return int32(uint32(v)>>1) ^ -int32(v&1), nil
+	case "sint64":
+		return int64(v>>1) ^ -int64(v&1), nil
+	case "bool":
+		return v != 0, nil
+	case "string":
+		return string(raw), nil
+	case "bytes":
+		return raw, nil
+	case "google.protobuf.Timestamp", ".google.protobuf.Timestamp":
+		ts, err := s.decodeMessage(protoTimestamp, raw)
+		if err != nil {
+			return nil, err
+		}
+		seconds, _ := ts["seconds"].(int64)
+		nanos, _ := ts["nanos"].(int32)
+		return time.Unix(seconds, int64(nanos)).UTC().Format(time.RFC3339Nano), nil
+	}
+
+	name, ok := s.resolve(field.typeName, field.scope)
+	if !ok {
+		return nil, fmt.Errorf("field %s: unknown type %s", field.name, field.typeName)
+	}
+	if values, isEnum := s.enums[name]; isEnum {
+		if symbol, ok := values[int(int32(v))]; ok {
+			return symbol, nil
+		}
+		return int32(v), nil
+	}
+	if wireType != wireBytes {
+		return nil, fmt.Errorf("field %s: expected embedded message", field.name)
+	}
+	return s.decodeMessage(s.messages[name], raw)
+}
+
+// protoTimestamp describes google.protobuf.Timestamp, which is imported
+// rather than defined in user schemas.
+var protoTimestamp = &protoMessage{
+	name: "google.protobuf.Timestamp",
+	fields: map[int]*protoField{
+		1: {name: "seconds", number: 1, typeName: "int64"},
+		2: {name: "nanos", number: 2, typeName: "int32"},
+	},
+}
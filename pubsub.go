@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// subscribe tails a message bus given as nats://host:port?subject=logs.> or
+// redis://host:port?channel=logs and hands each message to handle.
+func subscribe(ctx context.Context, rawURL string, decoder recordDecoder, handle lineHandler) error {
+	if rawURL == "" {
+		return errors.New("missing URL, e.g. nats://localhost:4222?subject=logs.> or redis://localhost:6379?channel=logs")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	deliver := func(payload []byte) {
+		handlePayload(payload, decoder, handle, "")
+	}
+
+	switch u.Scheme {
+	case "nats", "tls":
+		subjects := splitList(u.Query().Get("subject"))
+		if len(subjects) == 0 {
+			return errors.New("missing ?subject= in NATS URL")
+		}
+		conn, err := dialURL(ctx, u, "4222", u.Scheme == "tls")
+		if err != nil {
+			return err
+		}
+		return ignoreClosed(ctx, subscribeNATS(conn, u.User, subjects, deliver))
+	case "redis", "rediss":
+		channels := splitList(u.Query().Get("channel"))
+		if len(channels) == 0 {
+			return errors.New("missing ?channel= in Redis URL")
+		}
+		conn, err := dialURL(ctx, u, "6379", u.Scheme == "rediss")
+		if err != nil {
+			return err
+		}
+		return ignoreClosed(ctx, subscribeRedis(conn, u.User, channels, deliver))
+	}
+	return fmt.Errorf("unsupported URL scheme %q (expected nats, tls, redis or rediss)", u.Scheme)
+}
+
+// dialURL connects to the URL host and closes the connection once ctx is
+// done, which unblocks any pending read.
+func dialURL(ctx context.Context, u *url.URL, defaultPort string, useTLS bool) (net.Conn, error) {
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), defaultPort)
+	}
+
+	var conn net.Conn
+	var err error
+	if useTLS {
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: u.Hostname()}}
+		conn, err = dialer.DialContext(ctx, "tcp", host)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", host)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	return conn, nil
+}
+
+func ignoreClosed(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// subscribeNATS speaks the NATS client protocol: CONNECT, SUB, then MSG
+// deliveries interleaved with PING keep-alives.
+func subscribeNATS(conn net.Conn, user *url.Userinfo, subjects []string, deliver func([]byte)) error {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	greeting, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(greeting, "INFO") {
+		return fmt.Errorf("unexpected NATS greeting %q", strings.TrimSpace(greeting))
+	}
+
+	options := map[string]interface{}{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "logpipe",
+		"lang":     "go",
+		"version":  version,
+		"protocol": 1,
+		"headers":  true,
+	}
+	if user != nil {
+		if password, ok := user.Password(); ok {
+			options["user"] = user.Username()
+			options["pass"] = password
+		} else {
+			options["auth_token"] = user.Username()
+		}
+	}
+	connect, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+
+	var cmds strings.Builder
+	fmt.Fprintf(&cmds, "CONNECT %s\r\n", connect)
+	for i, subject := range subjects {
+		fmt.Fprintf(&cmds, "SUB %s %d\r\n", subject, i+1)
+	}
+	cmds.WriteString("PING\r\n")
+	if _, err := io.WriteString(conn, cmds.String()); err != nil {
+		return err
+	}
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "MSG", "HMSG":
+			// MSG <subject> <sid> [reply] <size>
+			// HMSG <subject> <sid> [reply] <header size> <total size>
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil || size < 0 {
+				return fmt.Errorf("malformed NATS message %q", line)
+			}
+			if size > maxRecordSize {
+				return fmt.Errorf("NATS message of %d bytes is too large", size)
+			}
+			headerSize := 0
+			if strings.ToUpper(fields[0]) == "HMSG" {
+				headerSize, err = strconv.Atoi(fields[len(fields)-2])
+				if err != nil || headerSize < 0 || headerSize > size {
+					return fmt.Errorf("malformed NATS message %q", line)
+				}
+			}
+			payload, err := readSized(r, size+2)
+			if err != nil {
+				return err
+			}
+			deliver(payload[headerSize:size])
+		case "PING":
+			if _, err := io.WriteString(conn, "PONG\r\n"); err != nil {
+				return err
+			}
+		case "-ERR":
+			return fmt.Errorf("NATS error: %s", strings.TrimSpace(strings.TrimPrefix(line, fields[0])))
+		}
+	}
+}
+
+// subscribeRedis authenticates if needed, then SUBSCRIBEs (or PSUBSCRIBEs
+// for glob patterns) and waits for pushed messages.
+func subscribeRedis(conn net.Conn, user *url.Userinfo, channels []string, deliver func([]byte)) error {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	if user != nil {
+		args := []string{"AUTH", user.Username()}
+		if password, ok := user.Password(); ok {
+			if user.Username() == "" {
+				args = []string{"AUTH", password}
+			} else {
+				args = append(args, password)
+			}
+		}
+		if err := writeRESP(conn, args...); err != nil {
+			return err
+		}
+		if _, err := readRESP(r); err != nil {
+			return err
+		}
+	}
+
+	var plain, patterns []string
+	for _, channel := range channels {
+		if strings.ContainsAny(channel, "*?[") {
+			patterns = append(patterns, channel)
+		} else {
+			plain = append(plain, channel)
+		}
+	}
+	if len(plain) > 0 {
+		if err := writeRESP(conn, append([]string{"SUBSCRIBE"}, plain...)...); err != nil {
+			return err
+		}
+	}
+	if len(patterns) > 0 {
+		if err := writeRESP(conn, append([]string{"PSUBSCRIBE"}, patterns...)...); err != nil {
+			return err
+		}
+	}
+
+	for {
+		reply, err := readRESP(r)
+		if err != nil {
+			return err
+		}
+		push, ok := reply.([]interface{})
+		if !ok || len(push) < 3 {
+			continue
+		}
+		kind, _ := push[0].([]byte)
+		switch string(kind) {
+		case "message":
+			payload, _ := push[2].([]byte)
+			deliver(payload)
+		case "pmessage":
+			if len(push) == 4 {
+				payload, _ := push[3].([]byte)
+				deliver(payload)
+			}
+		}
+	}
+}
+
+// writeRESP sends a command as a RESP array of bulk strings.
+func writeRESP(w io.Writer, args ...string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// maxRESPItems bounds the items of a RESP array and respMaxDepth how
+// deeply arrays may nest. Pushed messages are flat arrays of a few items.
+const (
+	maxRESPItems = 1 << 16
+	respMaxDepth = 16
+)
+
+// readRESP reads one RESP value. Bulk and simple strings are returned as
+// []byte, arrays as []interface{}, and error replies as Go errors.
+func readRESP(r *bufio.Reader) (interface{}, error) {
+	return readRESPValue(r, 0)
+}
+
+// readRESPValue reads one RESP value nested depth arrays deep.
+func readRESPValue(r *bufio.Reader, depth int) (interface{}, error) {
+	if depth > respMaxDepth {
+		return nil, errors.New("Redis reply nested too deep")
+	}
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty Redis reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return []byte(line[1:]), nil
+	case '-':
+		return nil, fmt.Errorf("Redis error: %s", line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		if size > maxRecordSize {
+			return nil, fmt.Errorf("Redis reply of %d bytes is too large", size)
+		}
+		data, err := readSized(r, size+2)
+		if err != nil {
+			return nil, err
+		}
+		return data[:size], nil
+	case '*', '>':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if count > maxRESPItems {
+			return nil, fmt.Errorf("Redis reply of %d items is too large", count)
+		}
+		// Grown as items arrive rather than sized by the declared count
+		var items []interface{}
+		for i := 0; i < count; i++ {
+			item, err := readRESPValue(r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unexpected Redis reply %q", line)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeServer accepts a single connection and runs serve on it.
+func fakeServer(t *testing.T, serve func(conn net.Conn, r *bufio.Reader)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		serve(conn, bufio.NewReader(conn))
+	}()
+	return ln.Addr().String()
+}
+
+// collectLines subscribes to rawURL until want lines arrive or a timeout.
+func collectLines(t *testing.T, rawURL string, want int) []string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var lines []string
+	err := subscribe(ctx, rawURL, nil, func(line, label string) {
+		lines = append(lines, line)
+		if len(lines) == want {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatalf("subscribe() error = %v", err)
+	}
+	return lines
+}
+
+func TestSubscribeNATS(t *testing.T) {
+	commands := make(chan string, 10)
+	addr := fakeServer(t, func(conn net.Conn, r *bufio.Reader) {
+		conn.Write([]byte("INFO {\"server_id\":\"test\"}\r\n"))
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			commands <- strings.TrimSpace(line)
+			if strings.HasPrefix(line, "PING") {
+				payload := `{"log.level":"info","message":"from nats"}`
+				conn.Write([]byte("PONG\r\nMSG logs.api 1 " + strconv.Itoa(len(payload)) + "\r\n" + payload + "\r\n"))
+				headers := "NATS/1.0\r\nX: y\r\n\r\n"
+				payload = `{"log.level":"warn","message":"with headers"}`
+				conn.Write([]byte("HMSG logs.db 1 " + strconv.Itoa(len(headers)) + " " + strconv.Itoa(len(headers)+len(payload)) + "\r\n" + headers + payload + "\r\n"))
+			}
+		}
+	})
+
+	lines := collectLines(t, "nats://"+addr+"?subject=logs.>", 2)
+	if len(lines) != 2 || !strings.Contains(lines[0], "from nats") || !strings.Contains(lines[1], "with headers") {
+		t.Errorf("lines = %v", lines)
+	}
+	if connect := <-commands; !strings.HasPrefix(connect, "CONNECT ") {
+		t.Errorf("first command = %q, want CONNECT", connect)
+	}
+	if sub := <-commands; sub != "SUB logs.> 1" {
+		t.Errorf("second command = %q, want SUB", sub)
+	}
+}
+
+func TestSubscribeRedis(t *testing.T) {
+	addr := fakeServer(t, func(conn net.Conn, r *bufio.Reader) {
+		cmd, err := readRESP(r)
+		if err != nil {
+			return
+		}
+		args := cmd.([]interface{})
+		if string(args[0].([]byte)) != "AUTH" || string(args[1].([]byte)) != "secret" {
+			conn.Write([]byte("-ERR bad auth\r\n"))
+			return
+		}
+		conn.Write([]byte("+OK\r\n"))
+
+		for i := 0; i < 2; i++ {
+			if _, err := readRESP(r); err != nil {
+				return
+			}
+		}
+		writeRESP(conn, "subscribe", "logs")
+		writeRESP(conn, "message", "logs", "{\"message\":\"first\"}\n{\"message\":\"second\"}")
+		writeRESP(conn, "pmessage", "app.*", "app.web", `{"message":"third"}`)
+	})
+
+	lines := collectLines(t, "redis://:secret@"+addr+"?channel=logs,app.*", 3)
+	want := []string{`{"message":"first"}`, `{"message":"second"}`, `{"message":"third"}`}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("lines = %v, want %v", lines, want)
+	}
+}
+
+func TestSubscribeErrors(t *testing.T) {
+	ctx := context.Background()
+	noop := func(string, string) {}
+	for _, rawURL := range []string{"", "nats://localhost", "redis://localhost", "ftp://localhost"} {
+		if err := subscribe(ctx, rawURL, nil, noop); err == nil {
+			t.Errorf("subscribe(%q) expected an error", rawURL)
+		}
+	}
+}
+
+func TestSubscribeNATSMalformedSizes(t *testing.T) {
+	for _, msg := range []string{
+		"MSG logs 1 -1",
+		"MSG logs 1 999999999999",
+		"HMSG logs 1 -1 10",
+		"HMSG logs 1 20 10",
+	} {
+		t.Run(msg, func(t *testing.T) {
+			addr := fakeServer(t, func(conn net.Conn, r *bufio.Reader) {
+				conn.Write([]byte("INFO {}\r\n" + msg + "\r\n"))
+				io.Copy(io.Discard, r)
+			})
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = subscribeNATS(conn, nil, []string{"logs"}, func([]byte) {
+				t.Error("unexpected delivery")
+			})
+			if err == nil || !strings.Contains(err.Error(), "NATS message") {
+				t.Errorf("error = %v, want a malformed or too large message", err)
+			}
+		})
+	}
+}
+
+func TestReadRESPLimits(t *testing.T) {
+	tests := []struct {
+		name, data, wantErr string
+	}{
+		{"huge bulk string", "$999999999999\r\n", "too large"},
+		{"huge array", "*999999999\r\n", "too large"},
+		{"truncated array", "*3\r\n:1\r\n", "EOF"},
+		{"nested too deep", strings.Repeat("*1\r\n", respMaxDepth+2), "nested too deep"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readRESP(bufio.NewReader(strings.NewReader(tt.data)))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"time"
+)
+
+// quietReportInterval is how often the count of suppressed entries is
+// shown while they keep coming.
+const quietReportInterval = 10 * time.Second
+
+// quietFilter drops known noise, such as health checks, and keeps count so
+// that hidden entries aren't silently lost.
+type quietFilter struct {
+	// paths are URL paths or path.Match patterns (e.g. /static/*).
+	paths []string
+	match *regexp.Regexp
+
+	hidden     int
+	lastReport time.Time
+	now        func() time.Time
+}
+
+// newQuietFilter returns a filter for --quiet-paths and --quiet-match, or
+// nil when neither is set.
+func newQuietFilter(paths []string, match string) (*quietFilter, error) {
+	if len(paths) == 0 && match == "" {
+		return nil, nil
+	}
+	for _, p := range paths {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid path pattern %q", p)
+		}
+	}
+	q := &quietFilter{paths: paths, now: time.Now}
+	if match != "" {
+		re, err := regexp.Compile(match)
+		if err != nil {
+			return nil, err
+		}
+		q.match = re
+	}
+	q.lastReport = q.now()
+	return q, nil
+}
+
+// quiet reports whether an entry is noise. entry is nil for lines that
+// could not be parsed, which are only checked against --quiet-match.
+func (q *quietFilter) quiet(line string, entry *LogEntry) bool {
+	if entry != nil && entry.URL.Path != "" {
+		for _, p := range q.paths {
+			if ok, _ := path.Match(p, entry.URL.Path); ok {
+				return true
+			}
+		}
+	}
+	return q.match != nil && q.match.MatchString(line)
+}
+
+// hide counts a suppressed entry, showing the count on w every
+// quietReportInterval.
+func (q *quietFilter) hide(w io.Writer) {
+	q.hidden++
+	if q.now().Sub(q.lastReport) >= quietReportInterval {
+		q.flush(w)
+	}
+}
+
+// flush shows how many entries were suppressed since the last report.
+func (q *quietFilter) flush(w io.Writer) {
+	q.lastReport = q.now()
+	if q.hidden == 0 {
+		return
+	}
+	noun := "entries"
+	if q.hidden == 1 {
+		noun = "entry"
+	}
+	fmt.Fprintf(w, "%s\n", labelColor.Sprintf("· %d quiet %s hidden", q.hidden, noun))
+	q.hidden = 0
+}
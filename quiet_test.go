@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestQuietFilter(t *testing.T) {
+	q, err := newQuietFilter([]string{"/healthz", "/static/*"}, "kube-probe")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		line string
+		want bool
+	}{
+		{"health check", `{"url":{"path":"/healthz"}}`, true},
+		{"pattern", `{"url":{"path":"/static/app.js"}}`, true},
+		{"pattern is one segment", `{"url":{"path":"/static/js/app.js"}}`, false},
+		{"other path", `{"url":{"path":"/healthz/deep"}}`, false},
+		{"match", `{"message":"GET / from kube-probe/1.29"}`, true},
+		{"unparseable match", `kube-probe ping`, true},
+		{"unparseable", `/healthz`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entry *LogEntry
+			if parsed, ok := parseLine(tt.line); ok {
+				entry = &parsed
+			}
+			if got := q.quiet(tt.line, entry); got != tt.want {
+				t.Errorf("quiet() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if q, err := newQuietFilter(nil, ""); q != nil || err != nil {
+		t.Errorf("newQuietFilter() without settings = %v, %v, want nil", q, err)
+	}
+	if _, err := newQuietFilter([]string{"[x"}, ""); err == nil {
+		t.Error("expected an invalid pattern to fail")
+	}
+}
+
+func TestQuietFilterReports(t *testing.T) {
+	clock := time.Date(2025, 6, 28, 12, 0, 0, 0, time.UTC)
+	q, _ := newQuietFilter([]string{"/healthz"}, "")
+	q.now = func() time.Time { return clock }
+	q.lastReport = clock
+
+	var buf bytes.Buffer
+	for i := 0; i < 3; i++ {
+		q.hide(&buf)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("reported before the interval: %q", buf.String())
+	}
+	clock = clock.Add(quietReportInterval)
+	q.hide(&buf)
+	if buf.String() != "· 4 quiet entries hidden\n" {
+		t.Errorf("report = %q", buf.String())
+	}
+
+	buf.Reset()
+	q.hide(&buf)
+	q.flush(&buf)
+	q.flush(&buf)
+	if buf.String() != "· 1 quiet entry hidden\n" {
+		t.Errorf("final report = %q", buf.String())
+	}
+}
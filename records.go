@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// maxRecordSize guards against allocating huge buffers when the input is
+// not actually framed the way we expect.
+const maxRecordSize = 64 << 20
+
+// readSized reads the n bytes a stream declares are coming. Large values
+// are read in chunks, so that memory grows with the data that arrives
+// rather than with a declared length a garbled stream can set to anything.
+func readSized(r io.Reader, n int) ([]byte, error) {
+	if n <= 512 {
+		data := make([]byte, n)
+		if _, err := io.ReadFull(r, data); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		return data, nil
+	}
+	var buf bytes.Buffer
+	read, err := buf.ReadFrom(io.LimitReader(r, int64(n)))
+	if err != nil {
+		return nil, err
+	}
+	if read < int64(n) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return buf.Bytes(), nil
+}
+
+// recordDecoder turns one binary record into JSON-compatible fields.
+type recordDecoder interface {
+	Decode(data []byte) (map[string]interface{}, error)
+}
+
+// newRecordDecoder builds the decoder selected by the schema flags, or
+// returns nil when the input is plain text.
+func newRecordDecoder(protoSchemaFile, protoMessage, avroSchemaFile string) (recordDecoder, error) {
+	switch {
+	case protoSchemaFile != "" && avroSchemaFile != "":
+		return nil, errors.New("--proto-schema and --avro-schema are mutually exclusive")
+	case protoSchemaFile != "":
+		if protoMessage == "" {
+			return nil, errors.New("--proto-schema requires --proto-message")
+		}
+		src, err := os.ReadFile(protoSchemaFile)
+		if err != nil {
+			return nil, err
+		}
+		schema, err := parseProtoSchema(string(src))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", protoSchemaFile, err)
+		}
+		return schema.decoderFor(protoMessage)
+	case avroSchemaFile != "":
+		src, err := os.ReadFile(avroSchemaFile)
+		if err != nil {
+			return nil, err
+		}
+		schema, err := parseAvroSchema(src)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", avroSchemaFile, err)
+		}
+		return schema, nil
+	case protoMessage != "":
+		return nil, errors.New("--proto-message requires --proto-schema")
+	}
+	return nil, nil
+}
+
+// readRecords reads length-prefixed records from r, decodes them and hands
+// them to handle as JSON lines. Records that fail to decode are reported on
+// stderr and skipped.
+func readRecords(r io.Reader, prefix string, decoder recordDecoder, handle lineHandler) error {
+	reader := bufio.NewReader(r)
+	for {
+		record, err := readRecord(reader, prefix)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		line, err := decodeRecord(decoder, record)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error decoding record: %v\n", err)
+			continue
+		}
+		handle(line, "")
+	}
+}
+
+// handlePayload hands a message received from a network source to handle,
+// either decoded as a binary record or split into its lines.
+func handlePayload(payload []byte, decoder recordDecoder, handle lineHandler, label string) {
+	if decoder != nil {
+		line, err := decodeRecord(decoder, payload)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error decoding record: %v\n", err)
+			return
+		}
+		handle(line, label)
+		return
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(payload), "\r\n"), "\n") {
+		handle(strings.TrimRight(line, "\r"), label)
+	}
+}
+
+// decodeRecord decodes a single binary record into a JSON line.
+func decodeRecord(decoder recordDecoder, record []byte) (string, error) {
+	fields, err := decoder.Decode(record)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// readRecord reads a single record framed by a big-endian uint32 length
+// (kcat's %R) or a protobuf-style varint length.
+func readRecord(r *bufio.Reader, prefix string) ([]byte, error) {
+	var size uint64
+	if prefix == "varint" {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		size = n
+	} else {
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil, err
+		}
+		size = uint64(binary.BigEndian.Uint32(header[:]))
+	}
+
+	if size > maxRecordSize {
+		return nil, fmt.Errorf("record of %d bytes exceeds limit, is --length-prefix correct?", size)
+	}
+	record := make([]byte, size)
+	if _, err := io.ReadFull(r, record); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return record, nil
+}
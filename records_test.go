@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+const testProtoSchema = `
+syntax = "proto3";
+package logs.v1;
+
+import "google/protobuf/timestamp.proto";
+
+// A single log record
+message LogRecord {
+  google.protobuf.Timestamp time = 1 [json_name = "@timestamp"];
+  Level level = 2 [json_name = "log.level"];
+  string message = 3;
+  map<string, string> labels = 4;
+  repeated int32 codes = 5;
+  Origin origin = 6;
+
+  message Origin {
+    string file = 1;
+    sint32 line = 2;
+  }
+
+  enum Level {
+    LEVEL_UNSPECIFIED = 0;
+    info = 1;
+    error = 2;
+  }
+}
+`
+
+// protoAppend appends a protobuf field to buf.
+func protoAppend(buf []byte, number, wireType int, value interface{}) []byte {
+	buf = binary.AppendUvarint(buf, uint64(number<<3|wireType))
+	switch v := value.(type) {
+	case uint64:
+		buf = binary.AppendUvarint(buf, v)
+	case []byte:
+		buf = binary.AppendUvarint(buf, uint64(len(v)))
+		buf = append(buf, v...)
+	case string:
+		buf = binary.AppendUvarint(buf, uint64(len(v)))
+		buf = append(buf, v...)
+	}
+	return buf
+}
+
+func TestProtoDecode(t *testing.T) {
+	schema, err := parseProtoSchema(testProtoSchema)
+	if err != nil {
+		t.Fatalf("parseProtoSchema() error = %v", err)
+	}
+	decoder, err := schema.decoderFor("LogRecord")
+	if err != nil {
+		t.Fatalf("decoderFor() error = %v", err)
+	}
+
+	ts := protoAppend(nil, 1, wireVarint, uint64(1751111400))
+	ts = protoAppend(ts, 2, wireVarint, uint64(500000000))
+	label := protoAppend(nil, 1, wireBytes, "pod")
+	label = protoAppend(label, 2, wireBytes, "api-0")
+	packed := binary.AppendUvarint(nil, 7)
+	packed = binary.AppendUvarint(packed, 9)
+	origin := protoAppend(nil, 1, wireBytes, "main.go")
+	origin = protoAppend(origin, 2, wireVarint, uint64(84)) // zigzag(42)
+
+	var record []byte
+	record = protoAppend(record, 1, wireBytes, ts)
+	record = protoAppend(record, 2, wireVarint, uint64(2))
+	record = protoAppend(record, 3, wireBytes, "disk full")
+	record = protoAppend(record, 4, wireBytes, label)
+	record = protoAppend(record, 5, wireBytes, packed)
+	record = protoAppend(record, 6, wireBytes, origin)
+	record = protoAppend(record, 99, wireVarint, uint64(1)) // unknown field
+
+	fields, err := decoder.Decode(record)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	if fields["@timestamp"] != "2025-06-28T11:50:00.5Z" {
+		t.Errorf("@timestamp = %v", fields["@timestamp"])
+	}
+	if fields["log.level"] != "error" {
+		t.Errorf("log.level = %v", fields["log.level"])
+	}
+	if fields["message"] != "disk full" {
+		t.Errorf("message = %v", fields["message"])
+	}
+	if labels, _ := fields["labels"].(map[string]interface{}); labels["pod"] != "api-0" {
+		t.Errorf("labels = %v", fields["labels"])
+	}
+	if codes, _ := fields["codes"].([]interface{}); len(codes) != 2 || codes[1] != int32(9) {
+		t.Errorf("codes = %v", fields["codes"])
+	}
+	if o, _ := fields["origin"].(map[string]interface{}); o["line"] != int32(42) {
+		t.Errorf("origin = %v", fields["origin"])
+	}
+}
+
+func TestParseProtoSchemaErrors(t *testing.T) {
+	if _, err := parseProtoSchema(`message Broken { string a = ; }`); err == nil {
+		t.Error("Expected invalid field number to fail")
+	}
+	schema, err := parseProtoSchema(`message A {}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := schema.decoderFor("Missing"); err == nil {
+		t.Error("Expected unknown message to fail")
+	}
+}
+
+func TestAvroDecode(t *testing.T) {
+	schema, err := parseAvroSchema([]byte(`{
+		"type": "record", "name": "LogRecord", "namespace": "logs",
+		"fields": [
+			{"name": "ts", "json_name": "@timestamp", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+			{"name": "level", "json_name": "log.level", "type": {"type": "enum", "name": "Level", "symbols": ["info", "warn", "error"]}},
+			{"name": "message", "type": "string"},
+			{"name": "error", "type": ["null", "string"]},
+			{"name": "tags", "type": {"type": "array", "items": "string"}}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("parseAvroSchema() error = %v", err)
+	}
+
+	var record []byte
+	record = binary.AppendVarint(record, 1751111400000)
+	record = binary.AppendVarint(record, 1)
+	record = binary.AppendVarint(record, int64(len("slow")))
+	record = append(record, "slow"...)
+	record = binary.AppendVarint(record, 0)
+	record = binary.AppendVarint(record, 1)
+	record = binary.AppendVarint(record, int64(len("db")))
+	record = append(record, "db"...)
+	record = binary.AppendVarint(record, 0)
+
+	fields, err := schema.Decode(record)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if fields["@timestamp"] != "2025-06-28T11:50:00Z" {
+		t.Errorf("@timestamp = %v", fields["@timestamp"])
+	}
+	if fields["log.level"] != "warn" || fields["message"] != "slow" || fields["error"] != nil {
+		t.Errorf("unexpected fields: %v", fields)
+	}
+	if tags, _ := fields["tags"].([]interface{}); len(tags) != 1 || tags[0] != "db" {
+		t.Errorf("tags = %v", fields["tags"])
+	}
+
+	if _, err := schema.Decode(record[:3]); err == nil {
+		t.Error("Expected truncated record to fail")
+	}
+}
+
+func TestAvroDecodeMalformed(t *testing.T) {
+	schema, err := parseAvroSchema([]byte(`{"type": "record", "name": "R", "fields": [
+		{"name": "ok", "type": "boolean"},
+		{"name": "message", "type": "string"},
+		{"name": "nulls", "type": {"type": "array", "items": "null"}}
+	]}`))
+	if err != nil {
+		t.Fatalf("parseAvroSchema() error = %v", err)
+	}
+	tests := []struct {
+		name   string
+		record []byte
+	}{
+		{"huge string length", binary.AppendVarint([]byte{0x01}, math.MaxInt64)},
+		{"negative string length", binary.AppendVarint([]byte{0x01}, -5)},
+		{"huge item count", binary.AppendVarint([]byte{0x01, 0x00}, math.MaxInt64)},
+		{"most negative item count", binary.AppendVarint(binary.AppendVarint([]byte{0x01, 0x00}, math.MinInt64), 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := schema.Decode(tt.record); err == nil {
+				t.Error("Decode() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func FuzzAvroDecode(f *testing.F) {
+	schema, err := parseAvroSchema([]byte(`{"type": "record", "name": "R", "fields": [
+		{"name": "ok", "type": "boolean"},
+		{"name": "message", "type": "string"},
+		{"name": "error", "type": ["null", "string"]},
+		{"name": "tags", "type": {"type": "array", "items": "string"}},
+		{"name": "labels", "type": {"type": "map", "values": "long"}},
+		{"name": "nulls", "type": {"type": "array", "items": "null"}}
+	]}`))
+	if err != nil {
+		f.Fatalf("parseAvroSchema() error = %v", err)
+	}
+	f.Add([]byte{0x01, 0x04, 'o', 'k', 0x00, 0x00, 0x00, 0x00})
+	f.Add(binary.AppendVarint([]byte{0x01}, math.MaxInt64))
+	f.Fuzz(func(t *testing.T, record []byte) {
+		schema.Decode(record)
+	})
+}
+
+func TestReadRecords(t *testing.T) {
+	schema, err := parseProtoSchema(`message M { string message = 1; }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder, _ := schema.decoderFor("M")
+
+	first := protoAppend(nil, 1, wireBytes, "one")
+	second := protoAppend(nil, 1, wireBytes, "two")
+
+	var uint32Input bytes.Buffer
+	for _, r := range [][]byte{first, second} {
+		binary.Write(&uint32Input, binary.BigEndian, uint32(len(r)))
+		uint32Input.Write(r)
+	}
+	var varintInput []byte
+	for _, r := range [][]byte{first, second} {
+		varintInput = binary.AppendUvarint(varintInput, uint64(len(r)))
+		varintInput = append(varintInput, r...)
+	}
+
+	for prefix, input := range map[string][]byte{"uint32": uint32Input.Bytes(), "varint": varintInput} {
+		t.Run(prefix, func(t *testing.T) {
+			var lines []string
+			err := readRecords(bytes.NewReader(input), prefix, decoder, func(line, label string) {
+				lines = append(lines, line)
+			})
+			if err != nil {
+				t.Fatalf("readRecords() error = %v", err)
+			}
+			if len(lines) != 2 || !strings.Contains(lines[1], `"two"`) {
+				t.Errorf("lines = %v", lines)
+			}
+		})
+	}
+
+	err = readRecords(bytes.NewReader([]byte{0, 0, 0, 9, 1}), "uint32", decoder, func(string, string) {})
+	if err == nil {
+		t.Error("Expected truncated record to fail")
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const redactedValue = "[REDACTED]"
+
+// redactDetector masks sensitive values found inside any string.
+type redactDetector struct {
+	re      *regexp.Regexp
+	replace func(match string) string
+}
+
+var redactDetectors = map[string]redactDetector{
+	"emails": {
+		re:      regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+		replace: func(string) string { return "[EMAIL]" },
+	},
+	"tokens": {
+		re: regexp.MustCompile(`\b(?:Bearer|Basic|Token|bearer|basic|token)\s+[A-Za-z0-9\-._~+/]+=*|(?i)\b[a-z_]*token[=:]\s*"?[A-Za-z0-9\-._~+/]+=*|\beyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+`),
+		replace: func(match string) string {
+			if strings.HasPrefix(match, "eyJ") {
+				return "[TOKEN]"
+			}
+			if i := strings.IndexAny(match, "=:"); i >= 0 {
+				// token=value: keep the key and separator
+				i++
+				for i < len(match) && (match[i] == ' ' || match[i] == '"') {
+					i++
+				}
+				return match[:i] + "[TOKEN]"
+			}
+			// Bearer, Basic and token are also common words; only mask
+			// what looks like a credential after them
+			scheme, credential, _ := strings.Cut(match, " ")
+			if !credentialShaped(strings.TrimSpace(credential)) {
+				return match
+			}
+			return scheme + " [TOKEN]"
+		},
+	},
+	"cards": {
+		re: regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`),
+		replace: func(match string) string {
+			if !luhnValid(match) {
+				return match
+			}
+			return "[CARD]"
+		},
+	},
+	"ips": {
+		re: regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b|\b[0-9A-Fa-f]{1,4}:[0-9A-Fa-f:.]*:[0-9A-Fa-f.]*`),
+		replace: func(match string) string {
+			if net.ParseIP(match) == nil {
+				return match
+			}
+			return "[IP]"
+		},
+	},
+}
+
+// redactor masks configured field paths and detector matches in input lines
+// before they are parsed, displayed or forwarded.
+type redactor struct {
+	paths     [][]string
+	detectors []redactDetector
+	// pseudonyms replace the values of fields instead of masking them, see
+	// newAnonymizer.
+	pseudonyms []pseudonymPath
+}
+
+// newRedactor builds a redactor from dotted field paths and detector names
+// ("emails", "tokens", "cards", "ips" or "all"). It returns nil when there is
+// nothing to redact.
+func newRedactor(fields, detectors []string) (*redactor, error) {
+	r := &redactor{}
+	for _, field := range fields {
+		if field = strings.TrimSpace(field); field != "" {
+			r.paths = append(r.paths, strings.Split(strings.ToLower(field), "."))
+		}
+	}
+	for _, name := range detectors {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case name == "":
+		case name == "all":
+			for _, all := range []string{"emails", "tokens", "cards", "ips"} {
+				r.detectors = append(r.detectors, redactDetectors[all])
+			}
+		default:
+			detector, ok := redactDetectors[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown redaction detector %q (expected emails, tokens, cards, ips or all)", name)
+			}
+			r.detectors = append(r.detectors, detector)
+		}
+	}
+	if len(r.paths) == 0 && len(r.detectors) == 0 {
+		return nil, nil
+	}
+	return r, nil
+}
+
+// redactLine returns line with sensitive values masked. JSON lines have
+// their fields redacted by path; other lines only go through detectors.
+func (r *redactor) redactLine(line string) string {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "{") {
+		var fields map[string]interface{}
+		decoder := json.NewDecoder(strings.NewReader(trimmed))
+		decoder.UseNumber()
+		if err := decoder.Decode(&fields); err == nil && !decoder.More() {
+			return r.redactObject(fields)
+		}
+	}
+	return r.redactString(line)
+}
+
+func (r *redactor) redactObject(fields map[string]interface{}) string {
+	for _, path := range r.paths {
+		redactPath(fields, path)
+	}
+	for _, p := range r.pseudonyms {
+		replacePath(fields, p.path, p.replace)
+	}
+	redacted := r.redactValue(fields)
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(redacted); err != nil {
+		return redactedValue
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// redactValue runs the detectors over every string of a decoded JSON value.
+// Strings holding JSON objects (e.g. Docker's "log") are redacted as JSON.
+func (r *redactor) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = r.redactValue(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = r.redactValue(item)
+		}
+		return v
+	case string:
+		if strings.HasPrefix(strings.TrimSpace(v), "{") {
+			suffix := v[len(strings.TrimRight(v, "\r\n")):]
+			return r.redactLine(v) + suffix
+		}
+		return r.redactString(v)
+	}
+	return value
+}
+
+func (r *redactor) redactString(s string) string {
+	for _, detector := range r.detectors {
+		s = detector.re.ReplaceAllStringFunc(s, detector.replace)
+	}
+	return s
+}
+
+// redactPath masks the value at a dotted path.
+func redactPath(fields map[string]interface{}, path []string) {
+	replacePath(fields, path, func(interface{}) interface{} { return redactedValue })
+}
+
+// replacePath replaces the value at a dotted path. Keys match
+// case-insensitively and may be nested objects, flattened dotted keys, or a
+// mix of both.
+func replacePath(fields map[string]interface{}, path []string, replace func(interface{}) interface{}) {
+	for key, value := range fields {
+		lower := strings.ToLower(key)
+		for i := 1; i <= len(path); i++ {
+			if lower != strings.Join(path[:i], ".") {
+				continue
+			}
+			if i == len(path) {
+				fields[key] = replace(value)
+			} else if nested, ok := value.(map[string]interface{}); ok {
+				replacePath(nested, path[i:], replace)
+			}
+		}
+	}
+}
+
+// credentialShaped reports whether s looks like a token rather than a word:
+// long enough, with a digit or a capital letter past its first character.
+func credentialShaped(s string) bool {
+	if len(s) < 8 {
+		return false
+	}
+	for i, c := range s {
+		if unicode.IsDigit(c) || (i > 0 && unicode.IsUpper(c)) {
+			return true
+		}
+	}
+	return false
+}
+
+// luhnValid reports whether the digits of s pass the Luhn checksum used by
+// payment card numbers.
+func luhnValid(s string) bool {
+	sum, count := 0, 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+		count++
+	}
+	return count >= 13 && sum%10 == 0
+}
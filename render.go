@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// renderBuffers pools the buffers entries are rendered into, so that an
+// entry costs a single write to the output and no new buffer.
+var renderBuffers = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// maxPooledBuffer is the largest buffer put back in the pool; the few
+// entries rendered with big bodies don't keep their memory around.
+const maxPooledBuffer = 64 << 10
+
+// paint is a color whose escape sequences are computed once, instead of
+// on every Sprint as color.Color does, and written around text straight
+// into a buffer. It honors color.NoColor like color.Color.
+type paint struct {
+	start, end string
+}
+
+func newPaint(attrs ...color.Attribute) paint {
+	return paintOf(color.New(attrs...))
+}
+
+// paintOf computes the sequences c writes when colors are enabled, so that
+// a paint and its color render the same bytes.
+func paintOf(c *color.Color) paint {
+	enabled := *c
+	enabled.EnableColor()
+	start, end, _ := strings.Cut(enabled.Sprint("\x00"), "\x00")
+	return paint{start: start, end: end}
+}
+
+// sprint returns s painted, or s itself when colors are off.
+func (p paint) sprint(s string) string {
+	if color.NoColor {
+		return s
+	}
+	return p.start + s + p.end
+}
+
+func (p paint) write(b *bytes.Buffer, s string) {
+	if color.NoColor {
+		b.WriteString(s)
+		return
+	}
+	b.WriteString(p.start)
+	b.WriteString(s)
+	b.WriteString(p.end)
+}
+
+// The colors of the parts of an entry, shared by all entries.
+var (
+	methodColor   = color.New(color.FgMagenta, color.Bold)
+	durationColor = color.New(color.FgYellow)
+	pathColor     = color.New(color.FgGreen)
+	messageColor  = color.New(color.FgWhite)
+
+	timestampPaint = newPaint(color.FgCyan)
+	methodPaint    = paintOf(methodColor)
+	durationPaint  = paintOf(durationColor)
+	pathPaint      = paintOf(pathColor)
+	messagePaint   = paintOf(messageColor)
+	keyValuePaint  = newPaint(color.FgBlue)
+	errorPaint     = newPaint(color.FgRed, color.Bold)
+)
+
+// levelColors are the built-in colors of the canonical levels.
+var levelColors = map[string]*color.Color{
+	"fatal":  color.New(color.FgWhite, color.BgRed, color.Bold),
+	"error":  color.New(color.FgRed, color.Bold),
+	"warn":   color.New(color.FgYellow, color.Bold),
+	"notice": color.New(color.FgCyan),
+	"info":   color.New(color.FgBlue),
+	"debug":  color.New(color.FgWhite),
+	"trace":  color.New(color.FgWhite, color.Faint),
+}
+
+// levelPaints are levelColors as paints.
+var levelPaints = func() map[string]paint {
+	paints := make(map[string]paint, len(levelColors))
+	for level, c := range levelColors {
+		paints[level] = paintOf(c)
+	}
+	return paints
+}()
+
+var (
+	defaultLevelColor = color.New(color.FgWhite)
+	defaultLevelPaint = paintOf(defaultLevelColor)
+
+	successStatusColor     = color.New(color.FgGreen)
+	redirectStatusColor    = color.New(color.FgYellow)
+	clientErrorStatusColor = color.New(color.FgRed)
+	serverErrorStatusColor = color.New(color.FgRed, color.Bold)
+	otherStatusColor       = color.New(color.FgWhite)
+
+	successStatusPaint     = paintOf(successStatusColor)
+	redirectStatusPaint    = paintOf(redirectStatusColor)
+	clientErrorStatusPaint = paintOf(clientErrorStatusColor)
+	serverErrorStatusPaint = paintOf(serverErrorStatusColor)
+	otherStatusPaint       = paintOf(otherStatusColor)
+)
+
+// statusPaint is getStatusColor as a paint.
+func statusPaint(status int) paint {
+	switch {
+	case status >= 200 && status < 300:
+		return successStatusPaint
+	case status >= 300 && status < 400:
+		return redirectStatusPaint
+	case status >= 400 && status < 500:
+		return clientErrorStatusPaint
+	case status >= 500:
+		return serverErrorStatusPaint
+	}
+	return otherStatusPaint
+}
+
+// writeTimeLevel writes the time and the level every entry line starts
+// with, e.g. "14:02:11.123 [warn]", without allocating for the usual
+// levels.
+func writeTimeLevel(b *bytes.Buffer, t time.Time, level string) {
+	if color.NoColor {
+		b.Write(t.AppendFormat(b.AvailableBuffer(), "15:04:05.000"))
+	} else {
+		b.WriteString(timestampPaint.start)
+		b.Write(t.AppendFormat(b.AvailableBuffer(), "15:04:05.000"))
+		b.WriteString(timestampPaint.end)
+	}
+	b.WriteString(" [")
+	label := padWidth(levelLabel(level), 4)
+	if style, ok := lookupLevelStyle(level); ok && style.color != nil {
+		b.WriteString(style.color.Sprint(label))
+	} else if p, ok := levelPaints[normalizeLevel(level)]; ok {
+		p.write(b, label)
+	} else {
+		defaultLevelPaint.write(b, label)
+	}
+	b.WriteByte(']')
+}
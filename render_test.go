@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+// benchmarkEntries are typical entries for the rendering benchmarks.
+var benchmarkEntries = map[string]string{
+	"general": `{"@timestamp":"2024-01-15T14:02:11.123Z","log.level":"warn","message":"cache miss for user 42, falling back to db","error":"timeout"}`,
+	"http":    `{"@timestamp":"2024-01-15T14:02:11.123Z","log.level":"info","message":"served","category":"http","http":{"request":{"method":"GET"},"response":{"status_code":200}},"url":{"path":"/api/users"},"event":{"duration":12000000},"user_agent":{"original":"curl/8.7.1"}}`,
+	"grpc":    `{"@timestamp":"2024-01-15T14:02:11.123Z","grpc.service":"helloworld.Greeter","grpc.method":"SayHello","grpc.code":"DeadlineExceeded","grpc.time_ms":12.5}`,
+	"sql":     `{"@timestamp":"2024-01-15T14:02:11.123Z","db":{"statement":"SELECT name FROM users WHERE id = $1"},"event":{"duration":2500000},"rows":1}`,
+}
+
+func benchmarkWritePrettyLog(b *testing.B, name string, colors bool) {
+	noColor := color.NoColor
+	color.NoColor = !colors
+	defer func() { color.NoColor = noColor }()
+
+	var entry LogEntry
+	if err := json.Unmarshal([]byte(benchmarkEntries[name]), &entry); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		writePrettyLog(io.Discard, entry)
+	}
+}
+
+func BenchmarkWritePrettyLogGeneral(b *testing.B) { benchmarkWritePrettyLog(b, "general", true) }
+func BenchmarkWritePrettyLogGeneralNoColor(b *testing.B) {
+	benchmarkWritePrettyLog(b, "general", false)
+}
+func BenchmarkWritePrettyLogHTTP(b *testing.B)        { benchmarkWritePrettyLog(b, "http", true) }
+func BenchmarkWritePrettyLogHTTPNoColor(b *testing.B) { benchmarkWritePrettyLog(b, "http", false) }
+func BenchmarkWritePrettyLogGRPC(b *testing.B)        { benchmarkWritePrettyLog(b, "grpc", true) }
+func BenchmarkWritePrettyLogGRPCNoColor(b *testing.B) { benchmarkWritePrettyLog(b, "grpc", false) }
+func BenchmarkWritePrettyLogSQL(b *testing.B)         { benchmarkWritePrettyLog(b, "sql", true) }
+func BenchmarkWritePrettyLogSQLNoColor(b *testing.B)  { benchmarkWritePrettyLog(b, "sql", false) }
+
+// benchmarkParseAndRender parses and renders a line the way the main loop
+// does, reading its fields for --where, --promote and --row-style.
+func benchmarkParseAndRender(b *testing.B, name string) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+
+	line := benchmarkEntries[name]
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		entry, ok := parseLine(line)
+		if !ok {
+			b.Fatal("line not parsed")
+		}
+		for j := 0; j < 3; j++ {
+			entryFields(line, entry)
+		}
+		writePrettyLog(io.Discard, entry)
+	}
+}
+
+func BenchmarkParseAndRenderGeneral(b *testing.B) { benchmarkParseAndRender(b, "general") }
+func BenchmarkParseAndRenderHTTP(b *testing.B)    { benchmarkParseAndRender(b, "http") }
+func BenchmarkParseAndRenderGRPC(b *testing.B)    { benchmarkParseAndRender(b, "grpc") }
+func BenchmarkParseAndRenderSQL(b *testing.B)     { benchmarkParseAndRender(b, "sql") }
+
+// countingWriter counts the writes made to it.
+type countingWriter struct{ writes int }
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.writes++
+	return len(p), nil
+}
+
+func TestWritePrettyLogWritesOnce(t *testing.T) {
+	for name, line := range benchmarkEntries {
+		var entry LogEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		var w countingWriter
+		writePrettyLog(&w, entry)
+		if w.writes != 1 {
+			t.Errorf("%s: %d writes, want 1", name, w.writes)
+		}
+	}
+}
+
+func TestPaint(t *testing.T) {
+	noColor := color.NoColor
+	defer func() { color.NoColor = noColor }()
+
+	p := newPaint(color.FgRed, color.Bold)
+	for _, colors := range []bool{true, false} {
+		color.NoColor = !colors
+		var b bytes.Buffer
+		p.write(&b, "boom")
+		if want := color.New(color.FgRed, color.Bold).Sprint("boom"); b.String() != want {
+			t.Errorf("colors %v: paint = %q, want %q", colors, b.String(), want)
+		}
+	}
+}
+
+var sgrRegex = regexp.MustCompile(`\x1b\[[\d;]*m`)
+
+func TestWritePrettyLogPlain(t *testing.T) {
+	noColor := color.NoColor
+	defer func() { color.NoColor = noColor }()
+
+	for name, line := range benchmarkEntries {
+		var entry LogEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		var colored, plain bytes.Buffer
+		color.NoColor = false
+		writePrettyLog(&colored, entry)
+		color.NoColor = true
+		writePrettyLog(&plain, entry)
+		if want := sgrRegex.ReplaceAllString(colored.String(), ""); plain.String() != want {
+			t.Errorf("%s: plain = %q, want %q", name, plain.String(), want)
+		}
+	}
+}
+
+func TestPaintSprint(t *testing.T) {
+	noColor := color.NoColor
+	defer func() { color.NoColor = noColor }()
+
+	p := newPaint(color.FgRed, color.Bold)
+	color.NoColor = false
+	if got, want := p.sprint("boom"), color.New(color.FgRed, color.Bold).Sprint("boom"); got != want {
+		t.Errorf("sprint() = %q, want %q", got, want)
+	}
+	color.NoColor = true
+	if got := testing.AllocsPerRun(10, func() { p.sprint("boom") }); got != 0 {
+		t.Errorf("sprint() without colors allocates %v times, want 0", got)
+	}
+}
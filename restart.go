@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// startupRegex matches the messages servers and frameworks log once they
+// are up, e.g. Spring's "Started App in 3.2 seconds".
+var startupRegex = regexp.MustCompile(`(?i)^(server|application|app|service) (has )?(started|is ready|ready)\b|^started \S+ in [\d.]+ ?s|\blistening on\b|\bstartup complete\b|\bready to accept connections\b`)
+
+// restartQuiet is how many entries of an input follow a version or PID
+// rule during which startup messages don't add another one.
+const restartQuiet = 50
+
+// restartRuleWidth is the width restart rules are drawn to.
+const restartRuleWidth = 80
+
+var restartColor = color.New(color.FgYellow)
+
+// restartDetector notices deploys and restarts in each input: a new
+// service.version, a new process.pid, or a startup message.
+type restartDetector struct {
+	inputs map[string]*restartState
+}
+
+type restartState struct {
+	version string
+	pid     int
+	// sinceRule counts the entries since the last rule, -1 before any
+	sinceRule int
+}
+
+func newRestartDetector() *restartDetector {
+	return &restartDetector{inputs: make(map[string]*restartState)}
+}
+
+// check returns the rule to draw in front of an entry of the input label,
+// or "" when it doesn't start a new deploy or process.
+func (d *restartDetector) check(label string, entry LogEntry, fields map[string]interface{}) string {
+	state := d.inputs[label]
+	if state == nil {
+		state = &restartState{sinceRule: -1}
+		d.inputs[label] = state
+	}
+	if state.sinceRule >= 0 {
+		state.sinceRule++
+	}
+
+	var reason string
+	version := fieldString(fields, "service.version")
+	switch {
+	case version != "" && state.version != "" && version != state.version:
+		reason = fmt.Sprintf("deploy: version %s → %s", state.version, version)
+	case entry.Process.PID != 0 && state.pid != 0 && entry.Process.PID != state.pid:
+		reason = fmt.Sprintf("restart: pid %d → %d", state.pid, entry.Process.PID)
+	case startupRegex.MatchString(entry.Message) && (state.sinceRule < 0 || state.sinceRule > restartQuiet):
+		reason = "started"
+		if entry.Process.PID != 0 {
+			reason += ": pid " + strconv.Itoa(entry.Process.PID)
+		}
+	}
+	if version != "" {
+		state.version = version
+	}
+	if entry.Process.PID != 0 {
+		state.pid = entry.Process.PID
+	}
+	if reason == "" {
+		return ""
+	}
+	state.sinceRule = 0
+	if label != "" {
+		reason = label + " " + reason
+	}
+	return restartRule(reason)
+}
+
+// restartRule draws a horizontal rule with text in it.
+func restartRule(text string) string {
+	rule := "──── " + text + " "
+	if width := displayWidth(rule); width < restartRuleWidth {
+		rule += strings.Repeat("─", restartRuleWidth-width)
+	}
+	return restartColor.Sprint(rule)
+}
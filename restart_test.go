@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestRestartDetector(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	d := newRestartDetector()
+	tests := []struct {
+		name  string
+		label string
+		line  string
+		want  string
+	}{
+		{"first entry", "", `{"message":"hello","service":{"version":"1.4.2"},"process":{"pid":10}}`, ""},
+		{"same process", "", `{"message":"working","service":{"version":"1.4.2"},"process":{"pid":10}}`, ""},
+		{"no fields", "", `{"message":"plain"}`, ""},
+		{"new pid", "", `{"message":"booting","service":{"version":"1.4.2"},"process":{"pid":11}}`, "restart: pid 10 → 11"},
+		{"startup right after", "", `{"message":"Started App in 3.2 seconds","process":{"pid":11}}`, ""},
+		{"new version", "", `{"message":"booting","service.version":"1.5.0","process":{"pid":12}}`, "deploy: version 1.4.2 → 1.5.0"},
+		{"startup of another input", "worker.log", `{"message":"Server started on port 8080"}`, "worker.log started"},
+		{"listening", "db.log", `{"message":"ready to accept connections","process":{"pid":4}}`, "db.log started: pid 4"},
+		{"not a startup", "api.log", `{"message":"user started checkout"}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entry LogEntry
+			json.Unmarshal([]byte(tt.line), &entry)
+			got := d.check(tt.label, entry, decodeJSONObject(tt.line))
+			if tt.want == "" {
+				if got != "" {
+					t.Errorf("check() = %q, want no rule", got)
+				}
+				return
+			}
+			if !strings.HasPrefix(got, "──── "+tt.want+" ─") {
+				t.Errorf("check() = %q, want a rule for %q", got, tt.want)
+			}
+			if width := displayWidth(got); width != restartRuleWidth {
+				t.Errorf("rule width = %d, want %d", width, restartRuleWidth)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// outputRoute copies the entries of some levels to another destination,
+// e.g. "level>=error:stderr" or "level=warn:warnings.log".
+type outputRoute struct {
+	op    string
+	level int
+	out   io.Writer
+}
+
+// routeOperators are the comparisons allowed in routes, longest first.
+var routeOperators = []string{">=", "<=", "!=", "==", ">", "<", "="}
+
+// newOutputRoutes parses --route rules. Destinations are stderr, stdout or
+// a file, which is appended to.
+func newOutputRoutes(rules []string) ([]outputRoute, error) {
+	var routes []outputRoute
+	for _, rule := range rules {
+		condition, dest, ok := strings.Cut(rule, ":")
+		if !ok || dest == "" {
+			return nil, fmt.Errorf("route %q: expected CONDITION:DESTINATION, e.g. level>=error:stderr", rule)
+		}
+		rest, ok := strings.CutPrefix(strings.TrimSpace(condition), "level")
+		if !ok {
+			return nil, fmt.Errorf("route %q: conditions compare the level, e.g. level>=error", rule)
+		}
+		route := outputRoute{}
+		for _, op := range routeOperators {
+			if value, found := strings.CutPrefix(strings.TrimSpace(rest), op); found {
+				route.op = op
+				rest = value
+				break
+			}
+		}
+		if route.op == "" {
+			return nil, fmt.Errorf("route %q: expected one of %s after level", rule, strings.Join(routeOperators, " "))
+		}
+		route.level = slices.Index(canonicalLevels, normalizeLevel(rest))
+		if route.level < 0 {
+			return nil, fmt.Errorf("route %q: unknown level %q (expected one of %s)", rule, strings.TrimSpace(rest), strings.Join(canonicalLevels, ", "))
+		}
+
+		switch dest {
+		case "stderr":
+			route.out = os.Stderr
+		case "stdout":
+			route.out = os.Stdout
+		default:
+			f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+			if err != nil {
+				return nil, fmt.Errorf("route %q: %w", rule, err)
+			}
+			route.out = f
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// matches reports whether an entry's level satisfies the route. Entries
+// without a known level never match.
+func (r outputRoute) matches(level string) bool {
+	index := slices.Index(canonicalLevels, normalizeLevel(level))
+	if index < 0 {
+		return false
+	}
+	switch r.op {
+	case ">=":
+		return index >= r.level
+	case "<=":
+		return index <= r.level
+	case ">":
+		return index > r.level
+	case "<":
+		return index < r.level
+	case "!=":
+		return index != r.level
+	}
+	return index == r.level
+}
+
+// routeLine writes the input line of an entry to every route it matches.
+func routeLine(routes []outputRoute, line, level string) {
+	for _, route := range routes {
+		if route.matches(level) {
+			fmt.Fprintln(route.out, line)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutputRoutes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.log")
+	routes, err := newOutputRoutes([]string{"level>=error:" + path, "level = WARNING:stdout"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 2 || routes[1].out != os.Stdout {
+		t.Fatalf("routes = %+v", routes)
+	}
+
+	tests := []struct {
+		level string
+		want  bool
+	}{
+		{"fatal", true},
+		{"ERROR", true},
+		{"50", true},
+		{"warn", false},
+		{"info", false},
+		{"", false},
+		{"custom", false},
+	}
+	for _, tt := range tests {
+		if got := routes[0].matches(tt.level); got != tt.want {
+			t.Errorf("level>=error matches(%q) = %v, want %v", tt.level, got, tt.want)
+		}
+	}
+	if !routes[1].matches("warn") || routes[1].matches("error") {
+		t.Error("level=warning should only match warn")
+	}
+
+	routeLine(routes[:1], `{"log.level":"error","message":"a"}`, "error")
+	routeLine(routes[:1], `{"log.level":"info","message":"b"}`, "info")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "{\"log.level\":\"error\",\"message\":\"a\"}\n" {
+		t.Errorf("routed file = %q", data)
+	}
+}
+
+func TestOutputRouteErrors(t *testing.T) {
+	tests := []struct {
+		rule    string
+		wantErr string
+	}{
+		{"level>=error", "expected CONDITION:DESTINATION"},
+		{"status>=500:stderr", "conditions compare the level"},
+		{"level~error:stderr", "expected one of"},
+		{"level>=loud:stderr", `unknown level "loud"`},
+		{"level>=error:" + filepath.Join(t.TempDir(), "missing", "x.log"), "no such file"},
+	}
+	for _, tt := range tests {
+		if _, err := newOutputRoutes([]string{tt.rule}); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("newOutputRoutes(%q) error = %v, want %q", tt.rule, err, tt.wantErr)
+		}
+	}
+}
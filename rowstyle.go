@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// StyleRule styles whole entries matching an expression, e.g. dimming
+// health checks or painting slow requests red.
+type StyleRule struct {
+	When      string `json:"when"`
+	Fg        string `json:"fg"`
+	Bg        string `json:"bg"`
+	Bold      bool   `json:"bold"`
+	Faint     bool   `json:"faint"`
+	Underline bool   `json:"underline"`
+}
+
+type compiledStyleRule struct {
+	when *expr
+	// sgr is the escape sequence turning the rule's attributes on.
+	sgr string
+}
+
+// styleRules holds the rules from the config, in order.
+var styleRules []compiledStyleRule
+
+// addStyleRules compiles row styling rules from the config.
+func addStyleRules(rules []StyleRule) error {
+	for i, rule := range rules {
+		if rule.When == "" {
+			return fmt.Errorf("style rule %d: missing condition", i+1)
+		}
+		when, err := compileExpr(rule.When)
+		if err != nil {
+			return fmt.Errorf("style rule %d: %w", i+1, err)
+		}
+		attrs, err := styleAttributes(rule.Fg, rule.Bg, rule.Bold, rule.Faint, rule.Underline)
+		if err != nil {
+			return fmt.Errorf("style rule %d: %w", i+1, err)
+		}
+		if len(attrs) == 0 {
+			return fmt.Errorf("style rule %d: no style set", i+1)
+		}
+		codes := make([]string, len(attrs))
+		for j, attr := range attrs {
+			codes[j] = strconv.Itoa(int(attr))
+		}
+		styleRules = append(styleRules, compiledStyleRule{when: when, sgr: "\x1b[" + strings.Join(codes, ";") + "m"})
+	}
+	return nil
+}
+
+// matchRowStyle returns the escape sequence of the first rule matching the
+// entry's fields, or "".
+func matchRowStyle(fields map[string]interface{}) string {
+	for _, rule := range styleRules {
+		if truthy(rule.when.evaluate(fields)) {
+			return rule.sgr
+		}
+	}
+	return ""
+}
+
+var sgrPattern = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// styleRow applies a rule's escape sequence to every line of a rendered
+// entry, restoring it after each of the entry's own colors so that the
+// rule's attributes hold across the whole line.
+func styleRow(rendered, sgr string) string {
+	if sgr == "" || color.NoColor {
+		return rendered
+	}
+	lines := strings.SplitAfter(rendered, "\n")
+	var b strings.Builder
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		text := strings.TrimSuffix(line, "\n")
+		b.WriteString(sgr)
+		b.WriteString(sgrPattern.ReplaceAllString(text, "${0}"+sgr))
+		b.WriteString("\x1b[0m")
+		if strings.HasSuffix(line, "\n") {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
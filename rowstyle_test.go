@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestStyleRules(t *testing.T) {
+	saved := styleRules
+	styleRules = nil
+	defer func() { styleRules = saved }()
+	err := addStyleRules([]StyleRule{
+		{When: `url.path =~ "^/healthz"`, Faint: true},
+		{When: "event.duration > 2s", Fg: "white", Bg: "red"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"health check", `{"url":{"path":"/healthz"},"event":{"duration":3000000000}}`, "\x1b[2m"},
+		{"slow request", `{"url":{"path":"/api"},"event":{"duration":3000000000}}`, "\x1b[37;41m"},
+		{"no match", `{"url":{"path":"/api"},"event":{"duration":1000}}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchRowStyle(decodeJSONObject(tt.line)); got != tt.want {
+				t.Errorf("matchRowStyle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	for _, rule := range []StyleRule{{Faint: true}, {When: "a >"}, {When: "a", Bg: "mauve"}, {When: "a"}} {
+		if err := addStyleRules([]StyleRule{rule}); err == nil {
+			t.Errorf("addStyleRules(%+v) should fail", rule)
+		}
+	}
+}
+
+func TestStyleRow(t *testing.T) {
+	noColor := color.NoColor
+	defer func() { color.NoColor = noColor }()
+	color.NoColor = false
+
+	rendered := "12:00:00 [\x1b[31merro\x1b[0m] boom\n  detail\n"
+	want := "\x1b[2m12:00:00 [\x1b[31m\x1b[2merro\x1b[0m\x1b[2m] boom\x1b[0m\n\x1b[2m  detail\x1b[0m\n"
+	if got := styleRow(rendered, "\x1b[2m"); got != want {
+		t.Errorf("styleRow() = %q, want %q", got, want)
+	}
+
+	color.NoColor = true
+	if got := styleRow(rendered, "\x1b[2m"); got != rendered {
+		t.Errorf("styleRow() without colors = %q", got)
+	}
+}
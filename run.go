@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+// runCommand starts command with its stdout and stderr captured separately,
+// hands their lines to handle and returns the command's exit code. Signals
+// sent to logpipe are forwarded to the command so it can shut down cleanly.
+func runCommand(command []string, handle func(line, stream string)) (int, error) {
+	if len(command) == 0 {
+		return 2, errors.New("no command given, usage: logpipe run [OPTIONS] -- COMMAND [ARGS...]")
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = os.Stdin
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 1, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return 1, err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
+	defer signal.Stop(signals)
+
+	if err := cmd.Start(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+			return 127, err
+		}
+		return 126, err
+	}
+	go func() {
+		for sig := range signals {
+			cmd.Process.Signal(sig)
+		}
+	}()
+
+	// Both pipes must be drained before Wait closes them
+	readErr := mergeStreamsByArrival(stdout, stderr, handle)
+	err = cmd.Wait()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitCode(exitErr), nil
+	}
+	if err != nil {
+		return 1, err
+	}
+	if readErr != nil {
+		return 1, fmt.Errorf("reading output: %w", readErr)
+	}
+	return 0, nil
+}
+
+// exitCode mirrors the shell's convention of 128+N for a command killed by
+// signal N.
+func exitCode(err *exec.ExitError) int {
+	if status, ok := err.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return err.ExitCode()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os/exec"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestRunCommand(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	tests := []struct {
+		name     string
+		script   string
+		wantCode int
+		want     []string
+	}{
+		{"success", "echo out", 0, []string{"stdout out"}},
+		{"both streams", "echo out; echo err >&2; exit 3", 3, []string{"stderr err", "stdout out"}},
+		{"killed", "echo bye; kill -TERM $$", 143, []string{"stdout bye"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			code, err := runCommand([]string{"sh", "-c", tt.script}, func(line, stream string) {
+				got = append(got, stream+" "+line)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+			sort.Strings(got)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("lines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if code, err := runCommand([]string{"logpipe-no-such-command"}, func(string, string) {}); err == nil || code != 127 {
+		t.Errorf("missing command = %d, %v, want 127 and an error", code, err)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// sanitizeModes are the values of --sanitize: strip removes terminal
+// control sequences from input lines, escape shows them as visible
+// symbols (␛[31m) and off passes them through.
+var sanitizeModes = []string{"strip", "escape", "off"}
+
+func checkSanitizeMode(mode string) error {
+	for _, m := range sanitizeModes {
+		if mode == m {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown mode %q (expected %s)", mode, strings.Join(sanitizeModes, ", "))
+}
+
+// controlUnit is one character of a line, either as written or as a JSON
+// \uXXXX escape, so escape sequences are found in both forms.
+type controlUnit struct {
+	r    rune
+	text string
+	json bool
+}
+
+// jsonControlEscapes are the short JSON escapes of control characters,
+// decoded only in JSON lines so Windows paths (C:\build) stay intact.
+var jsonControlEscapes = map[byte]rune{'b': '\b', 'f': '\f', 'r': '\r'}
+
+// splitControlUnits splits line into characters, decoding \uXXXX escapes
+// and keeping escaped backslashes as one unit, so \\u001b stays text.
+func splitControlUnits(line string) []controlUnit {
+	isJSON := strings.HasPrefix(strings.TrimSpace(line), "{")
+	var units []controlUnit
+	for i := 0; i < len(line); {
+		if line[i] == '\\' && i+1 < len(line) {
+			if line[i+1] == 'u' && i+6 <= len(line) {
+				if n, err := strconv.ParseUint(line[i+2:i+6], 16, 16); err == nil {
+					units = append(units, controlUnit{rune(n), line[i : i+6], true})
+					i += 6
+					continue
+				}
+			}
+			if r, ok := jsonControlEscapes[line[i+1]]; ok && isJSON {
+				units = append(units, controlUnit{r, line[i : i+2], true})
+				i += 2
+				continue
+			}
+			if line[i+1] == '\\' {
+				units = append(units, controlUnit{'\\', line[i : i+2], false})
+				i += 2
+				continue
+			}
+		}
+		r, size := utf8.DecodeRuneInString(line[i:])
+		units = append(units, controlUnit{r, line[i : i+size], false})
+		i += size
+	}
+	return units
+}
+
+// isControl reports whether r is a control character that can affect the
+// terminal. Tabs and newlines are kept.
+func isControl(r rune) bool {
+	return r >= 0 && r < 0x20 && r != '\t' && r != '\n' || r >= 0x7F && r < 0xA0
+}
+
+// mayNeedSanitizing reports whether line may hold control characters, raw
+// or escaped, so most lines skip the full scan.
+func mayNeedSanitizing(line string) bool {
+	for i := 0; i < len(line); i++ {
+		if c := line[i]; c < 0x20 && c != '\t' || c == 0x7F || c == 0xC2 {
+			return true
+		}
+	}
+	return strings.Contains(line, `\u00`) || strings.Contains(line, `\r`) || strings.Contains(line, `\b`) || strings.Contains(line, `\f`)
+}
+
+// sanitizeLine neutralizes terminal control sequences in an input line,
+// so log content can't move the cursor, retitle the window or hide text.
+// In strip mode, escape sequences are removed whole; in escape mode,
+// control characters are replaced by their Control Pictures symbols.
+func sanitizeLine(line, mode string) string {
+	if mode == "off" || !mayNeedSanitizing(line) {
+		return line
+	}
+	units := splitControlUnits(line)
+	var b strings.Builder
+	for i := 0; i < len(units); i++ {
+		u := units[i]
+		if !isControl(u.r) {
+			b.WriteString(u.text)
+			continue
+		}
+		if mode == "escape" {
+			b.WriteString(controlPicture(u))
+			continue
+		}
+		i = skipEscapeSequence(units, i)
+	}
+	return b.String()
+}
+
+// skipEscapeSequence returns the index of the last unit of the control
+// sequence starting at units[i]: CSI (ESC [ or 0x9B) up to its final
+// byte, OSC and other strings (ESC ], P, X, ^, _ or 0x9D) up to BEL or
+// ESC \, two-character ESC sequences, or a lone control character.
+func skipEscapeSequence(units []controlUnit, i int) int {
+	kind := units[i].r
+	if kind == 0x1B && i+1 < len(units) {
+		switch units[i+1].r {
+		case '[':
+			kind = 0x9B
+		case ']', 'P', 'X', '^', '_':
+			kind = 0x9D
+		default:
+			return i + 1
+		}
+		i++
+	}
+	switch kind {
+	case 0x9B:
+		for i++; i < len(units); i++ {
+			if r := units[i].r; r >= 0x40 && r <= 0x7E {
+				return i
+			}
+		}
+	case 0x9D:
+		for i++; i < len(units); i++ {
+			switch {
+			case units[i].r == 0x07 || units[i].r == 0x9C:
+				return i
+			case units[i].r == 0x1B && i+1 < len(units) && units[i+1].r == '\\':
+				return i + 1
+			}
+		}
+	default:
+		return i
+	}
+	return len(units) - 1
+}
+
+// controlPicture returns the visible symbol of a control character, in
+// the form it was written in.
+func controlPicture(u controlUnit) string {
+	picture := '�'
+	switch {
+	case u.r < 0x20:
+		picture = 0x2400 + u.r
+	case u.r == 0x7F:
+		picture = '␡'
+	}
+	if u.json {
+		return fmt.Sprintf(`\u%04x`, picture)
+	}
+	return string(picture)
+}
@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestSanitizeLine(t *testing.T) {
+	tests := []struct {
+		name, line, mode, want string
+	}{
+		{"plain", "hello world", "strip", "hello world"},
+		{"tabs kept", "a\tb", "strip", "a\tb"},
+		{"raw color", "\x1b[31mred\x1b[0m text", "strip", "red text"},
+		{"raw cursor movement", "ok\x1b[2K\x1b[1Afake", "strip", "okfake"},
+		{"raw title", "\x1b]0;pwned\x07after", "strip", "after"},
+		{"raw title with ST", "\x1b]0;pwned\x1b\\after", "strip", "after"},
+		{"raw lone controls", "bell\x07 back\bspace\r", "strip", "bell backspace"},
+		{"c1 csi", "x\u009b2Jy", "strip", "xy"},
+		{"two-character sequence", "a\x1bcb", "strip", "ab"},
+		{"unterminated", "a\x1b[31", "strip", "a"},
+		{"json color", `{"message":"\u001b[31mred\u001b[0m"}`, "strip", `{"message":"red"}`},
+		{"json uppercase", `{"message":"\u001B]2;x\u0007y"}`, "strip", `{"message":"y"}`},
+		{"json newline kept", `{"message":"a\nb\u000ac"}`, "strip", `{"message":"a\nb\u000ac"}`},
+		{"escaped backslash", `{"message":"C:\\u001b"}`, "strip", `{"message":"C:\\u001b"}`},
+		{"json carriage return", `{"message":"shown\rhidden"}`, "strip", `{"message":"shownhidden"}`},
+		{"windows path", `C:\build\release failed`, "strip", `C:\build\release failed`},
+		{"escape json carriage return", `{"message":"a\rb"}`, "escape", `{"message":"a\u240db"}`},
+		{"json unicode kept", `{"message":"caf\u00e9"}`, "strip", `{"message":"caf\u00e9"}`},
+		{"escape raw", "\x1b[31mred", "escape", "␛[31mred"},
+		{"escape json", `{"message":"\u001b[31mred"}`, "escape", `{"message":"\u241b[31mred"}`},
+		{"escape delete", "a\x7f", "escape", "a␡"},
+		{"off", "\x1b[31mred", "off", "\x1b[31mred"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeLine(tt.line, tt.mode); got != tt.want {
+				t.Errorf("sanitizeLine(%q, %q) = %q, want %q", tt.line, tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckSanitizeMode(t *testing.T) {
+	for _, mode := range sanitizeModes {
+		if err := checkSanitizeMode(mode); err != nil {
+			t.Errorf("checkSanitizeMode(%q) = %v", mode, err)
+		}
+	}
+	if err := checkSanitizeMode("remove"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
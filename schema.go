@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	// schemaMaxDistinct caps the distinct values counted per field.
+	schemaMaxDistinct = 1000
+	// schemaExamples is how many example values are shown per field.
+	schemaExamples = 3
+)
+
+// schemaField is what `logpipe schema` observed of one field path.
+type schemaField struct {
+	count    int
+	types    map[string]int
+	distinct map[string]bool
+	examples []string
+}
+
+// schemaReport collects the field paths of a stream of entries.
+type schemaReport struct {
+	name    string
+	entries int
+	fields  map[string]*schemaField
+}
+
+func newSchemaReport(name string) *schemaReport {
+	return &schemaReport{name: name, fields: make(map[string]*schemaField)}
+}
+
+// add records the fields of a line: JSON objects, nested or with dotted
+// keys, and logfmt pairs. Other lines only count as entries.
+func (r *schemaReport) add(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	r.entries++
+	fields := decodeJSONObject(line)
+	if fields == nil {
+		pairs, ok := splitLogfmt(line)
+		if !ok || len(pairs) < 2 {
+			return
+		}
+		fields = make(map[string]interface{}, len(pairs))
+		for _, kv := range pairs {
+			fields[kv.Key] = kv.Value
+		}
+	}
+	for path, value := range flattenFields(fields, nil) {
+		if path == "" {
+			continue
+		}
+		f := r.fields[path]
+		if f == nil {
+			f = &schemaField{types: make(map[string]int), distinct: make(map[string]bool)}
+			r.fields[path] = f
+		}
+		f.count++
+		f.types[jsonTypeName(value)]++
+		text := formatDiffValue(value)
+		if len(f.distinct) < schemaMaxDistinct {
+			f.distinct[text] = true
+		}
+		if len(f.examples) < schemaExamples && !slices.Contains(f.examples, text) {
+			f.examples = append(f.examples, text)
+		}
+	}
+}
+
+// typeNames returns the types seen, the most frequent first.
+func (f *schemaField) typeNames() string {
+	names := sortedKeys(f.types)
+	sort.SliceStable(names, func(i, j int) bool {
+		return f.types[names[i]] > f.types[names[j]]
+	})
+	return strings.Join(names, "|")
+}
+
+// write prints one row per field path: its types, the share of entries
+// having it, its number of distinct values and a few examples.
+func (r *schemaReport) write(w io.Writer) {
+	fmt.Fprintf(w, "%s: %d entries, %d fields\n", r.name, r.entries, len(r.fields))
+	if len(r.fields) == 0 {
+		return
+	}
+	paths := sortedKeys(r.fields)
+	width := len("field")
+	for _, path := range paths {
+		width = max(width, displayWidth(path))
+	}
+	fmt.Fprintf(w, "  %s %-14s %6s %8s  %s\n", padWidth("field", width), "type", "fill", "distinct", "examples")
+	for _, path := range paths {
+		f := r.fields[path]
+		distinct := fmt.Sprint(len(f.distinct))
+		if len(f.distinct) >= schemaMaxDistinct {
+			distinct += "+"
+		}
+		examples := make([]string, len(f.examples))
+		for i, example := range f.examples {
+			examples[i] = truncateWidth(example, 30)
+		}
+		fmt.Fprintf(w, "  %s %-14s %5.1f%% %8s  %s\n", padWidth(path, width), f.typeNames(),
+			100*float64(f.count)/float64(r.entries), distinct, labelColor.Sprint(strings.Join(examples, ", ")))
+	}
+}
+
+// runSchema reports the fields observed in log files, or in stdin when
+// none are given.
+func runSchema(files []string, w io.Writer) int {
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	for _, name := range files {
+		report := newSchemaReport(name)
+		if name == "-" {
+			report.name = "stdin"
+		}
+		if err := readLines(name, report.add); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", name, err)
+			return 2
+		}
+		report.write(w)
+	}
+	return 0
+}
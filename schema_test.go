@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestSchemaReport(t *testing.T) {
+	r := newSchemaReport("app.log")
+	for _, line := range []string{
+		`{"message":"a","http":{"response":{"status_code":200}},"tags":["x"]}`,
+		`{"message":"b","http.response.status_code":"500","user":null}`,
+		`{"message":"a"}`,
+		`level=info msg=started`,
+		`plain text`,
+		``,
+	} {
+		r.add(line)
+	}
+
+	if r.entries != 5 {
+		t.Errorf("entries = %d, want 5", r.entries)
+	}
+	tests := []struct {
+		path     string
+		count    int
+		types    string
+		distinct int
+		examples string
+	}{
+		{"message", 3, "string", 2, "a,b"},
+		{"http.response.status_code", 2, "number|string", 2, "200,500"},
+		{"tags", 1, "array", 1, `["x"]`},
+		{"user", 1, "null", 1, "null"},
+		{"level", 1, "string", 1, "info"},
+	}
+	for _, tt := range tests {
+		f := r.fields[tt.path]
+		if f == nil {
+			t.Errorf("%s not found", tt.path)
+			continue
+		}
+		if f.count != tt.count || f.typeNames() != tt.types || len(f.distinct) != tt.distinct || strings.Join(f.examples, ",") != tt.examples {
+			t.Errorf("%s = %d %s %d %q, want %d %s %d %q", tt.path, f.count, f.typeNames(), len(f.distinct), f.examples,
+				tt.count, tt.types, tt.distinct, tt.examples)
+		}
+	}
+}
+
+func TestSchemaDistinctCap(t *testing.T) {
+	r := newSchemaReport("ids")
+	for i := 0; i < schemaMaxDistinct+10; i++ {
+		r.add(fmt.Sprintf(`{"id":%d}`, i))
+	}
+	if n := len(r.fields["id"].distinct); n != schemaMaxDistinct {
+		t.Errorf("distinct = %d, want %d", n, schemaMaxDistinct)
+	}
+
+	defer func(noColor bool) { color.NoColor = noColor }(color.NoColor)
+	color.NoColor = true
+	var out bytes.Buffer
+	r.write(&out)
+	if !strings.Contains(out.String(), "1000+  0, 1, 2") {
+		t.Errorf("output doesn't show the capped count:\n%s", out.String())
+	}
+}
+
+func TestRunSchema(t *testing.T) {
+	defer func(noColor bool) { color.NoColor = noColor }(color.NoColor)
+	color.NoColor = true
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte(`{"message":"hi","log.level":"info"}`+"\n"+`{"message":"bye"}`+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if code := runSchema([]string{path}, &out); code != 0 {
+		t.Fatalf("runSchema() = %d", code)
+	}
+	want := []string{
+		path + ": 2 entries, 2 fields",
+		"field     type             fill distinct  examples",
+		"log.level string          50.0%        1  info",
+		"message   string         100.0%        2  hi, bye",
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("output =\n%s", out.String())
+	}
+	for i := range want {
+		if strings.TrimSpace(lines[i]) != want[i] {
+			t.Errorf("line %d = %q, want %q", i, strings.TrimSpace(lines[i]), want[i])
+		}
+	}
+
+	if code := runSchema([]string{filepath.Join(t.TempDir(), "missing")}, &out); code != 2 {
+		t.Errorf("runSchema() for a missing file = %d, want 2", code)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// SecurityEvent is an ECS entry that matters for security: an alert
+// (event.kind: alert), or an authentication or intrusion detection event.
+type SecurityEvent struct {
+	// Kind is "alert", "authentication" or "intrusion_detection".
+	Kind string
+	// Rule names the rule or signature that fired.
+	Rule string
+	// Severity is the logged severity or risk score, as logged.
+	Severity string
+	Outcome  string
+	User     string
+	SourceIP string
+}
+
+// securityCategories are the event.category values rendered as security
+// events.
+var securityCategories = []string{"authentication", "intrusion_detection"}
+
+// securityKindLabels are shown in front of security events.
+var securityKindLabels = map[string]string{
+	"alert":               "ALERT",
+	"authentication":      "AUTH",
+	"intrusion_detection": "IDS",
+}
+
+// parseSecurity extracts a security event from a JSON log line, returning
+// nil for other entries.
+func parseSecurity(line *lineFields) *SecurityEvent {
+	if !strings.Contains(line.text, "alert") && !strings.Contains(line.text, "authentication") && !strings.Contains(line.text, "intrusion_detection") {
+		return nil
+	}
+	fields := line.object()
+	if fields == nil {
+		return nil
+	}
+	kind := ""
+	if fieldString(fields, "event.kind") == "alert" {
+		kind = "alert"
+	} else {
+		categories, _ := lookupField(fields, "event.category")
+		for _, category := range securityCategories {
+			if hasCategory(categories, category) {
+				kind = category
+				break
+			}
+		}
+	}
+	if kind == "" {
+		return nil
+	}
+	return &SecurityEvent{
+		Kind:     kind,
+		Rule:     fieldString(fields, "rule.name", "alert.signature", "signature", "rule.description", "rule.id", "alert.signature_id"),
+		Severity: fieldString(fields, "event.risk_score", "event.severity", "rule.severity", "alert.severity"),
+		Outcome:  normalizeOutcome(fieldString(fields, "event.outcome")),
+		User:     fieldString(fields, "user.name", "user.email", "user.id"),
+		SourceIP: fieldString(fields, "source.ip", "src_ip", "client.ip"),
+	}
+}
+
+// hasCategory tells whether an ECS event.category, a string or a list of
+// them, holds category.
+func hasCategory(categories interface{}, category string) bool {
+	switch categories := categories.(type) {
+	case string:
+		return categories == category
+	case []interface{}:
+		for _, c := range categories {
+			if c == category {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var (
+	securityLabelPaint = newPaint(color.FgHiWhite, color.BgRed, color.Bold)
+	securityRulePaint  = newPaint(color.FgYellow, color.Bold)
+)
+
+// securityIcon is the shield shown in front of security events, from the
+// Nerd Font (shield) when --icon-set nerd is used.
+func securityIcon() string {
+	if iconSet == "nerd" {
+		return "\uf132"
+	}
+	return "🛡"
+}
+
+var (
+	highSeverityPaint   = newPaint(color.FgRed, color.Bold)
+	mediumSeverityPaint = newPaint(color.FgYellow)
+	lowSeverityPaint    = newPaint(color.FgCyan)
+)
+
+// severityPaint colors a severity or risk score: ECS risk scores and
+// severities run from 0 to 100, with 73 and above being high or critical.
+func severityPaint(severity string) paint {
+	score, err := strconv.ParseFloat(severity, 64)
+	switch {
+	case err != nil:
+		return mediumSeverityPaint
+	case score >= 73:
+		return highSeverityPaint
+	case score >= 47:
+		return mediumSeverityPaint
+	}
+	return lowSeverityPaint
+}
+
+// securityHeadline renders the front of a security event's line: the
+// shield, its kind, its severity and the rule that fired, e.g.
+// "🛡 ALERT sev=73 SSH brute force".
+func securityHeadline(event *SecurityEvent) string {
+	var b strings.Builder
+	b.WriteString(securityIcon() + " " + securityLabelPaint.sprint(" "+securityKindLabels[event.Kind]+" "))
+	if event.Severity != "" {
+		b.WriteString(" " + severityPaint(event.Severity).sprint("sev="+event.Severity))
+	}
+	if event.Rule != "" {
+		b.WriteString(" " + securityRulePaint.sprint(event.Rule))
+	}
+	return b.String()
+}
+
+// securityDetails renders who and where a security event is about, and
+// its outcome, e.g. " user=alice src=10.0.0.5 → failure".
+func securityDetails(event *SecurityEvent) string {
+	var b strings.Builder
+	if event.User != "" {
+		b.WriteString(" " + labelPaint.sprint("user="+event.User))
+	}
+	if event.SourceIP != "" {
+		b.WriteString(" " + labelPaint.sprint("src="+event.SourceIP))
+	}
+	if event.Outcome != "" {
+		b.WriteString(" " + auditOutcomePaint(event.Outcome).sprint("→ "+event.Outcome))
+	}
+	return b.String()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestParseSecurity(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want *SecurityEvent
+	}{
+		{
+			"alert",
+			`{"event":{"kind":"alert","risk_score":73,"category":["intrusion_detection"]},"rule":{"name":"SSH brute force"},"source":{"ip":"203.0.113.9"}}`,
+			&SecurityEvent{Kind: "alert", Rule: "SSH brute force", Severity: "73", SourceIP: "203.0.113.9"},
+		},
+		{
+			"authentication",
+			`{"event":{"category":["authentication"],"outcome":"failure"},"user":{"name":"alice"}}`,
+			&SecurityEvent{Kind: "authentication", Outcome: "failure", User: "alice"},
+		},
+		{
+			"flat intrusion detection",
+			`{"event.category":"intrusion_detection","alert.signature":"ET SCAN Nmap","alert.severity":2,"src_ip":"10.0.0.9"}`,
+			&SecurityEvent{Kind: "intrusion_detection", Rule: "ET SCAN Nmap", Severity: "2", SourceIP: "10.0.0.9"},
+		},
+		{"other category", `{"event":{"category":["web"]},"message":"authentication service ready"}`, nil},
+		{"alert in message only", `{"message":"alert sent"}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseSecurity(newLineFields([]byte(tt.line)))
+			if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+				t.Errorf("parseSecurity() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecurityRendering(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	event := &SecurityEvent{Kind: "alert", Rule: "SSH brute force", Severity: "73", User: "alice", SourceIP: "10.0.0.5", Outcome: "failure"}
+	if got, want := securityHeadline(event), "🛡  ALERT  sev=73 SSH brute force"; got != want {
+		t.Errorf("securityHeadline() = %q, want %q", got, want)
+	}
+	if got, want := securityDetails(event), " user=alice src=10.0.0.5 → failure"; got != want {
+		t.Errorf("securityDetails() = %q, want %q", got, want)
+	}
+	if got, want := securityHeadline(&SecurityEvent{Kind: "authentication"}), "🛡  AUTH "; got != want {
+		t.Errorf("securityHeadline() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// timeSeparator marks where entries cross into a new day (or hour, or any
+// interval), since entries only show the time of day.
+type timeSeparator struct {
+	// interval is 0 for calendar days in the entries' own time zone
+	interval time.Duration
+	last     time.Time
+}
+
+// newTimeSeparator reads --separators: day, hour or a duration such as
+// 15m.
+func newTimeSeparator(spec string) (*timeSeparator, error) {
+	switch spec {
+	case "day":
+		return &timeSeparator{}, nil
+	case "hour":
+		return &timeSeparator{interval: time.Hour}, nil
+	}
+	interval, err := time.ParseDuration(spec)
+	if err != nil {
+		return nil, errors.New("expected day, hour or a duration such as 15m")
+	}
+	if interval <= 0 {
+		return nil, errors.New("the interval must be positive")
+	}
+	return &timeSeparator{interval: interval}, nil
+}
+
+// check returns the separator to show in front of an entry logged at
+// timestamp, or "" when it is in the same period as the previous one.
+// Entries going back in time, as when merging inputs, don't add one.
+func (s *timeSeparator) check(timestamp string) string {
+	t, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return ""
+	}
+	period := t.Truncate(s.interval)
+	layout := "2006-01-02 15:04"
+	if s.interval == 0 {
+		period = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		layout = "Monday 2006-01-02"
+	}
+	if !period.After(s.last) {
+		return ""
+	}
+	first := s.last.IsZero()
+	s.last = period
+	if first {
+		return ""
+	}
+	return labelColor.Sprintf("── %s ──", period.Format(layout))
+}
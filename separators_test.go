@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestTimeSeparator(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	tests := []struct {
+		name       string
+		spec       string
+		timestamps []string
+		want       []string
+	}{
+		{
+			name:       "day",
+			spec:       "day",
+			timestamps: []string{"2024-01-15T23:59:58Z", "2024-01-15T23:59:59Z", "2024-01-16T00:00:03Z", "", "2024-01-15T23:00:00Z", "2024-01-17T08:00:00Z"},
+			want:       []string{"", "", "── Tuesday 2024-01-16 ──", "", "", "── Wednesday 2024-01-17 ──"},
+		},
+		{
+			name:       "day in the timestamps' zone",
+			spec:       "day",
+			timestamps: []string{"2024-01-15T23:30:00+01:00", "2024-01-16T00:30:00+01:00"},
+			want:       []string{"", "── Tuesday 2024-01-16 ──"},
+		},
+		{
+			name:       "interval",
+			spec:       "15m",
+			timestamps: []string{"2024-01-16T14:01:00Z", "2024-01-16T14:14:59Z", "2024-01-16T14:15:00Z", "2024-01-16T15:02:00Z"},
+			want:       []string{"", "", "── 2024-01-16 14:15 ──", "── 2024-01-16 15:00 ──"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := newTimeSeparator(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, ts := range tt.timestamps {
+				if got := s.check(ts); got != tt.want[i] {
+					t.Errorf("check(%q) = %q, want %q", ts, got, tt.want[i])
+				}
+			}
+		})
+	}
+
+	for _, spec := range []string{"week", "-1h", "0s"} {
+		if _, err := newTimeSeparator(spec); err == nil {
+			t.Errorf("newTimeSeparator(%q) succeeded", spec)
+		}
+	}
+}
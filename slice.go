@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultBufferLimit is how many entries --tail keeps at most unless
+// --buffer says otherwise.
+const defaultBufferLimit = "100000"
+
+// bufferLimit bounds the entries kept in memory, by count or by size. A
+// zero field is no bound.
+type bufferLimit struct {
+	entries int
+	bytes   int
+}
+
+// bufferSizeUnits are the suffixes --buffer accepts for a size.
+var bufferSizeUnits = []struct {
+	suffix string
+	bytes  int
+}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}}
+
+// parseBufferLimit reads --buffer: a number of entries (100000) or a size
+// (64MB, 512KB, 1GB).
+func parseBufferLimit(s string) (bufferLimit, error) {
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	for _, unit := range bufferSizeUnits {
+		if number, ok := strings.CutSuffix(upper, unit.suffix); ok {
+			n, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+			if err != nil || n <= 0 {
+				return bufferLimit{}, fmt.Errorf("invalid size %q", s)
+			}
+			return bufferLimit{bytes: int(n * float64(unit.bytes))}, nil
+		}
+	}
+	n, err := strconv.Atoi(upper)
+	if err != nil || n <= 0 {
+		return bufferLimit{}, fmt.Errorf("expected a number of entries or a size like 64MB, got %q", s)
+	}
+	return bufferLimit{entries: n}, nil
+}
+
+// outputSlice implements --skip, --head and --tail over the entries that
+// would be shown, after filtering.
+type outputSlice struct {
+	skip, head, tail int
+	seen, shown      int
+	// buffered holds the last rendered entries for --tail, oldest first,
+	// starting at index first.
+	buffered [][]byte
+	first    int
+	size     int
+	limit    bufferLimit
+	// spill holds the entries before buffered once --memory-limit is
+	// exceeded, or nil. noSpill is set when spilling failed.
+	spill   *tailSpill
+	noSpill bool
+}
+
+// newOutputSlice returns the slice for the flags, or nil when none is set.
+// limit bounds the memory --tail uses.
+func newOutputSlice(skip, head, tail int, limit bufferLimit) (*outputSlice, error) {
+	switch {
+	case skip < 0 || head < 0 || tail < 0:
+		return nil, errors.New("--skip, --head and --tail must not be negative")
+	case head > 0 && tail > 0:
+		return nil, errors.New("use either --head or --tail")
+	case skip == 0 && head == 0 && tail == 0:
+		return nil, nil
+	}
+	return &outputSlice{skip: skip, head: head, tail: tail, limit: limit}, nil
+}
+
+// admit reports whether the next entry is shown.
+func (s *outputSlice) admit() bool {
+	s.seen++
+	if s.seen <= s.skip || s.done() {
+		return false
+	}
+	s.shown++
+	return true
+}
+
+// done reports whether --head entries were shown, so input can stop.
+func (s *outputSlice) done() bool {
+	return s.head > 0 && s.shown >= s.head
+}
+
+// keep buffers a rendered entry for --tail, dropping the oldest ones
+// beyond tail entries or the buffer limit. With --memory-limit, entries
+// beyond the buffer limit or the memory budget are spilled to disk rather
+// than dropped.
+func (s *outputSlice) keep(rendered []byte) {
+	s.buffered = append(s.buffered, append([]byte(nil), rendered...))
+	s.size += len(rendered)
+	memoryLimit.grow(len(rendered))
+	for s.count() > s.tail {
+		s.dropOldest()
+	}
+	for s.buffers() > 1 && (s.limit.entries > 0 && s.buffers() > s.limit.entries || s.limit.bytes > 0 && s.size > s.limit.bytes || memoryLimit.exceeded()) {
+		if memoryLimit != nil && !s.noSpill && s.spillOldest() {
+			continue
+		}
+		s.dropOldest()
+	}
+	// Reclaim the dropped entries' slots once they make up half the slice
+	if s.first > 1024 && s.first > len(s.buffered)/2 {
+		s.buffered = append([][]byte(nil), s.buffered[s.first:]...)
+		s.first = 0
+	}
+}
+
+// count returns the number of entries kept, in memory or spilled.
+func (s *outputSlice) count() int {
+	if s.spill != nil {
+		return s.buffers() + s.spill.count()
+	}
+	return s.buffers()
+}
+
+// buffers returns the number of entries kept in memory.
+func (s *outputSlice) buffers() int {
+	return len(s.buffered) - s.first
+}
+
+func (s *outputSlice) dropOldest() {
+	if s.spill != nil && s.spill.count() > 0 {
+		if err := s.spill.dropOldest(); err != nil {
+			s.dropSpill(err)
+		}
+		return
+	}
+	s.size -= len(s.buffered[s.first])
+	memoryLimit.shrink(len(s.buffered[s.first]))
+	s.buffered[s.first] = nil
+	s.first++
+}
+
+// spillOldest moves the oldest entry in memory to the spill file, and
+// reports whether it could.
+func (s *outputSlice) spillOldest() bool {
+	if s.spill == nil {
+		spill, err := newTailSpill()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error spilling --tail entries to disk: %v\n", err)
+			s.noSpill = true
+			return false
+		}
+		s.spill = spill
+	}
+	oldest := s.buffered[s.first]
+	if err := s.spill.write(oldest); err != nil {
+		s.dropSpill(err)
+		return false
+	}
+	memoryLimit.record("--tail entries spilled to disk", 1)
+	s.size -= len(oldest)
+	memoryLimit.shrink(len(oldest))
+	s.buffered[s.first] = nil
+	s.first++
+	return true
+}
+
+// dropSpill gives up on the spill file after an error, and on spilling:
+// the entries in it are dropped, and entries beyond the limits are
+// dropped from then on.
+func (s *outputSlice) dropSpill(err error) {
+	fmt.Fprintf(os.Stderr, "Error spilling --tail entries to disk: %v\n", err)
+	s.spill.close()
+	s.spill = nil
+	s.noSpill = true
+}
+
+// flush writes the buffered --tail entries in order, after a note if the
+// buffer limit cut some of them.
+func (s *outputSlice) flush(w io.Writer) {
+	if evicted := s.evicted(); evicted > 0 {
+		fmt.Fprintf(w, "%s\n", labelColor.Sprintf("· %d earlier entries dropped, raise --buffer to keep more", evicted))
+	}
+	if s.spill != nil {
+		if err := s.spill.copyTo(w); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading spilled --tail entries: %v\n", err)
+		}
+		s.spill.close()
+		s.spill = nil
+	}
+	for _, entry := range s.buffered[s.first:] {
+		w.Write(entry)
+	}
+	memoryLimit.shrink(s.size)
+	s.buffered, s.first, s.size = nil, 0, 0
+}
+
+// evicted returns how many of the entries --tail asked for were dropped to
+// stay within the buffer limit.
+func (s *outputSlice) evicted() int {
+	return min(s.tail, s.shown) - s.count()
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestOutputSlice(t *testing.T) {
+	tests := []struct {
+		name             string
+		skip, head, tail int
+		entries          int
+		want             string
+		wantDone         bool
+	}{
+		{"skip", 3, 0, 0, 5, "4 5", false},
+		{"head", 0, 2, 0, 5, "1 2", true},
+		{"head not reached", 0, 10, 0, 5, "1 2 3 4 5", false},
+		{"skip and head", 1, 2, 0, 5, "2 3", true},
+		{"tail", 0, 0, 2, 5, "4 5", false},
+		{"tail longer than input", 0, 0, 10, 3, "1 2 3", false},
+		{"skip and tail", 2, 0, 5, 4, "3 4", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slice, err := newOutputSlice(tt.skip, tt.head, tt.tail, bufferLimit{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			for i := 1; i <= tt.entries && !slice.done(); i++ {
+				if !slice.admit() {
+					continue
+				}
+				if tt.tail > 0 {
+					slice.keep([]byte(fmt.Sprintf("%d ", i)))
+				} else {
+					fmt.Fprintf(&out, "%d ", i)
+				}
+			}
+			slice.flush(&out)
+			if got := strings.TrimSpace(out.String()); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+			if slice.done() != tt.wantDone {
+				t.Errorf("done() = %v, want %v", slice.done(), tt.wantDone)
+			}
+		})
+	}
+}
+
+func TestNewOutputSlice(t *testing.T) {
+	tests := []struct {
+		name             string
+		skip, head, tail int
+		wantNil, wantErr bool
+	}{
+		{"none", 0, 0, 0, true, false},
+		{"head", 0, 5, 0, false, false},
+		{"head and tail", 0, 5, 5, false, true},
+		{"negative", -1, 0, 0, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slice, err := newOutputSlice(tt.skip, tt.head, tt.tail, bufferLimit{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (slice == nil) != tt.wantNil {
+				t.Errorf("slice = %v, want nil %v", slice, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestOutputSliceBufferLimit(t *testing.T) {
+	tests := []struct {
+		name        string
+		limit       bufferLimit
+		want        string
+		wantEvicted int
+	}{
+		{"unbounded", bufferLimit{}, "3 4 5 6 7 8 9 10", 0},
+		{"entries", bufferLimit{entries: 3}, "8 9 10", 5},
+		{"bytes", bufferLimit{bytes: 9}, "7 8 9 10", 4},
+		{"bytes below one entry", bufferLimit{bytes: 1}, "10", 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slice, _ := newOutputSlice(0, 0, 8, tt.limit)
+			for i := 1; i <= 10; i++ {
+				slice.admit()
+				slice.keep([]byte(fmt.Sprintf("%d ", i)))
+			}
+			if slice.evicted() != tt.wantEvicted {
+				t.Errorf("evicted() = %d, want %d", slice.evicted(), tt.wantEvicted)
+			}
+			var out bytes.Buffer
+			slice.flush(&out)
+			got := out.String()
+			if tt.wantEvicted > 0 {
+				note, rest, _ := strings.Cut(got, "\n")
+				if !strings.Contains(note, fmt.Sprintf("%d earlier entries dropped", tt.wantEvicted)) {
+					t.Errorf("note = %q", note)
+				}
+				got = rest
+			}
+			if got = strings.TrimSpace(got); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBufferLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    bufferLimit
+		wantErr bool
+	}{
+		{"100000", bufferLimit{entries: 100000}, false},
+		{"64MB", bufferLimit{bytes: 64 << 20}, false},
+		{"512kb", bufferLimit{bytes: 512 << 10}, false},
+		{"1.5GB", bufferLimit{bytes: 3 << 29}, false},
+		{"0", bufferLimit{}, true},
+		{"lots", bufferLimit{}, true},
+		{"-1MB", bufferLimit{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseBufferLimit(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseBufferLimit(%q) = %+v, %v, want %+v (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
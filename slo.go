@@ -0,0 +1,250 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// sloOptions configures `logpipe slo`.
+type sloOptions struct {
+	objective string
+	by        string
+}
+
+func (o *sloOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.objective, "objective", "", "Objectives every group must meet, e.g. 'p99<500ms, error_rate<1%'")
+	fs.StringVar(&o.by, "by", "url.path_template", "Field to group requests by")
+}
+
+// sloObjective is one condition of --objective: a latency percentile, the
+// error rate or the availability compared with a limit.
+type sloObjective struct {
+	name string
+	// quantile is set for latency objectives, e.g. 0.99 for p99.
+	quantile float64
+	less     bool
+	orEqual  bool
+	// limit is in milliseconds for latencies, a ratio for rates.
+	limit float64
+}
+
+var sloObjectiveRegex = regexp.MustCompile(`^(p\d{1,2}(?:\.\d+)?|error_rate|availability)\s*(<=|<|>=|>)\s*(\S+)$`)
+
+// parseObjectives reads comma-separated objectives such as "p99<500ms",
+// "p50<=80ms", "error_rate<1%" or "availability>=99.9%".
+func parseObjectives(spec string) ([]sloObjective, error) {
+	var objectives []sloObjective
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		m := sloObjectiveRegex.FindStringSubmatch(part)
+		if m == nil {
+			return nil, fmt.Errorf("invalid objective %q (expected e.g. p99<500ms, error_rate<1%% or availability>99.9%%)", part)
+		}
+		o := sloObjective{name: m[1], less: m[2][0] == '<', orEqual: strings.HasSuffix(m[2], "=")}
+		if strings.HasPrefix(o.name, "p") {
+			q, _ := strconv.ParseFloat(o.name[1:], 64)
+			if q <= 0 || q >= 100 {
+				return nil, fmt.Errorf("objective %q: percentile must be between 0 and 100, e.g. p50 or p99.9", part)
+			}
+			d, err := time.ParseDuration(m[3])
+			if err != nil {
+				return nil, fmt.Errorf("objective %q: invalid duration %q", part, m[3])
+			}
+			o.quantile = q / 100
+			o.limit = float64(d) / float64(time.Millisecond)
+		} else {
+			rate, err := parseRate(m[3])
+			if err != nil {
+				return nil, fmt.Errorf("objective %q: %w", part, err)
+			}
+			o.limit = rate
+		}
+		objectives = append(objectives, o)
+	}
+	if len(objectives) == 0 {
+		return nil, fmt.Errorf("no objective given")
+	}
+	return objectives, nil
+}
+
+// parseRate reads a rate as a percentage ("1%") or a ratio ("0.01").
+func parseRate(s string) (float64, error) {
+	percent, isPercent := strings.CutSuffix(s, "%")
+	rate, err := strconv.ParseFloat(percent, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	if isPercent {
+		rate /= 100
+	}
+	return rate, nil
+}
+
+// sloGroup gathers the requests of one group.
+type sloGroup struct {
+	requests  int
+	errors    int
+	latencies []float64
+}
+
+// value returns the measure an objective is about, and false when the
+// group has no data for it.
+func (g *sloGroup) value(o sloObjective) (float64, bool) {
+	switch {
+	case o.quantile > 0:
+		if len(g.latencies) == 0 {
+			return 0, false
+		}
+		return percentile(g.latencies, o.quantile), true
+	case o.name == "error_rate":
+		return float64(g.errors) / float64(g.requests), true
+	}
+	return 1 - float64(g.errors)/float64(g.requests), true
+}
+
+func (o sloObjective) met(value float64) bool {
+	switch {
+	case o.less && o.orEqual:
+		return value <= o.limit
+	case o.less:
+		return value < o.limit
+	case o.orEqual:
+		return value >= o.limit
+	}
+	return value > o.limit
+}
+
+func (o sloObjective) format(value float64) string {
+	if o.quantile > 0 {
+		return formatMillis(value)
+	}
+	return fmt.Sprintf("%.2f%%", 100*value)
+}
+
+// sloReport computes objectives per group over a stream of requests.
+type sloReport struct {
+	name       string
+	by         string
+	objectives []sloObjective
+	groups     map[string]*sloGroup
+	requests   int
+}
+
+// add records a line if it is a request: an entry with an HTTP status code
+// or a duration (see entryDuration). Status codes of 500 and above count as errors.
+func (r *sloReport) add(line string) {
+	entry, ok := parseLine(line)
+	if !ok {
+		return
+	}
+	fields := entryFields(line, entry)
+	status := entry.HTTP.Response.StatusCode
+	duration, timed := entryDuration(fields)
+	if status == 0 && !timed {
+		return
+	}
+	key := fieldString(fields, r.by)
+	if key == "" && r.by == "url.path_template" {
+		key = fieldString(fields, "url.path")
+	}
+	if key == "" {
+		key = "(none)"
+	}
+	g := r.groups[key]
+	if g == nil {
+		g = &sloGroup{}
+		r.groups[key] = g
+	}
+	r.requests++
+	g.requests++
+	if status >= 500 {
+		g.errors++
+	}
+	if timed {
+		g.latencies = append(g.latencies, float64(duration)/1e6)
+	}
+}
+
+// write prints a pass/fail table, one row per group, and reports whether
+// every group met every objective.
+func (r *sloReport) write(w io.Writer) bool {
+	fmt.Fprintf(w, "%s: %d requests in %d groups by %s\n", r.name, r.requests, len(r.groups), r.by)
+	keys := sortedKeys(r.groups)
+	width := len(r.by)
+	for _, key := range keys {
+		width = max(width, displayWidth(key))
+	}
+
+	header := fmt.Sprintf("  %s %8s", padWidth(r.by, width), "requests")
+	for _, o := range r.objectives {
+		header += fmt.Sprintf("  %-12s", o.name)
+	}
+	fmt.Fprintln(w, header+"  result")
+
+	pass := color.New(color.FgGreen)
+	fail := color.New(color.FgRed, color.Bold)
+	allMet := true
+	for _, key := range keys {
+		g := r.groups[key]
+		fmt.Fprintf(w, "  %s %8d", padWidth(key, width), g.requests)
+		met := true
+		for _, o := range r.objectives {
+			value, ok := g.value(o)
+			switch {
+			case !ok:
+				fmt.Fprintf(w, "  %-12s", "-")
+			case o.met(value):
+				fmt.Fprintf(w, "  %s", pass.Sprint(padWidth(o.format(value)+" ✓", 12)))
+			default:
+				met = false
+				fmt.Fprintf(w, "  %s", fail.Sprint(padWidth(o.format(value)+" ✗", 12)))
+			}
+		}
+		if met {
+			fmt.Fprintf(w, "  %s\n", pass.Sprint("pass"))
+		} else {
+			fmt.Fprintf(w, "  %s\n", fail.Sprint("FAIL"))
+			allMet = false
+		}
+	}
+	return allMet
+}
+
+// runSLO checks objectives over log files, or stdin when none are given,
+// and returns 1 when a group misses one, so it can gate CI.
+func runSLO(opts sloOptions, files []string, w io.Writer) int {
+	objectives, err := parseObjectives(opts.objective)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --objective: %v\n", err)
+		return 2
+	}
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	code := 0
+	for _, name := range files {
+		report := &sloReport{name: name, by: opts.by, objectives: objectives, groups: make(map[string]*sloGroup)}
+		if name == "-" {
+			report.name = "stdin"
+		}
+		if err := readLines(name, report.add); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", name, err)
+			return 2
+		}
+		if !report.write(w) {
+			code = 1
+		}
+	}
+	return code
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestParseObjectives(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []sloObjective
+		wantErr bool
+	}{
+		{
+			spec: "p99<500ms, error_rate<1%",
+			want: []sloObjective{
+				{name: "p99", quantile: 0.99, less: true, limit: 500},
+				{name: "error_rate", less: true, limit: 0.01},
+			},
+		},
+		{
+			spec: "p50 <= 1.5s,availability>=0.999",
+			want: []sloObjective{
+				{name: "p50", quantile: 0.5, less: true, orEqual: true, limit: 1500},
+				{name: "availability", orEqual: true, limit: 0.999},
+			},
+		},
+		{spec: "", wantErr: true},
+		{spec: "p99<fast", wantErr: true},
+		{spec: "error_rate<lots", wantErr: true},
+		{spec: "latency<1s", wantErr: true},
+		{spec: "p0<1s", wantErr: true},
+		{spec: "p100<1s", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseObjectives(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseObjectives(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("parseObjectives(%q) = %+v, want %+v", tt.spec, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("parseObjectives(%q)[%d] = %+v, want %+v", tt.spec, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestRunSLO(t *testing.T) {
+	defer func(noColor bool) { color.NoColor = noColor }(color.NoColor)
+	color.NoColor = true
+
+	input := strings.Join([]string{
+		`{"http":{"response":{"status_code":200}},"url":{"path":"/users/1","path_template":"/users/{id}"},"event":{"duration":120000000}}`,
+		`{"http":{"response":{"status_code":200}},"url":{"path":"/users/2","path_template":"/users/{id}"},"event":{"duration":320000000}}`,
+		`{"http":{"response":{"status_code":503}},"url":{"path":"/health"},"event":{"duration":2000000}}`,
+		`{"http":{"response":{"status_code":200}},"url":{"path":"/health"}}`,
+		`{"message":"started"}`,
+		`not json`,
+	}, "\n")
+	path := filepath.Join(t.TempDir(), "access.log")
+	if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	code := runSLO(sloOptions{objective: "p99<500ms, availability>=99%", by: "url.path_template"}, []string{path}, &out)
+	if code != 1 {
+		t.Errorf("runSLO() = %d, want 1", code)
+	}
+	want := []string{
+		path + ": 4 requests in 2 groups by url.path_template",
+		"url.path_template requests  p99           availability  result",
+		"/health                  2  2ms ✓         50.00% ✗      FAIL",
+		"/users/{id}              2  320ms ✓       100.00% ✓     pass",
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("output =\n%s", out.String())
+	}
+	for i := range want {
+		if strings.TrimSpace(lines[i]) != want[i] {
+			t.Errorf("line %d = %q, want %q", i, strings.TrimSpace(lines[i]), want[i])
+		}
+	}
+
+	out.Reset()
+	if code := runSLO(sloOptions{objective: "p99<1s", by: "url.path_template"}, []string{path}, &out); code != 0 {
+		t.Errorf("runSLO() = %d, want 0\n%s", code, out.String())
+	}
+	if code := runSLO(sloOptions{objective: "fast", by: "url.path"}, []string{path}, &out); code != 2 {
+		t.Errorf("runSLO() with an invalid objective = %d, want 2", code)
+	}
+}
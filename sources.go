@@ -0,0 +1,69 @@
+package main
+
+import (
+	"hash/fnv"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// sourcePalette are the colors given to input sources. Red is left out, as
+// it marks errors.
+var sourcePalette = []*color.Color{
+	color.New(color.FgCyan),
+	color.New(color.FgGreen),
+	color.New(color.FgYellow),
+	color.New(color.FgBlue),
+	color.New(color.FgMagenta),
+	color.New(color.FgHiCyan),
+	color.New(color.FgHiGreen),
+	color.New(color.FgHiYellow),
+	color.New(color.FgHiBlue),
+	color.New(color.FgHiMagenta),
+}
+
+// sourcePaints are sourcePalette as paints.
+var sourcePaints = func() []paint {
+	paints := make([]paint, len(sourcePalette))
+	for i, c := range sourcePalette {
+		paints[i] = paintOf(c)
+	}
+	return paints
+}()
+
+// sourceColor returns the color of a source, picked by hashing its name so
+// it is the same on every run.
+func sourceColor(name string) *color.Color {
+	return sourcePalette[sourceIndex(name)]
+}
+
+// sourcePaint is sourceColor as a paint.
+func sourcePaint(name string) paint {
+	return sourcePaints[sourceIndex(name)]
+}
+
+func sourceIndex(name string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return h.Sum32() % uint32(len(sourcePalette))
+}
+
+// positionRegex matches label parts that give a position rather than a
+// source, such as Kafka's partition@offset.
+var positionRegex = regexp.MustCompile(`^\d+@\d+$`)
+
+// formatLabel renders an input label with each of its parts (a file, a
+// host, a pod) in the color of its name, so merged sources are told apart
+// at a glance. Positions stay dim.
+func formatLabel(label string) string {
+	parts := strings.Split(label, " ")
+	for i, part := range parts {
+		if part == "" || positionRegex.MatchString(part) {
+			parts[i] = labelPaint.sprint(part)
+			continue
+		}
+		parts[i] = sourcePaint(part).sprint(part)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestSourceColor(t *testing.T) {
+	if sourceColor("api-7d9f") != sourceColor("api-7d9f") {
+		t.Error("expected the same color for the same source")
+	}
+	seen := map[*color.Color]bool{}
+	for _, name := range []string{"api", "worker", "web", "db", "cache", "auth", "billing", "search"} {
+		seen[sourceColor(name)] = true
+	}
+	if len(seen) < 4 {
+		t.Errorf("8 sources got only %d colors", len(seen))
+	}
+}
+
+func TestFormatLabel(t *testing.T) {
+	noColor := color.NoColor
+	defer func() { color.NoColor = noColor }()
+	color.NoColor = false
+
+	tests := []struct {
+		label, want string
+	}{
+		{"api.log", sourceColor("api.log").Sprint("api.log")},
+		{"3@1042", labelColor.Sprint("3@1042")},
+		{"web01 nginx/access.log", sourceColor("web01").Sprint("web01") + " " + sourceColor("nginx/access.log").Sprint("nginx/access.log")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			if got := formatLabel(tt.label); got != tt.want {
+				t.Errorf("formatLabel(%q) = %q, want %q", tt.label, got, tt.want)
+			}
+		})
+	}
+}
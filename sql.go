@@ -0,0 +1,166 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/fatih/color"
+)
+
+// sqlTruncateLength is how many columns of a statement are shown unless
+// --sql-full is set.
+const sqlTruncateLength = 120
+
+// sqlFull is set by --sql-full to show statements in full.
+var sqlFull = false
+
+// SQLFields describes a database query log (db.statement and friends).
+type SQLFields struct {
+	Statement string
+	// DurationMs is the query duration in milliseconds, or -1 if unknown.
+	DurationMs float64
+	// Rows is the number of rows returned or affected, or -1 if unknown.
+	Rows int64
+}
+
+// parseSQL extracts query details from a JSON log line, returning nil when
+// the line has no SQL statement.
+func parseSQL(line *lineFields) *SQLFields {
+	if !strings.Contains(line.text, "statement") && !strings.Contains(line.text, "query") {
+		return nil
+	}
+	fields := line.object()
+	if fields == nil {
+		return nil
+	}
+
+	statement := fieldString(fields, "db.statement", "db.query.text")
+	if statement == "" {
+		return nil
+	}
+
+	query := &SQLFields{Statement: statement, DurationMs: -1, Rows: -1}
+	if ms, ok := fieldFloat(fields, "duration_ms", "db.duration_ms", "elapsed_ms"); ok {
+		query.DurationMs = ms
+	} else if ns, ok := fieldFloat(fields, "event.duration"); ok {
+		query.DurationMs = ns / 1e6
+	}
+	if rows, ok := fieldFloat(fields, "db.response.returned_rows", "db.rows_affected", "rows_affected", "rows"); ok {
+		query.Rows = int64(rows)
+	}
+	return query
+}
+
+var (
+	sqlFastPaint = newPaint(color.FgGreen)
+	sqlSlowPaint = newPaint(color.FgYellow)
+	sqlHungPaint = newPaint(color.FgRed, color.Bold)
+
+	sqlKeywordPaint = newPaint(color.FgBlue, color.Bold)
+	sqlStringPaint  = newPaint(color.FgGreen)
+	sqlNumberPaint  = newPaint(color.FgCyan)
+)
+
+// sqlDurationPaint colors queries green under 100ms, yellow under a
+// second and red beyond.
+func sqlDurationPaint(ms float64) paint {
+	switch {
+	case ms < 100:
+		return sqlFastPaint
+	case ms < 1000:
+		return sqlSlowPaint
+	}
+	return sqlHungPaint
+}
+
+// formatSQL collapses a statement onto one line, truncates it unless
+// --sql-full is set, and highlights it.
+func formatSQL(statement string) string {
+	statement = strings.Join(strings.Fields(statement), " ")
+	truncated := false
+	if !sqlFull {
+		if displayWidth(statement) > sqlTruncateLength {
+			statement = truncateWidth(statement, sqlTruncateLength)
+			truncated = true
+		}
+	}
+	if !color.NoColor {
+		statement = highlightSQL(statement)
+	}
+	if truncated {
+		statement += "..."
+	}
+	return statement
+}
+
+var sqlKeywords = map[string]bool{}
+
+func init() {
+	for _, keyword := range strings.Fields(`
+		SELECT FROM WHERE INSERT INTO VALUES UPDATE SET DELETE RETURNING
+		JOIN LEFT RIGHT INNER OUTER FULL CROSS ON USING AND OR NOT IN IS NULL
+		AS ORDER BY GROUP HAVING LIMIT OFFSET ASC DESC DISTINCT ALL UNION
+		EXCEPT INTERSECT WITH CASE WHEN THEN ELSE END LIKE ILIKE BETWEEN
+		EXISTS CREATE ALTER DROP TABLE INDEX VIEW BEGIN COMMIT ROLLBACK
+		TRUNCATE CONFLICT DO NOTHING FOR TRUE FALSE`) {
+		sqlKeywords[keyword] = true
+	}
+}
+
+// highlightSQL colors keywords, string literals, numbers and bind
+// placeholders ($1, ?, :name).
+func highlightSQL(statement string) string {
+	var out strings.Builder
+	runes := []rune(statement)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case r == '\'':
+			// String literal, with '' as an escaped quote
+			for i++; i < len(runes); i++ {
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						i++
+						continue
+					}
+					i++
+					break
+				}
+			}
+			out.WriteString(sqlStringPaint.sprint(string(runes[start:i])))
+		case unicode.IsLetter(r) || r == '_':
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			word := string(runes[start:i])
+			if sqlKeywords[strings.ToUpper(word)] {
+				out.WriteString(sqlKeywordPaint.sprint(word))
+			} else {
+				out.WriteString(word)
+			}
+		case unicode.IsDigit(r), r == '?', isSQLPlaceholder(runes, i):
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || unicode.IsLetter(runes[i]) || runes[i] == '.' || runes[i] == '_') {
+				i++
+			}
+			out.WriteString(sqlNumberPaint.sprint(string(runes[start:i])))
+		default:
+			out.WriteRune(r)
+			i++
+		}
+	}
+	return out.String()
+}
+
+// isSQLPlaceholder reports whether a named or numbered placeholder ($1,
+// :name) starts at i, as opposed to a PostgreSQL ::cast.
+func isSQLPlaceholder(runes []rune, i int) bool {
+	if runes[i] != '$' && runes[i] != ':' || i+1 >= len(runes) {
+		return false
+	}
+	if runes[i] == ':' && (runes[i+1] == ':' || i > 0 && runes[i-1] == ':') {
+		return false
+	}
+	return unicode.IsDigit(runes[i+1]) || unicode.IsLetter(runes[i+1])
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestParseSQL(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  *SQLFields
+	}{
+		{
+			name:  "ecs nested",
+			input: `{"db":{"statement":"SELECT 1"},"event":{"duration":2500000},"rows":1}`,
+			want:  &SQLFields{Statement: "SELECT 1", DurationMs: 2.5, Rows: 1},
+		},
+		{
+			name:  "otel flattened",
+			input: `{"db.query.text":"DELETE FROM t","duration_ms":"40","db.rows_affected":7}`,
+			want:  &SQLFields{Statement: "DELETE FROM t", DurationMs: 40, Rows: 7},
+		},
+		{
+			name:  "no duration or rows",
+			input: `{"db.statement":"BEGIN"}`,
+			want:  &SQLFields{Statement: "BEGIN", DurationMs: -1, Rows: -1},
+		},
+		{
+			name:  "no statement",
+			input: `{"message":"query cache warmed"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseSQL(newLineFields([]byte(tt.input)))
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("parseSQL() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatSQL(t *testing.T) {
+	noColor := color.NoColor
+	defer func() { color.NoColor, sqlFull = noColor, false }()
+	color.NoColor = true
+
+	statement := "SELECT *\n  FROM events\n  WHERE id IN (" + strings.Repeat("1, ", 60) + "1)"
+	got := formatSQL(statement)
+	if !strings.HasPrefix(got, "SELECT * FROM events WHERE id IN (1, 1,") || !strings.HasSuffix(got, "...") {
+		t.Errorf("formatSQL() = %q, want a collapsed, truncated statement", got)
+	}
+	if n := len([]rune(strings.TrimSuffix(got, "..."))); n != sqlTruncateLength {
+		t.Errorf("formatSQL() kept %d characters, want %d", n, sqlTruncateLength)
+	}
+
+	sqlFull = true
+	if got := formatSQL(statement); strings.HasSuffix(got, "...") || !strings.HasSuffix(got, "1)") {
+		t.Errorf("formatSQL() with --sql-full = %q, want the full statement", got)
+	}
+}
+
+func TestHighlightSQL(t *testing.T) {
+	noColor := color.NoColor
+	defer func() { color.NoColor = noColor }()
+	color.NoColor = false
+
+	got := highlightSQL("select name from users where id = $1 and note = 'it''s' and x::int > :min")
+	for _, want := range []string{
+		color.New(color.FgBlue, color.Bold).Sprint("select"),
+		color.New(color.FgBlue, color.Bold).Sprint("where"),
+		color.New(color.FgGreen).Sprint("'it''s'"),
+		color.New(color.FgCyan).Sprint("$1"),
+		color.New(color.FgCyan).Sprint(":min"),
+		" name ",
+		"::int",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("highlightSQL() = %q, missing %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// statusWindow is how many seconds of counts the status line keeps, the
+// longest of its rolling windows.
+const statusWindow = 300
+
+// statusBucket counts the entries seen during one second.
+type statusBucket struct {
+	second                    int64
+	entries, requests, errors int
+}
+
+// statusLine keeps a line at the bottom of the terminal with rolling
+// request and error rates, redrawn below the log lines as they scroll.
+type statusLine struct {
+	mu      sync.Mutex
+	out     io.Writer
+	now     func() time.Time
+	buckets [statusWindow]statusBucket
+	shown   bool
+	done    chan struct{}
+}
+
+// newStatusLine returns a status line for --status, or nil when stdout is
+// not a terminal.
+func newStatusLine() *statusLine {
+	if !isTerminal(os.Stdout) {
+		return nil
+	}
+	return &statusLine{out: os.Stdout, now: time.Now, done: make(chan struct{})}
+}
+
+// start redraws the line every second so rates decay when input pauses.
+func (s *statusLine) start() {
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.mu.Lock()
+				s.clear()
+				s.draw()
+				s.mu.Unlock()
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
+
+// stop removes the line for good.
+func (s *statusLine) stop() {
+	close(s.done)
+	s.mu.Lock()
+	s.clear()
+	s.mu.Unlock()
+}
+
+// pause clears the line before log output is written. It must be followed
+// by resume.
+func (s *statusLine) pause() {
+	s.mu.Lock()
+	s.clear()
+}
+
+// resume draws the line again below the new output.
+func (s *statusLine) resume() {
+	s.draw()
+	s.mu.Unlock()
+}
+
+func (s *statusLine) clear() {
+	if s.shown {
+		io.WriteString(s.out, "\r\x1b[K")
+		s.shown = false
+	}
+}
+
+func (s *statusLine) draw() {
+	io.WriteString(s.out, s.render())
+	s.shown = true
+}
+
+// record counts an entry. Requests are HTTP and gRPC entries; errors are
+// error and fatal entries and HTTP 5xx responses.
+func (s *statusLine) record(entry LogEntry) {
+	second := s.now().Unix()
+	bucket := &s.buckets[second%statusWindow]
+	if bucket.second != second {
+		*bucket = statusBucket{second: second}
+	}
+	bucket.entries++
+	if entry.HTTP.Request.Method != "" || entry.GRPC != nil {
+		bucket.requests++
+	}
+	if level := normalizeLevel(entry.Level); level == "error" || level == "fatal" || entry.HTTP.Response.StatusCode >= 500 {
+		bucket.errors++
+	}
+}
+
+// totals sums the buckets of the last seconds.
+func (s *statusLine) totals(seconds int64) statusBucket {
+	var total statusBucket
+	now := s.now().Unix()
+	for _, bucket := range s.buckets {
+		if bucket.second > now-seconds && bucket.second <= now {
+			total.entries += bucket.entries
+			total.requests += bucket.requests
+			total.errors += bucket.errors
+		}
+	}
+	return total
+}
+
+// render formats the line, e.g.
+// "1m 12.5 req/s 3 err/min (2.0%) │ 5m 10.1 req/s 1 err/min (0.5%)".
+func (s *statusLine) render() string {
+	var parts []string
+	for _, window := range []struct {
+		label   string
+		seconds int64
+	}{{"1m", 60}, {"5m", 300}} {
+		total := s.totals(window.seconds)
+		rate, unit := float64(total.requests), "req/s"
+		if total.requests == 0 {
+			rate, unit = float64(total.entries), "lines/s"
+		}
+		share := 0.0
+		if total.entries > 0 {
+			share = float64(total.errors) / float64(total.entries) * 100
+		}
+		parts = append(parts, fmt.Sprintf("%s %.1f %s %s",
+			labelPaint.sprint(window.label),
+			rate/float64(window.seconds),
+			unit,
+			errorSharePaint(share).sprint(fmt.Sprintf("%.0f err/min (%.1f%%)", float64(total.errors)*60/float64(window.seconds), share)),
+		))
+	}
+	return strings.Join(parts, labelPaint.sprint(" │ "))
+}
+
+// The colors of error rates in the status line.
+var (
+	highErrorSharePaint = newPaint(color.FgRed, color.Bold)
+	someErrorSharePaint = newPaint(color.FgYellow)
+	lowErrorSharePaint  = newPaint(color.FgGreen)
+)
+
+// errorSharePaint colors error rates green up to 1%, yellow up to 5% and
+// red beyond.
+func errorSharePaint(share float64) paint {
+	switch {
+	case share > 5:
+		return highErrorSharePaint
+	case share > 1:
+		return someErrorSharePaint
+	}
+	return lowErrorSharePaint
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestStatusLine(t *testing.T) {
+	clock := time.Date(2025, 6, 28, 12, 0, 0, 0, time.UTC)
+	var out bytes.Buffer
+	s := &statusLine{out: &out, now: func() time.Time { return clock }}
+
+	// Four minutes ago: 60 requests, 30 of them failing
+	clock = clock.Add(-4 * time.Minute)
+	for i := 0; i < 60; i++ {
+		entry := LogEntry{}
+		entry.HTTP.Request.Method = "GET"
+		entry.HTTP.Response.StatusCode = 200
+		if i%2 == 0 {
+			entry.HTTP.Response.StatusCode = 503
+		}
+		s.record(entry)
+	}
+	// Last minute: 120 requests and one error log
+	clock = clock.Add(4 * time.Minute)
+	for i := 0; i < 120; i++ {
+		entry := LogEntry{}
+		entry.HTTP.Request.Method = "GET"
+		s.record(entry)
+	}
+	s.record(LogEntry{Level: "ERROR"})
+
+	if got := s.totals(60); got.entries != 121 || got.requests != 120 || got.errors != 1 {
+		t.Errorf("1m totals = %+v", got)
+	}
+	if got := s.totals(300); got.entries != 181 || got.requests != 180 || got.errors != 31 {
+		t.Errorf("5m totals = %+v", got)
+	}
+
+	want := "1m 2.0 req/s 1 err/min (0.8%) │ 5m 0.6 req/s 6 err/min (17.1%)"
+	if got := s.render(); got != want {
+		t.Errorf("render() = %q, want %q", got, want)
+	}
+
+	// Output clears the line first and draws it again after
+	s.pause()
+	out.WriteString("entry\n")
+	s.resume()
+	s.pause()
+	s.resume()
+	if want := "entry\n" + s.render() + "\r\x1b[K" + s.render(); out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+
+	// Buckets older than the window are dropped
+	clock = clock.Add(10 * time.Minute)
+	if got := s.render(); got != "1m 0.0 lines/s 0 err/min (0.0%) │ 5m 0.0 lines/s 0 err/min (0.0%)" {
+		t.Errorf("render() after idle = %q", got)
+	}
+}
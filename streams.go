@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+var stderrMarkerPaint = newPaint(color.FgRed, color.Bold)
+
+// streamMarker returns the gutter shown in front of entries from a known
+// output stream: a red bar for stderr, blanks of the same width for stdout.
+func streamMarker(stream string) string {
+	switch stream {
+	case "stderr":
+		return stderrMarkerPaint.sprint("┃") + " "
+	case "stdout":
+		return "  "
+	}
+	return ""
+}
+
+// readStreams reads a program's stdout and stderr from two files or pipes
+// ("-" is stdin) and hands each line to handle with its stream name. When
+// both are regular files, lines are merged by timestamp; otherwise they are
+// interleaved in arrival order.
+func readStreams(stdoutPath, stderrPath string, handle func(line, stream string)) error {
+	stdout, err := openStream(stdoutPath)
+	if err != nil {
+		return err
+	}
+	defer stdout.Close()
+	stderr, err := openStream(stderrPath)
+	if err != nil {
+		return err
+	}
+	defer stderr.Close()
+
+	if isRegularFile(stdout) && isRegularFile(stderr) {
+		return mergeStreamsByTime(stdout, stderr, handle)
+	}
+	return mergeStreamsByArrival(stdout, stderr, handle)
+}
+
+func openStream(path string) (*os.File, error) {
+	if path == "-" || path == "" {
+		return os.Stdin, nil
+	}
+	return os.Open(path)
+}
+
+func isRegularFile(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode().IsRegular()
+}
+
+type streamLine struct {
+	line   string
+	stream string
+	done   bool
+	err    error
+}
+
+// mergeStreamsByArrival reads both streams concurrently and handles lines
+// as they come, from a single goroutine.
+func mergeStreamsByArrival(stdout, stderr io.Reader, handle func(line, stream string)) error {
+	lines := make(chan streamLine)
+	read := func(r io.Reader, stream string) {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			lines <- streamLine{line: scanner.Text(), stream: stream}
+		}
+		lines <- streamLine{stream: stream, done: true, err: scanner.Err()}
+	}
+	go read(stdout, "stdout")
+	go read(stderr, "stderr")
+
+	var firstErr error
+	for open := 2; open > 0; {
+		l := <-lines
+		if l.done {
+			if firstErr == nil {
+				firstErr = l.err
+			}
+			open--
+			continue
+		}
+		handle(l.line, l.stream)
+	}
+	return firstErr
+}
+
+// timedScanner reads a stream while tracking the timestamp of its latest
+// entry, so lines without one (e.g. stack traces) stay with their entry.
+type timedScanner struct {
+	scanner *bufio.Scanner
+	stream  string
+	line    string
+	time    time.Time
+	ok      bool
+	// joiner, when set, joins the lines of multi-line JSON values, and the
+	// lines it emits wait in joined until read.
+	joiner  *jsonJoiner
+	joined  []string
+	flushed bool
+}
+
+// newJoiningScanner returns a timedScanner whose lines go through a
+// jsonJoiner, so that pretty-printed values are read as one line each.
+func newJoiningScanner(scanner *bufio.Scanner, stream string) *timedScanner {
+	s := &timedScanner{scanner: scanner, stream: stream}
+	s.joiner = newJSONJoiner(func(line string) { s.joined = append(s.joined, line) })
+	return s
+}
+
+func (s *timedScanner) next() {
+	s.line, s.ok = s.read()
+	if !s.ok {
+		return
+	}
+	if entry, ok := parseLine(s.line); ok {
+		if t, err := time.Parse(time.RFC3339Nano, entry.Timestamp); err == nil {
+			s.time = t
+		}
+	}
+}
+
+// read returns the next line, joined when there is a joiner.
+func (s *timedScanner) read() (string, bool) {
+	if s.joiner == nil {
+		if !s.scanner.Scan() {
+			return "", false
+		}
+		return s.scanner.Text(), true
+	}
+	for len(s.joined) == 0 {
+		switch {
+		case s.scanner.Scan():
+			s.joiner.add(s.scanner.Text())
+		case !s.flushed:
+			s.joiner.flush()
+			s.flushed = true
+		default:
+			return "", false
+		}
+	}
+	line := s.joined[0]
+	s.joined = s.joined[1:]
+	return line, true
+}
+
+// mergeStreamsByTime merges two complete logs in timestamp order, stdout
+// first on ties.
+func mergeStreamsByTime(stdout, stderr io.Reader, handle func(line, stream string)) error {
+	outScanner, errScanner := bufio.NewScanner(stdout), bufio.NewScanner(stderr)
+	outScanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	errScanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	out := &timedScanner{scanner: outScanner, stream: "stdout"}
+	errs := &timedScanner{scanner: errScanner, stream: "stderr"}
+	out.next()
+	errs.next()
+
+	for out.ok || errs.ok {
+		s := out
+		if !out.ok || errs.ok && errs.time.Before(out.time) {
+			s = errs
+		}
+		handle(s.line, s.stream)
+		s.next()
+	}
+	if err := out.scanner.Err(); err != nil {
+		return err
+	}
+	return errs.scanner.Err()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeStreamsByTime(t *testing.T) {
+	stdout := strings.Join([]string{
+		`{"@timestamp":"2025-06-28T11:50:00Z","message":"start"}`,
+		`{"@timestamp":"2025-06-28T11:50:02Z","message":"done"}`,
+	}, "\n")
+	stderr := strings.Join([]string{
+		`{"@timestamp":"2025-06-28T11:50:01Z","message":"boom"}`,
+		`    at main.go:12`,
+		`{"@timestamp":"2025-06-28T11:50:02Z","message":"retry"}`,
+	}, "\n")
+
+	var got []string
+	err := mergeStreamsByTime(strings.NewReader(stdout), strings.NewReader(stderr), func(line, stream string) {
+		got = append(got, stream+" "+line)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`stdout {"@timestamp":"2025-06-28T11:50:00Z","message":"start"}`,
+		`stderr {"@timestamp":"2025-06-28T11:50:01Z","message":"boom"}`,
+		`stderr     at main.go:12`,
+		`stdout {"@timestamp":"2025-06-28T11:50:02Z","message":"done"}`,
+		`stderr {"@timestamp":"2025-06-28T11:50:02Z","message":"retry"}`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("merge order =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestReadStreamsFromPipes(t *testing.T) {
+	outR, outW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	errPath := filepath.Join(t.TempDir(), "err.log")
+	if err := os.WriteFile(errPath, []byte("e1\ne2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		io.WriteString(outW, "o1\no2\n")
+		outW.Close()
+	}()
+
+	stdin := os.Stdin
+	os.Stdin = outR
+	defer func() { os.Stdin = stdin }()
+
+	counts := map[string]int{}
+	err = readStreams("-", errPath, func(line, stream string) {
+		if !strings.HasPrefix(line, stream[3:4]) {
+			t.Errorf("line %q tagged as %s", line, stream)
+		}
+		counts[stream]++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts["stdout"] != 2 || counts["stderr"] != 2 {
+		t.Errorf("counts = %v, want 2 lines from each stream", counts)
+	}
+}
+
+func TestMergeStreamsLongLines(t *testing.T) {
+	long := `{"message":"` + strings.Repeat("x", 1<<20) + `"}`
+	merges := map[string]func(stdout, stderr io.Reader, handle func(line, stream string)) error{
+		"by arrival": mergeStreamsByArrival,
+		"by time":    mergeStreamsByTime,
+	}
+	for name, merge := range merges {
+		t.Run(name, func(t *testing.T) {
+			var got []int
+			err := merge(strings.NewReader(long+"\n"), strings.NewReader("short\n"), func(line, stream string) {
+				got = append(got, len(line))
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 2 || (got[0] != len(long) && got[1] != len(long)) {
+				t.Errorf("line lengths = %v, want one of %d", got, len(long))
+			}
+		})
+	}
+}
+
+func TestStreamMarker(t *testing.T) {
+	if streamMarker("") != "" {
+		t.Error("entries without a stream should have no marker")
+	}
+	if streamMarker("stdout") != "  " {
+		t.Errorf("stdout marker = %q", streamMarker("stdout"))
+	}
+	if !strings.Contains(streamMarker("stderr"), "┃") {
+		t.Errorf("stderr marker = %q", streamMarker("stderr"))
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// strictChecker validates input for --strict: lines must parse and JSON
+// entries must carry the required fields. Violations are allowed up to
+// budget before logpipe gives up.
+type strictChecker struct {
+	required   []string
+	budget     int
+	violations int
+	out        io.Writer
+}
+
+func newStrictChecker(required []string, budget int, out io.Writer) *strictChecker {
+	return &strictChecker{required: required, budget: budget, out: out}
+}
+
+// missingFields returns the required fields absent from a JSON line. Lines
+// in other formats are not checked.
+func (c *strictChecker) missingFields(line string) []string {
+	fields := decodeJSONObject(line)
+	if fields == nil {
+		return nil
+	}
+	var missing []string
+	for _, path := range c.required {
+		if value, ok := lookupField(fields, path); !ok || value == nil || value == "" {
+			missing = append(missing, path)
+		}
+	}
+	return missing
+}
+
+// violation reports a problem with an input line and returns false once the
+// error budget is exhausted.
+func (c *strictChecker) violation(lineNumber int, format string, args ...interface{}) bool {
+	c.violations++
+	fmt.Fprintf(c.out, "logpipe: line %d: %s\n", lineNumber, fmt.Sprintf(format, args...))
+	if c.violations > c.budget {
+		fmt.Fprintf(c.out, "logpipe: strict mode: %d violations exceed the budget of %d\n", c.violations, c.budget)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStrictMissingFields(t *testing.T) {
+	c := newStrictChecker([]string{"@timestamp", "log.level", "service.name"}, 0, &bytes.Buffer{})
+
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{`{"@timestamp":"t","log.level":"info","service":{"name":"api"}}`, nil},
+		{`{"@timestamp":"t","log":{"level":"info"},"service.name":"api"}`, nil},
+		{`{"log.level":"","service":{}}`, []string{"@timestamp", "log.level", "service.name"}},
+		{"<13>Jun 28 11:50:00 web01 app: syslog lines are not checked", nil},
+	}
+
+	for _, tt := range tests {
+		got := c.missingFields(tt.line)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("missingFields(%s) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestStrictBudget(t *testing.T) {
+	var out bytes.Buffer
+	c := newStrictChecker(nil, 2, &out)
+
+	for i := 1; i <= 2; i++ {
+		if !c.violation(i, "parse error: %s", "bad") {
+			t.Fatalf("violation %d should be within the budget", i)
+		}
+	}
+	if c.violation(3, "parse error: %s", "bad") {
+		t.Error("violation 3 should exceed the budget")
+	}
+	if !strings.Contains(out.String(), "logpipe: line 3: parse error: bad\n") || !strings.Contains(out.String(), "exceed the budget of 2") {
+		t.Errorf("output = %q", out.String())
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SyslogFields holds the syslog-specific metadata of an entry, following the
+// ECS log.syslog.* layout.
+type SyslogFields struct {
+	Priority int `json:"priority"`
+	Facility struct {
+		Code int    `json:"code"`
+		Name string `json:"name"`
+	} `json:"facility"`
+	Severity struct {
+		Code int    `json:"code"`
+		Name string `json:"name"`
+	} `json:"severity"`
+	Appname        string                       `json:"appname"`
+	ProcID         string                       `json:"procid"`
+	MsgID          string                       `json:"msgid"`
+	Version        int                          `json:"version"`
+	StructuredData map[string]map[string]string `json:"structured_data"`
+}
+
+var syslogFacilityNames = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// syslogSeverityLevels maps syslog severities (0-7) to the level names used
+// by getLevelColor.
+var syslogSeverityLevels = []string{
+	"emergency", "alert", "critical", "error", "warning", "notice", "info", "debug",
+}
+
+var (
+	// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD [MSG]
+	rfc5424Regex = regexp.MustCompile(`^<(\d{1,3})>(\d{1,2}) (\S+) (\S+) (\S+) (\S+) (\S+) (.*)$`)
+	// [<PRI>]Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG
+	rfc3164Regex = regexp.MustCompile(`^(?:<(\d{1,3})>)?([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}) (\S+) ([^\s\[:]+)(?:\[([^\]]*)\])?: ?(.*)$`)
+)
+
+// parseSyslog detects and parses an RFC5424 or RFC3164 syslog line.
+func parseSyslog(line string) (LogEntry, bool) {
+	if m := rfc5424Regex.FindStringSubmatch(line); m != nil {
+		return parseRFC5424(m)
+	}
+	if m := rfc3164Regex.FindStringSubmatch(line); m != nil {
+		return parseRFC3164(m)
+	}
+	return LogEntry{}, false
+}
+
+func parseRFC5424(m []string) (LogEntry, bool) {
+	var entry LogEntry
+	sl := &SyslogFields{}
+	if !applySyslogPriority(sl, &entry, m[1]) {
+		return LogEntry{}, false
+	}
+	sl.Version, _ = strconv.Atoi(m[2])
+
+	if m[3] != "-" {
+		if _, err := time.Parse(time.RFC3339, m[3]); err != nil {
+			return LogEntry{}, false
+		}
+		entry.Timestamp = m[3]
+	}
+
+	entry.Host.Hostname = syslogNil(m[4])
+	sl.Appname = syslogNil(m[5])
+	sl.ProcID = syslogNil(m[6])
+	sl.MsgID = syslogNil(m[7])
+
+	sd, msg, ok := parseStructuredData(m[8])
+	if !ok {
+		return LogEntry{}, false
+	}
+	sl.StructuredData = sd
+	// Strip the UTF-8 BOM that RFC5424 allows in front of MSG
+	entry.Message = strings.TrimPrefix(msg, "\ufeff")
+
+	entry.Process.Name = sl.Appname
+	entry.Process.PID, _ = strconv.Atoi(sl.ProcID)
+	entry.Log.Syslog = sl
+	return entry, true
+}
+
+func parseRFC3164(m []string) (LogEntry, bool) {
+	var entry LogEntry
+	sl := &SyslogFields{}
+	if m[1] != "" && !applySyslogPriority(sl, &entry, m[1]) {
+		return LogEntry{}, false
+	}
+
+	// RFC3164 timestamps carry no year: assume the current one, unless that
+	// would put the entry in the future (logs read across New Year).
+	ts, err := time.ParseInLocation("Jan _2 15:04:05", m[2], time.Local)
+	if err != nil {
+		return LogEntry{}, false
+	}
+	now := time.Now()
+	ts = ts.AddDate(now.Year(), 0, 0)
+	if ts.After(now.Add(24 * time.Hour)) {
+		ts = ts.AddDate(-1, 0, 0)
+	}
+	entry.Timestamp = ts.Format(time.RFC3339)
+
+	entry.Host.Hostname = m[3]
+	sl.Appname = m[4]
+	sl.ProcID = m[5]
+	entry.Message = m[6]
+
+	entry.Process.Name = sl.Appname
+	entry.Process.PID, _ = strconv.Atoi(sl.ProcID)
+	entry.Log.Syslog = sl
+	return entry, true
+}
+
+// applySyslogPriority decodes PRI into facility and severity.
+func applySyslogPriority(sl *SyslogFields, entry *LogEntry, pri string) bool {
+	p, err := strconv.Atoi(pri)
+	if err != nil || p > 191 {
+		return false
+	}
+	sl.Priority = p
+	sl.Facility.Code = p / 8
+	sl.Facility.Name = syslogFacilityNames[p/8]
+	sl.Severity.Code = p % 8
+	sl.Severity.Name = syslogSeverityLevels[p%8]
+	entry.Level = syslogSeverityLevels[p%8]
+	return true
+}
+
+// parseStructuredData consumes the STRUCTURED-DATA part of an RFC5424 line
+// and returns the remaining message.
+func parseStructuredData(s string) (map[string]map[string]string, string, bool) {
+	if s == "-" || strings.HasPrefix(s, "- ") {
+		return nil, strings.TrimPrefix(strings.TrimPrefix(s, "-"), " "), true
+	}
+
+	sd := make(map[string]map[string]string)
+	i := 0
+	for i < len(s) && s[i] == '[' {
+		i++
+		start := i
+		for i < len(s) && s[i] != ' ' && s[i] != ']' {
+			i++
+		}
+		if i >= len(s) {
+			return nil, "", false
+		}
+		params := make(map[string]string)
+		sd[s[start:i]] = params
+
+		for i < len(s) && s[i] == ' ' {
+			i++
+			start = i
+			for i < len(s) && s[i] != '=' {
+				i++
+			}
+			if i+1 >= len(s) || s[i+1] != '"' {
+				return nil, "", false
+			}
+			name := s[start:i]
+			i += 2
+
+			var value strings.Builder
+			for i < len(s) && s[i] != '"' {
+				if s[i] == '\\' && i+1 < len(s) && strings.ContainsRune(`"\]`, rune(s[i+1])) {
+					i++
+				}
+				value.WriteByte(s[i])
+				i++
+			}
+			if i >= len(s) {
+				return nil, "", false
+			}
+			params[name] = value.String()
+			i++
+		}
+		if i >= len(s) || s[i] != ']' {
+			return nil, "", false
+		}
+		i++
+	}
+	if i == 0 {
+		return nil, "", false
+	}
+	return sd, strings.TrimPrefix(s[i:], " "), true
+}
+
+func syslogNil(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+// syslogOrigin renders the "host app[pid]:" prefix of a syslog entry.
+func syslogOrigin(log LogEntry) string {
+	origin := log.Log.Syslog.Appname
+	if log.Log.Syslog.ProcID != "" {
+		origin += "[" + log.Log.Syslog.ProcID + "]"
+	}
+	if log.Host.Hostname != "" {
+		origin = strings.TrimSpace(log.Host.Hostname + " " + origin)
+	}
+	return origin + ":"
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseSyslog(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantOK   bool
+		level    string
+		host     string
+		appname  string
+		pid      int
+		message  string
+		facility string
+	}{
+		{
+			name:     "RFC5424 with structured data",
+			input:    `<165>1 2025-06-28T11:50:00.000Z web01 nginx 4242 ID47 [exampleSDID@32473 iut="3" eventSource="App\]lication"] Request served`,
+			wantOK:   true,
+			level:    "notice",
+			host:     "web01",
+			appname:  "nginx",
+			pid:      4242,
+			message:  "Request served",
+			facility: "local4",
+		},
+		{
+			name:     "RFC5424 without structured data",
+			input:    `<11>1 2025-06-28T11:50:00Z db01 postgres - - - connection refused`,
+			wantOK:   true,
+			level:    "error",
+			host:     "db01",
+			appname:  "postgres",
+			message:  "connection refused",
+			facility: "user",
+		},
+		{
+			name:     "RFC3164 with priority",
+			input:    `<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8`,
+			wantOK:   true,
+			level:    "critical",
+			host:     "mymachine",
+			appname:  "su",
+			message:  "'su root' failed for lonvick on /dev/pts/8",
+			facility: "auth",
+		},
+		{
+			name:    "RFC3164 without priority",
+			input:   `Jun  2 08:01:22 host1 sshd[1234]: Accepted publickey for root`,
+			wantOK:  true,
+			host:    "host1",
+			appname: "sshd",
+			pid:     1234,
+			message: "Accepted publickey for root",
+		},
+		{
+			name:   "plain text",
+			input:  `this is not syslog`,
+			wantOK: false,
+		},
+		{
+			name:   "out of range priority",
+			input:  `<999>1 2025-06-28T11:50:00Z host app - - - msg`,
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, ok := parseSyslog(tt.input)
+			if ok != tt.wantOK {
+				t.Fatalf("parseSyslog() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if entry.Level != tt.level {
+				t.Errorf("Level = %q, want %q", entry.Level, tt.level)
+			}
+			if entry.Host.Hostname != tt.host {
+				t.Errorf("Hostname = %q, want %q", entry.Host.Hostname, tt.host)
+			}
+			if entry.Log.Syslog.Appname != tt.appname {
+				t.Errorf("Appname = %q, want %q", entry.Log.Syslog.Appname, tt.appname)
+			}
+			if entry.Process.PID != tt.pid {
+				t.Errorf("PID = %d, want %d", entry.Process.PID, tt.pid)
+			}
+			if entry.Message != tt.message {
+				t.Errorf("Message = %q, want %q", entry.Message, tt.message)
+			}
+			if entry.Log.Syslog.Facility.Name != tt.facility {
+				t.Errorf("Facility = %q, want %q", entry.Log.Syslog.Facility.Name, tt.facility)
+			}
+			if entry.Timestamp == "" {
+				t.Error("Expected timestamp to be parsed")
+			}
+		})
+	}
+}
+
+func TestParseStructuredData(t *testing.T) {
+	sd, msg, ok := parseStructuredData(`[a@1 x="1" y="q\"uote"][b@2] hello`)
+	if !ok {
+		t.Fatal("Expected structured data to parse")
+	}
+	if msg != "hello" {
+		t.Errorf("msg = %q, want %q", msg, "hello")
+	}
+	if sd["a@1"]["x"] != "1" || sd["a@1"]["y"] != `q"uote` {
+		t.Errorf("Unexpected params: %v", sd["a@1"])
+	}
+	if _, ok := sd["b@2"]; !ok {
+		t.Error("Expected empty SD element b@2")
+	}
+
+	if _, _, ok := parseStructuredData(`[broken x="1"`); ok {
+		t.Error("Expected unterminated structured data to fail")
+	}
+}
+
+func TestParseLineSyslogFallback(t *testing.T) {
+	if _, ok := parseLine(`{"log.level":"info","message":"json"}`); !ok {
+		t.Error("Expected JSON line to parse")
+	}
+	entry, ok := parseLine(`<14>1 2025-06-28T11:50:00Z host app - - - from syslog`)
+	if !ok || entry.Log.Syslog == nil {
+		t.Error("Expected syslog line to parse")
+	}
+	if _, ok := parseLine("garbage"); ok {
+		t.Error("Expected garbage line to be rejected")
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// TerraformEvent is a message of Terraform's machine-readable UI, printed
+// by `terraform plan -json` and `terraform apply -json`.
+type TerraformEvent struct {
+	// Type is the message type, e.g. "apply_complete" or "diagnostic".
+	Type string
+	// Action is the change made to the resource, e.g. "create".
+	Action string
+	// Detail and Location describe a diagnostic, e.g. "main.tf:12".
+	Detail, Location string
+	// Message, Level and Time fill in the entry.
+	Message, Level, Time string
+}
+
+// parseTerraform recognizes a Terraform UI message, returning nil for other
+// log lines.
+func parseTerraform(line *lineFields) *TerraformEvent {
+	if !strings.Contains(line.text, `"terraform.ui"`) {
+		return nil
+	}
+	fields := line.object()
+	if fields == nil || fieldString(fields, "@module") != "terraform.ui" || fieldString(fields, "type") == "" {
+		return nil
+	}
+	event := &TerraformEvent{
+		Type:    fieldString(fields, "type"),
+		Action:  fieldString(fields, "hook.action", "change.action"),
+		Detail:  strings.TrimSpace(fieldString(fields, "diagnostic.detail")),
+		Message: fieldString(fields, "@message"),
+		Level:   fieldString(fields, "@level"),
+		Time:    fieldString(fields, "@timestamp"),
+	}
+	if file := fieldString(fields, "diagnostic.range.filename"); file != "" {
+		start, _ := fieldFloat(fields, "diagnostic.range.start.line")
+		event.Location = fmt.Sprintf("%s:%d", file, int(start))
+	}
+	return event
+}
+
+// terraformActions are the symbols Terraform's own output marks changes
+// with, and their colors.
+var terraformActions = map[string]terraformAction{
+	"create":  newTerraformAction("+", color.FgGreen),
+	"read":    newTerraformAction("<=", color.FgCyan),
+	"update":  newTerraformAction("~", color.FgYellow),
+	"replace": newTerraformAction("-/+", color.FgMagenta),
+	"delete":  newTerraformAction("-", color.FgRed),
+	"remove":  newTerraformAction("-", color.FgRed),
+	"move":    newTerraformAction("→", color.FgBlue),
+	"import":  newTerraformAction("←", color.FgBlue),
+}
+
+type terraformAction struct {
+	symbol       string
+	symbolPaint  paint
+	messagePaint paint
+}
+
+func newTerraformAction(symbol string, attr color.Attribute) terraformAction {
+	return terraformAction{symbol: symbol, symbolPaint: newPaint(attr, color.Bold), messagePaint: newPaint(attr)}
+}
+
+// terraformLine renders the body of a Terraform message: the message
+// behind the symbol of its change, and for diagnostics, where they are
+// and their detail indented below, e.g.
+// "+ aws_instance.web: Creation complete after 32s".
+func terraformLine(event *TerraformEvent, message string) string {
+	var b strings.Builder
+	if action, ok := terraformActions[event.Action]; ok {
+		b.WriteString(action.symbolPaint.sprint(action.symbol) + " " + action.messagePaint.sprint(message))
+	} else {
+		b.WriteString(messagePaint.sprint(message))
+	}
+	if event.Location != "" {
+		b.WriteString(" " + labelPaint.sprint(event.Location))
+	}
+	if event.Detail != "" {
+		for _, line := range strings.Split(event.Detail, "\n") {
+			b.WriteString("\n    " + labelPaint.sprint(line))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestParseTerraform(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want *TerraformEvent
+	}{
+		{
+			"apply complete",
+			`{"@level":"info","@message":"aws_instance.web: Creation complete after 32s","@module":"terraform.ui","@timestamp":"2024-01-15T14:02:43.000000Z","hook":{"resource":{"addr":"aws_instance.web"},"action":"create"},"type":"apply_complete"}`,
+			&TerraformEvent{Type: "apply_complete", Action: "create", Message: "aws_instance.web: Creation complete after 32s", Level: "info", Time: "2024-01-15T14:02:43.000000Z"},
+		},
+		{
+			"planned change",
+			`{"@level":"info","@message":"aws_instance.db: Plan to replace","@module":"terraform.ui","change":{"action":"replace"},"type":"planned_change"}`,
+			&TerraformEvent{Type: "planned_change", Action: "replace", Message: "aws_instance.db: Plan to replace", Level: "info"},
+		},
+		{
+			"diagnostic",
+			`{"@level":"error","@message":"Error: Unsupported argument","@module":"terraform.ui","diagnostic":{"severity":"error","detail":"An argument named \"foo\" is not expected here.","range":{"filename":"main.tf","start":{"line":12}}},"type":"diagnostic"}`,
+			&TerraformEvent{Type: "diagnostic", Detail: `An argument named "foo" is not expected here.`, Location: "main.tf:12", Message: "Error: Unsupported argument", Level: "error"},
+		},
+		{"hclog line", `{"@level":"info","@message":"starting","@module":"provider"}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseTerraform(newLineFields([]byte(tt.line)))
+			if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+				t.Errorf("parseTerraform() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTerraformLine(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	tests := []struct {
+		event TerraformEvent
+		want  string
+	}{
+		{TerraformEvent{Action: "delete"}, "- msg"},
+		{TerraformEvent{Action: "noop"}, "msg"},
+		{TerraformEvent{Location: "main.tf:3", Detail: "line one\nline two"}, "msg main.tf:3\n    line one\n    line two"},
+	}
+	for _, tt := range tests {
+		if got := terraformLine(&tt.event, "msg"); got != tt.want {
+			t.Errorf("terraformLine(%+v) = %q, want %q", tt.event, got, tt.want)
+		}
+	}
+}
+
+func TestTerraformEntry(t *testing.T) {
+	entry, ok := parseLine(`{"@level":"warn","@message":"Warning: Deprecated attribute","@module":"terraform.ui","@timestamp":"2024-01-15T14:02:44Z","type":"diagnostic"}`)
+	if !ok || entry.Terraform == nil {
+		t.Fatal("message not recognized")
+	}
+	if entry.Level != "warn" || entry.Message != "Warning: Deprecated attribute" || entry.Timestamp != "2024-01-15T14:02:44Z" {
+		t.Errorf("entry = level %q, message %q, timestamp %q", entry.Level, entry.Message, entry.Timestamp)
+	}
+}
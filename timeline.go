@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// timelineWidth is the width of the longest bar of the --timeline chart.
+const timelineWidth = 50
+
+// timelineMaxRows is how many rows the chart may have before minutes are
+// grouped into longer buckets.
+const timelineMaxRows = 60
+
+// timelineBucketSizes are the bucket lengths tried, shortest first.
+var timelineBucketSizes = []time.Duration{
+	time.Minute, 5 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour, 6 * time.Hour, 24 * time.Hour,
+}
+
+// timelineGroups are the level groups stacked in each bar, with the
+// character drawing them (distinct so the chart reads without colors).
+var timelineGroups = []struct {
+	name  string
+	char  string
+	color *color.Color
+}{
+	{"error", "█", color.New(color.FgRed)},
+	{"warn", "▓", color.New(color.FgYellow)},
+	{"info", "▒", color.New(color.FgBlue)},
+	{"other", "░", color.New(color.Faint)},
+}
+
+// timeline counts entries per minute and level group for --timeline.
+type timeline struct {
+	minutes map[int64]*[4]int
+	// location is the time zone of the first timestamp, so rows line up
+	// with the times shown on the entries.
+	location *time.Location
+	now      func() time.Time
+}
+
+func newTimeline() *timeline {
+	return &timeline{minutes: make(map[int64]*[4]int), location: time.Local, now: time.Now}
+}
+
+// record counts an entry in the minute of its timestamp, or of its arrival
+// when it has none.
+func (t *timeline) record(entry LogEntry) {
+	at, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
+	if err != nil {
+		at = t.now()
+	} else if len(t.minutes) == 0 {
+		t.location = at.Location()
+	}
+	minute := at.Unix() / 60
+	counts, ok := t.minutes[minute]
+	if !ok {
+		counts = &[4]int{}
+		t.minutes[minute] = counts
+	}
+	counts[timelineGroup(entry.Level)]++
+}
+
+// timelineGroup returns the index in timelineGroups of a level.
+func timelineGroup(level string) int {
+	switch normalizeLevel(level) {
+	case "fatal", "error":
+		return 0
+	case "warn":
+		return 1
+	case "info", "notice":
+		return 2
+	}
+	return 3
+}
+
+// write prints the chart: one row per bucket from the first entry to the
+// last, with bars stacked by level group.
+func (t *timeline) write(w io.Writer) {
+	if len(t.minutes) == 0 {
+		return
+	}
+	first, last := int64(-1), int64(-1)
+	for minute := range t.minutes {
+		if first < 0 || minute < first {
+			first = minute
+		}
+		last = max(last, minute)
+	}
+
+	size := timelineBucketSizes[len(timelineBucketSizes)-1]
+	for _, candidate := range timelineBucketSizes {
+		if (last-first)/int64(candidate/time.Minute) < timelineMaxRows {
+			size = candidate
+			break
+		}
+	}
+	step := int64(size / time.Minute)
+	first -= first % step
+
+	var rows [][4]int
+	peak := 0
+	for start := first; start <= last; start += step {
+		var row [4]int
+		for minute := start; minute < start+step; minute++ {
+			if counts, ok := t.minutes[minute]; ok {
+				for i, n := range counts {
+					row[i] += n
+				}
+			}
+		}
+		rows = append(rows, row)
+		peak = max(peak, row[0]+row[1]+row[2]+row[3])
+	}
+
+	layout := "15:04"
+	if time.Unix(last*60, 0).Sub(time.Unix(first*60, 0)) >= 24*time.Hour {
+		layout = "01-02 15:04"
+	}
+	per := "minute"
+	if size > time.Minute {
+		per = strings.TrimSuffix(strings.TrimSuffix(size.String(), "0s"), "0m")
+	}
+	var legend []string
+	for _, group := range timelineGroups {
+		legend = append(legend, group.color.Sprint(group.char)+" "+group.name)
+	}
+	fmt.Fprintf(w, "\n%s %s\n", color.New(color.Bold).Sprintf("Entries per %s", per), labelColor.Sprint("("+strings.Join(legend, "  ")+")"))
+
+	for i, row := range rows {
+		start := time.Unix((first+int64(i)*step)*60, 0).In(t.location)
+		total := row[0] + row[1] + row[2] + row[3]
+		fmt.Fprintf(w, "%s ", labelColor.Sprint(start.Format(layout)))
+		width := 0
+		for g, n := range row {
+			cells := n * timelineWidth / peak
+			if n > 0 && cells == 0 {
+				cells = 1
+			}
+			width += cells
+			fmt.Fprint(w, timelineGroups[g].color.Sprint(strings.Repeat(timelineGroups[g].char, cells)))
+		}
+		fmt.Fprintf(w, "%s %d\n", strings.Repeat(" ", max(0, timelineWidth-width)), total)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeline(t *testing.T) {
+	tl := newTimeline()
+	for _, line := range []string{
+		`{"@timestamp":"2025-06-28T11:50:00Z","log.level":"info"}`,
+		`{"@timestamp":"2025-06-28T11:50:30Z","log.level":"info"}`,
+		`{"@timestamp":"2025-06-28T11:50:59Z","log.level":"error"}`,
+		`{"@timestamp":"2025-06-28T11:50:59Z","log.level":"fatal"}`,
+		`{"@timestamp":"2025-06-28T11:52:00Z","log.level":"warn"}`,
+		`{"@timestamp":"2025-06-28T11:52:01Z","log.level":"debug"}`,
+	} {
+		entry, _ := parseLine(line)
+		tl.record(entry)
+	}
+
+	var buf bytes.Buffer
+	tl.write(&buf)
+	want := []string{
+		"",
+		"Entries per minute (█ error  ▓ warn  ▒ info  ░ other)",
+		"11:50 " + strings.Repeat("█", 25) + strings.Repeat("▒", 25) + " 4",
+		"11:51 " + strings.Repeat(" ", 50) + " 0",
+		"11:52 " + strings.Repeat("▓", 12) + strings.Repeat("░", 12) + strings.Repeat(" ", 26) + " 2",
+	}
+	if got := strings.TrimSuffix(buf.String(), "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("write() =\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestTimelineBuckets(t *testing.T) {
+	tl := newTimeline()
+	start := time.Date(2025, 6, 28, 9, 3, 0, 0, time.UTC)
+	for i := 0; i < 3*60; i++ {
+		tl.record(LogEntry{Timestamp: start.Add(time.Duration(i) * time.Minute).Format(time.RFC3339), Level: "info"})
+	}
+
+	var buf bytes.Buffer
+	tl.write(&buf)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.HasPrefix(lines[0], "Entries per 5m") {
+		t.Errorf("header = %q", lines[0])
+	}
+	if len(lines) != 38 || !strings.HasPrefix(lines[1], "09:00 ") || !strings.HasSuffix(lines[1], " 2") {
+		t.Errorf("rows = %d, first %q", len(lines)-1, lines[1])
+	}
+
+	// Entries without a timestamp count when they arrive
+	tl = newTimeline()
+	tl.now = func() time.Time { return start }
+	tl.record(LogEntry{Message: "no time"})
+	if counts := tl.minutes[start.Unix()/60]; counts == nil || counts[3] != 1 {
+		t.Errorf("arrival minute counts = %v", counts)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeRange keeps entries timestamped from since (inclusive) to until
+// (exclusive) for --since and --until. A zero bound is open.
+type timeRange struct {
+	since, until time.Time
+	// keep is the decision for the last entry with a timestamp, which
+	// lines without one (e.g. stack traces) follow.
+	keep bool
+}
+
+// absoluteLayouts are the accepted absolute times, with or without a
+// date; times without a zone are local.
+var absoluteLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+var clockLayouts = []string{"15:04:05", "15:04"}
+
+// newTimeRange parses --since and --until, either of which may be empty.
+func newTimeRange(since, until string, now time.Time) (*timeRange, error) {
+	if since == "" && until == "" {
+		return nil, nil
+	}
+	r := &timeRange{keep: true}
+	var err error
+	if since != "" {
+		if r.since, err = parseTimeBound(since, now, now); err != nil {
+			return nil, fmt.Errorf("--since: %w", err)
+		}
+	}
+	if until != "" {
+		// A time of day alone is on the day of --since, if given
+		day := now
+		if !r.since.IsZero() {
+			day = r.since
+		}
+		if r.until, err = parseTimeBound(until, now, day); err != nil {
+			return nil, fmt.Errorf("--until: %w", err)
+		}
+	}
+	if !r.since.IsZero() && !r.until.IsZero() && !r.until.After(r.since) {
+		return nil, fmt.Errorf("--until %s is not after --since %s", r.until.Format(time.RFC3339), r.since.Format(time.RFC3339))
+	}
+	return r, nil
+}
+
+// parseTimeBound reads an absolute time, a time of day on the given day,
+// or a duration before now ("90m", "2h", "3d").
+func parseTimeBound(s string, now, day time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if d, ok := parseRelativeDuration(s); ok {
+		return now.Add(-d), nil
+	}
+	for _, layout := range absoluteLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range clockLayouts {
+		if t, err := time.ParseInLocation(layout, s, day.Location()); err == nil {
+			return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), t.Second(), 0, day.Location()), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (expected e.g. 2024-01-15T14:00:00Z, 14:00 or 1h)", s)
+}
+
+// parseRelativeDuration parses Go durations, plus days ("3d").
+func parseRelativeDuration(s string) (time.Duration, bool) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		return time.Duration(n * float64(24*time.Hour)), true
+	}
+	d, err := time.ParseDuration(s)
+	return d, err == nil && d >= 0
+}
+
+// contains reports whether an entry with the given timestamp is in range.
+// Entries without a readable timestamp follow the previous entry.
+func (r *timeRange) contains(timestamp string) bool {
+	t, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return r.keep
+	}
+	r.keep = (r.since.IsZero() || !t.Before(r.since)) && (r.until.IsZero() || t.Before(r.until))
+	return r.keep
+}
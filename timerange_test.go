@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2025, 6, 28, 12, 0, 0, 0, time.UTC)
+	day := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{"2024-01-15T14:00:00Z", time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)},
+		{"2024-01-15T14:00:00+02:00", time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)},
+		{"2024-01-15 14:30", time.Date(2024, 1, 15, 14, 30, 0, 0, time.UTC)},
+		{"2024-01-15", time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"15:30", time.Date(2024, 1, 15, 15, 30, 0, 0, time.UTC)},
+		{"15:30:10", time.Date(2024, 1, 15, 15, 30, 10, 0, time.UTC)},
+		{"1h", now.Add(-time.Hour)},
+		{"90m", now.Add(-90 * time.Minute)},
+		{"2d", now.Add(-48 * time.Hour)},
+	}
+	for _, tt := range tests {
+		got, err := parseTimeBound(tt.input, now, day)
+		if err != nil {
+			t.Errorf("parseTimeBound(%q) error = %v", tt.input, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseTimeBound(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+
+	for _, input := range []string{"yesterday", "-1h", "25:00", "2024-13-01"} {
+		if _, err := parseTimeBound(input, now, day); err == nil {
+			t.Errorf("parseTimeBound(%q) should fail", input)
+		}
+	}
+}
+
+func TestTimeRange(t *testing.T) {
+	now := time.Date(2025, 6, 28, 12, 0, 0, 0, time.UTC)
+	r, err := newTimeRange("2024-01-15T14:00:00Z", "15:30", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		timestamp string
+		want      bool
+	}{
+		{"2024-01-15T13:59:59Z", false},
+		{"", false}, // follows the previous entry
+		{"2024-01-15T14:00:00Z", true},
+		{"", true},
+		{"2024-01-15T16:00:00+01:00", true},
+		{"2024-01-15T15:30:00Z", false},
+	}
+	for _, tt := range tests {
+		if got := r.contains(tt.timestamp); got != tt.want {
+			t.Errorf("contains(%q) = %v, want %v", tt.timestamp, got, tt.want)
+		}
+	}
+
+	if r, err := newTimeRange("", "", now); r != nil || err != nil {
+		t.Errorf("newTimeRange() without bounds = %v, %v", r, err)
+	}
+	if _, err := newTimeRange("2024-01-15T14:00:00Z", "13:00", now); err == nil {
+		t.Error("expected --until before --since to fail")
+	}
+	if r, _ := newTimeRange("1h", "", now); !r.contains("2025-06-28T11:30:00Z") || r.contains("2025-06-28T10:30:00Z") {
+		t.Error("--since 1h should keep the last hour only")
+	}
+}
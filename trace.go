@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Field paths read by logpipe trace: ECS names first, then the names of
+// OpenTelemetry and common loggers.
+var (
+	traceIDFields  = []string{"trace.id", "trace_id", "traceId", "traceID"}
+	spanIDFields   = []string{"span.id", "span_id", "spanId", "spanID"}
+	parentIDFields = []string{"parent.id", "span.parent_id", "parent_span_id", "parentSpanId", "parent_id"}
+	spanNameFields = []string{"span.name", "transaction.name", "name", "log.logger", "service.name"}
+)
+
+// traceEntry is a log entry of a trace.
+type traceEntry struct {
+	at      time.Time
+	level   string
+	message string
+}
+
+// traceSpan gathers the entries logged in one span.
+type traceSpan struct {
+	id, parent, name string
+	entries          []traceEntry
+	// logged is the span's duration if an entry gave it (see
+	// entryDuration), else its entries' time range is used.
+	logged   time.Duration
+	children []*traceSpan
+}
+
+func (s *traceSpan) start() time.Time {
+	for _, e := range s.entries {
+		if !e.at.IsZero() {
+			return e.at
+		}
+	}
+	return time.Time{}
+}
+
+func (s *traceSpan) duration() time.Duration {
+	if s.logged > 0 {
+		return s.logged
+	}
+	var first, last time.Time
+	for _, e := range s.entries {
+		if e.at.IsZero() {
+			continue
+		}
+		if first.IsZero() {
+			first = e.at
+		}
+		last = e.at
+	}
+	return last.Sub(first)
+}
+
+// traceTree is the spans and entries of one trace, in input order.
+type traceTree struct {
+	id    string
+	spans map[string]*traceSpan
+	order []*traceSpan
+	// loose are entries of the trace without a span ID.
+	loose   []traceEntry
+	entries int
+}
+
+func newTraceTree(id string) *traceTree {
+	return &traceTree{id: id, spans: make(map[string]*traceSpan)}
+}
+
+// add records an input line if it belongs to the trace.
+func (t *traceTree) add(line string) {
+	entry, ok := parseLine(line)
+	if !ok {
+		return
+	}
+	fields := entryFields(line, entry)
+	if fieldString(fields, traceIDFields...) != t.id {
+		return
+	}
+	t.entries++
+	e := traceEntry{level: entry.Level, message: entry.Message}
+	e.at, _ = time.Parse(time.RFC3339Nano, entry.Timestamp)
+
+	spanID := fieldString(fields, spanIDFields...)
+	if spanID == "" {
+		t.loose = append(t.loose, e)
+		return
+	}
+	span := t.spans[spanID]
+	if span == nil {
+		span = &traceSpan{id: spanID}
+		t.spans[spanID] = span
+		t.order = append(t.order, span)
+	}
+	span.entries = append(span.entries, e)
+	if span.parent == "" {
+		span.parent = fieldString(fields, parentIDFields...)
+	}
+	if span.name == "" {
+		span.name = fieldString(fields, spanNameFields...)
+	}
+	if d, ok := entryDuration(fields); ok {
+		span.logged = max(span.logged, d)
+	}
+}
+
+// roots links spans to their parents and returns the spans without a known
+// parent, all ordered by start time.
+func (t *traceTree) roots() []*traceSpan {
+	var roots []*traceSpan
+	for _, span := range t.order {
+		sort.SliceStable(span.entries, func(i, j int) bool {
+			return span.entries[i].at.Before(span.entries[j].at)
+		})
+		span.children = nil
+	}
+	for _, span := range t.order {
+		if parent := t.spans[span.parent]; parent != nil && parent != span {
+			parent.children = append(parent.children, span)
+		} else {
+			roots = append(roots, span)
+		}
+	}
+	byStart := func(spans []*traceSpan) {
+		sort.SliceStable(spans, func(i, j int) bool {
+			return spans[i].start().Before(spans[j].start())
+		})
+	}
+	byStart(roots)
+	for _, span := range t.order {
+		byStart(span.children)
+	}
+	return roots
+}
+
+// start returns the time of the earliest entry of the trace.
+func (t *traceTree) start() time.Time {
+	var start time.Time
+	check := func(at time.Time) {
+		if !at.IsZero() && (start.IsZero() || at.Before(start)) {
+			start = at
+		}
+	}
+	for _, e := range t.loose {
+		check(e.at)
+	}
+	for _, span := range t.order {
+		for _, e := range span.entries {
+			check(e.at)
+		}
+	}
+	return start
+}
+
+// write prints the trace as an indented tree of spans, each with its
+// offset from the start of the trace, its duration and its entries.
+func (t *traceTree) write(w io.Writer) {
+	roots := t.roots()
+	start := t.start()
+	spans := "spans"
+	if len(t.order) == 1 {
+		spans = "span"
+	}
+	fmt.Fprintf(w, "%s %s: %d %s, %d entries\n", color.New(color.Bold).Sprint("Trace"), t.id, len(t.order), spans, t.entries)
+
+	offset := func(at time.Time) string {
+		if at.IsZero() || start.IsZero() {
+			return padWidth("", 9)
+		}
+		return padWidth("+"+formatSpanDuration(at.Sub(start)), 9)
+	}
+	writeEntries := func(entries []traceEntry, indent string) {
+		for _, e := range entries {
+			fmt.Fprintf(w, "%s%s [%s] %s\n", indent,
+				labelColor.Sprint(offset(e.at)),
+				getLevelColor(e.level).Sprint(padWidth(levelLabel(e.level), 4)),
+				e.message)
+		}
+	}
+	var writeSpan func(span *traceSpan, depth int)
+	writeSpan = func(span *traceSpan, depth int) {
+		indent := strings.Repeat("  ", depth)
+		name := span.name
+		if name == "" {
+			name = "span"
+		}
+		fmt.Fprintf(w, "%s%s %s %s %s\n", indent,
+			color.New(color.FgCyan).Sprint("▸"),
+			color.New(color.Bold).Sprint(name),
+			color.New(color.FgYellow).Sprintf("%s %s", strings.TrimSpace(offset(span.start())), formatSpanDuration(span.duration())),
+			labelColor.Sprintf("span=%s", span.id))
+		writeEntries(span.entries, indent+"    ")
+		for _, child := range span.children {
+			writeSpan(child, depth+1)
+		}
+	}
+
+	writeEntries(t.loose, "  ")
+	for _, root := range roots {
+		writeSpan(root, 0)
+	}
+}
+
+// formatSpanDuration shows durations to the millisecond, or to the
+// microsecond below one.
+func formatSpanDuration(d time.Duration) string {
+	switch {
+	case d == 0:
+		return "0ms"
+	case d < time.Millisecond:
+		return d.Round(time.Microsecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
+
+// runTrace prints the span tree of a trace from log files, or from stdin
+// when none are given.
+func runTrace(id string, files []string, w io.Writer) int {
+	tree := newTraceTree(id)
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	for _, name := range files {
+		if err := readLines(name, tree.add); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", name, err)
+			return 2
+		}
+	}
+	if tree.entries == 0 {
+		fmt.Fprintf(os.Stderr, "No entries with trace ID %s\n", id)
+		return 1
+	}
+	tree.write(w)
+	return 0
+}
+
+// readLines hands each line of a file, or of stdin when name is "-", to
+// handle, with multi-line JSON joined onto one line.
+func readLines(name string, handle func(line string)) error {
+	r := io.Reader(os.Stdin)
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	joiner := newJSONJoiner(handle)
+	for scanner.Scan() {
+		joiner.add(scanner.Text())
+	}
+	joiner.flush()
+	return scanner.Err()
+}
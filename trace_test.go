@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+func TestRunTrace(t *testing.T) {
+	defer func(noColor bool) { color.NoColor = noColor }(color.NoColor)
+	color.NoColor = true
+
+	input := strings.Join([]string{
+		`{"@timestamp":"2024-01-15T10:00:00.000Z","log.level":"info","message":"request started","trace":{"id":"abc"},"span":{"id":"s1","name":"GET /users"}}`,
+		`{"@timestamp":"2024-01-15T10:00:00.092Z","log.level":"info","message":"query done","trace_id":"abc","span_id":"s2","parent_span_id":"s1","event":{"duration":80000000}}`,
+		`{"@timestamp":"2024-01-15T10:00:00.012Z","log.level":"debug","message":"query users","trace":{"id":"abc"},"span":{"id":"s2"},"parent":{"id":"s1"},"log.logger":"db"}`,
+		`{"@timestamp":"2024-01-15T10:00:00.050Z","log.level":"info","message":"other trace","trace":{"id":"zzz"},"span":{"id":"s9"}}`,
+		`{"@timestamp":"2024-01-15T10:00:00.001Z","log.level":"warn","message":"no span","trace":{"id":"abc"}}`,
+		`{"@timestamp":"2024-01-15T10:00:00.030Z","log.level":"info","message":"orphan","trace":{"id":"abc"},"span":{"id":"s3"},"parent":{"id":"gone"}}`,
+		`{"@timestamp":"2024-01-15T10:00:00.245Z","log.level":"info","message":"request done","trace":{"id":"abc"},"span":{"id":"s1"}}`,
+		`not json`,
+	}, "\n")
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if code := runTrace("abc", []string{path}, &out); code != 0 {
+		t.Fatalf("runTrace() = %d, want 0", code)
+	}
+	want := []string{
+		"Trace abc: 3 spans, 6 entries",
+		"+1ms      [warn] no span",
+		"▸ GET /users +0ms 245ms span=s1",
+		"+0ms      [info] request started",
+		"+245ms    [info] request done",
+		"▸ db +12ms 80ms span=s2",
+		"+12ms     [debu] query users",
+		"+92ms     [info] query done",
+		"▸ span +30ms 0ms span=s3",
+		"+30ms     [info] orphan",
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("output =\n%s", out.String())
+	}
+	for i := range want {
+		if strings.TrimSpace(lines[i]) != want[i] {
+			t.Errorf("line %d = %q, want %q", i, strings.TrimSpace(lines[i]), want[i])
+		}
+	}
+	if !strings.HasPrefix(lines[5], "  ▸ db") {
+		t.Errorf("child span not indented: %q", lines[5])
+	}
+
+	if code := runTrace("nope", []string{path}, &out); code != 1 {
+		t.Errorf("runTrace() for an unknown trace = %d, want 1", code)
+	}
+	if code := runTrace("abc", []string{filepath.Join(t.TempDir(), "missing.log")}, &out); code != 2 {
+		t.Errorf("runTrace() for a missing file = %d, want 2", code)
+	}
+}
+
+func TestFormatSpanDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0ms"},
+		{1500 * time.Nanosecond, "2µs"},
+		{12345 * time.Microsecond, "12ms"},
+		{1500 * time.Millisecond, "1.5s"},
+	}
+	for _, tt := range tests {
+		if got := formatSpanDuration(tt.d); got != tt.want {
+			t.Errorf("formatSpanDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
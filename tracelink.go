@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// traceURL is the --trace-url template linking trace IDs to a tracing UI,
+// e.g. https://jaeger.local/trace/{trace.id}. Empty disables trace links.
+var traceURL = ""
+
+func checkTraceURL(template string) error {
+	if !strings.Contains(template, "{trace.id}") {
+		return fmt.Errorf("%q has no {trace.id} placeholder", template)
+	}
+	return nil
+}
+
+// entryID returns the id of an ECS object field such as trace or span,
+// logged either as {"id": "..."} or directly as a string.
+func entryID(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		id, _ := v["id"].(string)
+		return id
+	}
+	return ""
+}
+
+// traceLink returns the URL of an entry's trace from the template, with
+// {trace.id} and {span.id} filled in.
+func traceLink(template string, log LogEntry) (string, bool) {
+	traceID := entryID(log.Trace)
+	if template == "" || traceID == "" {
+		return "", false
+	}
+	return strings.NewReplacer(
+		"{trace.id}", url.PathEscape(traceID),
+		"{span.id}", url.PathEscape(entryID(log.Span)),
+	).Replace(template), true
+}
+
+// hyperlink wraps text in an OSC 8 escape sequence, which terminals show
+// as a clickable link to target.
+func hyperlink(target, text string) string {
+	return "\x1b]8;;" + target + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+// traceSuffix returns the trace ID of an entry as a link to the tracing
+// UI, shown after the message. Without colors, e.g. when piped, the URL
+// itself is shown instead.
+func traceSuffix(log LogEntry) string {
+	target, ok := traceLink(traceURL, log)
+	if !ok {
+		return ""
+	}
+	if color.NoColor {
+		return " trace=" + target
+	}
+	return " " + hyperlink(target, labelPaint.sprint("trace="+entryID(log.Trace)))
+}
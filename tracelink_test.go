@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestTraceLink(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		line     string
+		want     string
+		ok       bool
+	}{
+		{"no template", "", `{"trace":{"id":"abc"}}`, "", false},
+		{"no trace", "https://jaeger.local/trace/{trace.id}", `{"message":"hi"}`, "", false},
+		{"trace object", "https://jaeger.local/trace/{trace.id}", `{"trace":{"id":"4bf92f35"}}`, "https://jaeger.local/trace/4bf92f35", true},
+		{"trace string", "https://jaeger.local/trace/{trace.id}", `{"trace":"4bf92f35"}`, "https://jaeger.local/trace/4bf92f35", true},
+		{"span", "https://ui/t/{trace.id}?span={span.id}", `{"trace":{"id":"a"},"span":{"id":"b"}}`, "https://ui/t/a?span=b", true},
+		{"escaped", "https://ui/t/{trace.id}", `{"trace":{"id":"a/b c"}}`, "https://ui/t/a%2Fb%20c", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, ok := parseLine(tt.line)
+			if !ok {
+				t.Fatalf("parseLine(%q) failed", tt.line)
+			}
+			got, ok := traceLink(tt.template, log)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("traceLink() = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestTraceSuffix(t *testing.T) {
+	defer func(url string, noColor bool) { traceURL, color.NoColor = url, noColor }(traceURL, color.NoColor)
+	traceURL = "https://jaeger.local/trace/{trace.id}"
+	log, _ := parseLine(`{"trace":{"id":"abc"}}`)
+
+	color.NoColor = true
+	if got, want := traceSuffix(log), " trace=https://jaeger.local/trace/abc"; got != want {
+		t.Errorf("traceSuffix() without colors = %q, want %q", got, want)
+	}
+
+	color.NoColor = false
+	want := " \x1b]8;;https://jaeger.local/trace/abc\x1b\\" + labelColor.Sprint("trace=abc") + "\x1b]8;;\x1b\\"
+	if got := traceSuffix(log); got != want {
+		t.Errorf("traceSuffix() = %q, want %q", got, want)
+	}
+}
+
+func TestCheckTraceURL(t *testing.T) {
+	if err := checkTraceURL("https://jaeger.local/trace/{trace.id}"); err != nil {
+		t.Errorf("checkTraceURL() = %v", err)
+	}
+	if err := checkTraceURL("https://jaeger.local/trace/"); err == nil {
+		t.Error("checkTraceURL() accepted a template without {trace.id}")
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+)
+
+// traceContext is a parsed W3C traceparent header, e.g.
+// 00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01.
+type traceContext struct {
+	traceID, spanID, flags string
+}
+
+// parseTraceparent reads a traceparent value. Versions other than 00 are
+// read the same way, as the specification asks, except the invalid ff.
+func parseTraceparent(value string) (traceContext, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return traceContext{}, false
+	}
+	for i, size := range []int{2, 32, 16, 2} {
+		if len(parts[i]) != size || !isLowerHex(parts[i]) {
+			return traceContext{}, false
+		}
+	}
+	if strings.Trim(parts[1], "0") == "" || strings.Trim(parts[2], "0") == "" {
+		return traceContext{}, false
+	}
+	return traceContext{traceID: parts[1], spanID: parts[2], flags: parts[3]}, true
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// findTraceparent returns the value of the first traceparent field of a
+// decoded JSON object, at any depth and in any case, such as a logged
+// request header. Header values logged as lists are read too.
+func findTraceparent(fields map[string]interface{}) (string, bool) {
+	for _, key := range sortedKeys(fields) {
+		lower := strings.ToLower(key)
+		if lower == "traceparent" || strings.HasSuffix(lower, ".traceparent") {
+			switch v := fields[key].(type) {
+			case string:
+				return v, true
+			case []interface{}:
+				if len(v) > 0 {
+					if s, ok := v[0].(string); ok {
+						return s, true
+					}
+				}
+			}
+		}
+		if nested, ok := fields[key].(map[string]interface{}); ok {
+			if value, ok := findTraceparent(nested); ok {
+				return value, true
+			}
+		}
+	}
+	return "", false
+}
+
+// applyTraceparent fills trace.id, span.id and trace.flags from a
+// traceparent field of an entry without a trace ID, so that trace links
+// and `logpipe trace` work for services logging raw headers. It returns
+// false when nothing was written.
+func applyTraceparent(fields map[string]interface{}) bool {
+	if fieldString(fields, traceIDFields...) != "" {
+		return false
+	}
+	value, ok := findTraceparent(fields)
+	if !ok {
+		return false
+	}
+	tc, ok := parseTraceparent(value)
+	if !ok {
+		return false
+	}
+	setEntryField(fields, "trace.id", tc.traceID)
+	setEntryField(fields, "trace.flags", tc.flags)
+	if fieldString(fields, spanIDFields...) == "" {
+		setEntryField(fields, "span.id", tc.spanID)
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  traceContext
+		ok    bool
+	}{
+		{"sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", traceContext{"4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", "01"}, true},
+		{"spaces trimmed", " 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00 ", traceContext{"4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", "00"}, true},
+		{"future version with more parts", "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", traceContext{"4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", "01"}, true},
+		{"version 00 with more parts", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", traceContext{}, false},
+		{"invalid version", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", traceContext{}, false},
+		{"zero trace ID", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", traceContext{}, false},
+		{"zero span ID", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", traceContext{}, false},
+		{"uppercase", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", traceContext{}, false},
+		{"short trace ID", "00-4bf92f3577b34da6-00f067aa0ba902b7-01", traceContext{}, false},
+		{"not a traceparent", "hello", traceContext{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseTraceparent(tt.value)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("parseTraceparent(%q) = %+v, %v, want %+v, %v", tt.value, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestApplyTraceparent(t *testing.T) {
+	tests := []struct {
+		name              string
+		line              string
+		trace, span, flag string
+	}{
+		{"top level", `{"traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}`, "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", "01"},
+		{"request header in any case", `{"http":{"request":{"headers":{"Traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}}}`, "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", "01"},
+		{"flat header list", `{"http.request.headers.traceparent":["00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"]}`, "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", "00"},
+		{"span ID kept", `{"span_id":"s1","traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}`, "4bf92f3577b34da6a3ce929d0e0e4736", "s1", "01"},
+		{"trace ID kept", `{"trace_id":"t1","traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}`, "t1", "", ""},
+		{"invalid", `{"traceparent":"00-nope"}`, "", "", ""},
+		{"none", `{"message":"x"}`, "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := decodeJSONObject(tt.line)
+			applyTraceparent(fields)
+			if got := fieldString(fields, traceIDFields...); got != tt.trace {
+				t.Errorf("trace ID = %q, want %q", got, tt.trace)
+			}
+			if got := fieldString(fields, spanIDFields...); got != tt.span {
+				t.Errorf("span ID = %q, want %q", got, tt.span)
+			}
+			if got := fieldString(fields, "trace.flags"); got != tt.flag {
+				t.Errorf("trace.flags = %q, want %q", got, tt.flag)
+			}
+		})
+	}
+}
+
+func TestTraceparentLogEntry(t *testing.T) {
+	var entry LogEntry
+	line := `{"message":"GET /users","http":{"request":{"headers":{"traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}}}`
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if got := entryID(entry.Trace); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Trace = %q", got)
+	}
+	if got := entryID(entry.Span); got != "00f067aa0ba902b7" {
+		t.Errorf("Span = %q", got)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// defaultUnwrapKeep lists the wrapper fields kept by --unwrap: Filebeat's
+// host and file path, and Fluentd's tag.
+var defaultUnwrapKeep = []string{"host.name", "host.hostname", "log.file.path", "tag"}
+
+// unwrapper extracts the real event from a shipper's wrapper document
+// (Filebeat, Fluentd, ...), where it sits in a single field either as a
+// JSON string or as an object.
+type unwrapper struct {
+	field string
+	keep  []string
+}
+
+// newUnwrapper returns nil when field is empty.
+func newUnwrapper(field string, keep []string) *unwrapper {
+	if field == "" {
+		return nil
+	}
+	if len(keep) == 0 {
+		keep = defaultUnwrapKeep
+	}
+	return &unwrapper{field: field, keep: keep}
+}
+
+// unwrapLine returns the inner event of a wrapper line along with the kept
+// wrapper metadata, formatted as a label. Lines without the field are
+// returned unchanged.
+func (u *unwrapper) unwrapLine(line string) (string, string) {
+	wrapper := decodeJSONObject(line)
+	if wrapper == nil {
+		return line, ""
+	}
+	value, ok := lookupField(wrapper, u.field)
+	if !ok {
+		return line, ""
+	}
+
+	var inner string
+	switch v := value.(type) {
+	case string:
+		inner = strings.TrimRight(v, "\r\n")
+	case map[string]interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return line, ""
+		}
+		inner = string(data)
+	default:
+		return line, ""
+	}
+
+	var extras []string
+	for _, path := range u.keep {
+		if s := fieldString(wrapper, path); s != "" {
+			extras = append(extras, s)
+		}
+	}
+	return inner, strings.Join(extras, " ")
+}
+
+// joinLabel combines input labels, skipping empty ones.
+func joinLabel(labels ...string) string {
+	var parts []string
+	for _, label := range labels {
+		if label != "" {
+			parts = append(parts, label)
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestUnwrapLine(t *testing.T) {
+	tests := []struct {
+		name       string
+		field      string
+		keep       []string
+		input      string
+		wantLine   string
+		wantExtras string
+	}{
+		{
+			name:       "filebeat string payload",
+			field:      "message",
+			input:      `{"host":{"name":"web01"},"log":{"file":{"path":"/var/log/app.log"}},"message":"{\"message\":\"boom\"}\n"}`,
+			wantLine:   `{"message":"boom"}`,
+			wantExtras: "web01 /var/log/app.log",
+		},
+		{
+			name:       "fluentd object payload",
+			field:      "log",
+			input:      `{"tag":"app.web","log":{"message":"hi"}}`,
+			wantLine:   `{"message":"hi"}`,
+			wantExtras: "app.web",
+		},
+		{
+			name:       "custom keep with flattened keys",
+			field:      "event.original",
+			keep:       []string{"agent.name"},
+			input:      `{"agent.name":"beat-1","event.original":"plain text"}`,
+			wantLine:   "plain text",
+			wantExtras: "beat-1",
+		},
+		{
+			name:     "missing field",
+			field:    "message",
+			input:    `{"log":"x"}`,
+			wantLine: `{"log":"x"}`,
+		},
+		{
+			name:     "not json",
+			field:    "message",
+			input:    "plain line",
+			wantLine: "plain line",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, extras := newUnwrapper(tt.field, tt.keep).unwrapLine(tt.input)
+			if line != tt.wantLine || extras != tt.wantExtras {
+				t.Errorf("unwrapLine() = %q, %q, want %q, %q", line, extras, tt.wantLine, tt.wantExtras)
+			}
+		})
+	}
+
+	if newUnwrapper("", nil) != nil {
+		t.Error("newUnwrapper(\"\") should return nil")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// verticalOutput is set by --vertical to render entries as blocks of
+// field: value lines, like psql's expanded display.
+var verticalOutput = false
+
+// verticalRuleWidth is the width of the rule above each block.
+const verticalRuleWidth = 60
+
+// verticalBlock renders the fields of an entry, normalized as for --kv,
+// one per line with the values aligned, under a rule numbering the
+// record. The fields --kv leads with come first under their full names,
+// and values spanning lines are indented under the first.
+func verticalBlock(line string, entry LogEntry, record int) string {
+	fields := normalizedFields(line, entry, entry.InputLabel)
+	keys := make([]string, 0, len(fields))
+	for _, leading := range kvLeadingKeys {
+		if _, ok := fields[leading.field]; ok {
+			keys = append(keys, leading.field)
+		}
+	}
+	for _, key := range sortedKeys(fields) {
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	width := 0
+	for _, key := range keys {
+		width = max(width, displayWidth(key))
+	}
+
+	var b strings.Builder
+	rule := fmt.Sprintf("─[ RECORD %d ]", record)
+	b.WriteString(labelColor.Sprint(rule + strings.Repeat("─", max(verticalRuleWidth-displayWidth(rule), 0))))
+	b.WriteByte('\n')
+	indent := strings.Repeat(" ", width+2)
+	for _, key := range keys {
+		value, ok := fields[key].(string)
+		if !ok {
+			data, _ := json.Marshal(fields[key])
+			value = string(data)
+		}
+		value = strings.ReplaceAll(strings.TrimRight(value, "\n"), "\n", "\n"+indent)
+		if key == "log.level" {
+			value = getLevelColor(entry.Level).Sprint(value)
+		}
+		fmt.Fprintf(&b, "%s: %s\n", kvKeyColor.Sprint(padWidth(key, width)), value)
+	}
+	return b.String()
+}
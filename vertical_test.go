@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestVerticalBlock(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	tests := []struct {
+		name   string
+		line   string
+		record int
+		want   string
+	}{
+		{
+			name:   "aligned",
+			line:   `{"@timestamp":"2024-01-15T14:00:00Z","log.level":"ERROR","message":"payment failed\n  at Checkout.pay(Checkout.java:42)\n","user":{"id":42},"tags":["a"]}`,
+			record: 1,
+			want: "─[ RECORD 1 ]───────────────────────────────────────────────\n" +
+				"@timestamp: 2024-01-15T14:00:00Z\n" +
+				"log.level : error\n" +
+				"message   : payment failed\n" +
+				"              at Checkout.pay(Checkout.java:42)\n" +
+				"tags      : [\"a\"]\n" +
+				"user.id   : 42\n",
+		},
+		{
+			name:   "logfmt",
+			line:   `level=info msg=started port=8080`,
+			record: 12,
+			want: "─[ RECORD 12 ]──────────────────────────────────────────────\n" +
+				"log.level: info\n" +
+				"message  : started\n" +
+				"port     : 8080\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, ok := parseLine(tt.line)
+			if !ok {
+				t.Fatalf("parseLine(%q) failed", tt.line)
+			}
+			if got := verticalBlock(tt.line, entry, tt.record); got != tt.want {
+				t.Errorf("verticalBlock() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,491 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// WebSocket opcodes (RFC 6455)
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xA
+)
+
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxWSMessage bounds the size of a single (possibly fragmented) message.
+const maxWSMessage = 16 << 20
+
+// wsAcceptKey computes the Sec-WebSocket-Accept value for a handshake key.
+func wsAcceptKey(key string) string {
+	h := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// writeWSFrame writes a single unfragmented frame. Clients must mask the
+// frames they send, servers must not.
+func writeWSFrame(w io.Writer, opcode byte, payload []byte, mask bool) error {
+	header := []byte{0x80 | opcode, 0}
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	if mask {
+		header[1] |= 0x80
+		var key [4]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+		header = append(header, key[:]...)
+		masked := make([]byte, len(payload))
+		for i := range payload {
+			masked[i] = payload[i] ^ key[i%4]
+		}
+		payload = masked
+	}
+
+	if _, err := w.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// readWSFrame reads a single frame and unmasks its payload.
+func readWSFrame(r *bufio.Reader) (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return
+	}
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+
+	size := uint64(header[1] & 0x7F)
+	switch size {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(r, ext[:]); err != nil {
+			return
+		}
+		size = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(r, ext[:]); err != nil {
+			return
+		}
+		size = binary.BigEndian.Uint64(ext[:])
+	}
+	if size > maxWSMessage {
+		err = fmt.Errorf("websocket frame of %d bytes exceeds limit", size)
+		return
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err = io.ReadFull(r, key[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, size)
+	if _, err = io.ReadFull(r, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= key[i%4]
+		}
+	}
+	return
+}
+
+// readWSMessage reads frames until a complete data message is assembled,
+// answering pings along the way. It returns io.EOF on a close frame.
+func readWSMessage(r *bufio.Reader, w io.Writer, mask bool) ([]byte, error) {
+	var message []byte
+	for {
+		fin, opcode, payload, err := readWSFrame(r)
+		if err != nil {
+			return nil, err
+		}
+		switch opcode {
+		case wsPing:
+			if err := writeWSFrame(w, wsPong, payload, mask); err != nil {
+				return nil, err
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			writeWSFrame(w, wsClose, nil, mask)
+			return nil, io.EOF
+		case wsText, wsBinary, wsContinuation:
+			if len(message)+len(payload) > maxWSMessage {
+				return nil, errors.New("websocket message exceeds limit")
+			}
+			message = append(message, payload...)
+			if fin {
+				return message, nil
+			}
+		default:
+			return nil, fmt.Errorf("unexpected websocket opcode %d", opcode)
+		}
+	}
+}
+
+// consumeWebSocket connects to a ws:// or wss:// URL and hands each received
+// message to handle until ctx is cancelled or the server closes the stream.
+func consumeWebSocket(ctx context.Context, rawURL string, decoder recordDecoder, handle lineHandler) error {
+	if rawURL == "" {
+		return errors.New("missing URL, e.g. ws://localhost:8081/ws")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	var defaultPort string
+	switch u.Scheme {
+	case "ws":
+		defaultPort = "80"
+	case "wss":
+		defaultPort = "443"
+	default:
+		return fmt.Errorf("unsupported URL scheme %q (expected ws or wss)", u.Scheme)
+	}
+
+	conn, r, err := dialWebSocket(ctx, u, defaultPort, nil)
+	if conn == nil {
+		return err
+	}
+	defer conn.Close()
+
+	for {
+		message, err := readWSMessage(r, conn, true)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return ignoreClosed(ctx, err)
+		}
+		handlePayload(message, decoder, handle, "")
+	}
+}
+
+// dialWebSocket opens a client WebSocket connection to u, sending header
+// along with the handshake. Messages are read from the returned reader.
+// The connection is nil on failure, with a nil error when ctx was
+// cancelled.
+func dialWebSocket(ctx context.Context, u *url.URL, defaultPort string, header http.Header) (net.Conn, *bufio.Reader, error) {
+	conn, err := dialURL(ctx, u, defaultPort, u.Scheme == "wss")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+
+	req := &http.Request{
+		Method: http.MethodGet,
+		URL:    &url.URL{Path: u.Path, RawPath: u.RawPath, RawQuery: u.RawQuery},
+		Host:   u.Host,
+		Header: http.Header{
+			"Upgrade":               {"websocket"},
+			"Connection":            {"Upgrade"},
+			"Sec-WebSocket-Key":     {key},
+			"Sec-WebSocket-Version": {"13"},
+			"User-Agent":            {"logpipe/" + version},
+		},
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if req.URL.Path == "" {
+		req.URL.Path = "/"
+	}
+	if u.User != nil {
+		password, _ := u.User.Password()
+		req.SetBasicAuth(u.User.Username(), password)
+	}
+	fail := func(err error) (net.Conn, *bufio.Reader, error) {
+		conn.Close()
+		return nil, nil, err
+	}
+	if err := req.Write(conn); err != nil {
+		return fail(ignoreClosed(ctx, err))
+	}
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		return fail(ignoreClosed(ctx, err))
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return fail(fmt.Errorf("websocket handshake failed: %s", resp.Status))
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != wsAcceptKey(key) {
+		return fail(errors.New("websocket handshake failed: invalid Sec-WebSocket-Accept"))
+	}
+	return conn, r, nil
+}
+
+// wsHub broadcasts entries to every connected browser client.
+type wsHub struct {
+	mu      sync.Mutex
+	clients map[chan string]struct{}
+	// origins are the pages of other sites allowed to connect, from
+	// --serve-ws-origins.
+	origins []string
+	// host is the host --serve-ws was given, if any.
+	host string
+}
+
+func newWSHub(origins []string) *wsHub {
+	return &wsHub{clients: make(map[chan string]struct{}), origins: origins}
+}
+
+// allowOrigin reports whether a client may connect. Browsers send the
+// Origin of the page opening the WebSocket, and any page may try, so only
+// the live-tail page itself and the --serve-ws-origins are let in, to keep
+// other sites from reading the logs. Clients other than browsers send no
+// Origin. A site can point its own name at the loopback address (DNS
+// rebinding) to pass as the live-tail page, so the page's host must also
+// be one that can't be rebound, see trustedHost.
+func (h *wsHub) allowOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) && h.trustedHost(r.Host) {
+		return true
+	}
+	for _, allowed := range h.origins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) || strings.EqualFold(allowed, u.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// trustedHost reports whether host, as a request's Host header names it,
+// is one logpipe was reached by directly rather than through a name any
+// site could resolve to it: an IP address, localhost, the host --serve-ws
+// was given, or one of the --serve-ws-origins.
+func (h *wsHub) trustedHost(host string) bool {
+	name := host
+	if split, _, err := net.SplitHostPort(host); err == nil {
+		name = split
+	}
+	name = strings.Trim(name, "[]")
+	if net.ParseIP(name) != nil || strings.EqualFold(name, "localhost") || (h.host != "" && strings.EqualFold(name, h.host)) {
+		return true
+	}
+	for _, allowed := range h.origins {
+		if u, err := url.Parse(allowed); err == nil && u.Host != "" {
+			allowed = u.Host
+		}
+		if strings.EqualFold(allowed, host) || strings.EqualFold(allowed, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// broadcast queues a message for every client. Slow clients drop messages
+// rather than stalling the pipeline.
+func (h *wsHub) broadcast(message string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for client := range h.clients {
+		select {
+		case client <- message:
+		default:
+		}
+	}
+}
+
+// listenAndServe starts serving the live-tail page on / and the stream on
+// /ws in the background.
+func (h *wsHub) listenAndServe(addr string) (net.Listener, error) {
+	ln, err := net.Listen("tcp", listenAddr(addr))
+	if err != nil {
+		return nil, err
+	}
+	h.host, _, _ = net.SplitHostPort(addr)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, wsPage)
+	})
+	mux.HandleFunc("/ws", h.serveClient)
+
+	go http.Serve(ln, mux)
+	return ln, nil
+}
+
+func (h *wsHub) serveClient(w http.ResponseWriter, r *http.Request) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || r.Header.Get("Sec-WebSocket-Key") == "" {
+		http.Error(w, "expected a websocket upgrade", http.StatusBadRequest)
+		return
+	}
+	if !h.allowOrigin(r) {
+		http.Error(w, "origin not allowed, see --serve-ws-origins", http.StatusForbidden)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+		wsAcceptKey(r.Header.Get("Sec-WebSocket-Key")))
+	if err := rw.Flush(); err != nil {
+		return
+	}
+
+	client := make(chan string, 256)
+	h.mu.Lock()
+	h.clients[client] = struct{}{}
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.clients, client)
+		h.mu.Unlock()
+	}()
+
+	// Frames from the browser only matter for pings and close. Pong replies
+	// and broadcast frames share writeMu so they never interleave.
+	var writeMu sync.Mutex
+	lockedConn := writerFunc(func(p []byte) (int, error) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return conn.Write(p)
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, err := readWSMessage(rw.Reader, lockedConn, false); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case message := <-client:
+			if err := writeWSFrame(lockedConn, wsText, []byte(message), false); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+// wsPage is the shared live-tail page. Rendered entries keep their ANSI
+// colors, which are translated to HTML on the fly.
+const wsPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>LogPipe</title>
+<style>
+body { background: #1e1e1e; color: #d4d4d4; font: 13px/1.4 monospace; margin: 0; }
+#status { position: fixed; top: 0; right: 0; padding: 4px 8px; background: #333; }
+#log { padding: 8px; white-space: pre-wrap; word-break: break-all; }
+</style>
+</head>
+<body>
+<div id="status">connecting...</div>
+<div id="log"></div>
+<script>
+const colors = {30: "#808080", 31: "#f44747", 32: "#6a9955", 33: "#dcdcaa", 34: "#569cd6", 35: "#c586c0", 36: "#4ec9b0", 37: "#d4d4d4"};
+const maxLines = 5000;
+const log = document.getElementById("log");
+const status = document.getElementById("status");
+
+function escapeHtml(s) {
+  return s.replace(/&/g, "&amp;").replace(/</g, "&lt;").replace(/>/g, "&gt;");
+}
+
+function ansiToHtml(s) {
+  let open = false;
+  let html = escapeHtml(s).replace(/\x1b\[([\d;]*)m/g, (_, codes) => {
+    let style = "";
+    for (const code of codes.split(";").map(Number)) {
+      if (code === 1) style += "font-weight:bold;";
+      else if (code === 2) style += "opacity:0.6;";
+      else if (colors[code]) style += "color:" + colors[code] + ";";
+    }
+    const out = (open ? "</span>" : "") + (style ? '<span style="' + style + '">' : "");
+    open = style !== "";
+    return out;
+  });
+  return open ? html + "</span>" : html;
+}
+
+function connect() {
+  const ws = new WebSocket((location.protocol === "https:" ? "wss://" : "ws://") + location.host + "/ws");
+  ws.onopen = () => { status.textContent = "live"; };
+  ws.onclose = () => { status.textContent = "disconnected, retrying..."; setTimeout(connect, 2000); };
+  ws.onmessage = (e) => {
+    const atBottom = window.innerHeight + window.scrollY >= document.body.scrollHeight - 20;
+    const line = document.createElement("div");
+    line.innerHTML = ansiToHtml(e.data);
+    log.appendChild(line);
+    while (log.childElementCount > maxLines) log.firstChild.remove();
+    if (atBottom) window.scrollTo(0, document.body.scrollHeight);
+  };
+}
+connect();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWSAcceptKey(t *testing.T) {
+	// Example from RFC 6455, section 1.3
+	if got := wsAcceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("wsAcceptKey() = %q", got)
+	}
+}
+
+func TestWSFrameRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 125, 126, 70000} {
+		for _, mask := range []bool{false, true} {
+			payload := bytes.Repeat([]byte("x"), size)
+			var buf bytes.Buffer
+			if err := writeWSFrame(&buf, wsText, payload, mask); err != nil {
+				t.Fatal(err)
+			}
+			fin, opcode, got, err := readWSFrame(bufio.NewReader(&buf))
+			if err != nil {
+				t.Fatalf("size %d mask %v: %v", size, mask, err)
+			}
+			if !fin || opcode != wsText || !bytes.Equal(got, payload) {
+				t.Errorf("size %d mask %v: frame did not round-trip", size, mask)
+			}
+		}
+	}
+}
+
+func TestReadWSMessageFragmentsAndPing(t *testing.T) {
+	var in bytes.Buffer
+	in.Write([]byte{0x01, 3})
+	in.WriteString("foo")
+	in.Write([]byte{0x80 | wsPing, 1, 'p'})
+	in.Write([]byte{0x80 | wsContinuation, 3})
+	in.WriteString("bar")
+
+	var out bytes.Buffer
+	message, err := readWSMessage(bufio.NewReader(&in), &out, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(message) != "foobar" {
+		t.Errorf("message = %q, want foobar", message)
+	}
+	if !bytes.Equal(out.Bytes(), []byte{0x80 | wsPong, 1, 'p'}) {
+		t.Errorf("expected a pong reply, got %v", out.Bytes())
+	}
+
+	in.Write([]byte{0x80 | wsClose, 0})
+	if _, err := readWSMessage(bufio.NewReader(&in), io.Discard, false); err != io.EOF {
+		t.Errorf("expected io.EOF on close, got %v", err)
+	}
+}
+
+func TestWSHubBroadcast(t *testing.T) {
+	hub := newWSHub(nil)
+	ln, err := hub.listenAndServe("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(page), "new WebSocket") {
+		t.Error("Expected the live-tail page on /")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Keep broadcasting until the client has registered and received a line
+	go func() {
+		for ctx.Err() == nil {
+			hub.broadcast(`{"message":"hello"}`)
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	var lines []string
+	err = consumeWebSocket(ctx, "ws://"+ln.Addr().String()+"/ws", nil, func(line, label string) {
+		lines = append(lines, line)
+		cancel()
+	})
+	if err != nil {
+		t.Fatalf("consumeWebSocket() error = %v", err)
+	}
+	if len(lines) == 0 || lines[0] != `{"message":"hello"}` {
+		t.Errorf("lines = %v", lines)
+	}
+}
+
+func TestWSHubAllowOrigin(t *testing.T) {
+	hub := newWSHub([]string{"https://dash.example.com/", "grafana.internal:3000"})
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true},
+		{"http://localhost:8081", true},
+		{"http://LOCALHOST:8081", true},
+		{"https://dash.example.com", true},
+		{"http://grafana.internal:3000", true},
+		{"https://evil.example.com", false},
+		{"http://localhost:9999", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8081/ws", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := hub.allowOrigin(r); got != tt.want {
+				t.Errorf("allowOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWSHubRejectsReboundHost(t *testing.T) {
+	hub := newWSHub([]string{"https://dash.example.com"})
+	hub.host = "dev-box"
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"localhost:8081", true},
+		{"127.0.0.1:8081", true},
+		{"[::1]:8081", true},
+		{"192.168.1.20:8081", true},
+		{"dev-box:8081", true},
+		{"dash.example.com", true},
+		{"evil.example.com:8081", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://"+tt.host+"/ws", nil)
+			r.Header.Set("Origin", "http://"+tt.host)
+			if got := hub.allowOrigin(r); got != tt.want {
+				t.Errorf("allowOrigin() with Host and Origin %q = %v, want %v", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWSHubRejectsCrossSiteUpgrade(t *testing.T) {
+	hub := newWSHub(nil)
+	ln, err := hub.listenAndServe("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, "http://"+ln.Addr().String()+"/ws", nil)
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Origin", "https://evil.example.com")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+}
+
+func TestListenAddr(t *testing.T) {
+	for addr, want := range map[string]string{
+		":8081":        "127.0.0.1:8081",
+		"0.0.0.0:8081": "0.0.0.0:8081",
+		"[::1]:8081":   "[::1]:8081",
+	} {
+		if got := listenAddr(addr); got != want {
+			t.Errorf("listenAddr(%q) = %q, want %q", addr, got, want)
+		}
+	}
+}